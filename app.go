@@ -3,18 +3,26 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"sync"
 	"time"
 	
 	"cache_app/internal/config"
+	"cache_app/internal/ui"
 	"cache_app/pkg/safety"
+	"cache_app/pkg/artifacts"
 	"cache_app/pkg/backup"
+	"cache_app/pkg/budget"
 	"cache_app/pkg/deletion"
+	"cache_app/pkg/devcache"
+	"cache_app/pkg/recommend"
+	"cache_app/pkg/scheduler"
 )
 
 // App struct
@@ -22,14 +30,30 @@ type App struct {
 	ctx              context.Context
 	cacheScanner     *CacheScanner
 	lastScanResult   *CacheLocation
+	lastScanResults  *ScanResult // Last multi-location scan
 	backupSystem     *backup.BackupSystem
 	deletionService  *deletion.DeletionService
 	progressManager  *deletion.ProgressManager
 	confirmationService *deletion.ConfirmationService
 	settingsManager  *config.SettingsManager
+	remoteSecret     string // S3 secret key, kept in memory only
+	scheduler        *scheduler.Scheduler // Automatic cleanups, nil when disabled
 	mu               sync.RWMutex
 }
 
+var errorLogger *ui.AppLogger
+
+func init() {
+	logManager, err := ui.SetupDefaultLogging("logs")
+	if err != nil {
+		log.Printf("Warning: Failed to set up error logger: %v", err)
+		return
+	}
+	if errLogger, ok := logManager.GetLogger("error"); ok {
+		errorLogger = errLogger
+	}
+}
+
 // NewApp creates a new App application struct
 func NewApp() *App {
 	backupSystem, err := backup.NewBackupSystem()
@@ -55,7 +79,7 @@ func NewApp() *App {
 		settingsManager = nil
 	}
 	
-	return &App{
+	app := &App{
 		cacheScanner:        NewCacheScanner(),
 		backupSystem:        backupSystem,
 		deletionService:     deletionService,
@@ -63,6 +87,452 @@ func NewApp() *App {
 		confirmationService: confirmationService,
 		settingsManager:     settingsManager,
 	}
+	app.applyBackupSettings()
+	app.setupQuarantine()
+	app.setupTrash()
+	app.setupInUseDetection()
+	app.applySafetySettings()
+	if err := app.applyScheduleSettings(); err != nil {
+		log.Printf("Warning: Automatic cleanup unavailable: %v", err)
+	}
+
+	return app
+}
+
+// setupQuarantine creates the quarantine holding area used by the quarantine
+// deletion mode and starts the automatic purge of expired batches
+func (a *App) setupQuarantine() {
+	if a.deletionService == nil {
+		return
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		log.Printf("Warning: Quarantine unavailable: %v", err)
+		return
+	}
+	quarantine, err := deletion.NewQuarantine(filepath.Join(homeDir, "CacheCleaner", "Quarantine"), a.quarantineHoldPeriod())
+	if err != nil {
+		log.Printf("Warning: Quarantine unavailable: %v", err)
+		return
+	}
+
+	a.deletionService.SetQuarantine(quarantine)
+	quarantine.StartAutoPurge(time.Hour, func(err error) {
+		log.Printf("Warning: Failed to purge expired quarantine: %v", err)
+	})
+}
+
+// setupTrash enables the freedesktop trash deletion mode where it is supported
+func (a *App) setupTrash() {
+	if a.deletionService == nil || runtime.GOOS != "linux" {
+		return
+	}
+
+	trash, err := deletion.NewFreedesktopTrash()
+	if err != nil {
+		log.Printf("Warning: Trash unavailable: %v", err)
+		return
+	}
+	a.deletionService.SetTrash(trash)
+}
+
+// setupInUseDetection makes deletion check for files used by running processes
+// and for caches whose owning application, according to the catalog, is running
+func (a *App) setupInUseDetection() {
+	if a.deletionService == nil {
+		return
+	}
+
+	var owner func(path string) *deletion.OwnerApp
+	if locations, err := loadCatalog(); err != nil {
+		log.Printf("Warning: Cache owning applications unknown: %v", err)
+	} else {
+		owner = func(path string) *deletion.OwnerApp {
+			location := locations.Match(path)
+			if location == nil || (location.Application == "" && location.BundleID == "") {
+				return nil
+			}
+			return &deletion.OwnerApp{Name: location.Application, BundleID: location.BundleID}
+		}
+	}
+	a.deletionService.SetInUseChecker(deletion.NewInUseChecker(a.inUseAction(), owner))
+}
+
+// inUseAction returns what deletion does with files used by running processes
+func (a *App) inUseAction() deletion.InUseAction {
+	if a.settingsManager == nil {
+		return deletion.InUseBlock
+	}
+	action, err := deletion.ParseInUseAction(a.settingsManager.GetSettings().Safety.InUseAction)
+	if err != nil {
+		log.Printf("Warning: %v, blocking files in use", err)
+		return deletion.InUseBlock
+	}
+	return action
+}
+
+// safetyClassifier creates a classifier following the safety settings
+func (a *App) safetyClassifier() *safety.SafetyClassifier {
+	config := safety.DefaultConfig()
+	if a.settingsManager != nil {
+		safetySettings := a.settingsManager.GetSettings().Safety
+		config.ProtectDevFiles = safetySettings.ProtectDevFiles
+		if weights, err := config.Weights.With(safetySettings.ClassifierWeights); err != nil {
+			log.Printf("Warning: Using default classifier weights: %v", err)
+		} else {
+			config.Weights = weights
+		}
+	}
+	config.PathRules = a.pathRules()
+	return safety.NewSafetyClassifier(config)
+}
+
+// pathRules compiles the user allow and deny path rules, nil when there are none
+func (a *App) pathRules() *safety.PathRules {
+	if a.settingsManager == nil || len(a.settingsManager.GetSettings().PathRules) == 0 {
+		return nil
+	}
+	
+	var rules []safety.PathRule
+	for _, rule := range a.settingsManager.GetSettings().PathRules {
+		rules = append(rules, safety.PathRule{Pattern: rule.Pattern, Action: safety.RuleAction(rule.Action), Note: rule.Note})
+	}
+	homeDir, _ := os.UserHomeDir()
+	pathRules, err := safety.NewPathRules(rules, homeDir)
+	if err != nil {
+		log.Printf("Warning: Ignoring path rules: %v", err)
+		return nil
+	}
+	return pathRules
+}
+
+// newCacheScanner creates a scanner following the safety settings, for scans
+// that must not collide with the one started from the UI
+func (a *App) newCacheScanner() *CacheScanner {
+	scanner := NewCacheScanner()
+	scanner.SetSafetyClassifier(a.safetyClassifier())
+	scanner.SetPathRules(a.pathRules())
+	return scanner
+}
+
+// applySafetySettings pushes the current safety settings into the scanner and deletion service
+func (a *App) applySafetySettings() {
+	rules := a.pathRules()
+	if a.cacheScanner != nil {
+		a.cacheScanner.SetSafetyClassifier(a.safetyClassifier())
+		a.cacheScanner.SetPathRules(rules)
+	}
+	if a.deletionService == nil {
+		return
+	}
+	a.deletionService.SetSafetyClassifier(a.safetyClassifier())
+	a.deletionService.SetPathRules(rules)
+	if quarantine := a.deletionService.GetQuarantine(); quarantine != nil {
+		quarantine.SetHoldPeriod(a.quarantineHoldPeriod())
+	}
+	if checker := a.deletionService.GetInUseChecker(); checker != nil {
+		checker.SetAction(a.inUseAction())
+	}
+}
+
+// quarantineHoldPeriod returns the configured quarantine holding period
+func (a *App) quarantineHoldPeriod() time.Duration {
+	if a.settingsManager == nil {
+		return deletion.DefaultQuarantineHoldPeriod
+	}
+	return time.Duration(a.settingsManager.GetSettings().Safety.QuarantineHoldDays) * 24 * time.Hour
+}
+
+// deletionMode returns the deletion mode selected in the safety settings
+func (a *App) deletionMode() deletion.DeletionMode {
+	if a.settingsManager == nil {
+		return deletion.DeletionModeBackup
+	}
+	return deletion.DeletionMode(a.settingsManager.GetSettings().Safety.DeletionMode)
+}
+
+// atomicDeletion reports whether backup deletions should be all or nothing
+func (a *App) atomicDeletion() bool {
+	if a.settingsManager == nil {
+		return false
+	}
+	settings := a.settingsManager.GetSettings().Safety
+	return settings.AtomicDeletion && a.deletionMode() == deletion.DeletionModeBackup
+}
+
+// applyBackupSettings pushes the current backup settings into the backup system
+func (a *App) applyBackupSettings() {
+	if a.backupSystem == nil || a.settingsManager == nil {
+		return
+	}
+
+	settings := a.settingsManager.GetSettings().Backup
+	options := a.backupSystem.GetManager().GetOptions()
+
+	algorithm, err := backup.ParseCompressionAlgorithm(settings.CompressionAlgorithm)
+	if err != nil {
+		log.Printf("Warning: %v, using default", err)
+		algorithm = backup.DefaultCompressionOptions().Algorithm
+	}
+	level, err := backup.ParseCompressionLevel(settings.CompressionLevel)
+	if err != nil {
+		log.Printf("Warning: %v, using default", err)
+		level = backup.DefaultCompressionOptions().Level
+	}
+
+	options.Compression.Enabled = settings.CompressBackups
+	options.Compression.Algorithm = algorithm
+	options.Compression.Level = level
+	options.Encryption.Enabled = settings.EncryptBackups
+	options.Encryption.KeyFile = settings.EncryptionKeyFile
+	a.backupSystem.GetManager().SetOptions(options)
+
+	remote, err := a.newRemoteBackend(settings)
+	if err != nil {
+		log.Printf("Warning: Remote backup target unavailable: %v", err)
+		remote = nil
+	}
+	a.backupSystem.GetManager().SetRemote(remote, nil)
+
+	a.backupSystem.GetManager().SetRetentionPolicy(&backup.RetentionPolicy{
+		MaxAge:       time.Duration(settings.RetentionDays) * 24 * time.Hour,
+		MaxTotalSize: settings.MaxBackupSize * 1024 * 1024,
+		MaxSessions:  settings.MaxSessions,
+		KeepLast:     settings.KeepLast,
+		KeepDaily:    settings.KeepDaily,
+		KeepWeekly:   settings.KeepWeekly,
+		KeepMonthly:  settings.KeepMonthly,
+		KeepYearly:   settings.KeepYearly,
+		AutoApply:    settings.AutoCleanup,
+	})
+}
+
+// newRemoteBackend creates the storage backend selected in the backup settings
+func (a *App) newRemoteBackend(settings config.BackupSettings) (backup.StorageBackend, error) {
+	switch settings.RemoteBackend {
+	case "local":
+		return backup.NewLocalBackend(settings.RemotePath)
+	case "network":
+		return backup.NewNetworkPathBackend(settings.RemotePath)
+	case "s3":
+		a.mu.RLock()
+		secret := a.remoteSecret
+		a.mu.RUnlock()
+		if secret == "" {
+			secret = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		}
+		return backup.NewS3Backend(backup.S3Config{
+			Endpoint:        settings.S3Endpoint,
+			Region:          settings.S3Region,
+			Bucket:          settings.S3Bucket,
+			Prefix:          settings.S3Prefix,
+			AccessKeyID:     settings.S3AccessKeyID,
+			SecretAccessKey: secret,
+			PathStyle:       settings.S3PathStyle,
+			StateFile:       filepath.Join(a.backupSystem.GetManager().GetBackupDir(), "metadata", "s3_uploads.json"),
+		})
+	default:
+		return nil, nil
+	}
+}
+
+// SetBackupRemoteSecret sets the S3 secret access key. The secret is kept in memory only.
+func (a *App) SetBackupRemoteSecret(secret string) (string, error) {
+	if a.backupSystem == nil {
+		return "", fmt.Errorf("backup system not available")
+	}
+
+	a.mu.Lock()
+	a.remoteSecret = secret
+	a.mu.Unlock()
+	a.applyBackupSettings()
+
+	result := map[string]interface{}{
+		"status":  "success",
+		"message": "Remote backup credentials updated",
+	}
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return string(jsonResult), nil
+}
+
+// SyncBackupsToRemote uploads backup sessions that are not yet on the remote target
+func (a *App) SyncBackupsToRemote() (string, error) {
+	if a.backupSystem == nil {
+		return "", fmt.Errorf("backup system not available")
+	}
+
+	result, err := a.backupSystem.SyncToRemote()
+	if err != nil {
+		log.Printf("Error syncing backups to remote: %v", err)
+		return "", err
+	}
+
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal sync result: %w", err)
+	}
+
+	log.Printf("Synced backups to %s: %d files uploaded", result.Backend, result.UploadedFiles)
+	return string(jsonResult), nil
+}
+
+// PullBackupsFromRemote makes sessions stored on the remote target available for restore
+func (a *App) PullBackupsFromRemote(downloadFiles bool) (string, error) {
+	if a.backupSystem == nil {
+		return "", fmt.Errorf("backup system not available")
+	}
+
+	result, err := a.backupSystem.PullFromRemote(downloadFiles)
+	if err != nil {
+		log.Printf("Error pulling backups from remote: %v", err)
+		return "", err
+	}
+
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal pull result: %w", err)
+	}
+
+	log.Printf("Pulled %d backup sessions from %s", result.ImportedSessions, result.Backend)
+	return string(jsonResult), nil
+}
+
+// SetBackupPassphrase sets the passphrase used to encrypt and unlock backups.
+// The passphrase is kept in memory only.
+func (a *App) SetBackupPassphrase(passphrase string) (string, error) {
+	if a.backupSystem == nil {
+		return "", fmt.Errorf("backup system not available")
+	}
+
+	manager := a.backupSystem.GetManager()
+	options := manager.GetOptions()
+	options.Encryption.Passphrase = passphrase
+	manager.SetOptions(options)
+
+	result := map[string]interface{}{
+		"status":   "success",
+		"unlocked": false,
+	}
+	if err := manager.VerifyEncryptionKey(); err == nil {
+		result["unlocked"] = true
+	} else if errors.Is(err, backup.ErrEncryptionKeyMismatch) {
+		return "", err
+	}
+
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return string(jsonResult), nil
+}
+
+// GenerateBackupKeyFile creates a new random key file for backup encryption
+func (a *App) GenerateBackupKeyFile(path string) (string, error) {
+	if err := backup.GenerateKeyFile(path); err != nil {
+		return "", err
+	}
+
+	result := map[string]interface{}{
+		"status":   "success",
+		"key_file": path,
+	}
+
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	log.Printf("Generated backup key file: %s", path)
+	return string(jsonResult), nil
+}
+
+// RotateBackupEncryptionKey re-encrypts all backups with a new passphrase or key file
+func (a *App) RotateBackupEncryptionKey(newPassphrase string, newKeyFile string) (string, error) {
+	if a.backupSystem == nil {
+		return "", fmt.Errorf("backup system not available")
+	}
+
+	result, err := a.backupSystem.RotateEncryptionKey(backup.EncryptionOptions{
+		Enabled:    true,
+		Passphrase: newPassphrase,
+		KeyFile:    newKeyFile,
+	})
+	if err != nil {
+		log.Printf("Error rotating backup encryption key: %v", err)
+		return "", err
+	}
+
+	// Keep settings in line with the key source now protecting the store
+	if a.settingsManager != nil {
+		backupSettings := a.settingsManager.GetSettings().Backup
+		backupSettings.EncryptBackups = true
+		backupSettings.EncryptionKeyFile = ""
+		if newPassphrase == "" {
+			backupSettings.EncryptionKeyFile = newKeyFile
+		}
+		if err := a.settingsManager.UpdateBackupSettings(backupSettings); err != nil {
+			log.Printf("Warning: Failed to persist encryption settings: %v", err)
+		}
+	}
+
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal rotation result: %w", err)
+	}
+
+	log.Printf("Rotated backup encryption key: %d blobs re-encrypted", result.RotatedBlobs)
+	return string(jsonResult), nil
+}
+
+// ExportBackupSession writes a backup session and its files into a portable archive
+func (a *App) ExportBackupSession(sessionID string, destPath string) (string, error) {
+	if a.backupSystem == nil {
+		return "", fmt.Errorf("backup system not available")
+	}
+
+	info, err := a.backupSystem.ExportSession(sessionID, destPath)
+	if err != nil {
+		log.Printf("Error exporting backup session %s: %v", sessionID, err)
+		return "", err
+	}
+
+	jsonResult, err := json.Marshal(info)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal archive info: %w", err)
+	}
+
+	log.Printf("Exported backup session %s to %s", sessionID, destPath)
+	return string(jsonResult), nil
+}
+
+// ImportBackupArchive imports a session archive into the backup store. The
+// passphrase or key file is only needed for archives encrypted with another key.
+func (a *App) ImportBackupArchive(archivePath string, renameOnConflict bool, passphrase string, keyFile string) (string, error) {
+	if a.backupSystem == nil {
+		return "", fmt.Errorf("backup system not available")
+	}
+
+	result, err := a.backupSystem.ImportArchive(archivePath, backup.ImportOptions{
+		RenameOnConflict: renameOnConflict,
+		SourceEncryption: backup.EncryptionOptions{Passphrase: passphrase, KeyFile: keyFile},
+	})
+	if err != nil {
+		log.Printf("Error importing backup archive %s: %v", archivePath, err)
+		return "", err
+	}
+
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal import result: %w", err)
+	}
+
+	log.Printf("Imported backup archive %s as session %s", archivePath, result.SessionID)
+	return string(jsonResult), nil
 }
 
 // startup is called when the app starts. The context is saved
@@ -148,6 +618,10 @@ func (a *App) ScanMultipleCacheLocations(locationsJSON string) (string, error) {
 		return "", err
 	}
 	
+	a.mu.Lock()
+	a.lastScanResults = result
+	a.mu.Unlock()
+	
 	resultJSON, err := result.ToJSON()
 	if err != nil {
 		log.Printf("Error serializing scan result: %v", err)
@@ -266,204 +740,679 @@ func (a *App) GetCacheLocationsFromConfig() (string, error) {
 		}{ID: loc.ID, Name: loc.Name, Path: loc.Path, Type: "user"})
 	}
 	
-	for _, loc := range config.ApplicationCaches {
-		allLocations = append(allLocations, struct {
-			ID   string `json:"id"`
-			Name string `json:"name"`
-			Path string `json:"path"`
-			Type string `json:"type"`
-		}{ID: loc.ID, Name: loc.Name, Path: loc.Path, Type: "application"})
+	for _, loc := range config.ApplicationCaches {
+		allLocations = append(allLocations, struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			Path string `json:"path"`
+			Type string `json:"type"`
+		}{ID: loc.ID, Name: loc.Name, Path: loc.Path, Type: "application"})
+	}
+	
+	result, err := json.Marshal(allLocations)
+	if err != nil {
+		log.Printf("Error marshaling locations: %v", err)
+		return "", fmt.Errorf("failed to marshal locations: %w", err)
+	}
+	
+	log.Printf("Returning %d locations to frontend", len(allLocations))
+	if len(allLocations) > 0 {
+		log.Printf("Sample location: %+v", allLocations[0])
+	} else {
+		log.Printf("No locations found")
+	}
+	
+	return string(result), nil
+}
+
+// GetCacheLocationInfo returns detailed information about a specific cache location
+func (a *App) GetCacheLocationInfo(locationID string) (string, error) {
+	configPath := filepath.Join(".", "cache_locations.json")
+	
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read config file: %w", err)
+	}
+	
+	// Parse the entire config to find the specific location
+	var config map[string]interface{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", fmt.Errorf("failed to parse config file: %w", err)
+	}
+	
+	// Search through all location arrays for the matching ID
+	locationArrays := []string{"system_caches", "user_caches", "application_caches", "special_locations"}
+	
+	for _, arrayName := range locationArrays {
+		if locations, ok := config[arrayName].([]interface{}); ok {
+			for _, loc := range locations {
+				if locationMap, ok := loc.(map[string]interface{}); ok {
+					if id, exists := locationMap["id"]; exists && id == locationID {
+						result, err := json.Marshal(locationMap)
+						if err != nil {
+							return "", fmt.Errorf("failed to marshal location info: %w", err)
+						}
+						return string(result), nil
+					}
+				}
+			}
+		}
+	}
+	
+	return "", fmt.Errorf("location with ID %s not found", locationID)
+}
+
+// GetSystemInfo returns basic system information
+func (a *App) GetSystemInfo() (string, error) {
+	info := map[string]interface{}{
+		"os":           "macOS",
+		"scan_time":    time.Now().Format(time.RFC3339),
+		"app_version":  "1.0.0",
+		"go_version":   "1.23+",
+	}
+	
+	result, err := json.Marshal(info)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal system info: %w", err)
+	}
+	
+	return string(result), nil
+}
+
+// GetSafetyClassificationSummary returns safety classification summary for a specific location
+func (a *App) GetSafetyClassificationSummary(locationID string) (string, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	
+	if a.lastScanResult == nil {
+		return "", fmt.Errorf("no scan result available")
+	}
+	
+	if a.lastScanResult.ID != locationID {
+		return "", fmt.Errorf("location ID %s does not match last scan result", locationID)
+	}
+	
+	summary := a.lastScanResult.GetSafetyClassificationSummary()
+	result, err := json.Marshal(summary)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal safety classification summary: %w", err)
+	}
+	
+	return string(result), nil
+}
+
+// ClassifyFileSafety classifies the safety of a specific file
+func (a *App) ClassifyFileSafety(filePath string) (string, error) {
+	// Get file info
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to get file info for %s: %w", filePath, err)
+	}
+	
+	// Create file metadata
+	fileMetadata := safety.FileMetadata{
+		Name:         info.Name(),
+		Path:         filePath,
+		Size:         info.Size(),
+		LastModified: info.ModTime(),
+		LastAccessed: backup.LastAccessTime(info),
+		IsDir:        info.IsDir(),
+		Permissions:  info.Mode().String(),
+	}
+	
+	// Classify the file
+	classifier := a.safetyClassifier()
+	classification := classifier.ClassifyFile(fileMetadata)
+	
+	result, err := json.Marshal(classification)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal classification result: %w", err)
+	}
+	
+	return string(result), nil
+}
+
+// GetSafetyClassificationRules returns the current safety classification rules
+func (a *App) GetSafetyClassificationRules() (string, error) {
+	config := safety.DefaultConfig()
+	
+	rules := map[string]interface{}{
+		"safe_age_threshold_days":    int(config.SafeAgeThreshold.Hours() / 24),
+		"caution_age_threshold_days": int(config.CautionAgeThreshold.Hours() / 24),
+		"large_file_threshold_mb":    config.LargeFileThreshold / (1024 * 1024),
+		"system_critical_paths":      config.SystemCriticalPaths,
+		"temp_dir_patterns":          config.TempDirPatterns,
+		"dev_cache_patterns":         config.DevCachePatterns,
+		"sniff_content":              config.SniffContent,
+		"user_content_patterns":      config.UserContentPatterns,
+		"protect_dev_files":          config.ProtectDevFiles,
+		"weights":                    config.Weights,
+		"safe_confidence":            config.SafeConfidence,
+		"caution_confidence":         config.CautionConfidence,
+	}
+	
+	result, err := json.Marshal(rules)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal classification rules: %w", err)
+	}
+	
+	return string(result), nil
+}
+
+// GetClassifierWeights returns the classifier signal weights in effect and their defaults
+func (a *App) GetClassifierWeights() (string, error) {
+	weights := safety.DefaultWeights()
+	if a.settingsManager != nil {
+		var err error
+		if weights, err = weights.With(a.settingsManager.GetSettings().Safety.ClassifierWeights); err != nil {
+			return "", fmt.Errorf("invalid classifier weights in settings: %w", err)
+		}
+	}
+	
+	result := map[string]interface{}{
+		"weights":  weights,
+		"defaults": safety.DefaultWeights(),
+	}
+	
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal classifier weights: %w", err)
+	}
+	
+	return string(jsonResult), nil
+}
+
+// UpdateClassifierWeights overrides the weights of classifier signals, an
+// empty object restoring the defaults
+func (a *App) UpdateClassifierWeights(weightsJSON string) (string, error) {
+	if a.settingsManager == nil {
+		return "", fmt.Errorf("settings manager not available")
+	}
+	
+	var overrides map[string]int
+	if err := json.Unmarshal([]byte(weightsJSON), &overrides); err != nil {
+		return "", fmt.Errorf("invalid classifier weights JSON: %w", err)
+	}
+	weights, err := safety.DefaultWeights().With(overrides)
+	if err != nil {
+		return "", fmt.Errorf("invalid classifier weights: %w", err)
+	}
+	
+	safetySettings := a.settingsManager.GetSettings().Safety
+	safetySettings.ClassifierWeights = overrides
+	if err := a.settingsManager.UpdateSafetySettings(safetySettings); err != nil {
+		return "", fmt.Errorf("failed to update classifier weights: %w", err)
+	}
+	a.applySafetySettings()
+	
+	result := map[string]interface{}{
+		"status":  "success",
+		"message": "Classifier weights updated successfully",
+		"weights": weights,
+	}
+	
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+	
+	return string(jsonResult), nil
+}
+
+// EvaluateClassifier classifies a labeled corpus of paths with the current
+// settings and reports precision and recall per safety level
+func (a *App) EvaluateClassifier(corpusPath string) (string, error) {
+	corpus, err := safety.LoadCorpus(corpusPath)
+	if err != nil {
+		return "", err
+	}
+	
+	evaluation := a.safetyClassifier().Evaluate(corpus)
+	log.Printf("Evaluated classifier on %d of %d paths: %.1f%% accurate", evaluation.Evaluated, evaluation.Total, evaluation.Accuracy*100)
+	
+	result, err := json.Marshal(evaluation)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal evaluation: %w", err)
+	}
+	
+	return string(result), nil
+}
+
+// GetFilesBySafetyLevel returns files filtered by safety level from the last scan
+func (a *App) GetFilesBySafetyLevel(locationID, safetyLevel string) (string, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	
+	if a.lastScanResult == nil {
+		return "", fmt.Errorf("no scan result available")
+	}
+	
+	if a.lastScanResult.ID != locationID {
+		return "", fmt.Errorf("location ID %s does not match last scan result", locationID)
+	}
+	
+	var filteredFiles []CacheFile
+	var targetLevel safety.SafetyLevel
+	
+	switch safetyLevel {
+	case "Safe":
+		targetLevel = safety.Safe
+	case "Caution":
+		targetLevel = safety.Caution
+	case "Risky":
+		targetLevel = safety.Risky
+	default:
+		return "", fmt.Errorf("invalid safety level: %s. Must be Safe, Caution, or Risky", safetyLevel)
+	}
+	
+	for _, file := range a.lastScanResult.Files {
+		if !file.IsDir && file.SafetyClassification != nil && file.SafetyClassification.Level == targetLevel {
+			filteredFiles = append(filteredFiles, file)
+		}
+	}
+	
+	result := map[string]interface{}{
+		"location_id":     locationID,
+		"safety_level":    safetyLevel,
+		"files":           filteredFiles,
+		"total_files":     len(filteredFiles),
+	}
+	
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal filtered files result: %w", err)
+	}
+	
+	return string(jsonResult), nil
+}
+
+// RecommendCleanup proposes the least risky files from the last scans that free targetBytes
+func (a *App) RecommendCleanup(targetBytes int64) (string, error) {
+	a.mu.RLock()
+	var locations []recommend.ScannedLocation
+	scannedIDs := make(map[string]bool)
+	if a.lastScanResults != nil {
+		for i := range a.lastScanResults.Locations {
+			locations = append(locations, a.lastScanResults.Locations[i].ToScannedLocation())
+			scannedIDs[a.lastScanResults.Locations[i].ID] = true
+		}
+	}
+	if a.lastScanResult != nil && !scannedIDs[a.lastScanResult.ID] {
+		locations = append(locations, a.lastScanResult.ToScannedLocation())
+	}
+	a.mu.RUnlock()
+	
+	if len(locations) == 0 {
+		return "", fmt.Errorf("no scan result available")
+	}
+	
+	locationCatalog, err := loadCatalog()
+	if err != nil {
+		return "", err
+	}
+	
+	recommendation := recommend.NewEngine(locationCatalog).Recommend(locations, recommend.Options{
+		TargetBytes: targetBytes,
+		MaxLevel:    safety.Caution,
+	})
+	
+	result, err := json.Marshal(recommendation)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal recommendation: %w", err)
+	}
+	
+	log.Printf("Recommended %d items freeing %d of %d bytes", len(recommendation.Items), recommendation.SelectedBytes, targetBytes)
+	return string(result), nil
+}
+
+// GetBudgets returns the configured location size budgets
+func (a *App) GetBudgets() (string, error) {
+	if a.settingsManager == nil {
+		return "", fmt.Errorf("settings manager not available")
+	}
+	
+	result, err := json.Marshal(a.settingsManager.GetSettings().Budgets)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal budgets: %w", err)
+	}
+	
+	return string(result), nil
+}
+
+// UpdateBudgets replaces the location size budgets
+func (a *App) UpdateBudgets(budgetsJSON string) (string, error) {
+	if a.settingsManager == nil {
+		return "", fmt.Errorf("settings manager not available")
+	}
+	
+	var budgets []config.LocationBudget
+	if err := json.Unmarshal([]byte(budgetsJSON), &budgets); err != nil {
+		return "", fmt.Errorf("invalid budgets JSON: %w", err)
+	}
+	
+	if err := a.settingsManager.UpdateBudgets(budgets); err != nil {
+		return "", fmt.Errorf("failed to update budgets: %w", err)
+	}
+	
+	result := map[string]interface{}{
+		"status":  "success",
+		"message": "Budgets updated successfully",
+		"budgets": a.settingsManager.GetSettings().Budgets,
+	}
+	
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+	
+	return string(jsonResult), nil
+}
+
+// GetPathRules returns the user allow and deny path rules
+func (a *App) GetPathRules() (string, error) {
+	if a.settingsManager == nil {
+		return "", fmt.Errorf("settings manager not available")
+	}
+	
+	result, err := json.Marshal(a.settingsManager.GetSettings().PathRules)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal path rules: %w", err)
+	}
+	
+	return string(result), nil
+}
+
+// UpdatePathRules replaces the user allow and deny path rules
+func (a *App) UpdatePathRules(rulesJSON string) (string, error) {
+	if a.settingsManager == nil {
+		return "", fmt.Errorf("settings manager not available")
+	}
+	
+	var rules []config.PathRule
+	if err := json.Unmarshal([]byte(rulesJSON), &rules); err != nil {
+		return "", fmt.Errorf("invalid path rules JSON: %w", err)
+	}
+	
+	if err := a.settingsManager.UpdatePathRules(rules); err != nil {
+		return "", fmt.Errorf("failed to update path rules: %w", err)
+	}
+	a.applySafetySettings()
+	
+	result := map[string]interface{}{
+		"status":     "success",
+		"message":    "Path rules updated successfully",
+		"path_rules": a.settingsManager.GetSettings().PathRules,
+	}
+	
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+	
+	return string(jsonResult), nil
+}
+
+// ExplainPathRules tells which path rules match a path and which one decides
+func (a *App) ExplainPathRules(path string) (string, error) {
+	expandedPath, err := expandTilde(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to expand path %s: %w", path, err)
+	}
+	
+	result, err := json.Marshal(a.pathRules().Explain(expandedPath))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal path rule explanation: %w", err)
+	}
+	
+	return string(result), nil
+}
+
+// EnforceBudgets evicts the least recently used files of every enabled budget
+// location until it fits its budget. Dry runs only report what would be evicted.
+func (a *App) EnforceBudgets(dryRun bool) (string, error) {
+	if a.settingsManager == nil {
+		return "", fmt.Errorf("settings manager not available")
+	}
+	if !dryRun && a.deletionService == nil {
+		return "", fmt.Errorf("deletion service not available")
+	}
+	
+	settings := a.settingsManager.GetSettings()
+	scanner := a.newCacheScanner()
+	reports := make([]*budget.Report, 0, len(settings.Budgets))
+	for _, locationBudget := range settings.Budgets {
+		if !locationBudget.Enabled {
+			continue
+		}
+		
+		limit := budget.Budget{Path: locationBudget.Path, MaxBytes: locationBudget.MaxSizeMB * 1024 * 1024}
+		scanned, err := scanner.ScanLocation("budget", filepath.Base(locationBudget.Path), locationBudget.Path)
+		if err != nil {
+			reports = append(reports, &budget.Report{Path: limit.Path, BudgetBytes: limit.MaxBytes, DryRun: dryRun, Errors: []string{err.Error()}})
+			continue
+		}
+		
+		report := budget.Plan(limit, scanned.ToScannedLocation())
+		if scanned.Error != "" {
+			report.Errors = append(report.Errors, scanned.Error)
+		}
+		if !dryRun && len(report.Evicted) > 0 {
+			report.Record(a.deletionService.DeleteFilesWithBackup(&deletion.DeletionRequest{
+				Files:     report.Files(),
+				Operation: "budget_eviction",
+				Mode:      deletion.DeletionMode(settings.Safety.DeletionMode),
+			}))
+		}
+		reports = append(reports, report)
+		log.Printf("Budget %s: %d of %d bytes, evicting %d files (%d bytes)", limit.Path, report.SizeBefore, limit.MaxBytes, len(report.Evicted), report.EvictedBytes)
+	}
+	
+	result, err := json.Marshal(reports)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal budget reports: %w", err)
 	}
 	
-	result, err := json.Marshal(allLocations)
+	return string(result), nil
+}
+
+// GetDevToolCaches lists the caches of installed developer tools with their sizes
+func (a *App) GetDevToolCaches() (string, error) {
+	sys, err := devcache.NewSystem()
 	if err != nil {
-		log.Printf("Error marshaling locations: %v", err)
-		return "", fmt.Errorf("failed to marshal locations: %w", err)
+		return "", err
 	}
 	
-	log.Printf("Returning %d locations to frontend", len(allLocations))
-	if len(allLocations) > 0 {
-		log.Printf("Sample location: %+v", allLocations[0])
-	} else {
-		log.Printf("No locations found")
+	caches := devcache.NewManager(sys, nil).Discover()
+	result, err := json.Marshal(caches)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal developer caches: %w", err)
 	}
 	
+	log.Printf("Found %d developer tool caches", len(caches))
 	return string(result), nil
 }
 
-// GetCacheLocationInfo returns detailed information about a specific cache location
-func (a *App) GetCacheLocationInfo(locationID string) (string, error) {
-	configPath := filepath.Join(".", "cache_locations.json")
+// CleanDevToolCache cleans a developer tool cache with the tool's own command,
+// deleting its files through the deletion service when the tool cannot
+func (a *App) CleanDevToolCache(provider string, kind string) (string, error) {
+	if a.deletionService == nil {
+		return "", fmt.Errorf("deletion service not available")
+	}
 	
-	data, err := os.ReadFile(configPath)
+	sys, err := devcache.NewSystem()
 	if err != nil {
-		return "", fmt.Errorf("failed to read config file: %w", err)
+		return "", err
 	}
 	
-	// Parse the entire config to find the specific location
-	var config map[string]interface{}
-	if err := json.Unmarshal(data, &config); err != nil {
-		return "", fmt.Errorf("failed to parse config file: %w", err)
+	mode := deletion.DeletionModeBackup
+	if a.settingsManager != nil {
+		mode = deletion.DeletionMode(a.settingsManager.GetSettings().Safety.DeletionMode)
+	}
+	remove := func(paths []string) error {
+		result, err := a.deletionService.DeleteFilesWithBackup(&deletion.DeletionRequest{
+			Files:     paths,
+			Operation: "dev_cache_" + provider,
+			Mode:      mode,
+		})
+		if err != nil {
+			return err
+		}
+		if result.FailedCount > 0 || len(result.SkippedFiles) > 0 {
+			return fmt.Errorf("%d of %d paths were not deleted", result.FailedCount+len(result.SkippedFiles), len(paths))
+		}
+		return nil
 	}
 	
-	// Search through all location arrays for the matching ID
-	locationArrays := []string{"system_caches", "user_caches", "application_caches", "special_locations"}
+	cleanResult, err := devcache.NewManager(sys, remove).Clean(provider, kind)
+	if err != nil {
+		return "", err
+	}
 	
-	for _, arrayName := range locationArrays {
-		if locations, ok := config[arrayName].([]interface{}); ok {
-			for _, loc := range locations {
-				if locationMap, ok := loc.(map[string]interface{}); ok {
-					if id, exists := locationMap["id"]; exists && id == locationID {
-						result, err := json.Marshal(locationMap)
-						if err != nil {
-							return "", fmt.Errorf("failed to marshal location info: %w", err)
-						}
-						return string(result), nil
-					}
-				}
-			}
-		}
+	result, err := json.Marshal(cleanResult)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal clean result: %w", err)
 	}
 	
-	return "", fmt.Errorf("location with ID %s not found", locationID)
+	log.Printf("Cleaned %s %s cache (%s): %d bytes freed", provider, kind, cleanResult.Method, cleanResult.FreedBytes)
+	return string(result), nil
 }
 
-// GetSystemInfo returns basic system information
-func (a *App) GetSystemInfo() (string, error) {
-	info := map[string]interface{}{
-		"os":           "macOS",
-		"scan_time":    time.Now().Format(time.RFC3339),
-		"app_version":  "1.0.0",
-		"go_version":   "1.23+",
+// GetWorkspaceSettings returns where project build artifacts are searched
+func (a *App) GetWorkspaceSettings() (string, error) {
+	if a.settingsManager == nil {
+		return "", fmt.Errorf("settings manager not available")
 	}
 	
-	result, err := json.Marshal(info)
+	result, err := json.Marshal(a.settingsManager.GetSettings().Workspaces)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal system info: %w", err)
+		return "", fmt.Errorf("failed to marshal workspace settings: %w", err)
 	}
 	
 	return string(result), nil
 }
 
-// GetSafetyClassificationSummary returns safety classification summary for a specific location
-func (a *App) GetSafetyClassificationSummary(locationID string) (string, error) {
-	a.mu.RLock()
-	defer a.mu.RUnlock()
+// UpdateWorkspaceSettings updates where project build artifacts are searched
+func (a *App) UpdateWorkspaceSettings(workspaceSettingsJSON string) (string, error) {
+	if a.settingsManager == nil {
+		return "", fmt.Errorf("settings manager not available")
+	}
 	
-	if a.lastScanResult == nil {
-		return "", fmt.Errorf("no scan result available")
+	var workspaceSettings config.WorkspaceSettings
+	if err := json.Unmarshal([]byte(workspaceSettingsJSON), &workspaceSettings); err != nil {
+		return "", fmt.Errorf("invalid workspace settings JSON: %w", err)
 	}
 	
-	if a.lastScanResult.ID != locationID {
-		return "", fmt.Errorf("location ID %s does not match last scan result", locationID)
+	if err := a.settingsManager.UpdateWorkspaceSettings(workspaceSettings); err != nil {
+		return "", fmt.Errorf("failed to update workspace settings: %w", err)
 	}
 	
-	summary := a.lastScanResult.GetSafetyClassificationSummary()
-	result, err := json.Marshal(summary)
+	result := map[string]interface{}{
+		"status":   "success",
+		"message":  "Workspace settings updated successfully",
+		"settings": a.settingsManager.GetSettings().Workspaces,
+	}
+	
+	jsonResult, err := json.Marshal(result)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal safety classification summary: %w", err)
+		return "", fmt.Errorf("failed to marshal result: %w", err)
 	}
 	
-	return string(result), nil
+	return string(jsonResult), nil
 }
 
-// ClassifyFileSafety classifies the safety of a specific file
-func (a *App) ClassifyFileSafety(filePath string) (string, error) {
-	// Get file info
-	info, err := os.Stat(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to get file info for %s: %w", filePath, err)
+// findProjectArtifacts walks the configured workspace roots for build artifacts
+func (a *App) findProjectArtifacts() (*artifacts.Report, error) {
+	if a.settingsManager == nil {
+		return nil, fmt.Errorf("settings manager not available")
 	}
 	
-	// Create file metadata
-	fileMetadata := safety.FileMetadata{
-		Name:         info.Name(),
-		Path:         filePath,
-		Size:         info.Size(),
-		LastModified: info.ModTime(),
-		LastAccessed: getLastAccessTime(info),
-		IsDir:        info.IsDir(),
-		Permissions:  info.Mode().String(),
+	workspaces := a.settingsManager.GetSettings().Workspaces
+	if len(workspaces.Roots) == 0 {
+		return nil, fmt.Errorf("no workspace roots configured")
 	}
 	
-	// Classify the file
-	classifier := safety.NewDefaultSafetyClassifier()
-	classification := classifier.ClassifyFile(fileMetadata)
-	
-	result, err := json.Marshal(classification)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal classification result: %w", err)
+	roots := make([]string, 0, len(workspaces.Roots))
+	for _, root := range workspaces.Roots {
+		expanded, err := expandTilde(root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand path %s: %w", root, err)
+		}
+		roots = append(roots, expanded)
 	}
 	
-	return string(result), nil
+	return artifacts.NewFinder(artifacts.DefaultRules(), workspaces.MaxDepth).Find(roots), nil
 }
 
-// GetSafetyClassificationRules returns the current safety classification rules
-func (a *App) GetSafetyClassificationRules() (string, error) {
-	config := safety.DefaultConfig()
-	
-	rules := map[string]interface{}{
-		"safe_age_threshold_days":    int(config.SafeAgeThreshold.Hours() / 24),
-		"caution_age_threshold_days": int(config.CautionAgeThreshold.Hours() / 24),
-		"large_file_threshold_mb":    config.LargeFileThreshold / (1024 * 1024),
-		"system_critical_paths":      config.SystemCriticalPaths,
-		"temp_dir_patterns":          config.TempDirPatterns,
-		"dev_cache_patterns":         config.DevCachePatterns,
+// FindProjectArtifacts lists node_modules, target and other build directories
+// of the projects under the workspace roots with their last activity
+func (a *App) FindProjectArtifacts() (string, error) {
+	report, err := a.findProjectArtifacts()
+	if err != nil {
+		return "", err
 	}
 	
-	result, err := json.Marshal(rules)
+	result, err := json.Marshal(report)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal classification rules: %w", err)
+		return "", fmt.Errorf("failed to marshal artifact report: %w", err)
 	}
 	
+	log.Printf("Found %d project artifacts using %d bytes", len(report.Artifacts), report.TotalSize)
 	return string(result), nil
 }
 
-// GetFilesBySafetyLevel returns files filtered by safety level from the last scan
-func (a *App) GetFilesBySafetyLevel(locationID, safetyLevel string) (string, error) {
-	a.mu.RLock()
-	defer a.mu.RUnlock()
-	
-	if a.lastScanResult == nil {
-		return "", fmt.Errorf("no scan result available")
-	}
-	
-	if a.lastScanResult.ID != locationID {
-		return "", fmt.Errorf("location ID %s does not match last scan result", locationID)
+// CleanProjectArtifacts deletes the build artifacts of projects inactive for at
+// least inactiveDays, or the configured number of days when it is not positive
+func (a *App) CleanProjectArtifacts(inactiveDays int, dryRun bool) (string, error) {
+	if a.deletionService == nil {
+		return "", fmt.Errorf("deletion service not available")
 	}
 	
-	var filteredFiles []CacheFile
-	var targetLevel safety.SafetyLevel
-	
-	switch safetyLevel {
-	case "Safe":
-		targetLevel = safety.Safe
-	case "Caution":
-		targetLevel = safety.Caution
-	case "Risky":
-		targetLevel = safety.Risky
-	default:
-		return "", fmt.Errorf("invalid safety level: %s. Must be Safe, Caution, or Risky", safetyLevel)
+	report, err := a.findProjectArtifacts()
+	if err != nil {
+		return "", err
 	}
 	
-	for _, file := range a.lastScanResult.Files {
-		if !file.IsDir && file.SafetyClassification != nil && file.SafetyClassification.Level == targetLevel {
-			filteredFiles = append(filteredFiles, file)
+	settings := a.settingsManager.GetSettings()
+	if inactiveDays <= 0 {
+		inactiveDays = settings.Workspaces.InactiveDays
+	}
+	stale := artifacts.Stale(report.Artifacts, inactiveDays)
+	
+	var selectedBytes int64
+	files := make([]string, 0, len(stale))
+	for _, artifact := range stale {
+		files = append(files, artifact.Path)
+		selectedBytes += artifact.Size
+	}
+	
+	var deletionResult *deletion.DeletionResult
+	if len(files) > 0 {
+		deletionResult, err = a.deletionService.DeleteFilesWithBackup(&deletion.DeletionRequest{
+			Files:     files,
+			Operation: "project_artifacts",
+			DryRun:    dryRun,
+			Mode:      deletion.DeletionMode(settings.Safety.DeletionMode),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to delete project artifacts: %w", err)
 		}
 	}
 	
 	result := map[string]interface{}{
-		"location_id":     locationID,
-		"safety_level":    safetyLevel,
-		"files":           filteredFiles,
-		"total_files":     len(filteredFiles),
+		"inactive_days":  inactiveDays,
+		"dry_run":        dryRun,
+		"artifacts":      stale,
+		"selected_bytes": selectedBytes,
+		"result":         deletionResult,
+		"errors":         report.Errors,
 	}
 	
 	jsonResult, err := json.Marshal(result)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal filtered files result: %w", err)
+		return "", fmt.Errorf("failed to marshal result: %w", err)
 	}
 	
+	log.Printf("Cleaning %d artifacts of projects inactive for %d days (%d bytes, dry run %v)", len(stale), inactiveDays, selectedBytes, dryRun)
 	return string(jsonResult), nil
 }
 
@@ -497,8 +1446,8 @@ func (a *App) BackupFiles(filesJSON string, operation string) (string, error) {
 
 // DeleteFilesWithBackup safely deletes files after creating backups
 func (a *App) DeleteFilesWithBackup(filesJSON string, operation string) (string, error) {
-	if a.backupSystem == nil {
-		return "", fmt.Errorf("backup system not available")
+	if a.deletionService == nil {
+		return "", fmt.Errorf("deletion service not available")
 	}
 
 	var files []string
@@ -508,7 +1457,13 @@ func (a *App) DeleteFilesWithBackup(filesJSON string, operation string) (string,
 
 	log.Printf("Starting safe deletion of %d files for operation: %s", len(files), operation)
 
-	result, err := a.backupSystem.DeleteFilesWithBackup(files, operation)
+	// Callers of this binding pick files themselves, so safety checks only warn
+	result, err := a.deletionService.DeleteFilesWithBackup(&deletion.DeletionRequest{
+		Files:       files,
+		Operation:   operation,
+		ForceDelete: true,
+		Mode:        deletion.DeletionModeBackup,
+	})
 	if err != nil {
 		log.Printf("Error during safe deletion: %v", err)
 		return "", err
@@ -683,6 +1638,45 @@ func (a *App) CleanupOldBackups(olderThanDays int) (string, error) {
 	return string(jsonResult), nil
 }
 
+// PreviewBackupRetention reports which backup sessions the retention settings would prune
+func (a *App) PreviewBackupRetention() (string, error) {
+	if a.backupSystem == nil {
+		return "", fmt.Errorf("backup system not available")
+	}
+
+	plan, err := a.backupSystem.PreviewRetention()
+	if err != nil {
+		return "", fmt.Errorf("failed to preview retention: %w", err)
+	}
+
+	jsonResult, err := json.Marshal(plan)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal retention plan: %w", err)
+	}
+
+	return string(jsonResult), nil
+}
+
+// ApplyBackupRetention prunes backup sessions according to the retention settings
+func (a *App) ApplyBackupRetention() (string, error) {
+	if a.backupSystem == nil {
+		return "", fmt.Errorf("backup system not available")
+	}
+
+	plan, err := a.backupSystem.ApplyRetention()
+	if err != nil {
+		return "", fmt.Errorf("failed to apply retention: %w", err)
+	}
+
+	jsonResult, err := json.Marshal(plan)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal retention plan: %w", err)
+	}
+
+	log.Printf("Applied backup retention: pruned %d sessions (%d bytes)", plan.PrunedCount, plan.PrunedSize)
+	return string(jsonResult), nil
+}
+
 // GetBackupSystemStatus returns the current status of the backup system
 func (a *App) GetBackupSystemStatus() (string, error) {
 	if a.backupSystem == nil {
@@ -783,6 +1777,8 @@ func (a *App) ConfirmDeletion(dialogJSON string, filesJSON string, confirmed boo
 		Operation:   dialog.Operation,
 		ForceDelete: forceDelete,
 		DryRun:      dryRun,
+		Mode:        a.deletionMode(),
+		Atomic:      a.atomicDeletion(),
 	}
 
 	// Create progress tracker
@@ -841,52 +1837,125 @@ func (a *App) GetDeletionProgress(operationID string) (string, error) {
 
 	progressJSON, err := tracker.GetProgressJSON()
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal progress: %w", err)
+		return "", fmt.Errorf("failed to marshal progress: %w", err)
+	}
+
+	return progressJSON, nil
+}
+
+// StopDeletion stops a running deletion operation
+func (a *App) StopDeletion(operationID string) error {
+	if a.deletionService == nil {
+		return fmt.Errorf("deletion service not available")
+	}
+
+	// Stop the deletion service
+	a.deletionService.StopDeletion()
+
+	// Update progress tracker
+	if tracker, err := a.progressManager.GetTracker(operationID); err == nil {
+		tracker.Cancel("Deletion stopped by user")
+	}
+
+	return nil
+}
+
+// RestoreFromBackup restores files from a backup session
+func (a *App) RestoreFromBackup(sessionID string, overwrite bool) (string, error) {
+	if a.deletionService == nil {
+		return "", fmt.Errorf("deletion service not available")
+	}
+
+	log.Printf("Starting restore from backup session: %s", sessionID)
+
+	result, err := a.deletionService.RestoreFromBackup(sessionID, overwrite)
+	if err != nil {
+		log.Printf("Error during restore: %v", err)
+		return "", err
+	}
+
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal restore result: %w", err)
+	}
+
+	log.Printf("Completed restore: %d files restored", result.SuccessCount)
+	return string(jsonResult), nil
+}
+
+// ListQuarantine returns the batches held in quarantine, newest first
+func (a *App) ListQuarantine() (string, error) {
+	if a.deletionService == nil || a.deletionService.GetQuarantine() == nil {
+		return "", fmt.Errorf("quarantine not available")
+	}
+
+	batches, err := a.deletionService.GetQuarantine().List()
+	if err != nil {
+		return "", fmt.Errorf("failed to list quarantine: %w", err)
+	}
+
+	result, err := json.Marshal(batches)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal quarantine batches: %w", err)
 	}
 
-	return progressJSON, nil
+	return string(result), nil
 }
 
-// StopDeletion stops a running deletion operation
-func (a *App) StopDeletion(operationID string) error {
-	if a.deletionService == nil {
-		return fmt.Errorf("deletion service not available")
+// ListTrash returns the items in the home trash, most recently deleted first
+func (a *App) ListTrash() (string, error) {
+	if a.deletionService == nil || a.deletionService.GetTrash() == nil {
+		return "", fmt.Errorf("trash not available")
 	}
 
-	// Stop the deletion service
-	a.deletionService.StopDeletion()
+	items, err := a.deletionService.GetTrash().List()
+	if err != nil {
+		return "", fmt.Errorf("failed to list trash: %w", err)
+	}
 
-	// Update progress tracker
-	if tracker, err := a.progressManager.GetTracker(operationID); err == nil {
-		tracker.Cancel("Deletion stopped by user")
+	result, err := json.Marshal(items)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal trash items: %w", err)
 	}
 
-	return nil
+	return string(result), nil
 }
 
-// RestoreFromBackup restores files from a backup session
-func (a *App) RestoreFromBackup(sessionID string, overwrite bool) (string, error) {
+// UndoQuarantine moves the files of a quarantine batch back to where they were deleted from
+func (a *App) UndoQuarantine(batchID string) (string, error) {
 	if a.deletionService == nil {
 		return "", fmt.Errorf("deletion service not available")
 	}
 
-	log.Printf("Starting restore from backup session: %s", sessionID)
-
-	result, err := a.deletionService.RestoreFromBackup(sessionID, overwrite)
+	result, err := a.deletionService.UndoQuarantine(batchID)
 	if err != nil {
-		log.Printf("Error during restore: %v", err)
+		log.Printf("Error undoing quarantine: %v", err)
 		return "", err
 	}
 
 	jsonResult, err := json.Marshal(result)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal restore result: %w", err)
+		return "", fmt.Errorf("failed to marshal undo result: %w", err)
 	}
 
-	log.Printf("Completed restore: %d files restored", result.SuccessCount)
+	log.Printf("Undid quarantine batch %s: %d files restored", batchID, len(result.RestoredFiles))
 	return string(jsonResult), nil
 }
 
+// PurgeQuarantineBatch permanently deletes a quarantine batch before its holding period ends
+func (a *App) PurgeQuarantineBatch(batchID string) error {
+	if a.deletionService == nil || a.deletionService.GetQuarantine() == nil {
+		return fmt.Errorf("quarantine not available")
+	}
+
+	if err := a.deletionService.GetQuarantine().Purge(batchID); err != nil {
+		return fmt.Errorf("failed to purge quarantine batch: %w", err)
+	}
+
+	log.Printf("Purged quarantine batch %s", batchID)
+	return nil
+}
+
 // GetDeletionHistory returns the history of deletion operations
 func (a *App) GetDeletionHistory() (string, error) {
 	if a.deletionService == nil {
@@ -955,6 +2024,63 @@ func (a *App) ValidateFilesForDeletion(filesJSON string, operation string) (stri
 	return string(result), nil
 }
 
+// PlanDeletion returns a reviewable plan for deleting files with the configured mode
+func (a *App) PlanDeletion(filesJSON string, operation string, forceDelete bool) (string, error) {
+	if a.deletionService == nil {
+		return "", fmt.Errorf("deletion service not available")
+	}
+
+	var files []string
+	if err := json.Unmarshal([]byte(filesJSON), &files); err != nil {
+		return "", fmt.Errorf("invalid files JSON: %w", err)
+	}
+
+	plan, err := a.deletionService.PlanDeletion(&deletion.DeletionRequest{
+		Files:       files,
+		Operation:   operation,
+		ForceDelete: forceDelete,
+		Mode:        a.deletionMode(),
+		Atomic:      a.atomicDeletion(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to plan deletion: %w", err)
+	}
+
+	result, err := json.Marshal(plan)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal deletion plan: %w", err)
+	}
+
+	log.Printf("Planned deletion %s: %d items, %d bytes to free", plan.ID, len(plan.Items), plan.PredictedFreedBytes)
+	return string(result), nil
+}
+
+// ExecuteDeletionPlan executes a plan returned by PlanDeletion unchanged
+func (a *App) ExecuteDeletionPlan(planJSON string) (string, error) {
+	if a.deletionService == nil {
+		return "", fmt.Errorf("deletion service not available")
+	}
+
+	var plan deletion.DeletionPlan
+	if err := json.Unmarshal([]byte(planJSON), &plan); err != nil {
+		return "", fmt.Errorf("invalid plan JSON: %w", err)
+	}
+
+	result, err := a.deletionService.ExecutePlan(&plan, nil)
+	if err != nil {
+		log.Printf("Error executing deletion plan %s: %v", plan.ID, err)
+		return "", err
+	}
+
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal deletion result: %w", err)
+	}
+
+	log.Printf("Executed deletion plan %s: %d files deleted, %d skipped", plan.ID, result.DeletedCount, result.SkippedCount)
+	return string(jsonResult), nil
+}
+
 // GetDeletionSystemStatus returns the current status of the deletion system
 func (a *App) GetDeletionSystemStatus() (string, error) {
 	if a.deletionService == nil {
@@ -1087,32 +2213,101 @@ func (a *App) GetBackupSessionDetails(sessionID string) (string, error) {
 
 // PreviewRestoreOperation shows what would be restored without actually restoring
 func (a *App) PreviewRestoreOperation(sessionID string, filesJSON string) (string, error) {
+	return a.PreviewRestoreWithStrategy(sessionID, filesJSON, string(backup.ConflictSkip), "")
+}
+
+// PreviewRestoreWithStrategy shows the per-file action a restore with the given
+// conflict strategy and optional alternate target root would take
+func (a *App) PreviewRestoreWithStrategy(sessionID string, filesJSON string, strategy string, targetRoot string) (string, error) {
 	if a.backupSystem == nil {
 		return "", fmt.Errorf("backup system not available")
 	}
 
-	var filePaths []string
-	if filesJSON != "" {
-		if err := json.Unmarshal([]byte(filesJSON), &filePaths); err != nil {
-			return "", fmt.Errorf("invalid files JSON: %w", err)
-		}
+	options, err := restoreOptions(filesJSON, strategy, targetRoot)
+	if err != nil {
+		return "", err
 	}
 
-	var preview *backup.RestoreResult
-	var err error
+	preview, err := a.backupSystem.PreviewRestore(sessionID, options)
+	if err != nil {
+		return "", fmt.Errorf("failed to preview restore: %w", err)
+	}
 
-	if len(filePaths) > 0 {
-		// Preview selective restore
-		preview, err = a.backupSystem.GetRestorer().PreviewRestore(sessionID)
-		if err != nil {
-			return "", fmt.Errorf("failed to preview restore: %w", err)
-		}
-	} else {
-		// Preview full restore
-		preview, err = a.backupSystem.GetRestorer().PreviewRestore(sessionID)
-		if err != nil {
-			return "", fmt.Errorf("failed to preview restore: %w", err)
-		}
+	result, err := json.Marshal(preview)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal preview result: %w", err)
+	}
+
+	return string(result), nil
+}
+
+// RestoreWithStrategy restores files using a conflict strategy (skip, overwrite,
+// keep_newer, rename, fail_fast), optionally below an alternate target root
+func (a *App) RestoreWithStrategy(sessionID string, filesJSON string, strategy string, targetRoot string) (string, error) {
+	if a.backupSystem == nil {
+		return "", fmt.Errorf("backup system not available")
+	}
+
+	options, err := restoreOptions(filesJSON, strategy, targetRoot)
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("Starting restore from session %s with strategy %s", sessionID, options.Conflict)
+
+	result, err := a.backupSystem.Restore(sessionID, options)
+	if err != nil {
+		log.Printf("Error during restore: %v", err)
+		return "", err
+	}
+
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal restore result: %w", err)
+	}
+
+	log.Printf("Completed restore: %d files restored, %d skipped", result.SuccessCount, result.SkippedCount)
+	return string(jsonResult), nil
+}
+
+// GetFileVersions lists every backed up version of a path and of the paths below it
+func (a *App) GetFileVersions(path string) (string, error) {
+	if a.backupSystem == nil {
+		return "", fmt.Errorf("backup system not available")
+	}
+
+	histories, err := a.backupSystem.FileVersions(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to get file versions: %w", err)
+	}
+
+	result, err := json.Marshal(histories)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal file versions: %w", err)
+	}
+
+	return string(result), nil
+}
+
+// PreviewRestoreAsOf shows which version of each file below path a point-in-time
+// restore to the RFC 3339 timestamp would pick and what it would do
+func (a *App) PreviewRestoreAsOf(path string, timestamp string, strategy string, targetRoot string) (string, error) {
+	if a.backupSystem == nil {
+		return "", fmt.Errorf("backup system not available")
+	}
+
+	asOf, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return "", fmt.Errorf("invalid timestamp: %w", err)
+	}
+	options, err := restoreOptions("", strategy, targetRoot)
+	if err != nil {
+		return "", err
+	}
+
+	preview, err := a.backupSystem.PreviewRestoreAsOf(path, asOf, options)
+	if err != nil {
+		return "", fmt.Errorf("failed to preview restore: %w", err)
 	}
 
 	result, err := json.Marshal(preview)
@@ -1123,6 +2318,62 @@ func (a *App) PreviewRestoreOperation(sessionID string, filesJSON string) (strin
 	return string(result), nil
 }
 
+// RestoreAsOf restores path and everything below it to the newest versions
+// backed up at or before the RFC 3339 timestamp, across all sessions
+func (a *App) RestoreAsOf(path string, timestamp string, strategy string, targetRoot string) (string, error) {
+	if a.backupSystem == nil {
+		return "", fmt.Errorf("backup system not available")
+	}
+
+	asOf, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return "", fmt.Errorf("invalid timestamp: %w", err)
+	}
+	options, err := restoreOptions("", strategy, targetRoot)
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("Starting point-in-time restore of %s as of %s", path, asOf.Format(time.RFC3339))
+
+	result, err := a.backupSystem.RestoreAsOf(path, asOf, options)
+	if err != nil {
+		log.Printf("Error during restore: %v", err)
+		return "", err
+	}
+
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal restore result: %w", err)
+	}
+
+	log.Printf("Completed point-in-time restore: %d files restored, %d skipped", result.SuccessCount, result.SkippedCount)
+	return string(jsonResult), nil
+}
+
+// restoreOptions builds restore options from frontend arguments
+func restoreOptions(filesJSON string, strategy string, targetRoot string) (backup.RestoreOptions, error) {
+	options := backup.RestoreOptions{
+		Conflict:   backup.ConflictStrategy(strategy),
+		TargetRoot: targetRoot,
+	}
+
+	if filesJSON != "" {
+		if err := json.Unmarshal([]byte(filesJSON), &options.Paths); err != nil {
+			return options, fmt.Errorf("invalid files JSON: %w", err)
+		}
+	}
+
+	if err := backup.ValidateConflictStrategy(options.Conflict); err != nil {
+		return options, err
+	}
+	if targetRoot != "" && !filepath.IsAbs(targetRoot) {
+		return options, fmt.Errorf("restore target must be an absolute path: %s", targetRoot)
+	}
+
+	return options, nil
+}
+
 // RestoreFromBackupWithOptions restores files with various options
 func (a *App) RestoreFromBackupWithOptions(sessionID string, filesJSON string, overwrite bool, createBackup bool) (string, error) {
 	if a.backupSystem == nil {
@@ -1211,12 +2462,8 @@ func (a *App) DeleteBackupSession(sessionID string) (string, error) {
 		manifest.TotalSize += s.TotalSize
 	}
 
-	// Save updated manifest - we need to create a new manager instance to access saveManifest
-	manager, err := backup.NewBackupManager()
-	if err != nil {
-		return "", fmt.Errorf("failed to create backup manager: %w", err)
-	}
-	if err := manager.SaveManifest(manifest); err != nil {
+	// Save updated manifest through the configured manager so encryption settings apply
+	if err := a.backupSystem.GetManager().SaveManifest(manifest); err != nil {
 		return "", fmt.Errorf("failed to update manifest: %w", err)
 	}
 
@@ -1293,6 +2540,7 @@ func (a *App) GetBackupProgress() (string, error) {
 	}
 
 	status := a.backupSystem.GetSystemStatus()
+	status["deleter_running"] = a.deletionService != nil && a.deletionService.IsDeleting()
 	
 	// Check if any operations are running
 	isRunning := status["backup_manager_running"].(bool) || 
@@ -1393,6 +2641,11 @@ func (a *App) UpdateSettings(settingsJSON string) (string, error) {
 		}
 		return "", fmt.Errorf("failed to update settings: %w", err)
 	}
+	a.applyBackupSettings()
+	a.applySafetySettings()
+	if err := a.applyScheduleSettings(); err != nil {
+		log.Printf("Warning: Automatic cleanup unavailable: %v", err)
+	}
 
 	result := map[string]interface{}{
 		"status":   "success",
@@ -1440,6 +2693,7 @@ func (a *App) UpdateBackupSettings(backupSettingsJSON string) (string, error) {
 	if err := a.settingsManager.UpdateBackupSettings(backupSettings); err != nil {
 		return "", fmt.Errorf("failed to update backup settings: %w", err)
 	}
+	a.applyBackupSettings()
 	
 	result := map[string]interface{}{
 		"status":   "success",
@@ -1455,6 +2709,120 @@ func (a *App) UpdateBackupSettings(backupSettingsJSON string) (string, error) {
 	return string(jsonResult), nil
 }
 
+// GetScheduleSettings returns the current automatic cleanup settings
+func (a *App) GetScheduleSettings() (string, error) {
+	if a.settingsManager == nil {
+		return "", fmt.Errorf("settings manager not available")
+	}
+	
+	settings := a.settingsManager.GetSettings()
+	result, err := json.Marshal(settings.Schedule)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal schedule settings: %w", err)
+	}
+	
+	return string(result), nil
+}
+
+// UpdateScheduleSettings updates the automatic cleanup settings and restarts the scheduler
+func (a *App) UpdateScheduleSettings(scheduleSettingsJSON string) (string, error) {
+	if a.settingsManager == nil {
+		return "", fmt.Errorf("settings manager not available")
+	}
+	
+	var scheduleSettings config.ScheduleSettings
+	if err := json.Unmarshal([]byte(scheduleSettingsJSON), &scheduleSettings); err != nil {
+		return "", fmt.Errorf("invalid schedule settings JSON: %w", err)
+	}
+	
+	if err := a.settingsManager.UpdateScheduleSettings(scheduleSettings); err != nil {
+		return "", fmt.Errorf("failed to update schedule settings: %w", err)
+	}
+	if err := a.applyScheduleSettings(); err != nil {
+		return "", err
+	}
+	
+	result := map[string]interface{}{
+		"status":   "success",
+		"message":  "Schedule settings updated successfully",
+		"settings": a.settingsManager.GetSettings().Schedule,
+	}
+	
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+	
+	return string(jsonResult), nil
+}
+
+// GetSchedulerStatus returns whether the scheduler is running and when it runs next
+func (a *App) GetSchedulerStatus() (string, error) {
+	a.mu.RLock()
+	current := a.scheduler
+	a.mu.RUnlock()
+	
+	status := scheduler.Status{}
+	if current != nil {
+		status = current.Status()
+	}
+	
+	result, err := json.Marshal(status)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal scheduler status: %w", err)
+	}
+	
+	return string(result), nil
+}
+
+// GetScheduledRuns returns the most recent automatic cleanup runs, including
+// those made by a headless daemon
+func (a *App) GetScheduledRuns(limit int) (string, error) {
+	runs, err := openRunStore()
+	if err != nil {
+		return "", err
+	}
+	
+	records, err := runs.List(limit)
+	if err != nil {
+		return "", err
+	}
+	
+	result, err := json.Marshal(records)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal scheduled runs: %w", err)
+	}
+	
+	return string(result), nil
+}
+
+// RunScheduledCleanupNow runs the automatic cleanup immediately with the schedule's policy
+func (a *App) RunScheduledCleanupNow() (string, error) {
+	a.mu.RLock()
+	current := a.scheduler
+	a.mu.RUnlock()
+	
+	if current == nil {
+		var err error
+		if current, err = a.newScheduler(); err != nil {
+			return "", fmt.Errorf("failed to create scheduler: %w", err)
+		}
+	}
+	
+	record, err := current.RunNow()
+	if err != nil {
+		return "", fmt.Errorf("failed to run scheduled cleanup: %w", err)
+	}
+	
+	result, err := json.Marshal(record)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal run record: %w", err)
+	}
+	
+	log.Printf("Manual scheduled cleanup %s: %s, %d files deleted", record.ID, record.Status, record.DeletedCount)
+	return string(result), nil
+}
+
 // GetSafetySettings returns the current safety settings
 func (a *App) GetSafetySettings() (string, error) {
 	if a.settingsManager == nil {
@@ -1484,6 +2852,7 @@ func (a *App) UpdateSafetySettings(safetySettingsJSON string) (string, error) {
 	if err := a.settingsManager.UpdateSafetySettings(safetySettings); err != nil {
 		return "", fmt.Errorf("failed to update safety settings: %w", err)
 	}
+	a.applySafetySettings()
 	
 	result := map[string]interface{}{
 		"status":   "success",
@@ -1640,6 +3009,11 @@ func (a *App) ResetSettings() (string, error) {
 	if err := a.settingsManager.ResetToDefaults(); err != nil {
 		return "", fmt.Errorf("failed to reset settings: %w", err)
 	}
+	a.applyBackupSettings()
+	a.applySafetySettings()
+	if err := a.applyScheduleSettings(); err != nil {
+		log.Printf("Warning: Automatic cleanup unavailable: %v", err)
+	}
 	
 	result := map[string]interface{}{
 		"status":   "success",
@@ -1688,6 +3062,11 @@ func (a *App) ImportSettings(importPath string) (string, error) {
 	if err := a.settingsManager.ImportSettings(importPath); err != nil {
 		return "", fmt.Errorf("failed to import settings: %w", err)
 	}
+	a.applyBackupSettings()
+	a.applySafetySettings()
+	if err := a.applyScheduleSettings(); err != nil {
+		log.Printf("Warning: Automatic cleanup unavailable: %v", err)
+	}
 	
 	result := map[string]interface{}{
 		"status":      "success",
@@ -11,6 +11,8 @@ import (
 	"sync"
 	"time"
 	
+	"cache_app/pkg/backup"
+	"cache_app/pkg/recommend"
 	"cache_app/pkg/safety"
 )
 
@@ -71,6 +73,7 @@ type CacheScanner struct {
 	isScanning       bool
 	scanStartTime    time.Time
 	safetyClassifier *safety.SafetyClassifier
+	pathRules        *safety.PathRules // Paths denied by the user are not scanned
 }
 
 // NewCacheScanner creates a new cache scanner instance
@@ -100,6 +103,20 @@ func (cs *CacheScanner) StopScan() {
 	}
 }
 
+// SetSafetyClassifier sets the classifier rating scanned files
+func (cs *CacheScanner) SetSafetyClassifier(classifier *safety.SafetyClassifier) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.safetyClassifier = classifier
+}
+
+// SetPathRules sets the user path rules, the scan skipping denied paths
+func (cs *CacheScanner) SetPathRules(rules *safety.PathRules) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.pathRules = rules
+}
+
 // IsScanning returns whether a scan is currently in progress
 func (cs *CacheScanner) IsScanning() bool {
 	cs.mu.RLock()
@@ -117,6 +134,10 @@ func (cs *CacheScanner) SetScanning(scanning bool) {
 // ScanLocation scans a single cache location
 func (cs *CacheScanner) ScanLocation(locationID, locationName, path string) (*CacheLocation, error) {
 	startTime := time.Now()
+	cs.mu.RLock()
+	classifier := cs.safetyClassifier
+	pathRules := cs.pathRules
+	cs.mu.RUnlock()
 	
 	// Expand tilde in path
 	expandedPath, err := expandTilde(path)
@@ -174,6 +195,14 @@ func (cs *CacheScanner) ScanLocation(locationID, locationName, path string) (*Ca
 			return err
 		}
 		
+		// Skip paths the user denied, they are never touched
+		if action, _ := pathRules.Match(path); action == safety.RuleDeny {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		
 		// Get file info
 		info, err := d.Info()
 		if err != nil {
@@ -202,7 +231,7 @@ func (cs *CacheScanner) ScanLocation(locationID, locationName, path string) (*Ca
 			Path:         path,
 			Size:         info.Size(),
 			LastModified: info.ModTime(),
-			LastAccessed: getLastAccessTime(info),
+			LastAccessed: backup.LastAccessTime(info),
 			IsDir:        d.IsDir(),
 			Permissions:  info.Mode().String(),
 		}
@@ -214,11 +243,11 @@ func (cs *CacheScanner) ScanLocation(locationID, locationName, path string) (*Ca
 				Path:         path,
 				Size:         info.Size(),
 				LastModified: info.ModTime(),
-				LastAccessed: getLastAccessTime(info),
+				LastAccessed: backup.LastAccessTime(info),
 				IsDir:        d.IsDir(),
 				Permissions:  info.Mode().String(),
 			}
-			classification := cs.safetyClassifier.ClassifyFile(fileMetadata)
+			classification := classifier.ClassifyFile(fileMetadata)
 			cacheFile.SafetyClassification = &classification
 		}
 		
@@ -365,17 +394,6 @@ func expandTilde(path string) (string, error) {
 	return filepath.Join(homeDir, path[1:]), nil
 }
 
-// getLastAccessTime extracts the last access time from file info
-func getLastAccessTime(info os.FileInfo) time.Time {
-	// On macOS, we can use Sys() to get more detailed file info
-	if sys := info.Sys(); sys != nil {
-		// This is a simplified version - in practice, you might need
-		// to use syscall.Stat_t for more accurate access time
-		return info.ModTime() // Fallback to modification time
-	}
-	return info.ModTime()
-}
-
 // isCacheFile checks if a file path appears to be a cache file that might be transient
 func isCacheFile(path string) bool {
 	// Check for common cache file patterns
@@ -437,6 +455,32 @@ func (sr *ScanResult) ToJSON() (string, error) {
 	return string(data), nil
 }
 
+// ToScannedLocation converts a scanned location for the recommendation engine
+func (cl *CacheLocation) ToScannedLocation() recommend.ScannedLocation {
+	root, err := expandTilde(cl.Path)
+	if err != nil {
+		root = cl.Path
+	}
+	
+	scanned := recommend.ScannedLocation{
+		ID:    cl.ID,
+		Name:  cl.Name,
+		Path:  filepath.Clean(root),
+		Files: make([]recommend.ScannedFile, 0, len(cl.Files)),
+	}
+	for _, file := range cl.Files {
+		scanned.Files = append(scanned.Files, recommend.ScannedFile{
+			Path:           file.Path,
+			Size:           file.Size,
+			IsDir:          file.IsDir,
+			LastModified:   file.LastModified,
+			LastAccessed:   file.LastAccessed,
+			Classification: file.SafetyClassification,
+		})
+	}
+	return scanned
+}
+
 // GetSafetyClassificationSummary returns a summary of safety classifications for a cache location
 func (cl *CacheLocation) GetSafetyClassificationSummary() map[string]interface{} {
 	var files []safety.FileMetadata
@@ -6,8 +6,11 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"cache_app/pkg/safety"
 )
 
 // TestCacheScanner tests the basic functionality of the cache scanner
@@ -88,6 +91,42 @@ func TestCacheScannerWithPermissions(t *testing.T) {
 	}
 }
 
+// TestCacheScannerSkipsDeniedPaths tests that paths denied by user rules are not scanned
+func TestCacheScannerSkipsDeniedPaths(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, file := range []string{"keep/a.cache", "IdeaIC/index/shard", "IdeaIC/log.txt"} {
+		fullPath := filepath.Join(tempDir, file)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte("test content"), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", fullPath, err)
+		}
+	}
+	
+	rules, err := safety.NewPathRules([]safety.PathRule{
+		{Pattern: filepath.ToSlash(tempDir) + "/*/index", Action: safety.RuleDeny},
+	}, "")
+	if err != nil {
+		t.Fatalf("Failed to compile path rules: %v", err)
+	}
+	scanner := NewCacheScanner()
+	scanner.SetPathRules(rules)
+	
+	location, err := scanner.ScanLocation("test", "Test Directory", tempDir)
+	if err != nil {
+		t.Fatalf("Failed to scan directory: %v", err)
+	}
+	if location.FileCount != 2 {
+		t.Errorf("Expected 2 files outside the denied directory, got %d", location.FileCount)
+	}
+	for _, file := range location.Files {
+		if strings.Contains(file.Path, "index") {
+			t.Errorf("Expected the denied directory to be skipped, got %s", file.Path)
+		}
+	}
+}
+
 // TestMultipleLocations tests scanning multiple locations
 func TestMultipleLocations(t *testing.T) {
 	scanner := NewCacheScanner()
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"cache_app/pkg/catalog"
+	"cache_app/pkg/deletion"
+	"cache_app/pkg/recommend"
+	"cache_app/pkg/scheduler"
+)
+
+// schedulerCheckInterval is how often the scheduler checks its triggers
+const schedulerCheckInterval = time.Minute
+
+// loadCatalog loads cache_locations.json from the working directory or, for
+// daemons started elsewhere, from next to the executable
+func loadCatalog() (*catalog.Catalog, error) {
+	path := filepath.Join(".", "cache_locations.json")
+	if _, err := os.Stat(path); err != nil {
+		if executable, exeErr := os.Executable(); exeErr == nil {
+			path = filepath.Join(filepath.Dir(executable), "cache_locations.json")
+		}
+	}
+	return catalog.Load(path)
+}
+
+// openRunStore opens the store of scheduled cleanup runs
+func openRunStore() (*scheduler.RunStore, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return scheduler.NewRunStore(filepath.Join(homeDir, "CacheCleaner", "Scheduler"))
+}
+
+// newScheduler builds the cleanup scheduler from the schedule settings
+func (a *App) newScheduler() (*scheduler.Scheduler, error) {
+	if a.settingsManager == nil || a.deletionService == nil {
+		return nil, fmt.Errorf("scheduler requires settings and the deletion service")
+	}
+	settings := a.settingsManager.GetSettings()
+
+	locations, err := loadCatalog()
+	if err != nil {
+		return nil, err
+	}
+	runs, err := openRunStore()
+	if err != nil {
+		return nil, err
+	}
+
+	// Scheduled scans use their own scanner so they never collide with a scan started from the UI
+	scanner := a.newCacheScanner()
+	scan := func(location catalog.Location) (recommend.ScannedLocation, error) {
+		result, err := scanner.ScanLocation(location.ID, location.Name, location.Path)
+		if err != nil {
+			return recommend.ScannedLocation{}, err
+		}
+		if result.Error != "" && len(result.Files) == 0 {
+			return recommend.ScannedLocation{}, fmt.Errorf("%s", result.Error)
+		}
+		return result.ToScannedLocation(), nil
+	}
+
+	mode := deletion.DeletionMode(settings.Safety.DeletionMode)
+	clean := func(files []string, operation string) (*deletion.DeletionResult, error) {
+		// The recommendation engine only selected Safe items in allowed locations
+		return a.deletionService.DeleteFilesWithBackup(&deletion.DeletionRequest{
+			Files:       files,
+			Operation:   operation,
+			ForceDelete: true,
+			Mode:        mode,
+		})
+	}
+
+	schedule := settings.Schedule
+	return scheduler.New(scheduler.Policy{
+		Cron:              schedule.Cron,
+		MinFreeBytes:      uint64(schedule.MinFreeSpaceMB) * 1024 * 1024,
+		WatchPath:         schedule.WatchPath,
+		Locations:         schedule.Locations,
+		QuietHoursStart:   schedule.QuietHoursStart,
+		QuietHoursEnd:     schedule.QuietHoursEnd,
+		SkipOnBattery:     schedule.SkipOnBattery,
+		MinBatteryPercent: schedule.MinBatteryPercent,
+	}, locations, scan, clean, runs)
+}
+
+// applyScheduleSettings restarts the scheduler with the current schedule settings
+func (a *App) applyScheduleSettings() error {
+	a.mu.Lock()
+	current := a.scheduler
+	a.scheduler = nil
+	a.mu.Unlock()
+	if current != nil {
+		current.Stop()
+	}
+
+	if a.settingsManager == nil || !a.settingsManager.GetSettings().Schedule.Enabled {
+		return nil
+	}
+
+	s, err := a.newScheduler()
+	if err != nil {
+		return fmt.Errorf("failed to create scheduler: %w", err)
+	}
+	err = s.Start(schedulerCheckInterval, func(record scheduler.RunRecord) {
+		log.Printf("Scheduled cleanup %s (%s): %s, %d files deleted, %d bytes freed %s",
+			record.ID, record.Trigger, record.Status, record.DeletedCount, record.FreedBytes, record.Reason)
+	}, func(err error) {
+		log.Printf("Warning: Failed to record scheduled cleanup: %v", err)
+	})
+	if errors.Is(err, scheduler.ErrLocked) {
+		log.Println("Automatic cleanup is handled by another process")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start scheduler: %w", err)
+	}
+
+	a.mu.Lock()
+	a.scheduler = s
+	a.mu.Unlock()
+	log.Printf("Scheduler started for %d locations", len(a.settingsManager.GetSettings().Schedule.Locations))
+	return nil
+}
+
+// runDaemon runs scheduled cleanups without a window until interrupted.
+// SIGHUP reloads the settings.
+func runDaemon() error {
+	app := NewApp()
+	if app.settingsManager == nil {
+		return fmt.Errorf("settings are not available")
+	}
+	if !app.settingsManager.GetSettings().Schedule.Enabled {
+		return fmt.Errorf("automatic cleanup is disabled in the settings")
+	}
+	if app.scheduler == nil {
+		return fmt.Errorf("scheduler did not start, another process may own it")
+	}
+	log.Printf("Cache cleaner daemon running (pid %d)", os.Getpid())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := range signals {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		log.Println("Reloading settings")
+		if err := app.settingsManager.LoadSettings(); err != nil {
+			log.Printf("Warning: Failed to reload settings: %v", err)
+			continue
+		}
+		if err := app.applyScheduleSettings(); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	app.mu.RLock()
+	current := app.scheduler
+	app.mu.RUnlock()
+	if current != nil {
+		current.Stop()
+	}
+	log.Println("Cache cleaner daemon stopped")
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"cache_app/internal/config"
+	"cache_app/pkg/safety"
+)
+
+// runClassifierEvaluation prints how well the classifier, configured from the
+// settings, rates a labeled corpus of paths
+func runClassifierEvaluation(corpusPath string) error {
+	app := &App{}
+	if settingsManager, err := config.NewSettingsManager(); err == nil {
+		app.settingsManager = settingsManager
+	} else {
+		log.Printf("Warning: Using default settings: %v", err)
+	}
+
+	corpus, err := safety.LoadCorpus(corpusPath)
+	if err != nil {
+		return err
+	}
+	result, err := json.MarshalIndent(app.safetyClassifier().Evaluate(corpus), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal evaluation: %w", err)
+	}
+	fmt.Println(string(result))
+	return nil
+}
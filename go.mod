@@ -2,7 +2,13 @@ module cache_app
 
 go 1.23
 
-require github.com/wailsapp/wails/v2 v2.10.2
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/wailsapp/mimetype v1.4.1
+	github.com/wailsapp/wails/v2 v2.10.2
+	golang.org/x/crypto v0.33.0
+	golang.org/x/sys v0.30.0
+)
 
 require (
 	github.com/bep/debounce v1.2.1 // indirect
@@ -27,10 +33,7 @@ require (
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
 	github.com/valyala/fasttemplate v1.2.2 // indirect
 	github.com/wailsapp/go-webview2 v1.0.19 // indirect
-	github.com/wailsapp/mimetype v1.4.1 // indirect
-	golang.org/x/crypto v0.33.0 // indirect
 	golang.org/x/net v0.35.0 // indirect
-	golang.org/x/sys v0.30.0 // indirect
 	golang.org/x/text v0.22.0 // indirect
 )
 
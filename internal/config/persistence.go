@@ -229,6 +229,46 @@ func (sm *SettingsManager) UpdateSafetySettings(safetySettings SafetySettings) e
 	return sm.SaveSettings()
 }
 
+// UpdateScheduleSettings updates only the schedule settings
+func (sm *SettingsManager) UpdateScheduleSettings(scheduleSettings ScheduleSettings) error {
+	if sm.settings == nil {
+		sm.settings = DefaultSettings()
+	}
+	
+	sm.settings.Schedule = scheduleSettings
+	return sm.SaveSettings()
+}
+
+// UpdateBudgets replaces the location size budgets
+func (sm *SettingsManager) UpdateBudgets(budgets []LocationBudget) error {
+	if sm.settings == nil {
+		sm.settings = DefaultSettings()
+	}
+	
+	sm.settings.Budgets = budgets
+	return sm.SaveSettings()
+}
+
+// UpdatePathRules replaces the user allow and deny path rules
+func (sm *SettingsManager) UpdatePathRules(rules []PathRule) error {
+	if sm.settings == nil {
+		sm.settings = DefaultSettings()
+	}
+	
+	sm.settings.PathRules = rules
+	return sm.SaveSettings()
+}
+
+// UpdateWorkspaceSettings updates only the workspace settings
+func (sm *SettingsManager) UpdateWorkspaceSettings(workspaceSettings WorkspaceSettings) error {
+	if sm.settings == nil {
+		sm.settings = DefaultSettings()
+	}
+	
+	sm.settings.Workspaces = workspaceSettings
+	return sm.SaveSettings()
+}
+
 // UpdatePerformanceSettings updates only the performance settings
 func (sm *SettingsManager) UpdatePerformanceSettings(performanceSettings PerformanceSettings) error {
 	if sm.settings == nil {
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"path"
+	"strings"
 	"time"
 )
 
@@ -13,6 +15,10 @@ type Settings struct {
 	Performance     PerformanceSettings `json:"performance"`
 	Privacy         PrivacySettings  `json:"privacy"`
 	UI              UISettings       `json:"ui"`
+	Schedule        ScheduleSettings `json:"schedule"`
+	Budgets         []LocationBudget `json:"budgets"`
+	Workspaces      WorkspaceSettings `json:"workspaces"`
+	PathRules       []PathRule       `json:"path_rules"`
 }
 
 // BackupSettings contains backup-related preferences
@@ -27,11 +33,31 @@ type BackupSettings struct {
 	MaxBackupSize       int64  `json:"max_backup_size_mb"` // in MB
 	AutoCleanup         bool   `json:"auto_cleanup"`
 	CleanupThreshold    int    `json:"cleanup_threshold_days"`
+	MaxSessions         int    `json:"max_sessions"`  // 0 for no limit
+	KeepLast            int    `json:"keep_last"`     // Most recent sessions that are never pruned
+	KeepDaily           int    `json:"keep_daily"`    // Grandfather-father-son rules, 0 disables
+	KeepWeekly          int    `json:"keep_weekly"`
+	KeepMonthly         int    `json:"keep_monthly"`
+	KeepYearly          int    `json:"keep_yearly"`
 	
 	// Backup behavior
 	CompressBackups     bool   `json:"compress_backups"`
+	CompressionAlgorithm string `json:"compression_algorithm"` // "gzip", "zstd"
+	CompressionLevel    string `json:"compression_level"`     // "fastest", "default", "better", "best"
+	EncryptBackups      bool   `json:"encrypt_backups"`
+	EncryptionKeyFile   string `json:"encryption_key_file"` // Key file used instead of a passphrase
 	VerifyIntegrity     bool   `json:"verify_integrity"`
 	CreateManifest      bool   `json:"create_manifest"`
+	
+	// Remote replication
+	RemoteBackend       string `json:"remote_backend"` // "none", "local", "network", "s3"
+	RemotePath          string `json:"remote_path"`    // Directory for local and network backends
+	S3Endpoint          string `json:"s3_endpoint"`
+	S3Region            string `json:"s3_region"`
+	S3Bucket            string `json:"s3_bucket"`
+	S3Prefix            string `json:"s3_prefix"`
+	S3AccessKeyID       string `json:"s3_access_key_id"` // Secret key is supplied at runtime, never stored
+	S3PathStyle         bool   `json:"s3_path_style"`
 }
 
 // SafetySettings contains safety-related preferences
@@ -55,6 +81,13 @@ type SafetySettings struct {
 	ProtectSystemPaths  bool   `json:"protect_system_paths"`
 	ProtectUserData     bool   `json:"protect_user_data"`
 	ProtectDevFiles     bool   `json:"protect_dev_files"`
+	
+	// Deletion mode
+	DeletionMode        string `json:"deletion_mode"`        // "backup", "quarantine", "trash"
+	QuarantineHoldDays  int    `json:"quarantine_hold_days"` // Days before quarantined files are purged
+	AtomicDeletion      bool   `json:"atomic_deletion"`      // Roll back the whole deletion if any file fails
+	InUseAction         string `json:"in_use_action"`        // "off", "warn", "block" files used by running apps
+	ClassifierWeights   map[string]int `json:"classifier_weights,omitempty"` // Confidence points by classifier signal, overriding the defaults
 }
 
 // PerformanceSettings contains performance-related preferences
@@ -117,6 +150,40 @@ type UISettings struct {
 	ScreenReader        bool   `json:"screen_reader"`
 }
 
+// ScheduleSettings contains automatic cleanup preferences
+type ScheduleSettings struct {
+	Enabled           bool     `json:"enabled"`
+	Cron              string   `json:"cron"`                // Five-field cron expression, empty disables
+	MinFreeSpaceMB    int64    `json:"min_free_space_mb"`   // Clean when free space drops below, 0 disables
+	WatchPath         string   `json:"watch_path"`          // Volume checked for free space, home if empty
+	Locations         []string `json:"locations"`           // Catalog location IDs automatic cleanups may touch
+	QuietHoursStart   string   `json:"quiet_hours_start"`   // "HH:MM", empty disables quiet hours
+	QuietHoursEnd     string   `json:"quiet_hours_end"`
+	SkipOnBattery     bool     `json:"skip_on_battery"`
+	MinBatteryPercent int      `json:"min_battery_percent"` // Also run on battery above this charge, 0 never
+}
+
+// LocationBudget caps the size of a cache location, evicting least recently used files
+type LocationBudget struct {
+	Path      string `json:"path"` // May start with ~
+	MaxSizeMB int64  `json:"max_size_mb"`
+	Enabled   bool   `json:"enabled"`
+}
+
+// PathRule always allows or never lets the cleaner touch paths matching a glob
+type PathRule struct {
+	Pattern string `json:"pattern"` // Anchored glob starting with / or ~/, ** matches any directories
+	Action  string `json:"action"`  // "allow" or "deny", a deny always wins
+	Note    string `json:"note,omitempty"`
+}
+
+// WorkspaceSettings contains where project build artifacts are searched
+type WorkspaceSettings struct {
+	Roots        []string `json:"roots"`         // Source trees, may start with ~
+	MaxDepth     int      `json:"max_depth"`     // Directories descended below each root
+	InactiveDays int      `json:"inactive_days"` // Artifacts of projects idle this long may be cleaned
+}
+
 // DefaultSettings returns the default settings configuration
 func DefaultSettings() *Settings {
 	return &Settings{
@@ -130,9 +197,14 @@ func DefaultSettings() *Settings {
 			MaxBackupSize:       1024, // 1GB
 			AutoCleanup:         true,
 			CleanupThreshold:    7,
+			KeepLast:            1,
 			CompressBackups:     true,
+			CompressionAlgorithm: "zstd",
+			CompressionLevel:    "default",
 			VerifyIntegrity:     true,
 			CreateManifest:      true,
+			RemoteBackend:       "none",
+			S3PathStyle:         true,
 		},
 		Safety: SafetySettings{
 			DefaultSafeLevel:    "Safe",
@@ -147,6 +219,9 @@ func DefaultSettings() *Settings {
 			ProtectSystemPaths:  true,
 			ProtectUserData:     true,
 			ProtectDevFiles:     true,
+			DeletionMode:        "backup",
+			QuarantineHoldDays:  7,
+			InUseAction:         "block",
 		},
 		Performance: PerformanceSettings{
 			ScanDepth:           5,
@@ -185,6 +260,21 @@ func DefaultSettings() *Settings {
 			ReduceAnimations:    false,
 			ScreenReader:        false,
 		},
+		Schedule: ScheduleSettings{
+			Enabled:           false,
+			Cron:              "0 3 * * 0", // Sundays at 03:00
+			Locations:         []string{},
+			QuietHoursStart:   "22:00",
+			QuietHoursEnd:     "07:00",
+			SkipOnBattery:     true,
+		},
+		Budgets: []LocationBudget{},
+		Workspaces: WorkspaceSettings{
+			Roots:        []string{},
+			MaxDepth:     5,
+			InactiveDays: 90,
+		},
+		PathRules: []PathRule{},
 	}
 }
 
@@ -202,6 +292,31 @@ func ValidateSettings(s *Settings) []string {
 	if s.Backup.CleanupThreshold < 1 || s.Backup.CleanupThreshold > s.Backup.RetentionDays {
 		errors = append(errors, "cleanup threshold must be between 1 and retention days")
 	}
+	if s.Backup.MaxSessions < 0 || s.Backup.KeepLast < 0 || s.Backup.KeepDaily < 0 ||
+		s.Backup.KeepWeekly < 0 || s.Backup.KeepMonthly < 0 || s.Backup.KeepYearly < 0 {
+		errors = append(errors, "backup session counts cannot be negative")
+	}
+	if s.Backup.CompressionAlgorithm != "gzip" && s.Backup.CompressionAlgorithm != "zstd" {
+		errors = append(errors, "compression algorithm must be gzip or zstd")
+	}
+	switch s.Backup.CompressionLevel {
+	case "fastest", "default", "better", "best":
+	default:
+		errors = append(errors, "compression level must be fastest, default, better, or best")
+	}
+	switch s.Backup.RemoteBackend {
+	case "none":
+	case "local", "network":
+		if s.Backup.RemotePath == "" {
+			errors = append(errors, "remote path is required for local and network backup targets")
+		}
+	case "s3":
+		if s.Backup.S3Endpoint == "" || s.Backup.S3Bucket == "" {
+			errors = append(errors, "s3 endpoint and bucket are required for s3 backup targets")
+		}
+	default:
+		errors = append(errors, "remote backend must be none, local, network, or s3")
+	}
 	
 	// Validate safety settings
 	if s.Safety.DefaultSafeLevel != "Safe" && s.Safety.DefaultSafeLevel != "Caution" && s.Safety.DefaultSafeLevel != "Risky" {
@@ -216,6 +331,40 @@ func ValidateSettings(s *Settings) []string {
 	if s.Safety.CautionAgeThreshold < 1 || s.Safety.CautionAgeThreshold > s.Safety.SafeAgeThreshold {
 		errors = append(errors, "caution age threshold must be between 1 and safe age threshold")
 	}
+	if s.Safety.DeletionMode != "backup" && s.Safety.DeletionMode != "quarantine" && s.Safety.DeletionMode != "trash" {
+		errors = append(errors, "deletion mode must be backup, quarantine or trash")
+	}
+	if s.Safety.QuarantineHoldDays < 1 || s.Safety.QuarantineHoldDays > 90 {
+		errors = append(errors, "quarantine hold days must be between 1 and 90")
+	}
+	if s.Safety.InUseAction != "off" && s.Safety.InUseAction != "warn" && s.Safety.InUseAction != "block" {
+		errors = append(errors, "in-use action must be off, warn or block")
+	}
+	for signal, weight := range s.Safety.ClassifierWeights {
+		if weight < -100 || weight > 100 {
+			errors = append(errors, "classifier weight of "+signal+" must be between -100 and 100")
+		}
+	}
+	
+	// Validate schedule settings
+	if s.Schedule.Cron != "" && len(strings.Fields(s.Schedule.Cron)) != 5 && !strings.HasPrefix(s.Schedule.Cron, "@") {
+		errors = append(errors, "schedule cron expression must have five fields")
+	}
+	if s.Schedule.MinFreeSpaceMB < 0 {
+		errors = append(errors, "schedule minimum free space cannot be negative")
+	}
+	if (s.Schedule.QuietHoursStart == "") != (s.Schedule.QuietHoursEnd == "") {
+		errors = append(errors, "quiet hours need both a start and an end")
+	}
+	for _, value := range []string{s.Schedule.QuietHoursStart, s.Schedule.QuietHoursEnd} {
+		if _, err := time.Parse("15:04", value); value != "" && err != nil {
+			errors = append(errors, "quiet hours must be given as HH:MM")
+			break
+		}
+	}
+	if s.Schedule.MinBatteryPercent < 0 || s.Schedule.MinBatteryPercent > 100 {
+		errors = append(errors, "minimum battery percent must be between 0 and 100")
+	}
 	
 	// Validate performance settings
 	if s.Performance.ScanDepth < 1 || s.Performance.ScanDepth > 20 {
@@ -262,6 +411,36 @@ func ValidateSettings(s *Settings) []string {
 		errors = append(errors, "notification duration must be between 1 and 10 seconds")
 	}
 	
+	// Validate workspace settings
+	if s.Workspaces.MaxDepth < 1 || s.Workspaces.MaxDepth > 20 {
+		errors = append(errors, "workspace max depth must be between 1 and 20")
+	}
+	if s.Workspaces.InactiveDays < 1 || s.Workspaces.InactiveDays > 3650 {
+		errors = append(errors, "workspace inactive days must be between 1 and 3650")
+	}
+	
+	// Validate budgets
+	for _, budget := range s.Budgets {
+		if budget.Path == "" {
+			errors = append(errors, "budget path must not be empty")
+		}
+		if budget.MaxSizeMB < 1 {
+			errors = append(errors, "budget for "+budget.Path+" must be at least 1MB")
+		}
+	}
+	
+	// Validate path rules
+	for _, rule := range s.PathRules {
+		if !strings.HasPrefix(rule.Pattern, "/") && rule.Pattern != "~" && !strings.HasPrefix(rule.Pattern, "~/") {
+			errors = append(errors, "path rule "+rule.Pattern+" must start with / or ~/")
+		} else if _, err := path.Match(rule.Pattern, ""); err != nil {
+			errors = append(errors, "path rule "+rule.Pattern+" is not a valid glob")
+		}
+		if rule.Action != "allow" && rule.Action != "deny" {
+			errors = append(errors, "path rule action must be allow or deny")
+		}
+	}
+	
 	return errors
 }
 
@@ -288,12 +467,38 @@ func MergeSettings(userSettings *Settings, defaults *Settings) *Settings {
 		merged.Backup.MaxBackupSize = userSettings.Backup.MaxBackupSize
 	}
 	merged.Backup.AutoCleanup = userSettings.Backup.AutoCleanup
+	merged.Backup.MaxSessions = userSettings.Backup.MaxSessions
+	if userSettings.Backup.KeepLast > 0 {
+		merged.Backup.KeepLast = userSettings.Backup.KeepLast
+	}
+	merged.Backup.KeepDaily = userSettings.Backup.KeepDaily
+	merged.Backup.KeepWeekly = userSettings.Backup.KeepWeekly
+	merged.Backup.KeepMonthly = userSettings.Backup.KeepMonthly
+	merged.Backup.KeepYearly = userSettings.Backup.KeepYearly
 	if userSettings.Backup.CleanupThreshold > 0 {
 		merged.Backup.CleanupThreshold = userSettings.Backup.CleanupThreshold
 	}
 	merged.Backup.CompressBackups = userSettings.Backup.CompressBackups
+	if userSettings.Backup.CompressionAlgorithm != "" {
+		merged.Backup.CompressionAlgorithm = userSettings.Backup.CompressionAlgorithm
+	}
+	if userSettings.Backup.CompressionLevel != "" {
+		merged.Backup.CompressionLevel = userSettings.Backup.CompressionLevel
+	}
+	merged.Backup.EncryptBackups = userSettings.Backup.EncryptBackups
+	merged.Backup.EncryptionKeyFile = userSettings.Backup.EncryptionKeyFile
 	merged.Backup.VerifyIntegrity = userSettings.Backup.VerifyIntegrity
 	merged.Backup.CreateManifest = userSettings.Backup.CreateManifest
+	if userSettings.Backup.RemoteBackend != "" {
+		merged.Backup.RemoteBackend = userSettings.Backup.RemoteBackend
+	}
+	merged.Backup.RemotePath = userSettings.Backup.RemotePath
+	merged.Backup.S3Endpoint = userSettings.Backup.S3Endpoint
+	merged.Backup.S3Region = userSettings.Backup.S3Region
+	merged.Backup.S3Bucket = userSettings.Backup.S3Bucket
+	merged.Backup.S3Prefix = userSettings.Backup.S3Prefix
+	merged.Backup.S3AccessKeyID = userSettings.Backup.S3AccessKeyID
+	merged.Backup.S3PathStyle = userSettings.Backup.S3PathStyle
 	
 	// Merge safety settings
 	if userSettings.Safety.DefaultSafeLevel != "" {
@@ -316,6 +521,19 @@ func MergeSettings(userSettings *Settings, defaults *Settings) *Settings {
 	merged.Safety.ProtectSystemPaths = userSettings.Safety.ProtectSystemPaths
 	merged.Safety.ProtectUserData = userSettings.Safety.ProtectUserData
 	merged.Safety.ProtectDevFiles = userSettings.Safety.ProtectDevFiles
+	merged.Safety.AtomicDeletion = userSettings.Safety.AtomicDeletion
+	if userSettings.Safety.DeletionMode != "" {
+		merged.Safety.DeletionMode = userSettings.Safety.DeletionMode
+	}
+	if userSettings.Safety.QuarantineHoldDays > 0 {
+		merged.Safety.QuarantineHoldDays = userSettings.Safety.QuarantineHoldDays
+	}
+	if userSettings.Safety.InUseAction != "" {
+		merged.Safety.InUseAction = userSettings.Safety.InUseAction
+	}
+	if userSettings.Safety.ClassifierWeights != nil {
+		merged.Safety.ClassifierWeights = userSettings.Safety.ClassifierWeights
+	}
 	
 	// Merge performance settings
 	if userSettings.Performance.ScanDepth > 0 {
@@ -384,6 +602,44 @@ func MergeSettings(userSettings *Settings, defaults *Settings) *Settings {
 	merged.UI.ReduceAnimations = userSettings.UI.ReduceAnimations
 	merged.UI.ScreenReader = userSettings.UI.ScreenReader
 	
+	// Merge schedule settings
+	merged.Schedule.Enabled = userSettings.Schedule.Enabled
+	if userSettings.Schedule.Cron != "" {
+		merged.Schedule.Cron = userSettings.Schedule.Cron
+	}
+	merged.Schedule.MinFreeSpaceMB = userSettings.Schedule.MinFreeSpaceMB
+	merged.Schedule.WatchPath = userSettings.Schedule.WatchPath
+	if userSettings.Schedule.Locations != nil {
+		merged.Schedule.Locations = userSettings.Schedule.Locations
+	}
+	if userSettings.Schedule.QuietHoursStart != "" && userSettings.Schedule.QuietHoursEnd != "" {
+		merged.Schedule.QuietHoursStart = userSettings.Schedule.QuietHoursStart
+		merged.Schedule.QuietHoursEnd = userSettings.Schedule.QuietHoursEnd
+	}
+	merged.Schedule.SkipOnBattery = userSettings.Schedule.SkipOnBattery
+	merged.Schedule.MinBatteryPercent = userSettings.Schedule.MinBatteryPercent
+	
+	// Merge budgets
+	if userSettings.Budgets != nil {
+		merged.Budgets = userSettings.Budgets
+	}
+	
+	// Merge path rules
+	if userSettings.PathRules != nil {
+		merged.PathRules = userSettings.PathRules
+	}
+	
+	// Merge workspace settings
+	if userSettings.Workspaces.Roots != nil {
+		merged.Workspaces.Roots = userSettings.Workspaces.Roots
+	}
+	if userSettings.Workspaces.MaxDepth > 0 {
+		merged.Workspaces.MaxDepth = userSettings.Workspaces.MaxDepth
+	}
+	if userSettings.Workspaces.InactiveDays > 0 {
+		merged.Workspaces.InactiveDays = userSettings.Workspaces.InactiveDays
+	}
+	
 	// Update metadata
 	merged.Version = userSettings.Version
 	merged.LastModified = time.Now()
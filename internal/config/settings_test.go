@@ -50,6 +50,41 @@ func TestValidateSettings(t *testing.T) {
 	if len(errors) == 0 {
 		t.Error("Invalid settings should produce validation errors")
 	}
+	
+	// Test invalid schedule settings
+	invalidSchedule := DefaultSettings()
+	invalidSchedule.Schedule.Cron = "0 3 * *"
+	invalidSchedule.Schedule.QuietHoursStart = "25:00"
+	invalidSchedule.Schedule.MinBatteryPercent = 150
+	
+	errors = ValidateSettings(invalidSchedule)
+	if len(errors) != 3 {
+		t.Errorf("Expected 3 schedule validation errors, got %v", errors)
+	}
+	
+	// Test path rules
+	rules := DefaultSettings()
+	rules.PathRules = []PathRule{
+		{Pattern: "~/.cache/JetBrains/*/index", Action: "deny"},
+		{Pattern: "/var/tmp/build-*", Action: "allow"},
+		{Pattern: "build-*", Action: "allow"},
+		{Pattern: "/tmp/[a-", Action: "deny"},
+		{Pattern: "/tmp/x", Action: "ignore"},
+	}
+	
+	errors = ValidateSettings(rules)
+	if len(errors) != 3 {
+		t.Errorf("Expected 3 path rule validation errors, got %v", errors)
+	}
+	
+	// Test classifier weights
+	weights := DefaultSettings()
+	weights.Safety.ClassifierWeights = map[string]int{"old_age": 30, "temp_directory": 150}
+	
+	errors = ValidateSettings(weights)
+	if len(errors) != 1 {
+		t.Errorf("Expected 1 classifier weight validation error, got %v", errors)
+	}
 }
 
 func TestMergeSettings(t *testing.T) {
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"runtime"
 	"sync"
@@ -74,6 +75,11 @@ func (e *AppError) Error() string {
 	return fmt.Sprintf("[%s] %s: %s", e.Type, e.Code, e.Message)
 }
 
+// Unwrap returns the original error so errors.Is and errors.As see through AppError
+func (e *AppError) Unwrap() error {
+	return e.OriginalErr
+}
+
 // ToJSON converts the error to JSON
 func (e *AppError) ToJSON() (string, error) {
 	data, err := json.Marshal(e)
@@ -411,8 +417,9 @@ func (er *ErrorRecovery) RetryWithBackoff(operation func() error) error {
 		
 		lastErr = err
 		
-		// Check if error is retryable
-		if appErr, ok := err.(*AppError); ok && !appErr.Retryable {
+		// Check if error is retryable, also when it was wrapped with context
+		var appErr *AppError
+		if errors.As(err, &appErr) && !appErr.Retryable {
 			return err
 		}
 	}
@@ -461,8 +468,8 @@ func (eh *ErrorHandler) HandleError(err error, context map[string]interface{}) *
 	}
 	
 	// Convert to AppError if needed
-	if appErr, ok := err.(*AppError); ok {
-		appErr = appErr
+	if existing, ok := err.(*AppError); ok {
+		appErr = existing
 	} else {
 		appErr = WrapError(err, ErrorTypeInternal, "unhandled error")
 	}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"embed"
+	"os"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -12,6 +13,24 @@ import (
 var assets embed.FS
 
 func main() {
+	// Run scheduled cleanups without a window
+	if len(os.Args) > 1 && os.Args[1] == "--daemon" {
+		if err := runDaemon(); err != nil {
+			println("Error:", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Measure the safety classifier against a labeled corpus of paths
+	if len(os.Args) > 2 && os.Args[1] == "--evaluate-classifier" {
+		if err := runClassifierEvaluation(os.Args[2]); err != nil {
+			println("Error:", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Create an instance of the app structure
 	app := NewApp()
 
@@ -0,0 +1,265 @@
+package artifacts
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxActivityFiles bounds how many source files are checked per project
+const maxActivityFiles = 50000
+
+// Activity sources reported in an Artifact
+const (
+	ActivityGit   = "git"   // The repository HEAD, index or reflog changed last
+	ActivityFiles = "files" // A source file changed last
+)
+
+// Rule detects an artifact directory by a marker file next to it
+type Rule struct {
+	Dir     string   `json:"dir"`
+	Markers []string `json:"markers"` // File names or glob patterns, any one suffices
+	Kind    string   `json:"kind"`
+}
+
+// DefaultRules returns the artifact directories of common build tools
+func DefaultRules() []Rule {
+	return []Rule{
+		{Dir: "node_modules", Markers: []string{"package.json"}, Kind: "node"},
+		{Dir: ".next", Markers: []string{"next.config.js", "next.config.mjs", "next.config.ts"}, Kind: "next"},
+		{Dir: "target", Markers: []string{"Cargo.toml"}, Kind: "cargo"},
+		{Dir: "target", Markers: []string{"pom.xml"}, Kind: "maven"},
+		{Dir: "build", Markers: []string{"build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"}, Kind: "gradle"},
+		{Dir: ".gradle", Markers: []string{"build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"}, Kind: "gradle"},
+		{Dir: ".build", Markers: []string{"Package.swift"}, Kind: "swift"},
+		{Dir: ".venv", Markers: []string{"pyproject.toml", "requirements.txt", "setup.py"}, Kind: "python"},
+		{Dir: "bin", Markers: []string{"*.csproj", "*.fsproj"}, Kind: "dotnet"},
+		{Dir: "obj", Markers: []string{"*.csproj", "*.fsproj"}, Kind: "dotnet"},
+	}
+}
+
+// Artifact is a regenerable build output directory of a project
+type Artifact struct {
+	Path           string    `json:"path"`
+	Kind           string    `json:"kind"`
+	ProjectPath    string    `json:"project_path"`
+	Marker         string    `json:"marker"`
+	Size           int64     `json:"size"`
+	FileCount      int       `json:"file_count"`
+	LastActivity   time.Time `json:"last_activity"` // Zero when the project has no sources or git metadata
+	ActivitySource string    `json:"activity_source,omitempty"`
+	InactiveDays   int       `json:"inactive_days"`
+}
+
+// Report lists the artifacts found under workspace roots
+type Report struct {
+	Roots     []string   `json:"roots"`
+	Artifacts []Artifact `json:"artifacts"`
+	TotalSize int64      `json:"total_size"`
+	Errors    []string   `json:"errors,omitempty"`
+}
+
+// projectActivity caches when a project last changed, shared by its artifacts
+type projectActivity struct {
+	at     time.Time
+	source string
+}
+
+// Finder walks workspace roots looking for project artifacts
+type Finder struct {
+	rules     []Rule
+	maxDepth  int
+	skip      map[string]bool // Directory names never descended into
+	artifacts map[string]bool // Directory names not counted as project sources
+	now       func() time.Time
+}
+
+// NewFinder creates a finder that descends at most maxDepth directories below each root
+func NewFinder(rules []Rule, maxDepth int) *Finder {
+	f := &Finder{
+		rules:     rules,
+		maxDepth:  maxDepth,
+		skip:      map[string]bool{".git": true, "node_modules": true},
+		artifacts: make(map[string]bool),
+		now:       time.Now,
+	}
+	for _, rule := range rules {
+		f.artifacts[rule.Dir] = true
+	}
+	return f
+}
+
+// Find returns the artifacts under roots, largest first
+func (f *Finder) Find(roots []string) *Report {
+	report := &Report{Roots: roots, Artifacts: []Artifact{}}
+	activity := make(map[string]projectActivity)
+	for _, root := range roots {
+		if err := f.walk(filepath.Clean(root), 0, report, activity); err != nil {
+			report.Errors = append(report.Errors, err.Error())
+		}
+	}
+
+	sort.Slice(report.Artifacts, func(i, j int) bool {
+		if report.Artifacts[i].Size != report.Artifacts[j].Size {
+			return report.Artifacts[i].Size > report.Artifacts[j].Size
+		}
+		return report.Artifacts[i].Path < report.Artifacts[j].Path
+	})
+	return report
+}
+
+// walk looks for artifacts among the subdirectories of dir
+func (f *Finder) walk(dir string, depth int, report *Report, activity map[string]projectActivity) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if rule, marker, ok := f.match(entry.Name(), entries); ok {
+			project, seen := activity[dir]
+			if !seen {
+				project.at, project.source = f.lastActivity(dir)
+				activity[dir] = project
+			}
+			artifact := Artifact{
+				Path:           path,
+				Kind:           rule.Kind,
+				ProjectPath:    dir,
+				Marker:         marker,
+				LastActivity:   project.at,
+				ActivitySource: project.source,
+			}
+			artifact.Size, artifact.FileCount = dirSize(path)
+			if !artifact.LastActivity.IsZero() {
+				artifact.InactiveDays = int(f.now().Sub(artifact.LastActivity).Hours() / 24)
+			}
+			report.Artifacts = append(report.Artifacts, artifact)
+			report.TotalSize += artifact.Size
+			continue
+		}
+		if f.skip[entry.Name()] || depth >= f.maxDepth {
+			continue
+		}
+		if err := f.walk(path, depth+1, report, activity); err != nil {
+			report.Errors = append(report.Errors, err.Error())
+		}
+	}
+	return nil
+}
+
+// match returns the rule whose directory is name and whose marker is among entries
+func (f *Finder) match(name string, entries []os.DirEntry) (Rule, string, bool) {
+	for _, rule := range f.rules {
+		if rule.Dir != name {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			for _, marker := range rule.Markers {
+				if matched, _ := filepath.Match(marker, entry.Name()); matched {
+					return rule, entry.Name(), true
+				}
+			}
+		}
+	}
+	return Rule{}, "", false
+}
+
+// lastActivity returns when a project last changed: the newest source file or
+// git metadata, whichever is later. Artifact and hidden directories are not sources.
+func (f *Finder) lastActivity(project string) (time.Time, string) {
+	var latest time.Time
+	source := ""
+	if gitTime := gitActivity(project); !gitTime.IsZero() {
+		latest, source = gitTime, ActivityGit
+	}
+
+	checked := 0
+	filepath.WalkDir(project, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if path != project && (f.artifacts[entry.Name()] || strings.HasPrefix(entry.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if checked++; checked > maxActivityFiles {
+			return filepath.SkipAll
+		}
+		if info, err := entry.Info(); err == nil && info.Mode().IsRegular() && info.ModTime().After(latest) {
+			latest, source = info.ModTime(), ActivityFiles
+		}
+		return nil
+	})
+	return latest, source
+}
+
+// gitActivity returns when the repository at project last moved HEAD, staged
+// or committed, or zero when project is not a repository root
+func gitActivity(project string) time.Time {
+	gitDir := filepath.Join(project, ".git")
+	info, err := os.Stat(gitDir)
+	if err != nil {
+		return time.Time{}
+	}
+	if !info.IsDir() {
+		// Worktrees and submodules point to their git directory
+		data, err := os.ReadFile(gitDir)
+		if err != nil || !strings.HasPrefix(string(data), "gitdir:") {
+			return time.Time{}
+		}
+		gitDir = strings.TrimSpace(strings.TrimPrefix(string(data), "gitdir:"))
+		if !filepath.IsAbs(gitDir) {
+			gitDir = filepath.Join(project, gitDir)
+		}
+	}
+
+	var latest time.Time
+	for _, name := range []string{"HEAD", "index", filepath.Join("logs", "HEAD")} {
+		if info, err := os.Stat(filepath.Join(gitDir, name)); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+// Stale returns the artifacts of projects inactive for at least days.
+// Artifacts without any known activity are left alone.
+func Stale(artifacts []Artifact, days int) []Artifact {
+	var stale []Artifact
+	for _, artifact := range artifacts {
+		if !artifact.LastActivity.IsZero() && artifact.InactiveDays >= days {
+			stale = append(stale, artifact)
+		}
+	}
+	return stale
+}
+
+// dirSize returns the total size and number of the regular files under path
+func dirSize(path string) (int64, int) {
+	var size int64
+	var count int
+	filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+				count++
+			}
+		}
+		return nil
+	})
+	return size, count
+}
@@ -0,0 +1,82 @@
+package artifacts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFinder(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	root := t.TempDir()
+	write := func(path string, size int, modified time.Time) {
+		t.Helper()
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := now.AddDate(0, 0, -200)
+	recent := now.AddDate(0, 0, -3)
+
+	// An old node project whose git HEAD moved recently
+	write("web/package.json", 10, old)
+	write("web/src/index.js", 10, old)
+	write("web/node_modules/left-pad/index.js", 100, recent) // Artifacts do not count as activity
+	write("web/.git/HEAD", 10, now.AddDate(0, 0, -10))
+	// An abandoned rust project
+	write("tools/cli/Cargo.toml", 10, old)
+	write("tools/cli/src/main.rs", 10, old)
+	write("tools/cli/target/debug/cli", 500, old)
+	// A gradle project with two artifacts
+	write("android/app/build.gradle", 10, recent)
+	write("android/app/build/outputs/app.apk", 300, recent)
+	write("android/app/.gradle/checksums", 20, recent)
+	// Directories named like artifacts without a marker are not artifacts
+	write("notes/build/index.html", 50, old)
+	write("deep/a/b/c/d/package.json", 10, old)
+	write("deep/a/b/c/d/node_modules/x.js", 10, old)
+
+	finder := NewFinder(DefaultRules(), 3)
+	finder.now = func() time.Time { return now }
+	report := finder.Find([]string{root})
+
+	found := make(map[string]Artifact)
+	for _, artifact := range report.Artifacts {
+		rel, _ := filepath.Rel(root, artifact.Path)
+		found[filepath.ToSlash(rel)] = artifact
+	}
+	if len(found) != 4 {
+		t.Fatalf("Expected 4 artifacts, got %v", found)
+	}
+	if report.Artifacts[0].Path != filepath.Join(root, "tools", "cli", "target") || report.TotalSize != 920 {
+		t.Errorf("Expected the largest artifact first and 920 bytes total, got %+v", report)
+	}
+
+	node := found["web/node_modules"]
+	if node.Kind != "node" || node.Marker != "package.json" || node.Size != 100 ||
+		node.ActivitySource != ActivityGit || node.InactiveDays != 10 {
+		t.Errorf("Unexpected node artifact: %+v", node)
+	}
+	if cargo := found["tools/cli/target"]; cargo.Kind != "cargo" || cargo.ActivitySource != ActivityFiles || cargo.InactiveDays != 200 {
+		t.Errorf("Unexpected cargo artifact: %+v", cargo)
+	}
+	if gradle := found["android/app/.gradle"]; gradle.Kind != "gradle" || gradle.InactiveDays != 3 {
+		t.Errorf("Unexpected gradle artifact: %+v", gradle)
+	}
+	if _, ok := found["android/app/build"]; !ok {
+		t.Error("Expected the gradle build directory")
+	}
+
+	stale := Stale(report.Artifacts, 30)
+	if len(stale) != 1 || stale[0].Path != filepath.Join(root, "tools", "cli", "target") {
+		t.Errorf("Expected only the rust target to be stale, got %+v", stale)
+	}
+}
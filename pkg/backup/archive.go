@@ -0,0 +1,604 @@
+package backup
+
+import (
+	"archive/tar"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+const (
+	archiveFormat       = "cache_app.backup_archive"
+	archiveVersion      = 1
+	archiveManifestName = "archive.json"
+	archiveBlobDir      = "blobs"
+)
+
+// ErrSessionExists is returned when an imported session ID is already present in the store
+var ErrSessionExists = errors.New("backup session already exists")
+
+// ArchiveManifest describes the content of a session archive
+type ArchiveManifest struct {
+	Format     string            `json:"format"`
+	Version    int               `json:"version"`
+	CreatedAt  time.Time         `json:"created_at"`
+	SourceHost string            `json:"source_host,omitempty"`
+	Session    BackupSession     `json:"session"`
+	Checksums  map[string]string `json:"checksums"` // Archive blob path -> SHA256 of the stored bytes
+	Encryption *encryptionHeader `json:"encryption,omitempty"`
+}
+
+// sealedArchiveManifest is the descriptor of an archive of an encrypted
+// session. Only the key check is readable; the archive manifest, which names
+// the original paths, is encrypted with the store key.
+type sealedArchiveManifest struct {
+	Format     string            `json:"format"`
+	Version    int               `json:"version"`
+	CreatedAt  time.Time         `json:"created_at"`
+	Encryption *encryptionHeader `json:"encryption,omitempty"`
+	Sealed     []byte            `json:"sealed,omitempty"` // Encrypted ArchiveManifest
+}
+
+// ArchiveInfo summarises an exported archive
+type ArchiveInfo struct {
+	Path      string    `json:"path"`
+	SessionID string    `json:"session_id"`
+	BlobCount int       `json:"blob_count"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ImportOptions controls how an archive is imported
+type ImportOptions struct {
+	// RenameOnConflict imports the session under a new ID when its ID already exists
+	RenameOnConflict bool `json:"rename_on_conflict"`
+	// SourceEncryption unlocks archives encrypted with a different key than the store
+	SourceEncryption EncryptionOptions `json:"-"`
+}
+
+// ImportResult summarises an archive import
+type ImportResult struct {
+	OriginalSessionID string `json:"original_session_id"`
+	SessionID         string `json:"session_id"`
+	Renamed           bool   `json:"renamed"`
+	ImportedBlobs     int    `json:"imported_blobs"`
+	ReencryptedBlobs  int    `json:"reencrypted_blobs"`
+	TotalSize         int64  `json:"total_size"`
+}
+
+// ExportSession writes a backup session and its blobs into a single tar
+// archive. Archives of encrypted sessions keep their descriptor encrypted and
+// name blobs by position, so no original path is readable without the key.
+func (bm *BackupManager) ExportSession(sessionID, destPath string) (*ArchiveInfo, error) {
+	session, err := bm.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	sealed := session.Encrypted
+	for _, entry := range session.Entries {
+		sealed = sealed || entry.Encrypted
+	}
+
+	archive := ArchiveManifest{
+		Format:    archiveFormat,
+		Version:   archiveVersion,
+		CreatedAt: time.Now(),
+		Session:   *session,
+		Checksums: make(map[string]string),
+	}
+	archive.SourceHost, _ = os.Hostname()
+	archive.Session.Entries = make([]BackupEntry, len(session.Entries))
+
+	// Blobs are referenced relative to the archive and checksummed up front so
+	// the descriptor can be written first and validated while streaming on import
+	blobSources := make(map[string]string)
+	for i, entry := range session.Entries {
+		if entry.hasBlob() {
+			name := path.Join(archiveBlobDir, fmt.Sprintf("%06d_%s", i, filepath.Base(entry.BackupPath)))
+			if sealed {
+				name = path.Join(archiveBlobDir, fmt.Sprintf("%06d", i))
+				if entry.Encrypted {
+					name += encryptionExtension
+				}
+			}
+			if err := bm.ensureLocalBlob(entry); err != nil {
+				return nil, fmt.Errorf("failed to fetch blob %s: %w", entry.BackupPath, err)
+			}
+			checksum, err := fileChecksum(entry.BackupPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read blob %s: %w", entry.BackupPath, err)
+			}
+			archive.Checksums[name] = checksum
+			blobSources[name] = entry.BackupPath
+			entry.BackupPath = name
+		} else {
+			entry.BackupPath = ""
+		}
+		archive.Session.Entries[i] = entry
+	}
+
+	var descriptor []byte
+	if sealed {
+		descriptor, err = bm.sealArchiveManifest(&archive)
+	} else {
+		descriptor, err = json.MarshalIndent(archive, "", "  ")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write archive manifest: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	file, err := os.Create(destPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer file.Close()
+
+	tw := tar.NewWriter(file)
+
+	if err := writeTarFile(tw, archiveManifestName, 0644, archive.CreatedAt, descriptor); err != nil {
+		return nil, err
+	}
+
+	for _, entry := range archive.Session.Entries {
+		if entry.BackupPath == "" {
+			continue
+		}
+		if err := copyFileToTar(tw, entry.BackupPath, blobSources[entry.BackupPath]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get archive info: %w", err)
+	}
+
+	return &ArchiveInfo{
+		Path:      destPath,
+		SessionID: sessionID,
+		BlobCount: len(blobSources),
+		Size:      info.Size(),
+		CreatedAt: archive.CreatedAt,
+	}, nil
+}
+
+// ImportArchive validates a session archive and adds its session to the backup store
+func (bm *BackupManager) ImportArchive(archivePath string, opts ImportOptions) (*ImportResult, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	tr := tar.NewReader(file)
+
+	archive, sealed, err := readArchiveManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceKey, targetKey, err := bm.importKeys(archive, opts)
+	if err != nil {
+		return nil, err
+	}
+	if sealed != nil {
+		if archive, err = bm.unsealArchiveManifest(archive, sealed, sourceKey); err != nil {
+			return nil, err
+		}
+	}
+
+	manifest, err := bm.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	session := archive.Session
+	result := &ImportResult{
+		OriginalSessionID: session.SessionID,
+		SessionID:         session.SessionID,
+	}
+
+	if sessionExists(manifest, session.SessionID) {
+		if !opts.RenameOnConflict {
+			return nil, fmt.Errorf("%w: %s", ErrSessionExists, session.SessionID)
+		}
+		for n := 1; sessionExists(manifest, result.SessionID); n++ {
+			result.SessionID = fmt.Sprintf("%s_imported_%d", session.SessionID, n)
+		}
+		result.Renamed = true
+	}
+
+	sessionDir := filepath.Join(bm.backupDir, "files", result.SessionID)
+	stagingDir := sessionDir + ".importing"
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return nil, fmt.Errorf("failed to clear staging directory: %w", err)
+	}
+	if err := os.MkdirAll(stagingDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	fail := func(err error) (*ImportResult, error) {
+		os.RemoveAll(stagingDir)
+		return nil, err
+	}
+
+	// Index entries by their archive blob path
+	blobEntries := make(map[string]int)
+	for i, entry := range session.Entries {
+		if entry.BackupPath != "" {
+			blobEntries[entry.BackupPath] = i
+		}
+	}
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(fmt.Errorf("failed to read archive: %w", err))
+		}
+
+		index, ok := blobEntries[header.Name]
+		if !ok || header.Typeflag != tar.TypeReg {
+			return fail(fmt.Errorf("unexpected archive member: %s", header.Name))
+		}
+		delete(blobEntries, header.Name)
+
+		entry := &session.Entries[index]
+		target := filepath.Join(stagingDir, path.Base(header.Name))
+		checksum, err := writeVerifiedBlob(tr, target, os.FileMode(header.Mode).Perm())
+		if err != nil {
+			return fail(err)
+		}
+		if checksum != archive.Checksums[header.Name] {
+			return fail(fmt.Errorf("checksum mismatch for %s: expected %s, got %s", header.Name, archive.Checksums[header.Name], checksum))
+		}
+
+		entry.BackupPath = target
+		if entry.Encrypted && sourceKey != nil {
+			if err := bm.reencodeImportedBlob(entry, target, sourceKey, targetKey); err != nil {
+				return fail(fmt.Errorf("failed to re-encrypt %s: %w", header.Name, err))
+			}
+			result.ReencryptedBlobs++
+		}
+
+		result.ImportedBlobs++
+		result.TotalSize += entry.Size
+	}
+
+	if len(blobEntries) > 0 {
+		return fail(fmt.Errorf("archive is incomplete: %d blobs missing", len(blobEntries)))
+	}
+
+	if err := os.Rename(stagingDir, sessionDir); err != nil {
+		return fail(fmt.Errorf("failed to move imported session into place: %w", err))
+	}
+
+	// Point entries at the final session directory; re-encoded blobs may have changed names
+	for i := range session.Entries {
+		entry := &session.Entries[i]
+		if entry.BackupPath != "" {
+			entry.BackupPath = filepath.Join(sessionDir, filepath.Base(entry.BackupPath))
+		}
+	}
+
+	session.SessionID = result.SessionID
+	if err := bm.saveSessionToManifest(&session); err != nil {
+		os.RemoveAll(sessionDir)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// importKeys determines whether encrypted blobs in an archive can be used as-is.
+// It returns a source key when blobs must be re-encoded for the target store.
+func (bm *BackupManager) importKeys(archive *ArchiveManifest, opts ImportOptions) (*encryptionKey, *encryptionKey, error) {
+	if archive.Encryption == nil {
+		return nil, nil, nil
+	}
+
+	// Same store key: blobs can be copied verbatim
+	if header, err := bm.loadEncryptionHeader(); err == nil && header != nil && header.KeyID == archive.Encryption.KeyID {
+		return nil, nil, nil
+	}
+
+	if !opts.SourceEncryption.HasKey() {
+		return nil, nil, fmt.Errorf("%w: archive was encrypted with key %s", ErrEncryptionKeyRequired, archive.Encryption.KeyID)
+	}
+	sourceKey, err := deriveEncryptionKey(opts.SourceEncryption, archive.Encryption)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !hmac.Equal([]byte(sourceKey.check), []byte(archive.Encryption.Check)) {
+		return nil, nil, fmt.Errorf("%w: wrong key for archive", ErrEncryptionKeyMismatch)
+	}
+
+	var targetKey *encryptionKey
+	if bm.GetOptions().Encryption.Enabled {
+		if targetKey, err = bm.resolveEncryptionKey(true); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return sourceKey, targetKey, nil
+}
+
+// reencodeImportedBlob decrypts a staged blob with the archive key and either
+// encrypts it with the store key or leaves it as plaintext if the store is unencrypted
+func (bm *BackupManager) reencodeImportedBlob(entry *BackupEntry, stagedPath string, sourceKey, targetKey *encryptionKey) error {
+	source, err := os.Open(stagedPath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	plain, err := newDecryptReader(source, sourceKey)
+	if err != nil {
+		return err
+	}
+
+	outputPath := stagedPath
+	if targetKey == nil {
+		outputPath = stagedPath[:len(stagedPath)-len(encryptionExtension)]
+	}
+	tempPath := outputPath + ".reencoding"
+
+	dest, err := os.Create(tempPath)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+
+	counter := &countingWriter{w: dest}
+	var sink io.WriteCloser = nopWriteCloser{counter}
+	if targetKey != nil {
+		if sink, err = newEncryptWriter(counter, targetKey); err != nil {
+			return err
+		}
+	}
+	if _, err := io.Copy(sink, plain); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	if err := sink.Close(); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	if err := os.Rename(tempPath, outputPath); err != nil {
+		return err
+	}
+	if outputPath != stagedPath {
+		os.Remove(stagedPath)
+	}
+
+	entry.BackupPath = outputPath
+	entry.StoredSize = counter.count
+	entry.Encrypted = targetKey != nil
+	entry.KeyID = ""
+	if targetKey != nil {
+		entry.KeyID = targetKey.id
+	}
+	return nil
+}
+
+// readArchiveManifest reads and validates the leading descriptor of an
+// archive. For sealed archives it returns the readable part of the descriptor
+// and the encrypted manifest, which unsealArchiveManifest opens.
+func readArchiveManifest(tr *tar.Reader) (*ArchiveManifest, []byte, error) {
+	header, err := tr.Next()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	if header.Name != archiveManifestName {
+		return nil, nil, fmt.Errorf("not a backup archive: expected %s, found %s", archiveManifestName, header.Name)
+	}
+
+	data, err := io.ReadAll(tr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read archive manifest: %w", err)
+	}
+	var envelope sealedArchiveManifest
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse archive manifest: %w", err)
+	}
+	if envelope.Format != archiveFormat {
+		return nil, nil, fmt.Errorf("not a backup archive: unknown format %q", envelope.Format)
+	}
+	if envelope.Version > archiveVersion {
+		return nil, nil, fmt.Errorf("archive version %d is newer than supported version %d", envelope.Version, archiveVersion)
+	}
+	if envelope.Sealed != nil {
+		if envelope.Encryption == nil {
+			return nil, nil, fmt.Errorf("sealed archive has no encryption header")
+		}
+		return &ArchiveManifest{
+			Format:     envelope.Format,
+			Version:    envelope.Version,
+			CreatedAt:  envelope.CreatedAt,
+			Encryption: envelope.Encryption,
+		}, envelope.Sealed, nil
+	}
+
+	var archive ArchiveManifest
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse archive manifest: %w", err)
+	}
+	if archive.Session.SessionID == "" {
+		return nil, nil, fmt.Errorf("archive does not contain a session ID")
+	}
+
+	return &archive, nil, nil
+}
+
+// sealArchiveManifest encrypts an archive manifest with the store key and
+// returns the descriptor to write in its place
+func (bm *BackupManager) sealArchiveManifest(archive *ArchiveManifest) ([]byte, error) {
+	header, err := bm.loadEncryptionHeader()
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("backup store has no encryption header")
+	}
+	key, err := bm.resolveEncryptionKey(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unlock encryption key: %w", err)
+	}
+
+	archive.Encryption = header
+	data, err := json.Marshal(archive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal archive manifest: %w", err)
+	}
+	sealed, err := encryptBytes(data, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt archive manifest: %w", err)
+	}
+
+	descriptor, err := json.MarshalIndent(sealedArchiveManifest{
+		Format:     archive.Format,
+		Version:    archive.Version,
+		CreatedAt:  archive.CreatedAt,
+		Encryption: header,
+		Sealed:     sealed,
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal archive manifest: %w", err)
+	}
+	return descriptor, nil
+}
+
+// unsealArchiveManifest decrypts the manifest of a sealed archive with the
+// archive key, or with the store key when importKeys found them to be the same
+func (bm *BackupManager) unsealArchiveManifest(envelope *ArchiveManifest, sealed []byte, sourceKey *encryptionKey) (*ArchiveManifest, error) {
+	key := sourceKey
+	if key == nil {
+		var err error
+		if key, err = bm.resolveEncryptionKey(false); err != nil {
+			return nil, fmt.Errorf("failed to unlock encryption key: %w", err)
+		}
+	}
+
+	data, err := decryptBytes(sealed, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt archive manifest: %w", err)
+	}
+	var archive ArchiveManifest
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return nil, fmt.Errorf("failed to parse archive manifest: %w", err)
+	}
+	if archive.Session.SessionID == "" {
+		return nil, fmt.Errorf("archive does not contain a session ID")
+	}
+
+	// The readable header decides which key opens the blobs
+	archive.Encryption = envelope.Encryption
+	return &archive, nil
+}
+
+// sessionExists reports whether the manifest contains a session with the given ID
+func sessionExists(manifest *BackupManifest, sessionID string) bool {
+	for _, session := range manifest.Sessions {
+		if session.SessionID == sessionID {
+			return true
+		}
+	}
+	return false
+}
+
+// fileChecksum calculates the SHA256 checksum of a file's raw bytes
+func fileChecksum(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writeTarFile adds an in-memory file to a tar archive
+func writeTarFile(tw *tar.Writer, name string, mode int64, modTime time.Time, data []byte) error {
+	header := &tar.Header{
+		Name:     name,
+		Mode:     mode,
+		Size:     int64(len(data)),
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// copyFileToTar streams a file from disk into a tar archive under name
+func copyFileToTar(tw *tar.Writer, name, source string) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to open blob %s: %w", source, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to get blob info %s: %w", source, err)
+	}
+
+	header := &tar.Header{
+		Name:     name,
+		Mode:     int64(info.Mode().Perm()),
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := io.Copy(tw, file); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// writeVerifiedBlob writes an archive member to target and returns the SHA256 of its bytes
+func writeVerifiedBlob(r io.Reader, target string, mode os.FileMode) (string, error) {
+	if mode == 0 {
+		mode = 0644
+	}
+	dest, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode|0200)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", target, err)
+	}
+	defer dest.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(dest, hash), r); err != nil {
+		return "", fmt.Errorf("failed to extract %s: %w", target, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,252 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cache_app/internal/ui"
+)
+
+func TestSessionArchiveRoundTrip(t *testing.T) {
+	testDir := t.TempDir()
+	content := []byte(strings.Repeat("archived cache data\n", 256))
+	cacheFile := filepath.Join(testDir, "cache.db")
+	if err := os.WriteFile(cacheFile, content, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	source, err := NewBackupManagerWithDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create backup manager: %v", err)
+	}
+	options := source.GetOptions()
+	options.Compression = CompressionOptions{Enabled: true, Algorithm: CompressionZstd, Level: CompressionLevelDefault}
+	source.SetOptions(options)
+
+	session, err := source.BackupFiles([]string{cacheFile}, "archive_test")
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "session.tar")
+	info, err := source.ExportSession(session.SessionID, archivePath)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if info.BlobCount != 1 || info.Size == 0 {
+		t.Errorf("Unexpected archive info: %+v", info)
+	}
+
+	target, err := NewBackupManagerWithDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create backup manager: %v", err)
+	}
+
+	t.Run("Import", func(t *testing.T) {
+		result, err := target.ImportArchive(archivePath, ImportOptions{})
+		if err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+		if result.SessionID != session.SessionID || result.ImportedBlobs != 1 {
+			t.Errorf("Unexpected import result: %+v", result)
+		}
+
+		valid, problems, err := target.VerifyBackupIntegrity(session.SessionID)
+		if err != nil || !valid {
+			t.Fatalf("Integrity verification of imported session failed: %v %v", err, problems)
+		}
+
+		os.Remove(cacheFile)
+		restore, err := NewRestoreManager(target).RestoreSession(session.SessionID, false)
+		if err != nil || restore.SuccessCount != 1 {
+			t.Fatalf("Restore from imported session failed: %v (%+v)", err, restore)
+		}
+		restored, _ := os.ReadFile(cacheFile)
+		if !bytes.Equal(restored, content) {
+			t.Error("Restored content does not match original")
+		}
+	})
+
+	t.Run("Collision", func(t *testing.T) {
+		if _, err := target.ImportArchive(archivePath, ImportOptions{}); !errors.Is(err, ErrSessionExists) {
+			t.Fatalf("Expected ErrSessionExists, got %v", err)
+		}
+
+		result, err := target.ImportArchive(archivePath, ImportOptions{RenameOnConflict: true})
+		if err != nil {
+			t.Fatalf("Import with rename failed: %v", err)
+		}
+		if !result.Renamed || result.SessionID == session.SessionID {
+			t.Errorf("Expected renamed session, got %+v", result)
+		}
+		if valid, problems, err := target.VerifyBackupIntegrity(result.SessionID); err != nil || !valid {
+			t.Errorf("Integrity verification of renamed session failed: %v %v", err, problems)
+		}
+	})
+
+	t.Run("Corrupted", func(t *testing.T) {
+		corrupted := filepath.Join(t.TempDir(), "corrupted.tar")
+		rewriteArchive(t, archivePath, corrupted, func(name string, data []byte) []byte {
+			if strings.HasPrefix(name, archiveBlobDir+"/") {
+				data[len(data)/2] ^= 0xff
+			}
+			return data
+		})
+
+		fresh, _ := NewBackupManagerWithDir(t.TempDir())
+		if _, err := fresh.ImportArchive(corrupted, ImportOptions{}); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+			t.Fatalf("Expected checksum mismatch, got %v", err)
+		}
+		if sessions, _ := fresh.ListSessions(); len(sessions) != 0 {
+			t.Error("Corrupted archive should not add a session")
+		}
+	})
+}
+
+func TestExportRemoteOnlySession(t *testing.T) {
+	content := []byte(strings.Repeat("remote only blob\n", 128))
+	cacheFile := filepath.Join(t.TempDir(), "cache.db")
+	if err := os.WriteFile(cacheFile, content, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	remote, err := NewLocalBackend(filepath.Join(t.TempDir(), "remote"))
+	if err != nil {
+		t.Fatalf("Failed to create local backend: %v", err)
+	}
+	recovery := ui.NewErrorRecovery(2, time.Millisecond)
+
+	source, _ := NewBackupManagerWithDir(t.TempDir())
+	source.SetRemote(remote, recovery)
+	session, err := source.BackupFiles([]string{cacheFile}, "remote_export")
+	if err != nil || session.RemoteError != "" {
+		t.Fatalf("Backup failed: %v %s", err, session.RemoteError)
+	}
+
+	// The pulled session has no local blobs until they are fetched
+	target, _ := NewBackupManagerWithDir(t.TempDir())
+	target.SetRemote(remote, recovery)
+	if _, err := target.PullFromRemote(false); err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "session.tar")
+	if _, err := target.ExportSession(session.SessionID, archivePath); err != nil {
+		t.Fatalf("Export of remote-only session failed: %v", err)
+	}
+
+	imported, _ := NewBackupManagerWithDir(t.TempDir())
+	result, err := imported.ImportArchive(archivePath, ImportOptions{})
+	if err != nil || result.ImportedBlobs != 1 {
+		t.Fatalf("Import failed: %v %+v", err, result)
+	}
+	if valid, problems, err := imported.VerifyBackupIntegrity(session.SessionID); err != nil || !valid {
+		t.Fatalf("Integrity verification of imported session failed: %v %v", err, problems)
+	}
+}
+
+func TestEncryptedSessionArchiveImport(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "Cookies")
+	content := []byte("token=secret")
+	if err := os.WriteFile(cacheFile, content, 0600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	source, _ := NewBackupManagerWithDir(t.TempDir())
+	source.SetOptions(BackupOptions{Encryption: EncryptionOptions{Enabled: true, Passphrase: "source"}})
+	session, err := source.BackupFiles([]string{cacheFile}, "archive_test")
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "session.tar")
+	if _, err := source.ExportSession(session.SessionID, archivePath); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	// Neither the descriptor nor the blob names may reveal the original paths
+	archiveData, _ := os.ReadFile(archivePath)
+	if bytes.Contains(archiveData, []byte(cacheFile)) || bytes.Contains(archiveData, []byte("Cookies")) {
+		t.Fatal("Encrypted archive contains plaintext paths")
+	}
+
+	// The same store opens the sealed descriptor with its own key
+	if result, err := source.ImportArchive(archivePath, ImportOptions{RenameOnConflict: true}); err != nil || result.ReencryptedBlobs != 0 {
+		t.Fatalf("Import into the source store failed: %v (%+v)", err, result)
+	}
+
+	target, _ := NewBackupManagerWithDir(t.TempDir())
+	target.SetOptions(BackupOptions{Encryption: EncryptionOptions{Enabled: true, Passphrase: "target"}})
+
+	if _, err := target.ImportArchive(archivePath, ImportOptions{}); !errors.Is(err, ErrEncryptionKeyRequired) {
+		t.Fatalf("Expected ErrEncryptionKeyRequired, got %v", err)
+	}
+	wrongKey := ImportOptions{SourceEncryption: EncryptionOptions{Passphrase: "wrong"}}
+	if _, err := target.ImportArchive(archivePath, wrongKey); !errors.Is(err, ErrEncryptionKeyMismatch) {
+		t.Fatalf("Expected ErrEncryptionKeyMismatch, got %v", err)
+	}
+
+	result, err := target.ImportArchive(archivePath, ImportOptions{SourceEncryption: EncryptionOptions{Passphrase: "source"}})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.ReencryptedBlobs != 1 {
+		t.Errorf("Expected 1 re-encrypted blob, got %+v", result)
+	}
+
+	imported, err := target.GetSession(result.SessionID)
+	if err != nil {
+		t.Fatalf("Failed to load imported session: %v", err)
+	}
+	var restored bytes.Buffer
+	reader, err := target.openBlob(imported.Entries[0])
+	if err != nil {
+		t.Fatalf("Failed to open imported blob with target key: %v", err)
+	}
+	io.Copy(&restored, reader)
+	reader.Close()
+	if !bytes.Equal(restored.Bytes(), content) {
+		t.Error("Imported blob does not decrypt to original content")
+	}
+}
+
+// rewriteArchive copies a tar archive, passing each member through modify
+func rewriteArchive(t *testing.T, src, dst string, modify func(name string, data []byte) []byte) {
+	t.Helper()
+
+	in, err := os.Open(src)
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	defer out.Close()
+
+	tr := tar.NewReader(in)
+	tw := tar.NewWriter(out)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read archive: %v", err)
+		}
+		data, _ := io.ReadAll(tr)
+		data = modify(header.Name, data)
+		header.Size = int64(len(data))
+		tw.WriteHeader(header)
+		tw.Write(data)
+	}
+	tw.Close()
+}
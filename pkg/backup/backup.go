@@ -9,7 +9,6 @@ import (
 type BackupSystem struct {
 	manager  *BackupManager
 	restorer *RestoreManager
-	deleter  *SafeDeleter
 }
 
 // NewBackupSystem creates a new backup system instance
@@ -19,14 +18,26 @@ func NewBackupSystem() (*BackupSystem, error) {
 		return nil, fmt.Errorf("failed to create backup manager: %w", err)
 	}
 
-	restorer := NewRestoreManager(manager)
-	deleter := NewSafeDeleter(manager)
+	return newBackupSystem(manager), nil
+}
+
+// NewBackupSystemWithDir creates a new backup system rooted at a custom backup directory
+func NewBackupSystemWithDir(backupDir string) (*BackupSystem, error) {
+	manager, err := NewBackupManagerWithDir(backupDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup manager: %w", err)
+	}
 
+	return newBackupSystem(manager), nil
+}
+
+// newBackupSystem wires the restorer around a backup manager. Deleting backed
+// up files is the job of the deletion package.
+func newBackupSystem(manager *BackupManager) *BackupSystem {
 	return &BackupSystem{
 		manager:  manager,
-		restorer: restorer,
-		deleter:  deleter,
-	}, nil
+		restorer: NewRestoreManager(manager),
+	}
 }
 
 // GetManager returns the backup manager
@@ -39,16 +50,22 @@ func (bs *BackupSystem) GetRestorer() *RestoreManager {
 	return bs.restorer
 }
 
-// GetDeleter returns the safe deleter
-func (bs *BackupSystem) GetDeleter() *SafeDeleter {
-	return bs.deleter
-}
-
 // BackupFiles creates backups of the specified files
 func (bs *BackupSystem) BackupFiles(files []string, operation string) (*BackupSession, error) {
 	return bs.manager.BackupFiles(files, operation)
 }
 
+// BackupFilesForDeletion creates backups of files that are deleted right afterwards
+func (bs *BackupSystem) BackupFilesForDeletion(files []string, operation string) (*BackupSession, error) {
+	return bs.manager.BackupFilesForDeletion(files, operation)
+}
+
+// UnlinkOriginals breaks the hard links between a session and the originals a
+// deletion kept
+func (bs *BackupSystem) UnlinkOriginals(session *BackupSession) error {
+	return bs.manager.UnlinkOriginals(session)
+}
+
 // RestoreSession restores all files from a backup session
 func (bs *BackupSystem) RestoreSession(sessionID string, overwrite bool) (*RestoreResult, error) {
 	return bs.restorer.RestoreSession(sessionID, overwrite)
@@ -59,14 +76,33 @@ func (bs *BackupSystem) RestoreFiles(sessionID string, filePaths []string, overw
 	return bs.restorer.RestoreFiles(sessionID, filePaths, overwrite)
 }
 
-// DeleteFilesWithBackup safely deletes files after creating backups
-func (bs *BackupSystem) DeleteFilesWithBackup(files []string, operation string) (*DeletionResult, error) {
-	return bs.deleter.DeleteFilesWithBackup(files, operation)
+// Restore restores entries of a backup session using the given conflict strategy and target
+func (bs *BackupSystem) Restore(sessionID string, options RestoreOptions) (*RestoreResult, error) {
+	return bs.restorer.Restore(sessionID, options)
+}
+
+// PreviewRestore reports what Restore would do with each entry without changing anything
+func (bs *BackupSystem) PreviewRestore(sessionID string, options RestoreOptions) (*RestoreResult, error) {
+	return bs.restorer.PreviewRestoreWithOptions(sessionID, options)
+}
+
+// FileVersions returns the backup history of path and every backed up path below it
+func (bs *BackupSystem) FileVersions(path string) ([]PathHistory, error) {
+	index, err := bs.manager.BuildVersionIndex()
+	if err != nil {
+		return nil, err
+	}
+	return index.Under(path), nil
+}
+
+// RestoreAsOf restores path and its contents to the newest versions backed up at or before asOf
+func (bs *BackupSystem) RestoreAsOf(path string, asOf time.Time, options RestoreOptions) (*RestoreResult, error) {
+	return bs.restorer.RestoreAsOf(path, asOf, options)
 }
 
-// DeleteFilesWithoutBackup deletes files without creating backups
-func (bs *BackupSystem) DeleteFilesWithoutBackup(files []string, operation string) (*DeletionResult, error) {
-	return bs.deleter.DeleteFilesWithoutBackup(files, operation)
+// PreviewRestoreAsOf reports what RestoreAsOf would do without changing anything
+func (bs *BackupSystem) PreviewRestoreAsOf(path string, asOf time.Time, options RestoreOptions) (*RestoreResult, error) {
+	return bs.restorer.PreviewRestoreAsOf(path, asOf, options)
 }
 
 // GetManifest returns the current backup manifest
@@ -94,6 +130,53 @@ func (bs *BackupSystem) CleanupOldBackups(olderThan time.Duration) error {
 	return bs.manager.CleanupOldBackups(olderThan)
 }
 
+// PreviewRetention reports which sessions the retention policy would prune
+func (bs *BackupSystem) PreviewRetention() (*RetentionPlan, error) {
+	return bs.manager.PreviewRetention()
+}
+
+// ApplyRetention prunes the sessions selected by the retention policy
+func (bs *BackupSystem) ApplyRetention() (*RetentionPlan, error) {
+	if bs.IsAnyOperationRunning() {
+		return nil, fmt.Errorf("cannot apply retention while an operation is running")
+	}
+	return bs.manager.ApplyRetention()
+}
+
+// RotateEncryptionKey re-encrypts all backups with a key derived from the new options
+func (bs *BackupSystem) RotateEncryptionKey(newOptions EncryptionOptions) (*KeyRotationResult, error) {
+	if bs.IsAnyOperationRunning() {
+		return nil, fmt.Errorf("cannot rotate encryption key while an operation is running")
+	}
+	return bs.manager.RotateEncryptionKey(newOptions)
+}
+
+// ExportSession writes a backup session into a portable archive
+func (bs *BackupSystem) ExportSession(sessionID, destPath string) (*ArchiveInfo, error) {
+	return bs.manager.ExportSession(sessionID, destPath)
+}
+
+// ImportArchive adds the session contained in an archive to the backup store
+func (bs *BackupSystem) ImportArchive(archivePath string, opts ImportOptions) (*ImportResult, error) {
+	if bs.IsAnyOperationRunning() {
+		return nil, fmt.Errorf("cannot import archive while an operation is running")
+	}
+	return bs.manager.ImportArchive(archivePath, opts)
+}
+
+// SyncToRemote uploads sessions that are not yet replicated to the remote backend
+func (bs *BackupSystem) SyncToRemote() (*RemoteSyncResult, error) {
+	return bs.manager.SyncToRemote()
+}
+
+// PullFromRemote adds sessions stored on the remote backend to the local store
+func (bs *BackupSystem) PullFromRemote(downloadBlobs bool) (*RemoteSyncResult, error) {
+	if bs.IsAnyOperationRunning() {
+		return nil, fmt.Errorf("cannot pull from remote while an operation is running")
+	}
+	return bs.manager.PullFromRemote(downloadBlobs)
+}
+
 // GetBackupProgressChannel returns the backup progress channel
 func (bs *BackupSystem) GetBackupProgressChannel() <-chan BackupProgress {
 	return bs.manager.GetProgressChannel()
@@ -104,21 +187,15 @@ func (bs *BackupSystem) GetRestoreProgressChannel() <-chan RestoreProgress {
 	return bs.restorer.GetProgressChannel()
 }
 
-// GetDeletionProgressChannel returns the deletion progress channel
-func (bs *BackupSystem) GetDeletionProgressChannel() <-chan DeletionProgress {
-	return bs.deleter.GetProgressChannel()
-}
-
 // StopAllOperations stops all running operations
 func (bs *BackupSystem) StopAllOperations() {
 	bs.manager.StopBackup()
 	bs.restorer.StopRestore()
-	bs.deleter.StopDeletion()
 }
 
 // IsAnyOperationRunning returns true if any operation is currently running
 func (bs *BackupSystem) IsAnyOperationRunning() bool {
-	return bs.manager.IsBackingUp() || bs.restorer.IsRestoring() || bs.deleter.IsDeleting()
+	return bs.manager.IsBackingUp() || bs.restorer.IsRestoring()
 }
 
 // GetSystemStatus returns the current status of all components
@@ -128,13 +205,20 @@ func (bs *BackupSystem) GetSystemStatus() map[string]interface{} {
 		manifest = &BackupManifest{}
 	}
 
+	var storedSize, originalSize int64
+	for _, session := range manifest.Sessions {
+		storedSize += session.StoredSize
+		originalSize += session.BackupSize
+	}
+
 	return map[string]interface{}{
 		"backup_manager_running":  bs.manager.IsBackingUp(),
 		"restore_manager_running": bs.restorer.IsRestoring(),
-		"deleter_running":         bs.deleter.IsDeleting(),
 		"total_sessions":          manifest.TotalSessions,
 		"total_files":             manifest.TotalFiles,
 		"total_size":              manifest.TotalSize,
+		"original_size":           originalSize,
+		"stored_size":             storedSize,
 		"last_updated":            manifest.LastUpdated,
 		"backup_directory":         bs.manager.backupDir,
 	}
@@ -0,0 +1,217 @@
+package backup
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+)
+
+// blobInfo describes a blob written to the backup store
+type blobInfo struct {
+	Path        string               // Location of the stored blob
+	Checksum    string               // SHA256 of the original content
+	Compression CompressionAlgorithm // Codec applied to the stored blob
+	StoredSize  int64                // Size of the blob on disk
+	KeyID       string               // Key the blob is encrypted with, empty if plaintext
+	SkipReason  string               // Why compression was not applied, if enabled
+}
+
+// compressionExtension returns the file extension used for blobs of the given codec
+func compressionExtension(algorithm CompressionAlgorithm) string {
+	switch algorithm {
+	case CompressionGzip:
+		return ".gz"
+	case CompressionZstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+// backupPathTaken reports whether a blob already exists for the given base
+// path, with or without a compression extension
+func backupPathTaken(path string) bool {
+	for _, algorithm := range []CompressionAlgorithm{CompressionNone, CompressionGzip, CompressionZstd} {
+		for _, suffix := range []string{"", encryptionExtension} {
+			if _, err := os.Lstat(path + compressionExtension(algorithm) + suffix); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeBlob copies src into the backup store at dst, compressing it when the
+// manager options allow and the content is not already compressed, then
+// encrypting it when encryption is enabled. Blobs get extensions matching the
+// transformations applied. The returned checksum always refers to the
+// original content.
+func (bm *BackupManager) writeBlob(src, dst string) (blobInfo, error) {
+	var info blobInfo
+
+	sourceFile, err := os.Open(src)
+	if err != nil {
+		return info, fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer sourceFile.Close()
+
+	sourceInfo, err := sourceFile.Stat()
+	if err != nil {
+		return info, fmt.Errorf("failed to get source file info: %w", err)
+	}
+
+	reader := bufio.NewReaderSize(sourceFile, sniffSize)
+	header, err := reader.Peek(sniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return info, fmt.Errorf("failed to read source file: %w", err)
+	}
+
+	opts := bm.GetOptions()
+	info.Compression, info.SkipReason = chooseCompression(opts.Compression, sourceInfo.Size(), header)
+	info.Path = dst + compressionExtension(info.Compression)
+
+	var key *encryptionKey
+	if opts.Encryption.Enabled {
+		key, err = bm.resolveEncryptionKey(true)
+		if err != nil {
+			return info, err
+		}
+		info.KeyID = key.id
+		info.Path += encryptionExtension
+	}
+
+	destFile, err := os.Create(info.Path)
+	if err != nil {
+		return info, fmt.Errorf("failed to create destination file: %w", err)
+	}
+	defer destFile.Close()
+
+	counter := &countingWriter{w: destFile}
+	var sink io.WriteCloser = nopWriteCloser{counter}
+	if key != nil {
+		if sink, err = newEncryptWriter(counter, key); err != nil {
+			return info, err
+		}
+	}
+
+	encoder, err := newCompressWriter(sink, info.Compression, opts.Compression.Level)
+	if err != nil {
+		return info, err
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(encoder, io.TeeReader(reader, hash)); err != nil {
+		encoder.Close()
+		return info, fmt.Errorf("failed to copy file content: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return info, fmt.Errorf("failed to finish compressed stream: %w", err)
+	}
+	if err := sink.Close(); err != nil {
+		return info, fmt.Errorf("failed to finish encrypted stream: %w", err)
+	}
+
+	if err := destFile.Chmod(sourceInfo.Mode()); err != nil {
+		return info, fmt.Errorf("failed to set destination file permissions: %w", err)
+	}
+
+	info.Checksum = fmt.Sprintf("%x", hash.Sum(nil))
+	info.StoredSize = counter.count
+	return info, nil
+}
+
+// openBlob opens the stored blob of an entry and returns a reader yielding
+// the original, decrypted and decompressed content
+func (bm *BackupManager) openBlob(entry BackupEntry) (io.ReadCloser, error) {
+	if err := bm.ensureLocalBlob(entry); err != nil {
+		return nil, fmt.Errorf("failed to fetch backup file: %w", err)
+	}
+
+	file, err := os.Open(entry.BackupPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup file: %w", err)
+	}
+
+	var stored io.Reader = file
+	if entry.Encrypted {
+		key, err := bm.keyForEntry(entry)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if stored, err = newDecryptReader(file, key); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	decoder, err := newDecompressReader(stored, entry.Compression)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open %s stream: %w", entry.Compression, err)
+	}
+
+	return &blobReader{ReadCloser: decoder, file: file}, nil
+}
+
+// extractBlob writes the original content of an entry to dst
+func (bm *BackupManager) extractBlob(entry BackupEntry, dst string) error {
+	blob, err := bm.openBlob(entry)
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+
+	blobInfo, err := os.Stat(entry.BackupPath)
+	if err != nil {
+		return fmt.Errorf("failed to get backup file info: %w", err)
+	}
+
+	destFile, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create destination file: %w", err)
+	}
+	defer destFile.Close()
+
+	if _, err := io.Copy(destFile, blob); err != nil {
+		return fmt.Errorf("failed to copy file content: %w", err)
+	}
+
+	if err := destFile.Chmod(blobInfo.Mode()); err != nil {
+		return fmt.Errorf("failed to set destination file permissions: %w", err)
+	}
+
+	return nil
+}
+
+// blobChecksum calculates the SHA256 checksum of the original content of an entry
+func (bm *BackupManager) blobChecksum(entry BackupEntry) (string, error) {
+	blob, err := bm.openBlob(entry)
+	if err != nil {
+		return "", err
+	}
+	defer blob.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, blob); err != nil {
+		return "", fmt.Errorf("failed to calculate hash: %w", err)
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// blobReader closes both the decoder and the underlying file
+type blobReader struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (br *blobReader) Close() error {
+	err := br.ReadCloser.Close()
+	if fileErr := br.file.Close(); err == nil {
+		err = fileErr
+	}
+	return err
+}
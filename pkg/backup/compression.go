@@ -0,0 +1,205 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressionAlgorithm identifies the codec used for a backup blob
+type CompressionAlgorithm string
+
+const (
+	CompressionNone CompressionAlgorithm = "none"
+	CompressionGzip CompressionAlgorithm = "gzip"
+	CompressionZstd CompressionAlgorithm = "zstd"
+)
+
+// CompressionLevel is a codec-independent compression level
+type CompressionLevel string
+
+const (
+	CompressionLevelFastest CompressionLevel = "fastest"
+	CompressionLevelDefault CompressionLevel = "default"
+	CompressionLevelBetter  CompressionLevel = "better"
+	CompressionLevelBest    CompressionLevel = "best"
+)
+
+// sniffSize is the number of leading bytes inspected for magic numbers
+const sniffSize = 512
+
+// CompressionOptions controls compression of backup blobs
+type CompressionOptions struct {
+	Enabled   bool                 `json:"enabled"`
+	Algorithm CompressionAlgorithm `json:"algorithm"`
+	Level     CompressionLevel     `json:"level"`
+	MinSize   int64                `json:"min_size"` // Files smaller than this are stored raw
+}
+
+// DefaultCompressionOptions returns the default compression configuration
+func DefaultCompressionOptions() CompressionOptions {
+	return CompressionOptions{
+		Enabled:   false,
+		Algorithm: CompressionZstd,
+		Level:     CompressionLevelDefault,
+		MinSize:   1024,
+	}
+}
+
+// ParseCompressionAlgorithm converts a settings value into a CompressionAlgorithm
+func ParseCompressionAlgorithm(value string) (CompressionAlgorithm, error) {
+	switch CompressionAlgorithm(value) {
+	case CompressionGzip, CompressionZstd, CompressionNone:
+		return CompressionAlgorithm(value), nil
+	case "":
+		return CompressionZstd, nil
+	default:
+		return "", fmt.Errorf("unsupported compression algorithm: %s", value)
+	}
+}
+
+// ParseCompressionLevel converts a settings value into a CompressionLevel
+func ParseCompressionLevel(value string) (CompressionLevel, error) {
+	switch CompressionLevel(value) {
+	case CompressionLevelFastest, CompressionLevelDefault, CompressionLevelBetter, CompressionLevelBest:
+		return CompressionLevel(value), nil
+	case "":
+		return CompressionLevelDefault, nil
+	default:
+		return "", fmt.Errorf("unsupported compression level: %s", value)
+	}
+}
+
+// compressedMagic lists signatures of formats that are already compressed
+var compressedMagic = []struct {
+	format string
+	offset int
+	magic  []byte
+}{
+	{"gzip", 0, []byte{0x1f, 0x8b}},
+	{"zstd", 0, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{"xz", 0, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+	{"bzip2", 0, []byte("BZh")},
+	{"lz4", 0, []byte{0x04, 0x22, 0x4d, 0x18}},
+	{"zip", 0, []byte{'P', 'K', 0x03, 0x04}},
+	{"7z", 0, []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}},
+	{"rar", 0, []byte("Rar!\x1a\x07")},
+	{"png", 0, []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}},
+	{"jpeg", 0, []byte{0xff, 0xd8, 0xff}},
+	{"gif", 0, []byte("GIF8")},
+	{"webp", 8, []byte("WEBP")},
+	{"mp4", 4, []byte("ftyp")},
+	{"matroska", 0, []byte{0x1a, 0x45, 0xdf, 0xa3}},
+	{"ogg", 0, []byte("OggS")},
+	{"mp3", 0, []byte("ID3")},
+	{"woff2", 0, []byte("wOF2")},
+}
+
+// detectCompressedFormat returns the name of an already-compressed format
+// recognised from the leading bytes of a file, or an empty string
+func detectCompressedFormat(header []byte) string {
+	for _, sig := range compressedMagic {
+		end := sig.offset + len(sig.magic)
+		if len(header) >= end && bytes.Equal(header[sig.offset:end], sig.magic) {
+			return sig.format
+		}
+	}
+	return ""
+}
+
+// chooseCompression decides which codec to use for a blob based on the
+// options, the file size and the first bytes of its content
+func chooseCompression(opts CompressionOptions, size int64, header []byte) (CompressionAlgorithm, string) {
+	if !opts.Enabled || opts.Algorithm == CompressionNone || opts.Algorithm == "" {
+		return CompressionNone, ""
+	}
+	if size < opts.MinSize {
+		return CompressionNone, "below minimum size"
+	}
+	if format := detectCompressedFormat(header); format != "" {
+		return CompressionNone, fmt.Sprintf("already compressed (%s)", format)
+	}
+	return opts.Algorithm, ""
+}
+
+// newCompressWriter wraps w with an encoder for the given algorithm
+func newCompressWriter(w io.Writer, algorithm CompressionAlgorithm, level CompressionLevel) (io.WriteCloser, error) {
+	switch algorithm {
+	case CompressionGzip:
+		return gzip.NewWriterLevel(w, gzipLevel(level))
+	case CompressionZstd:
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstdLevel(level)))
+	case CompressionNone, "":
+		return nopWriteCloser{w}, nil
+	default:
+		return nil, fmt.Errorf("unsupported compression algorithm: %s", algorithm)
+	}
+}
+
+// newDecompressReader wraps r with a decoder for the given algorithm
+func newDecompressReader(r io.Reader, algorithm CompressionAlgorithm) (io.ReadCloser, error) {
+	switch algorithm {
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	case CompressionZstd:
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	case CompressionNone, "":
+		return io.NopCloser(r), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression algorithm: %s", algorithm)
+	}
+}
+
+// gzipLevel maps a CompressionLevel onto a gzip level
+func gzipLevel(level CompressionLevel) int {
+	switch level {
+	case CompressionLevelFastest:
+		return gzip.BestSpeed
+	case CompressionLevelBetter:
+		return 7
+	case CompressionLevelBest:
+		return gzip.BestCompression
+	default:
+		return gzip.DefaultCompression
+	}
+}
+
+// zstdLevel maps a CompressionLevel onto a zstd encoder level
+func zstdLevel(level CompressionLevel) zstd.EncoderLevel {
+	switch level {
+	case CompressionLevelFastest:
+		return zstd.SpeedFastest
+	case CompressionLevelBetter:
+		return zstd.SpeedBetterCompression
+	case CompressionLevelBest:
+		return zstd.SpeedBestCompression
+	default:
+		return zstd.SpeedDefault
+	}
+}
+
+// nopWriteCloser adds a no-op Close to an io.Writer
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w     io.Writer
+	count int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.count += int64(n)
+	return n, err
+}
@@ -0,0 +1,95 @@
+package backup
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompressedBackupRoundTrip(t *testing.T) {
+	for _, algorithm := range []CompressionAlgorithm{CompressionGzip, CompressionZstd} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			testDir := t.TempDir()
+			original := []byte(strings.Repeat("compressible cache line\n", 512))
+			textFile := filepath.Join(testDir, "cache.txt")
+			if err := os.WriteFile(textFile, original, 0640); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			// Already-gzipped content must be stored raw
+			gzipFile := filepath.Join(testDir, "bundle.bin")
+			gzipped := append([]byte{0x1f, 0x8b, 0x08, 0x00}, bytes.Repeat([]byte{0xaa}, 4096)...)
+			if err := os.WriteFile(gzipFile, gzipped, 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			system, err := NewBackupSystemWithDir(t.TempDir())
+			if err != nil {
+				t.Fatalf("Failed to create backup system: %v", err)
+			}
+			options := system.GetManager().GetOptions()
+			options.Compression = CompressionOptions{Enabled: true, Algorithm: algorithm, Level: CompressionLevelBest}
+			system.GetManager().SetOptions(options)
+
+			session, err := system.BackupFiles([]string{textFile, gzipFile}, "compression_test")
+			if err != nil {
+				t.Fatalf("Backup failed: %v", err)
+			}
+
+			text, raw := session.Entries[0], session.Entries[1]
+			if text.Compression != algorithm {
+				t.Errorf("Expected %s compression, got %q", algorithm, text.Compression)
+			}
+			if text.StoredSize >= text.Size {
+				t.Errorf("Expected stored size below %d, got %d", text.Size, text.StoredSize)
+			}
+			if raw.Compression != CompressionNone {
+				t.Errorf("Expected already-compressed file to be stored raw, got %q", raw.Compression)
+			}
+			if session.CompressedCount != 1 {
+				t.Errorf("Expected 1 compressed entry, got %d", session.CompressedCount)
+			}
+			if session.StoredSize >= session.BackupSize {
+				t.Errorf("Expected stored size %d below original size %d", session.StoredSize, session.BackupSize)
+			}
+
+			valid, problems, err := system.VerifyBackupIntegrity(session.SessionID)
+			if err != nil || !valid {
+				t.Fatalf("Integrity verification failed: %v %v", err, problems)
+			}
+
+			if err := os.Remove(textFile); err != nil {
+				t.Fatalf("Failed to remove original: %v", err)
+			}
+			result, err := system.RestoreFiles(session.SessionID, []string{textFile}, false)
+			if err != nil || result.SuccessCount != 1 {
+				t.Fatalf("Restore failed: %v (%+v)", err, result)
+			}
+
+			restored, err := os.ReadFile(textFile)
+			if err != nil {
+				t.Fatalf("Failed to read restored file: %v", err)
+			}
+			if !bytes.Equal(restored, original) {
+				t.Error("Restored content does not match original")
+			}
+		})
+	}
+}
+
+func TestDetectCompressedFormat(t *testing.T) {
+	cases := map[string][]byte{
+		"zstd": {0x28, 0xb5, 0x2f, 0xfd, 0x00},
+		"png":  {0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'},
+		"webp": []byte("RIFF\x00\x00\x00\x00WEBPVP8 "),
+		"":     []byte("plain text"),
+	}
+
+	for expected, header := range cases {
+		if got := detectCompressedFormat(header); got != expected {
+			t.Errorf("detectCompressedFormat(%q) = %q, want %q", header, got, expected)
+		}
+	}
+}
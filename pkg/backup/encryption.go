@@ -0,0 +1,686 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/scrypt"
+)
+
+// EncryptionOptions controls authenticated encryption of backup blobs and the manifest.
+// The passphrase is only held in memory and is never written to disk.
+type EncryptionOptions struct {
+	Enabled    bool   `json:"enabled"`
+	Passphrase string `json:"-"`
+	KeyFile    string `json:"key_file,omitempty"`
+}
+
+// HasKey reports whether key material is configured
+func (eo EncryptionOptions) HasKey() bool {
+	return eo.Passphrase != "" || eo.KeyFile != ""
+}
+
+var (
+	// ErrEncryptionKeyRequired is returned when encrypted data is accessed without key material
+	ErrEncryptionKeyRequired = errors.New("backup is encrypted: a passphrase or key file is required")
+	// ErrEncryptionKeyMismatch is returned when the configured key does not match the backup store
+	ErrEncryptionKeyMismatch = errors.New("encryption key does not match the backup store")
+)
+
+const (
+	encryptionMagic      = "CCENC1"
+	encryptionChunkSize  = 64 * 1024
+	encryptionSaltSize   = 16
+	encryptionKeySize    = 32
+	encryptionHeaderFile = "encryption.json"
+	encryptionExtension  = ".enc"
+	minKeyFileSize       = 32
+
+	kdfScrypt  = "scrypt"
+	kdfKeyFile = "keyfile"
+)
+
+// Default scrypt parameters for passphrase-derived keys
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// encryptionHeader describes how the store key is derived and how to verify it
+type encryptionHeader struct {
+	Version int    `json:"version"`
+	KeyID   string `json:"key_id"`
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt"`
+	N       int    `json:"n,omitempty"`
+	R       int    `json:"r,omitempty"`
+	P       int    `json:"p,omitempty"`
+	Check   string `json:"check"`
+}
+
+// encryptionKey is a derived store key
+type encryptionKey struct {
+	id    string
+	check string
+	key   []byte
+}
+
+// newEncryptionHeader creates a header with a fresh salt for the given options
+func newEncryptionHeader(opts EncryptionOptions) (*encryptionHeader, error) {
+	if !opts.HasKey() {
+		return nil, ErrEncryptionKeyRequired
+	}
+
+	salt := make([]byte, encryptionSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	header := &encryptionHeader{Version: 1, Salt: salt}
+	if opts.Passphrase != "" {
+		header.KDF = kdfScrypt
+		header.N, header.R, header.P = scryptN, scryptR, scryptP
+	} else {
+		header.KDF = kdfKeyFile
+	}
+
+	key, err := deriveEncryptionKey(opts, header)
+	if err != nil {
+		return nil, err
+	}
+	header.KeyID = key.id
+	header.Check = key.check
+
+	return header, nil
+}
+
+// deriveEncryptionKey derives the store key described by header from the options
+func deriveEncryptionKey(opts EncryptionOptions, header *encryptionHeader) (*encryptionKey, error) {
+	var raw []byte
+
+	switch header.KDF {
+	case kdfScrypt:
+		if opts.Passphrase == "" {
+			return nil, fmt.Errorf("%w: the backup store is protected by a passphrase", ErrEncryptionKeyRequired)
+		}
+		derived, err := scrypt.Key([]byte(opts.Passphrase), header.Salt, header.N, header.R, header.P, encryptionKeySize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive key from passphrase: %w", err)
+		}
+		raw = derived
+	case kdfKeyFile:
+		if opts.KeyFile == "" {
+			return nil, fmt.Errorf("%w: the backup store is protected by a key file", ErrEncryptionKeyRequired)
+		}
+		material, err := os.ReadFile(opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		if len(material) < minKeyFileSize {
+			return nil, fmt.Errorf("key file %s is too short: need at least %d bytes", opts.KeyFile, minKeyFileSize)
+		}
+		raw = make([]byte, encryptionKeySize)
+		if _, err := io.ReadFull(hkdf.New(sha256.New, material, header.Salt, []byte("cache_app key file")), raw); err != nil {
+			return nil, fmt.Errorf("failed to derive key from key file: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key derivation function: %s", header.KDF)
+	}
+
+	mac := hmac.New(sha256.New, raw)
+	mac.Write([]byte("cache_app backup key check"))
+	check := hex.EncodeToString(mac.Sum(nil))
+
+	return &encryptionKey{id: check[:16], check: check, key: raw}, nil
+}
+
+// GenerateKeyFile writes a new random key file suitable for EncryptionOptions.KeyFile
+func GenerateKeyFile(path string) error {
+	key := make([]byte, encryptionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create key file directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create key file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write([]byte(hex.EncodeToString(key))); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+
+	return nil
+}
+
+// loadEncryptionHeader reads the encryption header of the store, returning nil if none exists
+func (bm *BackupManager) loadEncryptionHeader() (*encryptionHeader, error) {
+	data, err := os.ReadFile(filepath.Join(bm.backupDir, encryptionHeaderFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read encryption header: %w", err)
+	}
+
+	var header encryptionHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("failed to parse encryption header: %w", err)
+	}
+
+	return &header, nil
+}
+
+// saveEncryptionHeader writes the encryption header of the store
+func (bm *BackupManager) saveEncryptionHeader(header *encryptionHeader) error {
+	data, err := json.MarshalIndent(header, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal encryption header: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(bm.backupDir, encryptionHeaderFile), data, 0600); err != nil {
+		return fmt.Errorf("failed to write encryption header: %w", err)
+	}
+
+	return nil
+}
+
+// resolveEncryptionKey returns the store key for the configured options. When
+// create is true and the store has no key yet, a new header is written.
+func (bm *BackupManager) resolveEncryptionKey(create bool) (*encryptionKey, error) {
+	opts := bm.GetOptions().Encryption
+
+	bm.keyMu.Lock()
+	defer bm.keyMu.Unlock()
+
+	if bm.key != nil {
+		return bm.key, nil
+	}
+	if !opts.HasKey() {
+		return nil, ErrEncryptionKeyRequired
+	}
+
+	header, err := bm.loadEncryptionHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	if header == nil {
+		if !create {
+			return nil, fmt.Errorf("backup store has no encryption header")
+		}
+		header, err = newEncryptionHeader(opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := bm.saveEncryptionHeader(header); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := deriveEncryptionKey(opts, header)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(key.check), []byte(header.Check)) {
+		return nil, ErrEncryptionKeyMismatch
+	}
+
+	bm.key = key
+	return key, nil
+}
+
+// keyForEntry returns the store key and verifies it matches the key the entry was written with
+func (bm *BackupManager) keyForEntry(entry BackupEntry) (*encryptionKey, error) {
+	key, err := bm.resolveEncryptionKey(false)
+	if err != nil {
+		return nil, err
+	}
+	if entry.KeyID != "" && entry.KeyID != key.id {
+		return nil, fmt.Errorf("%w: %s was encrypted with key %s", ErrEncryptionKeyMismatch, entry.OriginalPath, entry.KeyID)
+	}
+	return key, nil
+}
+
+// VerifyEncryptionKey checks the configured key material against the backup store
+func (bm *BackupManager) VerifyEncryptionKey() error {
+	header, err := bm.loadEncryptionHeader()
+	if err != nil {
+		return err
+	}
+	if header == nil {
+		return fmt.Errorf("backup store is not encrypted")
+	}
+	_, err = bm.resolveEncryptionKey(false)
+	return err
+}
+
+// isEncryptedData reports whether data starts with the encrypted stream magic
+func isEncryptedData(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encryptionMagic))
+}
+
+// encryptBytes encrypts a small payload such as the manifest
+func encryptBytes(plaintext []byte, key *encryptionKey) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := newEncryptWriter(&buf, key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(plaintext); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decryptBytes decrypts a payload produced by encryptBytes
+func decryptBytes(ciphertext []byte, key *encryptionKey) ([]byte, error) {
+	reader, err := newDecryptReader(bytes.NewReader(ciphertext), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+// newStreamCipher derives a per-blob AEAD from the store key and blob salt
+func newStreamCipher(key *encryptionKey, salt []byte) (cipher.AEAD, error) {
+	blobKey := make([]byte, encryptionKeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key.key, salt, []byte("cache_app backup blob")), blobKey); err != nil {
+		return nil, fmt.Errorf("failed to derive blob key: %w", err)
+	}
+
+	block, err := aes.NewCipher(blobKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// streamNonce builds the nonce for a chunk: a big-endian counter followed by
+// a flag marking the final chunk, which protects against truncation
+func streamNonce(counter uint64, final bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if final {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// encryptWriter encrypts a stream in fixed-size authenticated chunks
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	buf     []byte
+	counter uint64
+	closed  bool
+}
+
+// newEncryptWriter writes the stream header to w and returns a writer that encrypts into it
+func newEncryptWriter(w io.Writer, key *encryptionKey) (io.WriteCloser, error) {
+	salt := make([]byte, encryptionSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	aead, err := newStreamCipher(key, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(append([]byte(encryptionMagic), salt...)); err != nil {
+		return nil, fmt.Errorf("failed to write encryption header: %w", err)
+	}
+
+	return &encryptWriter{w: w, aead: aead, buf: make([]byte, 0, encryptionChunkSize)}, nil
+}
+
+func (ew *encryptWriter) Write(p []byte) (int, error) {
+	if ew.closed {
+		return 0, fmt.Errorf("write to closed encryption stream")
+	}
+
+	written := 0
+	for len(p) > 0 {
+		// Only flush a full chunk once more data arrives, so the last chunk is always marked final
+		if len(ew.buf) == encryptionChunkSize {
+			if err := ew.flush(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(ew.buf[len(ew.buf):encryptionChunkSize], p)
+		ew.buf = ew.buf[:len(ew.buf)+n]
+		p = p[n:]
+		written += n
+	}
+
+	return written, nil
+}
+
+func (ew *encryptWriter) flush(final bool) error {
+	sealed := ew.aead.Seal(nil, streamNonce(ew.counter, final), ew.buf, nil)
+	ew.counter++
+	ew.buf = ew.buf[:0]
+	_, err := ew.w.Write(sealed)
+	return err
+}
+
+func (ew *encryptWriter) Close() error {
+	if ew.closed {
+		return nil
+	}
+	ew.closed = true
+	return ew.flush(true)
+}
+
+// decryptReader authenticates and decrypts a stream produced by encryptWriter
+type decryptReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	chunk   []byte
+	plain   []byte
+	counter uint64
+	done    bool
+}
+
+// newDecryptReader reads the stream header from r and returns a reader yielding plaintext
+func newDecryptReader(r io.Reader, key *encryptionKey) (io.Reader, error) {
+	header := make([]byte, len(encryptionMagic)+encryptionSaltSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read encryption header: %w", err)
+	}
+	if !isEncryptedData(header) {
+		return nil, fmt.Errorf("data is not an encrypted backup stream")
+	}
+
+	aead, err := newStreamCipher(key, header[len(encryptionMagic):])
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{
+		r:     bufio.NewReaderSize(r, encryptionChunkSize+aead.Overhead()),
+		aead:  aead,
+		chunk: make([]byte, encryptionChunkSize+aead.Overhead()),
+	}, nil
+}
+
+func (dr *decryptReader) Read(p []byte) (int, error) {
+	for len(dr.plain) == 0 {
+		if dr.done {
+			return 0, io.EOF
+		}
+		if err := dr.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, dr.plain)
+	dr.plain = dr.plain[n:]
+	return n, nil
+}
+
+func (dr *decryptReader) next() error {
+	n, err := io.ReadFull(dr.r, dr.chunk)
+	final := false
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		final = true
+	case err != nil:
+		return fmt.Errorf("failed to read encrypted chunk: %w", err)
+	default:
+		if _, peekErr := dr.r.Peek(1); peekErr == io.EOF {
+			final = true
+		}
+	}
+
+	if n < dr.aead.Overhead() {
+		return fmt.Errorf("encrypted stream is truncated")
+	}
+
+	plain, err := dr.aead.Open(dr.chunk[:0], streamNonce(dr.counter, final), dr.chunk[:n], nil)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt backup data (wrong key or corrupted data): %w", err)
+	}
+
+	dr.counter++
+	dr.plain = plain
+	dr.done = final
+	return nil
+}
+
+// KeyRotationResult summarises a key rotation
+type KeyRotationResult struct {
+	OldKeyID     string `json:"old_key_id,omitempty"`
+	NewKeyID     string `json:"new_key_id"`
+	SessionCount int    `json:"session_count"`
+	RotatedBlobs int    `json:"rotated_blobs"`
+}
+
+// pendingBlob is a re-encrypted blob waiting to replace the original
+type pendingBlob struct {
+	tempPath  string
+	finalPath string
+	oldPath   string
+}
+
+// RotateEncryptionKey re-encrypts every blob and the manifest with a key
+// derived from newOptions; plaintext blobs from before encryption was enabled
+// are encrypted as well. All blobs are re-encrypted into temporary files
+// before anything is replaced, and the old blobs, header and options are put
+// back when a later step fails, so a failure leaves the store unchanged.
+func (bm *BackupManager) RotateEncryptionKey(newOptions EncryptionOptions) (*KeyRotationResult, error) {
+	if bm.IsBackingUp() {
+		return nil, fmt.Errorf("backup in progress, cannot rotate encryption key")
+	}
+
+	manifest, err := bm.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	newHeader, err := newEncryptionHeader(newOptions)
+	if err != nil {
+		return nil, err
+	}
+	newKey, err := deriveEncryptionKey(newOptions, newHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &KeyRotationResult{
+		NewKeyID:     newKey.id,
+		SessionCount: len(manifest.Sessions),
+	}
+	if header, err := bm.loadEncryptionHeader(); err == nil && header != nil {
+		result.OldKeyID = header.KeyID
+	}
+
+	var pending []pendingBlob
+	discard := func() {
+		for _, blob := range pending {
+			os.Remove(blob.tempPath)
+		}
+	}
+
+	for i := range manifest.Sessions {
+		session := &manifest.Sessions[i]
+		session.StoredSize = 0
+
+		for j := range session.Entries {
+			entry := &session.Entries[j]
+			if !entry.hasBlob() {
+				continue
+			}
+
+			finalPath := entry.BackupPath
+			if !entry.Encrypted {
+				finalPath += encryptionExtension
+			}
+			blob := pendingBlob{tempPath: finalPath + ".rotating", finalPath: finalPath, oldPath: entry.BackupPath}
+
+			storedSize, err := bm.reencryptBlob(*entry, blob.tempPath, newKey)
+			if err != nil {
+				os.Remove(blob.tempPath)
+				discard()
+				return nil, fmt.Errorf("failed to re-encrypt %s: %w", entry.BackupPath, err)
+			}
+			pending = append(pending, blob)
+
+			entry.BackupPath = finalPath
+			entry.Encrypted = true
+			entry.KeyID = newKey.id
+			entry.StoredSize = storedSize
+			session.StoredSize += storedSize
+		}
+		session.Encrypted = true
+	}
+
+	// Commit: swap the blobs in, keeping the old ones aside, then write the
+	// header and manifest; any failure puts the old blobs, header and
+	// options back
+	swapped, err := swapRotatedBlobs(pending)
+	if err != nil {
+		discard()
+		return nil, err
+	}
+
+	headerPath := filepath.Join(bm.backupDir, encryptionHeaderFile)
+	oldHeader, headerErr := os.ReadFile(headerPath)
+	oldOptions := bm.GetOptions()
+	rollback := func() {
+		if headerErr == nil {
+			os.WriteFile(headerPath, oldHeader, 0600)
+		} else {
+			os.Remove(headerPath)
+		}
+		bm.SetOptions(oldOptions)
+		unswapRotatedBlobs(swapped)
+	}
+
+	if err := bm.saveEncryptionHeader(newHeader); err != nil {
+		rollback()
+		return nil, err
+	}
+
+	options := oldOptions
+	newOptions.Enabled = true
+	options.Encryption = newOptions
+	bm.SetOptions(options)
+
+	if err := bm.SaveManifest(manifest); err != nil {
+		rollback()
+		return nil, err
+	}
+
+	for _, blob := range swapped {
+		os.Remove(blob.asidePath())
+		result.RotatedBlobs++
+	}
+
+	return result, nil
+}
+
+// asidePath is where the old blob is kept until the rotation is committed
+func (blob pendingBlob) asidePath() string {
+	return blob.oldPath + ".prerotate"
+}
+
+// swapRotatedBlobs moves every old blob aside and the re-encrypted one into
+// its place. On failure the blobs swapped so far are put back.
+func swapRotatedBlobs(pending []pendingBlob) ([]pendingBlob, error) {
+	swapped := make([]pendingBlob, 0, len(pending))
+	for _, blob := range pending {
+		if err := os.Rename(blob.oldPath, blob.asidePath()); err != nil {
+			unswapRotatedBlobs(swapped)
+			return nil, fmt.Errorf("failed to move aside %s: %w", blob.oldPath, err)
+		}
+		if err := os.Rename(blob.tempPath, blob.finalPath); err != nil {
+			os.Rename(blob.asidePath(), blob.oldPath)
+			unswapRotatedBlobs(swapped)
+			return nil, fmt.Errorf("failed to replace %s: %w", blob.finalPath, err)
+		}
+		swapped = append(swapped, blob)
+	}
+	return swapped, nil
+}
+
+// unswapRotatedBlobs puts the old blobs back in reverse order
+func unswapRotatedBlobs(swapped []pendingBlob) {
+	for i := len(swapped) - 1; i >= 0; i-- {
+		blob := swapped[i]
+		if blob.finalPath != blob.oldPath {
+			os.Remove(blob.finalPath)
+		}
+		os.Rename(blob.asidePath(), blob.oldPath)
+	}
+}
+
+// reencryptBlob writes the stored (still compressed) bytes of an entry to
+// dst, encrypted with key, and returns the size written
+func (bm *BackupManager) reencryptBlob(entry BackupEntry, dst string, key *encryptionKey) (int64, error) {
+	source, err := os.Open(entry.BackupPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer source.Close()
+
+	sourceInfo, err := source.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get backup file info: %w", err)
+	}
+
+	var stored io.Reader = source
+	if entry.Encrypted {
+		oldKey, err := bm.keyForEntry(entry)
+		if err != nil {
+			return 0, err
+		}
+		if stored, err = newDecryptReader(source, oldKey); err != nil {
+			return 0, err
+		}
+	}
+
+	dest, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, sourceInfo.Mode())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create destination file: %w", err)
+	}
+	defer dest.Close()
+
+	counter := &countingWriter{w: dest}
+	writer, err := newEncryptWriter(counter, key)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := io.Copy(writer, stored); err != nil {
+		return 0, err
+	}
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+
+	return counter.count, nil
+}
@@ -0,0 +1,192 @@
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptedBackupRestore(t *testing.T) {
+	testDir := t.TempDir()
+	backupDir := t.TempDir()
+	secret := []byte(strings.Repeat("session_cookie=abc123; ", 4000))
+	cookieFile := filepath.Join(testDir, "Cookies")
+	if err := os.WriteFile(cookieFile, secret, 0600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	manager, err := NewBackupManagerWithDir(backupDir)
+	if err != nil {
+		t.Fatalf("Failed to create backup manager: %v", err)
+	}
+	options := manager.GetOptions()
+	options.Compression = CompressionOptions{Enabled: true, Algorithm: CompressionZstd, Level: CompressionLevelDefault}
+	options.Encryption = EncryptionOptions{Enabled: true, Passphrase: "correct horse"}
+	manager.SetOptions(options)
+
+	session, err := manager.BackupFiles([]string{cookieFile}, "encryption_test")
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	entry := session.Entries[0]
+	if !entry.Encrypted || entry.KeyID == "" {
+		t.Fatalf("Expected encrypted entry, got %+v", entry)
+	}
+
+	// Neither the blob nor the manifest may contain plaintext
+	blob, _ := os.ReadFile(entry.BackupPath)
+	manifestData, _ := os.ReadFile(filepath.Join(backupDir, "manifest.json"))
+	if bytes.Contains(blob, []byte("session_cookie")) || bytes.Contains(manifestData, []byte(cookieFile)) {
+		t.Fatal("Backup store contains plaintext data")
+	}
+
+	t.Run("MissingKey", func(t *testing.T) {
+		locked, _ := NewBackupManagerWithDir(backupDir)
+		if _, err := locked.GetManifest(); !errors.Is(err, ErrEncryptionKeyRequired) {
+			t.Errorf("Expected ErrEncryptionKeyRequired, got %v", err)
+		}
+	})
+
+	t.Run("WrongKey", func(t *testing.T) {
+		wrong, _ := NewBackupManagerWithDir(backupDir)
+		wrong.SetOptions(BackupOptions{Encryption: EncryptionOptions{Passphrase: "wrong"}})
+		if err := wrong.VerifyEncryptionKey(); !errors.Is(err, ErrEncryptionKeyMismatch) {
+			t.Errorf("Expected ErrEncryptionKeyMismatch, got %v", err)
+		}
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		os.Remove(cookieFile)
+		result, err := NewRestoreManager(manager).RestoreSession(session.SessionID, false)
+		if err != nil || result.SuccessCount != 1 {
+			t.Fatalf("Restore failed: %v (%+v)", err, result)
+		}
+		restored, _ := os.ReadFile(cookieFile)
+		if !bytes.Equal(restored, secret) {
+			t.Error("Restored content does not match original")
+		}
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		tampered := append([]byte(nil), blob...)
+		tampered[len(tampered)-1] ^= 0xff
+		if err := os.WriteFile(entry.BackupPath, tampered, 0600); err != nil {
+			t.Fatalf("Failed to tamper blob: %v", err)
+		}
+		if valid, _, _ := manager.VerifyBackupIntegrity(session.SessionID); valid {
+			t.Error("Tampered blob should fail verification")
+		}
+		os.WriteFile(entry.BackupPath, blob, 0600)
+	})
+
+	t.Run("RotateToKeyFile", func(t *testing.T) {
+		keyFile := filepath.Join(t.TempDir(), "backup.key")
+		if err := GenerateKeyFile(keyFile); err != nil {
+			t.Fatalf("Failed to generate key file: %v", err)
+		}
+
+		result, err := manager.RotateEncryptionKey(EncryptionOptions{KeyFile: keyFile})
+		if err != nil {
+			t.Fatalf("Rotation failed: %v", err)
+		}
+		if result.RotatedBlobs != 1 || result.NewKeyID == result.OldKeyID {
+			t.Errorf("Unexpected rotation result: %+v", result)
+		}
+
+		reopened, _ := NewBackupManagerWithDir(backupDir)
+		reopened.SetOptions(BackupOptions{Encryption: EncryptionOptions{KeyFile: keyFile}})
+		valid, problems, err := reopened.VerifyBackupIntegrity(session.SessionID)
+		if err != nil || !valid {
+			t.Fatalf("Integrity verification after rotation failed: %v %v", err, problems)
+		}
+
+		old, _ := NewBackupManagerWithDir(backupDir)
+		old.SetOptions(BackupOptions{Encryption: EncryptionOptions{Passphrase: "correct horse"}})
+		if _, err := old.GetManifest(); err == nil {
+			t.Error("Old passphrase should no longer unlock the manifest")
+		}
+	})
+}
+
+func TestEncryptStreamChunkBoundaries(t *testing.T) {
+	key := &encryptionKey{id: "test", key: bytes.Repeat([]byte{7}, encryptionKeySize)}
+
+	for _, size := range []int{0, 1, encryptionChunkSize, encryptionChunkSize + 1, 3 * encryptionChunkSize} {
+		plaintext := bytes.Repeat([]byte{'x'}, size)
+		ciphertext, err := encryptBytes(plaintext, key)
+		if err != nil {
+			t.Fatalf("encrypt %d bytes: %v", size, err)
+		}
+
+		decrypted, err := decryptBytes(ciphertext, key)
+		if err != nil || !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("round trip of %d bytes failed: %v", size, err)
+		}
+
+		// Dropping the final chunk must be detected
+		if size > encryptionChunkSize {
+			truncated := ciphertext[:len(encryptionMagic)+encryptionSaltSize+encryptionChunkSize+16]
+			if _, err := decryptBytes(truncated, key); err == nil {
+				t.Errorf("truncated stream of %d bytes was accepted", size)
+			}
+		}
+	}
+}
+
+func TestKeyRotationFailureLeavesStoreUnchanged(t *testing.T) {
+	testDir := t.TempDir()
+	backupDir := t.TempDir()
+	files := []string{filepath.Join(testDir, "first"), filepath.Join(testDir, "second")}
+	for _, file := range files {
+		os.WriteFile(file, []byte("content of "+file), 0600)
+	}
+
+	manager, err := NewBackupManagerWithDir(backupDir)
+	if err != nil {
+		t.Fatalf("Failed to create backup manager: %v", err)
+	}
+	options := manager.GetOptions()
+	options.Encryption = EncryptionOptions{Enabled: true, Passphrase: "correct horse"}
+	manager.SetOptions(options)
+
+	session, err := manager.BackupFiles(files, "rotation_test")
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	header, _ := os.ReadFile(filepath.Join(backupDir, encryptionHeaderFile))
+
+	// A directory in the way makes moving the second blob aside fail after
+	// the first one was swapped
+	blocker := session.Entries[1].BackupPath + ".prerotate"
+	os.MkdirAll(filepath.Join(blocker, "keep"), 0755)
+	if _, err := manager.RotateEncryptionKey(EncryptionOptions{Passphrase: "new passphrase"}); err == nil {
+		t.Fatal("Expected the rotation to fail")
+	}
+	os.RemoveAll(blocker)
+
+	if current, _ := os.ReadFile(filepath.Join(backupDir, encryptionHeaderFile)); !bytes.Equal(current, header) {
+		t.Error("Failed rotation must keep the encryption header")
+	}
+	if manager.GetOptions().Encryption.Passphrase != "correct horse" {
+		t.Error("Failed rotation must keep the encryption options")
+	}
+	sessionDir := filepath.Join(backupDir, "files", session.SessionID)
+	rotating, _ := filepath.Glob(filepath.Join(sessionDir, "*.rotating"))
+	aside, _ := filepath.Glob(filepath.Join(sessionDir, "*.prerotate"))
+	if leftovers := append(rotating, aside...); len(leftovers) > 0 {
+		t.Errorf("Failed rotation left files behind: %v", leftovers)
+	}
+
+	reopened, _ := NewBackupManagerWithDir(backupDir)
+	reopened.SetOptions(BackupOptions{Encryption: EncryptionOptions{Passphrase: "correct horse"}})
+	if valid, problems, err := reopened.VerifyBackupIntegrity(session.SessionID); err != nil || !valid {
+		t.Fatalf("Old key must still read every blob: %v %v", err, problems)
+	}
+
+	if result, err := manager.RotateEncryptionKey(EncryptionOptions{Passphrase: "new passphrase"}); err != nil || result.RotatedBlobs != 2 {
+		t.Fatalf("Rotation should succeed once unblocked: %v (%+v)", err, result)
+	}
+}
@@ -35,16 +35,8 @@ func ExampleUsage() {
 	fmt.Printf("Files backed up: %d/%d\n", session.SuccessCount, session.TotalFiles)
 	fmt.Printf("Backup size: %d bytes\n", session.BackupSize)
 
-	// 2. Safely delete files with backup
-	fmt.Println("\n2. Safely deleting files...")
-	deletionResult, err := backupSystem.DeleteFilesWithBackup(files, "example_deletion")
-	if err != nil {
-		log.Printf("Safe deletion failed: %v", err)
-		return
-	}
-
-	fmt.Printf("Files deleted: %d/%d\n", deletionResult.DeletedCount, deletionResult.TotalFiles)
-	fmt.Printf("Backup session ID: %s\n", deletionResult.BackupSessionID)
+	// 2. Files are deleted by deletion.DeletionService, which backs them up first
+	fmt.Println("\n2. Deletion is handled by the deletion package")
 
 	// 3. List backup sessions
 	fmt.Println("\n3. Listing backup sessions...")
@@ -132,27 +124,12 @@ func ExampleProgressMonitoring() {
 		}
 	}()
 
-	// Start monitoring deletion progress in a goroutine
-	go func() {
-		for progress := range backupSystem.GetDeletionProgressChannel() {
-			fmt.Printf("Deletion Progress: %.1f%% (%d/%d files) - %s\n", 
-				progress.Progress, progress.FilesProcessed, progress.TotalFiles, progress.CurrentFile)
-		}
-	}()
-
-	// Perform backup and deletion
+	// Perform backup
 	session, err := backupSystem.BackupFiles(files, "progress_example")
 	if err != nil {
 		log.Printf("Backup failed: %v", err)
 		return
 	}
 
-	deletionResult, err := backupSystem.DeleteFilesWithBackup(files, "progress_example")
-	if err != nil {
-		log.Printf("Deletion failed: %v", err)
-		return
-	}
-
-	fmt.Printf("Completed: %d files backed up, %d files deleted\n", 
-		session.SuccessCount, deletionResult.DeletedCount)
+	fmt.Printf("Completed: %d files backed up\n", session.SuccessCount)
 }
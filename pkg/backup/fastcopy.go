@@ -0,0 +1,192 @@
+package backup
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// CopyStrategy is how the blob of a backup entry was written
+type CopyStrategy string
+
+const (
+	// CopyStrategyReflink shares the data blocks of the original copy-on-write
+	CopyStrategyReflink CopyStrategy = "reflink"
+	// CopyStrategyHardlink links the blob to the inode of the original, which
+	// is only safe when the original is unlinked right after the backup.
+	// Originals that are kept get their link broken by UnlinkOriginals.
+	CopyStrategyHardlink CopyStrategy = "hardlink"
+	// CopyStrategyCopy writes the content byte by byte
+	CopyStrategyCopy CopyStrategy = "copy"
+)
+
+// storeBlob stores src at dst with the cheapest safe strategy. Blobs that are
+// compressed or encrypted are always written by writeBlob; raw blobs are
+// reflinked where the filesystem supports it, hardlinked when linkOriginal is
+// set and the original has no other links, and copied otherwise. The reasons
+// the fast strategies could not be used are returned for diagnostics.
+func (bm *BackupManager) storeBlob(src, dst string, info os.FileInfo, linkOriginal bool) (blobInfo, CopyStrategy, string, error) {
+	compression, skipReason := bm.rawBlob(src, info)
+	if compression != CompressionNone {
+		blob, err := bm.writeBlob(src, dst)
+		return blob, CopyStrategyCopy, "", err
+	}
+
+	var fallbacks []string
+	if err := reflinkFile(src, dst, info.Mode().Perm()); err != nil {
+		fallbacks = append(fallbacks, fmt.Sprintf("reflink: %v", err))
+	} else if blob, err := finishLinkedBlob(dst, info, skipReason); err != nil {
+		os.Remove(dst)
+		fallbacks = append(fallbacks, fmt.Sprintf("reflink: %v", err))
+	} else {
+		return blob, CopyStrategyReflink, "", nil
+	}
+
+	if linkOriginal {
+		if links, ok := linkCount(info); !ok || links != 1 {
+			fallbacks = append(fallbacks, "hardlink: original has other links")
+		} else if err := os.Link(src, dst); err != nil {
+			fallbacks = append(fallbacks, fmt.Sprintf("hardlink: %v", err))
+		} else if blob, err := finishLinkedBlob(dst, info, skipReason); err != nil {
+			os.Remove(dst)
+			fallbacks = append(fallbacks, fmt.Sprintf("hardlink: %v", err))
+		} else {
+			return blob, CopyStrategyHardlink, "", nil
+		}
+	}
+
+	blob, err := bm.writeBlob(src, dst)
+	return blob, CopyStrategyCopy, strings.Join(fallbacks, "; "), err
+}
+
+// rawBlob returns the compression writeBlob would choose for src, which is
+// CompressionNone when the blob is stored as is. Encrypted blobs are never raw.
+func (bm *BackupManager) rawBlob(src string, info os.FileInfo) (CompressionAlgorithm, string) {
+	opts := bm.GetOptions()
+	if opts.Encryption.Enabled {
+		return opts.Compression.Algorithm, ""
+	}
+	if !opts.Compression.Enabled || opts.Compression.Algorithm == CompressionNone || opts.Compression.Algorithm == "" {
+		return CompressionNone, ""
+	}
+
+	file, err := os.Open(src)
+	if err != nil {
+		return opts.Compression.Algorithm, ""
+	}
+	defer file.Close()
+
+	header, err := bufio.NewReaderSize(file, sniffSize).Peek(sniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return opts.Compression.Algorithm, ""
+	}
+	return chooseCompression(opts.Compression, info.Size(), header)
+}
+
+// finishLinkedBlob checksums a blob that shares its content with the original
+// and checks that it still has the size seen when the backup started
+func finishLinkedBlob(dst string, info os.FileInfo, skipReason string) (blobInfo, error) {
+	blob := blobInfo{Path: dst, Compression: CompressionNone, SkipReason: skipReason}
+
+	file, err := os.Open(dst)
+	if err != nil {
+		return blob, fmt.Errorf("failed to open blob: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return blob, fmt.Errorf("failed to checksum blob: %w", err)
+	}
+	if size != info.Size() {
+		return blob, fmt.Errorf("file changed during backup")
+	}
+
+	blob.Checksum = fmt.Sprintf("%x", hash.Sum(nil))
+	blob.StoredSize = size
+	return blob, nil
+}
+
+// UnlinkOriginals gives every hardlinked blob of the session whose original
+// still exists a copy of its own, so the backup no longer changes with the
+// original. Deletions call it for the originals they did not remove.
+func (bm *BackupManager) UnlinkOriginals(session *BackupSession) error {
+	var errs []error
+	unlinked := make(map[string]bool)
+	for i := range session.Entries {
+		entry := &session.Entries[i]
+		if entry.CopyStrategy != CopyStrategyHardlink {
+			continue
+		}
+		original, err := os.Lstat(entry.OriginalPath)
+		if err != nil {
+			continue
+		}
+		blob, err := os.Lstat(entry.BackupPath)
+		if err != nil || !os.SameFile(original, blob) {
+			continue
+		}
+		if err := copyBlobInPlace(entry.BackupPath, blob.Mode().Perm()); err != nil {
+			errs = append(errs, fmt.Errorf("failed to unlink backup of %s: %w", entry.OriginalPath, err))
+			continue
+		}
+		entry.CopyStrategy = CopyStrategyCopy
+		unlinked[entry.BackupPath] = true
+	}
+	if len(unlinked) == 0 {
+		return errors.Join(errs...)
+	}
+
+	// Sessions cancelled during the backup were never saved
+	manifest, err := bm.loadManifest()
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	for i := range manifest.Sessions {
+		if manifest.Sessions[i].SessionID != session.SessionID {
+			continue
+		}
+		for j := range manifest.Sessions[i].Entries {
+			if entry := &manifest.Sessions[i].Entries[j]; unlinked[entry.BackupPath] {
+				entry.CopyStrategy = CopyStrategyCopy
+			}
+		}
+		if err := bm.SaveManifest(manifest); err != nil {
+			errs = append(errs, err)
+		}
+		break
+	}
+	return errors.Join(errs...)
+}
+
+// copyBlobInPlace replaces a blob with a copy of itself, which breaks its
+// hard link to the original
+func copyBlobInPlace(path string, perm os.FileMode) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	tmpPath := path + ".unlink"
+	dest, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dest, source)
+	if closeErr := dest.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+	}
+	return err
+}
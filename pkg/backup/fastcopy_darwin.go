@@ -0,0 +1,25 @@
+package backup
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// reflinkFile clones src to dst with clonefile, which APFS supports
+func reflinkFile(src, dst string, perm os.FileMode) error {
+	if err := unix.Clonefile(src, dst, unix.CLONE_NOFOLLOW); err != nil {
+		return err
+	}
+	return os.Chmod(dst, perm)
+}
+
+// linkCount returns the number of hard links to a file
+func linkCount(info os.FileInfo) (uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(stat.Nlink), true
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin
+
+package backup
+
+import (
+	"errors"
+	"os"
+)
+
+// reflinkFile is not supported on this platform
+func reflinkFile(src, dst string, perm os.FileMode) error {
+	return errors.ErrUnsupported
+}
+
+// linkCount is unknown on this platform, so originals are never hardlinked
+func linkCount(info os.FileInfo) (uint64, bool) {
+	return 0, false
+}
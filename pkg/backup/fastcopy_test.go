@@ -0,0 +1,80 @@
+//go:build linux || darwin
+
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackupCopyStrategies(t *testing.T) {
+	testDir := t.TempDir()
+	system, err := NewBackupSystemWithDir(filepath.Join(testDir, "backups"))
+	if err != nil {
+		t.Fatalf("Failed to create backup system: %v", err)
+	}
+	options := system.GetManager().GetOptions()
+	options.Compression = CompressionOptions{Enabled: true, Algorithm: CompressionGzip, MinSize: 1024}
+	system.GetManager().SetOptions(options)
+
+	write := func(name, content string) string {
+		path := filepath.Join(testDir, name)
+		if err := os.WriteFile(path, []byte(content), 0640); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		return path
+	}
+	small := write("small.bin", "below the compression minimum")
+	large := write("large.txt", strings.Repeat("compressible\n", 200))
+	shared := write("shared.bin", "linked elsewhere")
+	if err := os.Link(shared, filepath.Join(testDir, "shared-link.bin")); err != nil {
+		t.Fatalf("Failed to create hard link: %v", err)
+	}
+
+	t.Run("Backup", func(t *testing.T) {
+		session, err := system.BackupFiles([]string{small}, "strategy_test")
+		if err != nil {
+			t.Fatalf("Backup failed: %v", err)
+		}
+		// Plain backups keep the original, so its blob must never share the inode
+		if strategy := session.Entries[0].CopyStrategy; strategy != CopyStrategyReflink && strategy != CopyStrategyCopy {
+			t.Errorf("Expected reflink or copy, got %q", strategy)
+		}
+	})
+
+	time.Sleep(1100 * time.Millisecond)
+
+	t.Run("ForDeletion", func(t *testing.T) {
+		session, err := system.BackupFilesForDeletion([]string{small, large, shared}, "strategy_test")
+		if err != nil {
+			t.Fatalf("Backup failed: %v", err)
+		}
+		entries := session.Entries
+		if strategy := entries[0].CopyStrategy; strategy != CopyStrategyReflink && strategy != CopyStrategyHardlink {
+			t.Errorf("Expected a raw blob to be reflinked or hardlinked, got %q (%v)", strategy, entries[0].Metadata["copy_fallback"])
+		}
+		if entries[1].CopyStrategy != CopyStrategyCopy || entries[1].Compression != CompressionGzip {
+			t.Errorf("Compressed blobs must be copied, got %q/%q", entries[1].CopyStrategy, entries[1].Compression)
+		}
+		if entries[2].CopyStrategy == CopyStrategyHardlink {
+			t.Error("A file with other hard links must not be hardlinked")
+		}
+
+		for _, path := range []string{small, large, shared} {
+			os.Remove(path)
+		}
+		result, err := system.RestoreSession(session.SessionID, false)
+		if err != nil || result.FailureCount > 0 {
+			t.Fatalf("Restore failed: %v (%+v)", err, result)
+		}
+		if data, _ := os.ReadFile(small); string(data) != "below the compression minimum" {
+			t.Errorf("Unexpected restored content %q", data)
+		}
+		if valid, problems, err := system.VerifyBackupIntegrity(session.SessionID); err != nil || !valid {
+			t.Errorf("Integrity verification failed: %v %v", err, problems)
+		}
+	})
+}
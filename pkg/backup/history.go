@@ -0,0 +1,173 @@
+package backup
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileVersion is one backed up version of a path
+type FileVersion struct {
+	SessionID    string      `json:"session_id"`
+	SessionStart time.Time   `json:"session_start"`
+	Operation    string      `json:"operation"`
+	Entry        BackupEntry `json:"entry"`
+}
+
+// PathHistory lists the versions of a single path, newest first
+type PathHistory struct {
+	Path     string        `json:"path"`
+	Versions []FileVersion `json:"versions"`
+}
+
+// VersionIndex indexes the successful entries of all sessions by original path
+type VersionIndex struct {
+	versions map[string][]FileVersion // Newest first
+	paths    []string                 // Sorted, for prefix lookups
+}
+
+// NewVersionIndex builds an index over the entries of the given sessions
+func NewVersionIndex(sessions []BackupSession) *VersionIndex {
+	index := &VersionIndex{versions: make(map[string][]FileVersion)}
+
+	for _, session := range sessions {
+		for _, entry := range session.Entries {
+			if !entry.Success {
+				continue
+			}
+			index.versions[entry.OriginalPath] = append(index.versions[entry.OriginalPath], FileVersion{
+				SessionID:    session.SessionID,
+				SessionStart: session.StartTime,
+				Operation:    session.Operation,
+				Entry:        entry,
+			})
+		}
+	}
+
+	index.paths = make([]string, 0, len(index.versions))
+	for path, versions := range index.versions {
+		index.paths = append(index.paths, path)
+		sort.SliceStable(versions, func(i, j int) bool {
+			return versions[i].Entry.BackupTime.After(versions[j].Entry.BackupTime)
+		})
+	}
+	sort.Strings(index.paths)
+
+	return index
+}
+
+// BuildVersionIndex indexes every session in the manifest
+func (bm *BackupManager) BuildVersionIndex() (*VersionIndex, error) {
+	manifest, err := bm.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+	return NewVersionIndex(manifest.Sessions), nil
+}
+
+// Versions returns every backed up version of path, newest first
+func (vi *VersionIndex) Versions(path string) []FileVersion {
+	return vi.versions[filepath.Clean(path)]
+}
+
+// Under returns the history of path and every indexed path below it, sorted by path
+func (vi *VersionIndex) Under(path string) []PathHistory {
+	var histories []PathHistory
+	for _, p := range vi.pathsUnder(path) {
+		histories = append(histories, PathHistory{Path: p, Versions: vi.versions[p]})
+	}
+	return histories
+}
+
+// AsOf returns, for path and every indexed path below it, the newest version
+// backed up at or before the given time, sorted by path so that directories
+// precede their contents
+func (vi *VersionIndex) AsOf(path string, asOf time.Time) []FileVersion {
+	var selected []FileVersion
+	for _, p := range vi.pathsUnder(path) {
+		for _, version := range vi.versions[p] {
+			if !version.Entry.BackupTime.After(asOf) {
+				selected = append(selected, version)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+// pathsUnder returns path itself, if indexed, followed by its indexed descendants
+func (vi *VersionIndex) pathsUnder(path string) []string {
+	path = filepath.Clean(path)
+	prefix := strings.TrimSuffix(path, string(filepath.Separator)) + string(filepath.Separator)
+
+	var paths []string
+	if _, ok := vi.versions[path]; ok {
+		paths = append(paths, path)
+	}
+	for i := sort.SearchStrings(vi.paths, prefix); i < len(vi.paths) && strings.HasPrefix(vi.paths[i], prefix); i++ {
+		paths = append(paths, vi.paths[i])
+	}
+	return paths
+}
+
+// RestoreAsOf restores path and everything backed up below it to the newest
+// version saved at or before asOf, combining entries from several sessions.
+// options.Paths is ignored.
+func (rm *RestoreManager) RestoreAsOf(path string, asOf time.Time, options RestoreOptions) (*RestoreResult, error) {
+	if err := ValidateConflictStrategy(options.Conflict); err != nil {
+		return nil, err
+	}
+
+	if rm.IsRestoring() {
+		return nil, fmt.Errorf("restore already in progress")
+	}
+
+	rm.setRestoring(true)
+	defer rm.setRestoring(false)
+
+	result, entries, actions, err := rm.planRestoreAsOf(path, asOf, options)
+	if err != nil {
+		return nil, err
+	}
+	return rm.executeRestore(result, entries, actions, options)
+}
+
+// PreviewRestoreAsOf reports which version RestoreAsOf would pick for each path and what it would do
+func (rm *RestoreManager) PreviewRestoreAsOf(path string, asOf time.Time, options RestoreOptions) (*RestoreResult, error) {
+	if err := ValidateConflictStrategy(options.Conflict); err != nil {
+		return nil, err
+	}
+
+	result, _, actions, err := rm.planRestoreAsOf(path, asOf, options)
+	if err != nil {
+		return nil, err
+	}
+	return previewResult(result, actions), nil
+}
+
+// planRestoreAsOf selects the version of each path to restore and decides the action for each
+func (rm *RestoreManager) planRestoreAsOf(path string, asOf time.Time, options RestoreOptions) (*RestoreResult, []BackupEntry, []RestoreAction, error) {
+	index, err := rm.backupManager.BuildVersionIndex()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to build version index: %w", err)
+	}
+
+	versions := index.AsOf(path, asOf)
+	if len(versions) == 0 {
+		return nil, nil, nil, fmt.Errorf("no backup of %s exists from before %s", path, asOf.Format(time.RFC3339))
+	}
+
+	entries := make([]BackupEntry, len(versions))
+	for i, version := range versions {
+		entries[i] = version.Entry
+	}
+
+	result, actions := planEntries(entries, nil, options)
+	result.AsOf = &asOf
+	for i := range actions {
+		actions[i].SessionID = versions[i].SessionID
+	}
+	return result, entries, actions, nil
+}
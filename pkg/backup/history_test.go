@@ -0,0 +1,127 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVersionIndex(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := func(path string, hour int) BackupEntry {
+		return BackupEntry{OriginalPath: path, BackupTime: base.Add(time.Duration(hour) * time.Hour), Success: true}
+	}
+	sessions := []BackupSession{
+		{SessionID: "s1", Entries: []BackupEntry{entry("/data/app", 1), entry("/data/app/a", 1), entry("/data/app/b", 1)}},
+		{SessionID: "s2", Entries: []BackupEntry{entry("/data/app/a", 2), entry("/data/app-old/a", 2)}},
+		{SessionID: "s3", Entries: []BackupEntry{entry("/data/app/a", 3), {OriginalPath: "/data/app/b", Error: "failed"}}},
+	}
+	index := NewVersionIndex(sessions)
+
+	versions := index.Versions("/data/app/a")
+	if len(versions) != 3 || versions[0].SessionID != "s3" || versions[2].SessionID != "s1" {
+		t.Fatalf("Expected three versions newest first, got %+v", versions)
+	}
+	if len(index.Versions("/data/app/b")) != 1 {
+		t.Error("Failed entries must not be listed as versions")
+	}
+
+	histories := index.Under("/data/app/")
+	if len(histories) != 3 || histories[0].Path != "/data/app" {
+		t.Fatalf("Expected the directory and its two files, got %+v", histories)
+	}
+	for _, history := range histories {
+		if history.Path == "/data/app-old/a" {
+			t.Error("Sibling with a common prefix must not match")
+		}
+	}
+
+	selected := index.AsOf("/data/app", base.Add(2*time.Hour+30*time.Minute))
+	got := make(map[string]string)
+	for _, version := range selected {
+		got[version.Entry.OriginalPath] = version.SessionID
+	}
+	want := map[string]string{"/data/app": "s1", "/data/app/a": "s2", "/data/app/b": "s1"}
+	if len(got) != len(want) {
+		t.Fatalf("AsOf selected %v, want %v", got, want)
+	}
+	for path, session := range want {
+		if got[path] != session {
+			t.Errorf("%s: selected %s, want %s", path, got[path], session)
+		}
+	}
+
+	if len(index.AsOf("/data/app", base)) != 0 {
+		t.Error("Nothing was backed up before the first session")
+	}
+}
+
+func TestRestoreAsOf(t *testing.T) {
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	index := filepath.Join(cacheDir, "index")
+	stale := filepath.Join(cacheDir, "stale")
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	os.WriteFile(stale, []byte("stale"), 0644)
+
+	system, err := NewBackupSystemWithDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create backup system: %v", err)
+	}
+
+	var checkpoints []time.Time
+	for _, content := range []string{"v1", "v2", "v3"} {
+		if err := os.WriteFile(index, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if _, err := system.BackupFiles([]string{cacheDir}, "history_test"); err != nil {
+			t.Fatalf("Backup failed: %v", err)
+		}
+		checkpoints = append(checkpoints, time.Now())
+		os.Remove(stale)
+
+		// Session IDs have second resolution; keep sessions distinct
+		time.Sleep(1100 * time.Millisecond)
+	}
+
+	histories, err := system.FileVersions(cacheDir)
+	if err != nil || len(histories) != 3 {
+		t.Fatalf("Expected history for the directory and two files, got %d (%v)", len(histories), err)
+	}
+
+	os.RemoveAll(cacheDir)
+	options := RestoreOptions{Conflict: ConflictOverwrite}
+
+	preview, err := system.PreviewRestoreAsOf(cacheDir, checkpoints[1], options)
+	if err != nil || preview.SuccessCount != 3 {
+		t.Fatalf("Preview failed: %v (%+v)", err, preview)
+	}
+	if _, err := os.Stat(cacheDir); !os.IsNotExist(err) {
+		t.Fatal("Preview must not restore anything")
+	}
+
+	result, err := system.RestoreAsOf(cacheDir, checkpoints[1], options)
+	if err != nil || result.FailureCount != 0 || result.SuccessCount != 3 {
+		t.Fatalf("Restore failed: %v (%+v)", err, result)
+	}
+	if data, _ := os.ReadFile(index); string(data) != "v2" {
+		t.Errorf("Restored %q, want v2", data)
+	}
+	if data, _ := os.ReadFile(stale); string(data) != "stale" {
+		t.Errorf("File only in an older session should be restored, got %q", data)
+	}
+
+	sessions := make(map[string]bool)
+	for _, action := range result.Actions {
+		sessions[action.SessionID] = true
+	}
+	if len(sessions) != 2 {
+		t.Errorf("Expected entries from two sessions, got %v", sessions)
+	}
+
+	if _, err := system.RestoreAsOf(cacheDir, checkpoints[0].Add(-time.Hour), options); err == nil {
+		t.Error("Restoring to before the first backup should fail")
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
+
+	"cache_app/internal/ui"
 )
 
 // BackupManager handles backup operations for cache files
@@ -19,6 +21,26 @@ type BackupManager struct {
 	progressChan chan BackupProgress
 	stopChan     chan bool
 	isBackingUp  bool
+	options      BackupOptions
+	keyMu        sync.Mutex
+	key          *encryptionKey // Cached store key, derived on first use
+	remote       StorageBackend // Optional replication target
+	remoteRetry  *ui.ErrorRecovery
+	remoteStateMu sync.Mutex
+	retention    *RetentionPolicy
+}
+
+// BackupOptions controls how backup blobs are written
+type BackupOptions struct {
+	Compression CompressionOptions `json:"compression"`
+	Encryption  EncryptionOptions  `json:"encryption"`
+}
+
+// DefaultBackupOptions returns the default backup options
+func DefaultBackupOptions() BackupOptions {
+	return BackupOptions{
+		Compression: DefaultCompressionOptions(),
+	}
 }
 
 // BackupEntry represents a single file backup entry
@@ -31,6 +53,16 @@ type BackupEntry struct {
 	Operation       string    `json:"operation"`
 	Success         bool      `json:"success"`
 	Error           string    `json:"error,omitempty"`
+	Compression     CompressionAlgorithm `json:"compression,omitempty"`
+	StoredSize      int64     `json:"stored_size,omitempty"`
+	Encrypted       bool      `json:"encrypted,omitempty"`
+	KeyID           string    `json:"key_id,omitempty"`
+	Type            EntryType `json:"type,omitempty"`
+	Mode            os.FileMode `json:"mode,omitempty"`
+	LinkTarget      string    `json:"link_target,omitempty"`
+	RootPath        string    `json:"root_path,omitempty"` // Directory the entry was backed up as part of
+	Attributes      *FileAttributes `json:"attributes,omitempty"`
+	CopyStrategy    CopyStrategy `json:"copy_strategy,omitempty"` // How the blob was written; empty for older backups
 	Metadata        map[string]interface{} `json:"metadata,omitempty"`
 }
 
@@ -45,9 +77,15 @@ type BackupSession struct {
 	FailureCount    int            `json:"failure_count"`
 	TotalSize       int64          `json:"total_size"`
 	BackupSize      int64          `json:"backup_size"`
+	StoredSize      int64          `json:"stored_size"`
+	CompressedCount int            `json:"compressed_count"`
+	Encrypted       bool           `json:"encrypted"`
 	Entries         []BackupEntry  `json:"entries"`
 	Status          string         `json:"status"`
 	Error           string         `json:"error,omitempty"`
+	RemoteError     string         `json:"remote_error,omitempty"` // Replication failure; retried by SyncToRemote
+	PrunedSessions  []string       `json:"pruned_sessions,omitempty"` // Sessions removed by automatic retention
+	RetentionError  string         `json:"retention_error,omitempty"`
 }
 
 // BackupProgress represents progress information during backup operations
@@ -81,7 +119,11 @@ func NewBackupManager() (*BackupManager, error) {
 		return nil, fmt.Errorf("failed to get home directory: %w", err)
 	}
 
-	backupDir := filepath.Join(homeDir, "CacheCleaner", "Backups")
+	return NewBackupManagerWithDir(filepath.Join(homeDir, "CacheCleaner", "Backups"))
+}
+
+// NewBackupManagerWithDir creates a new backup manager rooted at a custom backup directory
+func NewBackupManagerWithDir(backupDir string) (*BackupManager, error) {
 	manifestFile := filepath.Join(backupDir, "manifest.json")
 
 	bm := &BackupManager{
@@ -89,6 +131,7 @@ func NewBackupManager() (*BackupManager, error) {
 		manifestFile: manifestFile,
 		progressChan: make(chan BackupProgress, 100),
 		stopChan:     make(chan bool, 1),
+		options:      DefaultBackupOptions(),
 	}
 
 	// Ensure backup directory exists
@@ -142,6 +185,30 @@ func (bm *BackupManager) IsBackingUp() bool {
 	return bm.isBackingUp
 }
 
+// SetOptions replaces the options used for subsequent backups
+func (bm *BackupManager) SetOptions(options BackupOptions) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	bm.options = options
+
+	// Key material may have changed, derive the key again on next use
+	bm.keyMu.Lock()
+	bm.key = nil
+	bm.keyMu.Unlock()
+}
+
+// GetOptions returns the options used for backups
+func (bm *BackupManager) GetOptions() BackupOptions {
+	bm.mu.RLock()
+	defer bm.mu.RUnlock()
+	return bm.options
+}
+
+// GetBackupDir returns the root directory of the backup store
+func (bm *BackupManager) GetBackupDir() string {
+	return bm.backupDir
+}
+
 // setBackingUp sets the backing up state
 func (bm *BackupManager) setBackingUp(backingUp bool) {
 	bm.mu.Lock()
@@ -151,6 +218,17 @@ func (bm *BackupManager) setBackingUp(backingUp bool) {
 
 // BackupFiles creates backups of the specified files before deletion
 func (bm *BackupManager) BackupFiles(files []string, operation string) (*BackupSession, error) {
+	return bm.backupFiles(files, operation, false)
+}
+
+// BackupFilesForDeletion backs up files that are deleted right after the
+// backup, which allows blobs to be hardlinks to the originals
+func (bm *BackupManager) BackupFilesForDeletion(files []string, operation string) (*BackupSession, error) {
+	return bm.backupFiles(files, operation, true)
+}
+
+// backupFiles creates a backup session; linkOriginals allows hardlinked blobs
+func (bm *BackupManager) backupFiles(files []string, operation string, linkOriginals bool) (*BackupSession, error) {
 	if bm.IsBackingUp() {
 		return nil, fmt.Errorf("backup already in progress")
 	}
@@ -158,6 +236,13 @@ func (bm *BackupManager) BackupFiles(files []string, operation string) (*BackupS
 	bm.setBackingUp(true)
 	defer bm.setBackingUp(false)
 
+	// Fail early rather than producing a session of unencrypted or failed entries
+	if bm.GetOptions().Encryption.Enabled {
+		if _, err := bm.resolveEncryptionKey(true); err != nil {
+			return nil, fmt.Errorf("failed to unlock encryption key: %w", err)
+		}
+	}
+
 	sessionID := fmt.Sprintf("backup_%d", time.Now().Unix())
 	session := &BackupSession{
 		SessionID:    sessionID,
@@ -166,6 +251,7 @@ func (bm *BackupManager) BackupFiles(files []string, operation string) (*BackupS
 		TotalFiles:   len(files),
 		Entries:      make([]BackupEntry, 0, len(files)),
 		Status:       "in_progress",
+		Encrypted:    bm.GetOptions().Encryption.Enabled,
 	}
 
 	// Create session directory
@@ -188,16 +274,21 @@ func (bm *BackupManager) BackupFiles(files []string, operation string) (*BackupS
 		default:
 		}
 
-		entry := bm.backupSingleFile(filePath, sessionDir, operation)
-		session.Entries = append(session.Entries, entry)
-
-		if entry.Success {
-			session.SuccessCount++
-			session.BackupSize += entry.Size
-		} else {
-			session.FailureCount++
+		for _, entry := range bm.backupPath(filePath, sessionDir, operation, linkOriginals) {
+			session.Entries = append(session.Entries, entry)
+
+			if entry.Success {
+				session.SuccessCount++
+				session.BackupSize += entry.fileSize()
+				session.StoredSize += entry.StoredSize
+				if entry.Compression != CompressionNone {
+					session.CompressedCount++
+				}
+			} else {
+				session.FailureCount++
+			}
+			session.TotalSize += entry.fileSize()
 		}
-		session.TotalSize += entry.Size
 
 		// Send progress update
 		progress := BackupProgress{
@@ -227,6 +318,7 @@ func (bm *BackupManager) BackupFiles(files []string, operation string) (*BackupS
 
 	session.EndTime = time.Now()
 	session.Status = "completed"
+	session.TotalFiles = len(session.Entries)
 
 	// Save session to manifest
 	if err := bm.saveSessionToManifest(session); err != nil {
@@ -235,97 +327,106 @@ func (bm *BackupManager) BackupFiles(files []string, operation string) (*BackupS
 		return session, fmt.Errorf("failed to save session to manifest: %w", err)
 	}
 
+	// A failed upload leaves the local backup intact; SyncToRemote catches up later
+	if bm.GetRemote() != nil {
+		if _, err := bm.pushSessions([]BackupSession{*session}); err != nil {
+			session.RemoteError = err.Error()
+		}
+	}
+
+	if policy := bm.GetRetentionPolicy(); policy != nil && policy.AutoApply {
+		plan, err := bm.ApplyRetention()
+		if err != nil {
+			session.RetentionError = err.Error()
+		}
+		if plan != nil && plan.Applied {
+			session.PrunedSessions = plan.PruneSessions
+		}
+	}
+
 	return session, nil
 }
 
-// backupSingleFile creates a backup of a single file
-func (bm *BackupManager) backupSingleFile(originalPath, sessionDir, operation string) BackupEntry {
+// backupSingleFile backs up a single filesystem object to backupPath. Regular
+// files are stored as blobs; directories and symlinks are recorded in the entry.
+func (bm *BackupManager) backupSingleFile(originalPath, backupPath, operation string, info os.FileInfo, rootPath string, linkOriginals bool) BackupEntry {
 	entry := BackupEntry{
 		OriginalPath: originalPath,
 		BackupTime:   time.Now(),
 		Operation:    operation,
+		Type:         EntryTypeFile,
+		Mode:         info.Mode(),
+		RootPath:     rootPath,
 		Metadata:     make(map[string]interface{}),
 	}
 
-	// Get file info
-	info, err := os.Stat(originalPath)
-	if err != nil {
-		entry.Error = fmt.Sprintf("failed to get file info: %v", err)
-		return entry
-	}
-
-	entry.Size = info.Size()
 	entry.Metadata["permissions"] = info.Mode().String()
 	entry.Metadata["mod_time"] = info.ModTime()
 	entry.Metadata["is_dir"] = info.IsDir()
 
-	// Generate backup path
-	fileName := filepath.Base(originalPath)
-	backupPath := filepath.Join(sessionDir, fileName)
-	
-	// Handle duplicate file names
-	counter := 1
-	for {
-		if _, err := os.Stat(backupPath); os.IsNotExist(err) {
-			break
-		}
-		ext := filepath.Ext(fileName)
-		name := fileName[:len(fileName)-len(ext)]
-		backupPath = filepath.Join(sessionDir, fmt.Sprintf("%s_%d%s", name, counter, ext))
-		counter++
+	attrs, err := captureAttributes(originalPath, info)
+	entry.Attributes = attrs
+	if err != nil {
+		entry.Metadata["attributes_error"] = err.Error()
 	}
 
-	entry.BackupPath = backupPath
+	switch {
+	case info.IsDir():
+		entry.Type = EntryTypeDir
+		if err := os.MkdirAll(backupPath, 0755); err != nil {
+			entry.Error = fmt.Sprintf("failed to create backup directory: %v", err)
+			return entry
+		}
+		entry.BackupPath = backupPath
+		entry.Success = true
+		return entry
 
-	// Copy file
-	if err := bm.copyFile(originalPath, backupPath); err != nil {
-		entry.Error = fmt.Sprintf("failed to copy file: %v", err)
+	case info.Mode()&os.ModeSymlink != 0:
+		entry.Type = EntryTypeSymlink
+		target, err := os.Readlink(originalPath)
+		if err != nil {
+			entry.Error = fmt.Sprintf("failed to read symlink: %v", err)
+			return entry
+		}
+		entry.LinkTarget = target
+		entry.Success = true
 		return entry
-	}
 
-	// Calculate checksum
-	checksum, err := bm.calculateChecksum(backupPath)
-	if err != nil {
-		entry.Error = fmt.Sprintf("failed to calculate checksum: %v", err)
+	case !info.Mode().IsRegular():
+		entry.Error = fmt.Sprintf("unsupported file type: %s", info.Mode().Type())
 		return entry
 	}
 
-	entry.Checksum = checksum
-	entry.Success = true
-
-	return entry
-}
-
-// copyFile copies a file from source to destination
-func (bm *BackupManager) copyFile(src, dst string) error {
-	sourceFile, err := os.Open(src)
-	if err != nil {
-		return fmt.Errorf("failed to open source file: %w", err)
-	}
-	defer sourceFile.Close()
+	entry.Size = info.Size()
 
-	destFile, err := os.Create(dst)
-	if err != nil {
-		return fmt.Errorf("failed to create destination file: %w", err)
+	if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
+		entry.Error = fmt.Sprintf("failed to create backup directory: %v", err)
+		return entry
 	}
-	defer destFile.Close()
 
-	_, err = io.Copy(destFile, sourceFile)
-	if err != nil {
-		return fmt.Errorf("failed to copy file content: %w", err)
+	// Reflink, hardlink or copy the file, compressing the blob when enabled
+	blob, strategy, fallback, err := bm.storeBlob(originalPath, backupPath, info, linkOriginals)
+	entry.BackupPath = blob.Path
+	entry.CopyStrategy = strategy
+	if fallback != "" {
+		entry.Metadata["copy_fallback"] = fallback
 	}
-
-	// Copy file permissions
-	sourceInfo, err := sourceFile.Stat()
 	if err != nil {
-		return fmt.Errorf("failed to get source file info: %w", err)
+		entry.Error = fmt.Sprintf("failed to copy file: %v", err)
+		return entry
 	}
 
-	if err := destFile.Chmod(sourceInfo.Mode()); err != nil {
-		return fmt.Errorf("failed to set destination file permissions: %w", err)
+	entry.Checksum = blob.Checksum
+	entry.Compression = blob.Compression
+	entry.StoredSize = blob.StoredSize
+	entry.Encrypted = blob.KeyID != ""
+	entry.KeyID = blob.KeyID
+	if blob.SkipReason != "" {
+		entry.Metadata["compression_skipped"] = blob.SkipReason
 	}
+	entry.Success = true
 
-	return nil
+	return entry
 }
 
 // calculateChecksum calculates SHA256 checksum of a file
@@ -346,13 +447,11 @@ func (bm *BackupManager) calculateChecksum(filePath string) (string, error) {
 
 // saveSessionToManifest saves a backup session to the manifest file
 func (bm *BackupManager) saveSessionToManifest(session *BackupSession) error {
+	// loadManifest returns a fresh manifest when none exists yet; any other
+	// error (e.g. a missing encryption key) must not overwrite the existing one
 	manifest, err := bm.loadManifest()
 	if err != nil {
-		// Create new manifest if it doesn't exist
-		manifest = &BackupManifest{
-			Version:   "1.0",
-			CreatedAt: time.Now(),
-		}
+		return err
 	}
 
 	manifest.Sessions = append(manifest.Sessions, *session)
@@ -378,6 +477,22 @@ func (bm *BackupManager) loadManifest() (*BackupManifest, error) {
 		return nil, fmt.Errorf("failed to read manifest file: %w", err)
 	}
 
+	return bm.decodeManifest(data)
+}
+
+// decodeManifest parses manifest data, decrypting it if necessary
+func (bm *BackupManager) decodeManifest(data []byte) (*BackupManifest, error) {
+	if isEncryptedData(data) {
+		key, err := bm.resolveEncryptionKey(false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unlock manifest: %w", err)
+		}
+		data, err = decryptBytes(data, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt manifest: %w", err)
+		}
+	}
+
 	var manifest BackupManifest
 	if err := json.Unmarshal(data, &manifest); err != nil {
 		return nil, fmt.Errorf("failed to parse manifest file: %w", err)
@@ -388,9 +503,9 @@ func (bm *BackupManager) loadManifest() (*BackupManifest, error) {
 
 // SaveManifest saves the backup manifest to disk
 func (bm *BackupManager) SaveManifest(manifest *BackupManifest) error {
-	data, err := json.MarshalIndent(manifest, "", "  ")
+	data, err := bm.encodeManifest(manifest)
 	if err != nil {
-		return fmt.Errorf("failed to marshal manifest: %w", err)
+		return err
 	}
 
 	if err := os.WriteFile(bm.manifestFile, data, 0644); err != nil {
@@ -400,6 +515,27 @@ func (bm *BackupManager) SaveManifest(manifest *BackupManifest) error {
 	return nil
 }
 
+// encodeManifest serialises a manifest, encrypting it when encryption is enabled
+func (bm *BackupManager) encodeManifest(manifest *BackupManifest) ([]byte, error) {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	if bm.GetOptions().Encryption.Enabled {
+		key, err := bm.resolveEncryptionKey(true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt manifest: %w", err)
+		}
+		data, err = encryptBytes(data, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt manifest: %w", err)
+		}
+	}
+
+	return data, nil
+}
+
 // GetManifest returns the current backup manifest
 func (bm *BackupManager) GetManifest() (*BackupManifest, error) {
 	return bm.loadManifest()
@@ -442,19 +578,19 @@ func (bm *BackupManager) VerifyBackupIntegrity(sessionID string) (bool, []string
 	allValid := true
 
 	for _, entry := range session.Entries {
-		if !entry.Success {
+		if !entry.hasBlob() {
 			continue
 		}
 
-		// Check if backup file exists
-		if _, err := os.Stat(entry.BackupPath); os.IsNotExist(err) {
+		// Check if backup file exists, fetching it from the remote backend if needed
+		if err := bm.ensureLocalBlob(entry); err != nil {
 			errors = append(errors, fmt.Sprintf("backup file missing: %s", entry.BackupPath))
 			allValid = false
 			continue
 		}
 
-		// Verify checksum
-		currentChecksum, err := bm.calculateChecksum(entry.BackupPath)
+		// Verify checksum of the original content
+		currentChecksum, err := bm.blobChecksum(entry)
 		if err != nil {
 			errors = append(errors, fmt.Sprintf("failed to calculate checksum for %s: %v", entry.BackupPath, err))
 			allValid = false
@@ -479,37 +615,12 @@ func (bm *BackupManager) CleanupOldBackups(olderThan time.Duration) error {
 	}
 
 	cutoffTime := time.Now().Add(-olderThan)
-	var remainingSessions []BackupSession
-	var sessionsToDelete []BackupSession
-
+	var sessionsToDelete []string
 	for _, session := range manifest.Sessions {
 		if session.StartTime.Before(cutoffTime) {
-			sessionsToDelete = append(sessionsToDelete, session)
-		} else {
-			remainingSessions = append(remainingSessions, session)
-		}
-	}
-
-	// Delete backup files for old sessions
-	for _, session := range sessionsToDelete {
-		sessionDir := filepath.Join(bm.backupDir, "files", session.SessionID)
-		if err := os.RemoveAll(sessionDir); err != nil {
-			return fmt.Errorf("failed to remove session directory %s: %w", sessionDir, err)
+			sessionsToDelete = append(sessionsToDelete, session.SessionID)
 		}
 	}
 
-	// Update manifest
-	manifest.Sessions = remainingSessions
-	manifest.LastUpdated = time.Now()
-	manifest.TotalSessions = len(remainingSessions)
-
-	// Recalculate totals
-	manifest.TotalFiles = 0
-	manifest.TotalSize = 0
-	for _, session := range remainingSessions {
-		manifest.TotalFiles += session.TotalFiles
-		manifest.TotalSize += session.TotalSize
-	}
-
-	return bm.SaveManifest(manifest)
+	return bm.deleteSessions(sessionsToDelete)
 }
@@ -0,0 +1,121 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// FileAttributes holds the filesystem metadata captured alongside an entry
+type FileAttributes struct {
+	ModTime    time.Time         `json:"mod_time"`
+	AccessTime time.Time         `json:"access_time,omitempty"`
+	UID        int               `json:"uid"`
+	GID        int               `json:"gid"`
+	HasOwner   bool              `json:"has_owner,omitempty"`
+	Xattrs     map[string][]byte `json:"xattrs,omitempty"`
+}
+
+// MetadataIssue records an attribute that could not be reapplied during restore
+type MetadataIssue struct {
+	Path      string `json:"path"`
+	Attribute string `json:"attribute"` // mode, times, ownership or xattr:<name>
+	Error     string `json:"error"`
+}
+
+// LastAccessTime returns the last access time of a file, or its modification
+// time where the platform does not report access times
+func LastAccessTime(info os.FileInfo) time.Time {
+	if atime := accessTime(info); !atime.IsZero() {
+		return atime
+	}
+	return info.ModTime()
+}
+
+// captureAttributes records timestamps, ownership and extended attributes of
+// path. Failures to read extended attributes are returned but do not prevent
+// the remaining attributes from being captured.
+func captureAttributes(path string, info os.FileInfo) (*FileAttributes, error) {
+	attrs := &FileAttributes{ModTime: info.ModTime()}
+	attrs.AccessTime = accessTime(info)
+	attrs.UID, attrs.GID, attrs.HasOwner = fileOwner(info)
+
+	xattrs, err := readXattrs(path)
+	if len(xattrs) > 0 {
+		attrs.Xattrs = xattrs
+	}
+	return attrs, err
+}
+
+// entryAttributes returns the captured attributes of an entry, falling back to
+// the modification time recorded in the metadata of older manifests
+func entryAttributes(entry BackupEntry) *FileAttributes {
+	if entry.Attributes != nil {
+		return entry.Attributes
+	}
+
+	switch modTime := entry.Metadata["mod_time"].(type) {
+	case time.Time:
+		return &FileAttributes{ModTime: modTime}
+	case string:
+		if parsed, err := time.Parse(time.RFC3339Nano, modTime); err == nil {
+			return &FileAttributes{ModTime: parsed}
+		}
+	}
+	return nil
+}
+
+// applyAttributes reapplies ownership, mode, extended attributes and
+// timestamps to a restored entry and returns what could not be applied.
+// Ownership is applied first because chown clears the setuid and setgid bits,
+// and timestamps last because the other changes may touch them.
+func applyAttributes(entry BackupEntry) []MetadataIssue {
+	var issues []MetadataIssue
+	report := func(attribute string, err error) {
+		issues = append(issues, MetadataIssue{Path: entry.OriginalPath, Attribute: attribute, Error: err.Error()})
+	}
+
+	attrs := entryAttributes(entry)
+	symlink := entry.Kind() == EntryTypeSymlink
+
+	if attrs != nil && attrs.HasOwner {
+		if err := restoreOwner(entry.OriginalPath, attrs.UID, attrs.GID); err != nil {
+			report("ownership", err)
+		}
+	}
+
+	if entry.Mode != 0 && !symlink {
+		mode := entry.Mode & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+		if err := os.Chmod(entry.OriginalPath, mode); err != nil {
+			report("mode", err)
+		}
+	}
+
+	if attrs == nil {
+		return issues
+	}
+
+	names := make([]string, 0, len(attrs.Xattrs))
+	for name := range attrs.Xattrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writeXattr(entry.OriginalPath, name, attrs.Xattrs[name]); err != nil {
+			report("xattr:"+name, err)
+		}
+	}
+
+	if !attrs.ModTime.IsZero() {
+		atime := attrs.AccessTime
+		if atime.IsZero() {
+			atime = attrs.ModTime
+		}
+		if err := setFileTimes(entry.OriginalPath, atime, attrs.ModTime, symlink); err != nil {
+			report("times", fmt.Errorf("failed to set timestamps: %w", err))
+		}
+	}
+
+	return issues
+}
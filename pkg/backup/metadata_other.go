@@ -0,0 +1,44 @@
+//go:build !linux && !darwin
+
+package backup
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+var errAttributeUnsupported = errors.New("not supported on this platform")
+
+// accessTime is not available on this platform
+func accessTime(info os.FileInfo) time.Time {
+	return time.Time{}
+}
+
+// fileOwner is not available on this platform
+func fileOwner(info os.FileInfo) (int, int, bool) {
+	return 0, 0, false
+}
+
+// restoreOwner is not supported on this platform
+func restoreOwner(path string, uid, gid int) error {
+	return errAttributeUnsupported
+}
+
+// readXattrs reports no extended attributes on this platform
+func readXattrs(path string) (map[string][]byte, error) {
+	return nil, nil
+}
+
+// writeXattr is not supported on this platform
+func writeXattr(path, name string, value []byte) error {
+	return errAttributeUnsupported
+}
+
+// setFileTimes sets access and modification times; symlinks are left unchanged
+func setFileTimes(path string, atime, mtime time.Time, symlink bool) error {
+	if symlink {
+		return nil
+	}
+	return os.Chtimes(path, atime, mtime)
+}
@@ -0,0 +1,127 @@
+package backup
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRestoreReappliesMetadata(t *testing.T) {
+	cacheDir := filepath.Join(t.TempDir(), "Cache")
+	filePath := filepath.Join(cacheDir, "entry.bin")
+	linkPath := filepath.Join(cacheDir, "current")
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filePath, []byte("cached entry"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Symlink("entry.bin", linkPath); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	xattrValue := []byte("etag-1234")
+	hasXattr := writeXattr(filePath, "user.cache_app.test", xattrValue) == nil
+
+	atime := time.Date(2023, 5, 1, 8, 30, 0, 0, time.UTC)
+	mtime := time.Date(2023, 4, 1, 12, 0, 0, 500, time.UTC)
+	dirTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	os.Chmod(filePath, 0640)
+	if err := os.Chtimes(filePath, atime, mtime); err != nil {
+		t.Fatalf("Failed to set file times: %v", err)
+	}
+	if err := setFileTimes(linkPath, dirTime, dirTime, true); err != nil {
+		t.Logf("Symlink timestamps not supported: %v", err)
+	}
+	if err := os.Chtimes(cacheDir, dirTime, dirTime); err != nil {
+		t.Fatalf("Failed to set directory times: %v", err)
+	}
+
+	system, err := NewBackupSystemWithDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create backup system: %v", err)
+	}
+	deleted := backupAndRemove(t, system, []string{cacheDir}, "metadata_test")
+
+	result, err := system.RestoreSession(deleted.SessionID, false)
+	if err != nil || result.FailureCount != 0 {
+		t.Fatalf("Restore failed: %v (%+v)", err, result)
+	}
+	if len(result.MetadataIssues) != 0 {
+		t.Errorf("Unexpected metadata issues: %+v", result.MetadataIssues)
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatalf("Restored file missing: %v", err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("Restored mode %v, want 0640", info.Mode().Perm())
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("Restored mtime %v, want %v", info.ModTime(), mtime)
+	}
+	if got := accessTime(info); !got.IsZero() && !got.Equal(atime) {
+		t.Errorf("Restored atime %v, want %v", got, atime)
+	}
+
+	dirInfo, err := os.Stat(cacheDir)
+	if err != nil {
+		t.Fatalf("Restored directory missing: %v", err)
+	}
+	if !dirInfo.ModTime().Equal(dirTime) {
+		t.Errorf("Restored directory mtime %v, want %v", dirInfo.ModTime(), dirTime)
+	}
+
+	if hasXattr {
+		xattrs, err := readXattrs(filePath)
+		if err != nil || !bytes.Equal(xattrs["user.cache_app.test"], xattrValue) {
+			t.Errorf("Extended attribute not restored: %v (%v)", xattrs, err)
+		}
+	}
+}
+
+func TestRestoreReportsMetadataIssues(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "entry.bin")
+	if err := os.WriteFile(filePath, []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	mtime := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	entry := BackupEntry{
+		OriginalPath: filePath,
+		Type:         EntryTypeFile,
+		Mode:         0600,
+		Attributes: &FileAttributes{
+			ModTime: mtime,
+			Xattrs:  map[string][]byte{"invalid-namespace": []byte("value")},
+		},
+	}
+
+	issues := applyAttributes(entry)
+	if len(issues) != 1 || issues[0].Attribute != "xattr:invalid-namespace" || issues[0].Path != filePath {
+		t.Fatalf("Expected a single xattr issue, got %+v", issues)
+	}
+
+	// The remaining attributes are still applied
+	info, _ := os.Stat(filePath)
+	if info.Mode().Perm() != 0600 || !info.ModTime().Equal(mtime) {
+		t.Errorf("Attributes not applied: mode %v, mtime %v", info.Mode().Perm(), info.ModTime())
+	}
+
+	t.Run("LegacyManifest", func(t *testing.T) {
+		legacy := BackupEntry{
+			OriginalPath: filePath,
+			Metadata:     map[string]interface{}{"mod_time": "2021-06-01T10:00:00Z"},
+		}
+		if issues := applyAttributes(legacy); len(issues) != 0 {
+			t.Fatalf("Unexpected issues: %+v", issues)
+		}
+		info, _ := os.Stat(filePath)
+		if want := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC); !info.ModTime().Equal(want) {
+			t.Errorf("Restored mtime %v, want %v", info.ModTime(), want)
+		}
+	})
+}
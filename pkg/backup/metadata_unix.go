@@ -0,0 +1,117 @@
+//go:build linux || darwin
+
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// fileOwner returns the owning user and group of a file
+func fileOwner(info os.FileInfo) (int, int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(stat.Uid), int(stat.Gid), true
+}
+
+// restoreOwner changes the owner of path unless it already matches. Changing
+// ownership requires privileges, so this usually only succeeds as root.
+func restoreOwner(path string, uid, gid int) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if currentUID, currentGID, ok := fileOwner(info); ok && currentUID == uid && currentGID == gid {
+		return nil
+	}
+	if err := os.Lchown(path, uid, gid); err != nil {
+		return fmt.Errorf("failed to change owner to %d:%d: %w", uid, gid, err)
+	}
+	return nil
+}
+
+// readXattrs returns the extended attributes of path without following symlinks
+func readXattrs(path string) (map[string][]byte, error) {
+	size, err := unix.Llistxattr(path, nil)
+	if err != nil {
+		if xattrsUnsupported(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list extended attributes: %w", err)
+	}
+	if size == 0 {
+		return nil, nil
+	}
+
+	buf := make([]byte, size)
+	size, err = unix.Llistxattr(path, buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list extended attributes: %w", err)
+	}
+
+	xattrs := make(map[string][]byte)
+	var failed []string
+	for _, name := range strings.Split(string(buf[:size]), "\x00") {
+		if name == "" {
+			continue
+		}
+		value, err := readXattr(path, name)
+		if err != nil {
+			failed = append(failed, name)
+			continue
+		}
+		xattrs[name] = value
+	}
+
+	if len(failed) > 0 {
+		return xattrs, fmt.Errorf("failed to read extended attributes: %s", strings.Join(failed, ", "))
+	}
+	return xattrs, nil
+}
+
+// readXattr reads a single extended attribute, retrying if it grows between calls
+func readXattr(path, name string) ([]byte, error) {
+	for {
+		size, err := unix.Lgetxattr(path, name, nil)
+		if err != nil {
+			return nil, err
+		}
+		value := make([]byte, size)
+		n, err := unix.Lgetxattr(path, name, value)
+		if errors.Is(err, unix.ERANGE) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return value[:n], nil
+	}
+}
+
+// writeXattr sets an extended attribute without following symlinks
+func writeXattr(path, name string, value []byte) error {
+	return unix.Lsetxattr(path, name, value, 0)
+}
+
+// xattrsUnsupported reports whether err means the filesystem has no extended attributes
+func xattrsUnsupported(err error) bool {
+	return errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.EOPNOTSUPP)
+}
+
+// setFileTimes sets access and modification times, on the link itself for symlinks
+func setFileTimes(path string, atime, mtime time.Time, symlink bool) error {
+	flags := 0
+	if symlink {
+		flags = unix.AT_SYMLINK_NOFOLLOW
+	}
+	times := []unix.Timespec{unix.NsecToTimespec(atime.UnixNano()), unix.NsecToTimespec(mtime.UnixNano())}
+	return unix.UtimesNanoAt(unix.AT_FDCWD, path, times, flags)
+}
@@ -0,0 +1,462 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cache_app/internal/ui"
+)
+
+const (
+	remoteManifestKey     = "manifest.json"
+	remoteStateFile       = "remote_state.json"
+	remoteStateCheckpoint = 64 // Uploads recorded between writes of the remote state
+)
+
+// RemoteSyncResult summarises a transfer between the local store and a remote backend
+type RemoteSyncResult struct {
+	Backend          string   `json:"backend"`
+	UploadedFiles    int      `json:"uploaded_files"`
+	DownloadedFiles  int      `json:"downloaded_files"`
+	SkippedFiles     int      `json:"skipped_files"`
+	TransferredBytes int64    `json:"transferred_bytes"`
+	ImportedSessions int      `json:"imported_sessions"`
+	Errors           []string `json:"errors,omitempty"`
+}
+
+// remoteSyncState records which local file versions have been uploaded to which backend
+type remoteSyncState struct {
+	Backends map[string]map[string]string `json:"backends"` // backend name -> key -> fingerprint
+}
+
+// remoteUploads tracks the uploads of one sync against the state loaded when
+// it started, writing new fingerprints at checkpoints rather than per file
+type remoteUploads struct {
+	backend  string
+	uploaded map[string]string // Fingerprints recorded before the sync
+	pending  map[string]string // Uploaded by this sync, not yet recorded
+}
+
+// SetRemote replicates the backup store to backend after every backup. A nil
+// backend disables replication; a nil recovery uses the default retry policy.
+func (bm *BackupManager) SetRemote(backend StorageBackend, recovery *ui.ErrorRecovery) {
+	if recovery == nil {
+		recovery = ui.NewErrorRecovery(3, time.Second)
+	}
+
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	bm.remote = backend
+	bm.remoteRetry = recovery
+}
+
+// GetRemote returns the remote backend, or nil if replication is disabled
+func (bm *BackupManager) GetRemote() StorageBackend {
+	bm.mu.RLock()
+	defer bm.mu.RUnlock()
+	return bm.remote
+}
+
+// SyncToRemote uploads every session that is not yet fully replicated
+func (bm *BackupManager) SyncToRemote() (*RemoteSyncResult, error) {
+	manifest, err := bm.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+	return bm.pushSessions(manifest.Sessions)
+}
+
+// pushSessions uploads the blobs of sessions followed by the encryption header
+// and manifest, so the remote manifest never references missing blobs
+func (bm *BackupManager) pushSessions(sessions []BackupSession) (*RemoteSyncResult, error) {
+	remote := bm.GetRemote()
+	if remote == nil {
+		return nil, fmt.Errorf("no remote backend configured")
+	}
+
+	state, err := bm.loadRemoteState()
+	if err != nil {
+		return nil, err
+	}
+	uploads := &remoteUploads{
+		backend:  remote.Name(),
+		uploaded: state.Backends[remote.Name()],
+		pending:  make(map[string]string),
+	}
+
+	result := &RemoteSyncResult{Backend: remote.Name()}
+	for _, session := range sessions {
+		for _, entry := range session.Entries {
+			if !entry.hasBlob() {
+				continue
+			}
+			if err := bm.pushFile(entry.BackupPath, uploads, result); err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			}
+		}
+	}
+	if err := bm.recordRemoteUploads(uploads); err != nil {
+		return result, err
+	}
+	if len(result.Errors) > 0 {
+		return result, fmt.Errorf("failed to upload %d files to %s", len(result.Errors), remote.Name())
+	}
+
+	headerPath := filepath.Join(bm.backupDir, encryptionHeaderFile)
+	if _, err := os.Stat(headerPath); err == nil {
+		err := bm.pushFile(headerPath, uploads, result)
+		if recordErr := bm.recordRemoteUploads(uploads); err == nil {
+			err = recordErr
+		}
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if err := bm.pushManifest(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// pushFile uploads a file of the local store unless the same version was uploaded before
+func (bm *BackupManager) pushFile(localPath string, uploads *remoteUploads, result *RemoteSyncResult) error {
+	remote := bm.GetRemote()
+	key, err := bm.remoteKey(localPath)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", localPath, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", localPath, err)
+	}
+	fingerprint := fmt.Sprintf("%d-%d", info.Size(), info.ModTime().UnixNano())
+
+	if uploads.uploaded[key] == fingerprint || uploads.pending[key] == fingerprint {
+		result.SkippedFiles++
+		return nil
+	}
+
+	source := UploadSource{Reader: file, Size: info.Size(), Fingerprint: fingerprint}
+	if err := bm.withRemoteRetry(func() error { return remote.Upload(key, source) }); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+
+	result.UploadedFiles++
+	result.TransferredBytes += info.Size()
+	uploads.pending[key] = fingerprint
+	if len(uploads.pending) >= remoteStateCheckpoint {
+		return bm.recordRemoteUploads(uploads)
+	}
+	return nil
+}
+
+// pushManifest uploads the manifest with blob paths relative to the store root
+func (bm *BackupManager) pushManifest() error {
+	remote := bm.GetRemote()
+	manifest, err := bm.loadManifest()
+	if err != nil {
+		return err
+	}
+
+	for i := range manifest.Sessions {
+		entries := manifest.Sessions[i].Entries
+		for j := range entries {
+			if entries[j].BackupPath == "" {
+				continue
+			}
+			if key, err := bm.remoteKey(entries[j].BackupPath); err == nil {
+				entries[j].BackupPath = key
+			}
+		}
+	}
+
+	data, err := bm.encodeManifest(manifest)
+	if err != nil {
+		return err
+	}
+
+	source := UploadSource{Reader: strings.NewReader(string(data)), Size: int64(len(data))}
+	if err := bm.withRemoteRetry(func() error { return remote.Upload(remoteManifestKey, source) }); err != nil {
+		return fmt.Errorf("failed to upload manifest: %w", err)
+	}
+	return nil
+}
+
+// PullFromRemote adds sessions from the remote manifest that are missing
+// locally. Blobs are fetched on demand during restore unless downloadBlobs is set.
+func (bm *BackupManager) PullFromRemote(downloadBlobs bool) (*RemoteSyncResult, error) {
+	remote := bm.GetRemote()
+	if remote == nil {
+		return nil, fmt.Errorf("no remote backend configured")
+	}
+	result := &RemoteSyncResult{Backend: remote.Name()}
+
+	// The encryption header is needed to read an encrypted remote manifest
+	headerPath := filepath.Join(bm.backupDir, encryptionHeaderFile)
+	if _, err := os.Stat(headerPath); os.IsNotExist(err) {
+		if err := bm.downloadFile(encryptionHeaderFile, headerPath); err == nil {
+			result.DownloadedFiles++
+		} else if !errors.Is(err, ErrObjectNotFound) {
+			return nil, err
+		}
+	}
+
+	var data []byte
+	err := bm.withRemoteRetry(func() error {
+		reader, err := remote.Open(remoteManifestKey)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		data, err = io.ReadAll(reader)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download remote manifest: %w", err)
+	}
+
+	remoteManifest, err := bm.decodeManifest(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remote manifest: %w", err)
+	}
+
+	manifest, err := bm.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	var imported []BackupSession
+	for _, session := range remoteManifest.Sessions {
+		if sessionExists(manifest, session.SessionID) {
+			continue
+		}
+		for i := range session.Entries {
+			if key := session.Entries[i].BackupPath; key != "" {
+				session.Entries[i].BackupPath = filepath.Join(bm.backupDir, filepath.FromSlash(key))
+			}
+		}
+		manifest.Sessions = append(manifest.Sessions, session)
+		manifest.TotalFiles += session.TotalFiles
+		manifest.TotalSize += session.TotalSize
+		imported = append(imported, session)
+	}
+
+	if len(imported) > 0 {
+		manifest.TotalSessions = len(manifest.Sessions)
+		manifest.LastUpdated = time.Now()
+		if err := bm.SaveManifest(manifest); err != nil {
+			return nil, err
+		}
+	}
+	result.ImportedSessions = len(imported)
+
+	if downloadBlobs {
+		for _, session := range manifest.Sessions {
+			for _, entry := range session.Entries {
+				if !entry.hasBlob() {
+					continue
+				}
+				if _, err := os.Stat(entry.BackupPath); err == nil {
+					result.SkippedFiles++
+					continue
+				}
+				if err := bm.ensureLocalBlob(entry); err != nil {
+					result.Errors = append(result.Errors, err.Error())
+					continue
+				}
+				result.DownloadedFiles++
+			}
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		return result, fmt.Errorf("failed to download %d files from %s", len(result.Errors), remote.Name())
+	}
+	return result, nil
+}
+
+// ensureLocalBlob downloads the blob of an entry from the remote backend if it is missing locally
+func (bm *BackupManager) ensureLocalBlob(entry BackupEntry) error {
+	_, err := os.Stat(entry.BackupPath)
+	if err == nil || !os.IsNotExist(err) || bm.GetRemote() == nil {
+		return err
+	}
+
+	key, keyErr := bm.remoteKey(entry.BackupPath)
+	if keyErr != nil {
+		return err
+	}
+	if err := bm.downloadFile(key, entry.BackupPath); err != nil {
+		return fmt.Errorf("backup file missing locally and on remote: %w", err)
+	}
+	return nil
+}
+
+// downloadFile copies an object from the remote backend to localPath
+func (bm *BackupManager) downloadFile(key, localPath string) error {
+	remote := bm.GetRemote()
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", localPath, err)
+	}
+
+	tempPath := localPath + ".downloading"
+	err := bm.withRemoteRetry(func() error {
+		reader, err := remote.Open(key)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		file, err := os.Create(tempPath)
+		if err != nil {
+			return permanentStorageError(err)
+		}
+		defer file.Close()
+
+		if _, err := io.Copy(file, reader); err != nil {
+			return err
+		}
+		return file.Close()
+	})
+	if err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to download %s: %w", key, err)
+	}
+
+	if err := os.Rename(tempPath, localPath); err != nil {
+		return fmt.Errorf("failed to move %s into place: %w", localPath, err)
+	}
+	return nil
+}
+
+// removeRemoteSessions deletes the blobs of sessions from the remote backend
+// and uploads the updated manifest
+func (bm *BackupManager) removeRemoteSessions(sessions []BackupSession) error {
+	remote := bm.GetRemote()
+	if remote == nil || len(sessions) == 0 {
+		return nil
+	}
+
+	for _, session := range sessions {
+		prefix := "files/" + session.SessionID + "/"
+		var objects []ObjectInfo
+		err := bm.withRemoteRetry(func() error {
+			var err error
+			objects, err = remote.List(prefix)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list remote session %s: %w", session.SessionID, err)
+		}
+
+		deleted := &remoteUploads{backend: remote.Name(), pending: make(map[string]string)}
+		for _, object := range objects {
+			if err := bm.withRemoteRetry(func() error { return remote.Delete(object.Key) }); err != nil {
+				bm.recordRemoteUploads(deleted)
+				return fmt.Errorf("failed to delete remote object %s: %w", object.Key, err)
+			}
+			deleted.pending[object.Key] = ""
+		}
+		bm.recordRemoteUploads(deleted)
+	}
+
+	return bm.pushManifest()
+}
+
+// remoteKey maps a path inside the local store to its backend key
+func (bm *BackupManager) remoteKey(localPath string) (string, error) {
+	rel, err := filepath.Rel(bm.backupDir, localPath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("%s is outside the backup directory", localPath)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// withRemoteRetry runs a backend operation with the configured retry policy
+func (bm *BackupManager) withRemoteRetry(operation func() error) error {
+	bm.mu.RLock()
+	recovery := bm.remoteRetry
+	bm.mu.RUnlock()
+
+	if recovery == nil {
+		return operation()
+	}
+	return recovery.RetryWithBackoff(operation)
+}
+
+// loadRemoteState reads which file versions have been uploaded
+func (bm *BackupManager) loadRemoteState() (*remoteSyncState, error) {
+	state := &remoteSyncState{Backends: make(map[string]map[string]string)}
+
+	data, err := os.ReadFile(filepath.Join(bm.backupDir, "metadata", remoteStateFile))
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remote state: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse remote state: %w", err)
+	}
+	if state.Backends == nil {
+		state.Backends = make(map[string]map[string]string)
+	}
+	return state, nil
+}
+
+// recordRemoteUploads stores the pending fingerprints of uploads, forgetting
+// keys whose fingerprint is empty, and clears them
+func (bm *BackupManager) recordRemoteUploads(uploads *remoteUploads) error {
+	if len(uploads.pending) == 0 {
+		return nil
+	}
+
+	bm.remoteStateMu.Lock()
+	defer bm.remoteStateMu.Unlock()
+
+	state, err := bm.loadRemoteState()
+	if err != nil {
+		return err
+	}
+	if state.Backends[uploads.backend] == nil {
+		state.Backends[uploads.backend] = make(map[string]string)
+	}
+	for key, fingerprint := range uploads.pending {
+		if fingerprint == "" {
+			delete(state.Backends[uploads.backend], key)
+		} else {
+			state.Backends[uploads.backend][key] = fingerprint
+		}
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal remote state: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(bm.backupDir, "metadata", remoteStateFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write remote state: %w", err)
+	}
+
+	if uploads.uploaded == nil {
+		uploads.uploaded = make(map[string]string)
+	}
+	for key, fingerprint := range uploads.pending {
+		uploads.uploaded[key] = fingerprint
+	}
+	clear(uploads.pending)
+	return nil
+}
@@ -2,7 +2,6 @@ package backup
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"sync"
@@ -45,6 +44,13 @@ type RestoreResult struct {
 	Error           string    `json:"error,omitempty"`
 	RestoredFiles   []string  `json:"restored_files"`
 	FailedFiles     []string  `json:"failed_files"`
+	SkippedCount    int       `json:"skipped_count"`
+	SkippedFiles    []string  `json:"skipped_files"`
+	Strategy        ConflictStrategy `json:"strategy,omitempty"`
+	TargetRoot      string    `json:"target_root,omitempty"`
+	Actions         []RestoreAction `json:"actions,omitempty"` // Per-entry decisions, in session order
+	AsOf            *time.Time `json:"as_of,omitempty"`         // Set for point-in-time restores across sessions
+	MetadataIssues  []MetadataIssue `json:"metadata_issues,omitempty"` // Attributes that could not be reapplied
 }
 
 // NewRestoreManager creates a new restore manager instance
@@ -88,8 +94,25 @@ func (rm *RestoreManager) setRestoring(restoring bool) {
 	rm.isRestoring = restoring
 }
 
-// RestoreSession restores all files from a backup session
+// RestoreSession restores all files from a backup session. Existing files are
+// replaced if overwrite is set and skipped otherwise.
 func (rm *RestoreManager) RestoreSession(sessionID string, overwrite bool) (*RestoreResult, error) {
+	return rm.Restore(sessionID, RestoreOptions{Conflict: conflictStrategy(overwrite)})
+}
+
+// RestoreFiles restores specific files from a backup session. Requested
+// directories restore their whole tree.
+func (rm *RestoreManager) RestoreFiles(sessionID string, filePaths []string, overwrite bool) (*RestoreResult, error) {
+	return rm.Restore(sessionID, RestoreOptions{Paths: filePaths, Conflict: conflictStrategy(overwrite)})
+}
+
+// Restore restores the selected entries of a session using the given conflict
+// strategy, optionally below an alternate root directory
+func (rm *RestoreManager) Restore(sessionID string, options RestoreOptions) (*RestoreResult, error) {
+	if err := ValidateConflictStrategy(options.Conflict); err != nil {
+		return nil, err
+	}
+
 	if rm.IsRestoring() {
 		return nil, fmt.Errorf("restore already in progress")
 	}
@@ -97,57 +120,93 @@ func (rm *RestoreManager) RestoreSession(sessionID string, overwrite bool) (*Res
 	rm.setRestoring(true)
 	defer rm.setRestoring(false)
 
-	// Get the backup session
-	session, err := rm.backupManager.GetSession(sessionID)
+	result, entries, actions, err := rm.planRestore(sessionID, options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get backup session: %w", err)
+		return nil, err
 	}
+	return rm.executeRestore(result, entries, actions, options)
+}
 
-	result := &RestoreResult{
-		SessionID:     sessionID,
-		StartTime:     time.Now(),
-		TotalFiles:    len(session.Entries),
-		Status:        "in_progress",
-		RestoredFiles: make([]string, 0),
-		FailedFiles:   make([]string, 0),
+// executeRestore carries out planned actions. Entries must be ordered so that
+// directories precede their contents.
+func (rm *RestoreManager) executeRestore(result *RestoreResult, entries []BackupEntry, actions []RestoreAction, options RestoreOptions) (*RestoreResult, error) {
+	result.StartTime = time.Now()
+	result.Status = "in_progress"
+
+	// Fail-fast refuses to write anything if any target exists
+	if options.Conflict == ConflictFailFast {
+		for _, action := range actions {
+			if action.Conflict {
+				result.Status = "conflict"
+				result.EndTime = time.Now()
+				result.Actions = actions
+				return result, fmt.Errorf("restore aborted: %s already exists", action.TargetPath)
+			}
+		}
+	}
+
+	if options.TargetRoot != "" {
+		if err := os.MkdirAll(options.TargetRoot, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create restore target directory: %w", err)
+		}
 	}
 
 	startTime := time.Now()
+	var restoredDirs []BackupEntry
+	defer func() {
+		result.MetadataIssues = append(result.MetadataIssues, applyDirectoryAttributes(restoredDirs)...)
+	}()
 
-	for i, entry := range session.Entries {
+	for i, entry := range entries {
 		// Check for stop signal
 		select {
 		case <-rm.stopChan:
 			result.Status = "cancelled"
 			result.EndTime = time.Now()
+			result.Actions = actions[:i]
 			return result, fmt.Errorf("restore cancelled by user")
 		default:
 		}
 
-		if !entry.Success {
-			result.FailedFiles = append(result.FailedFiles, entry.OriginalPath)
-			result.FailureCount++
-			continue
-		}
+		action := &actions[i]
+		target := entry
+		target.OriginalPath = action.TargetPath
 
-		// Restore the file
-		if err := rm.restoreSingleFile(entry, overwrite); err != nil {
-			result.FailedFiles = append(result.FailedFiles, entry.OriginalPath)
+		switch action.Action {
+		case RestoreActionSkip:
+			result.SkippedFiles = append(result.SkippedFiles, action.OriginalPath)
+			result.SkippedCount++
+		case RestoreActionFail:
+			result.FailedFiles = append(result.FailedFiles, action.OriginalPath)
 			result.FailureCount++
-		} else {
-			result.RestoredFiles = append(result.RestoredFiles, entry.OriginalPath)
+		default:
+			// Restore the file
+			if err := rm.restoreSingleFile(target, action.Action == RestoreActionOverwrite); err != nil {
+				action.Action = RestoreActionFail
+				action.Reason = err.Error()
+				result.FailedFiles = append(result.FailedFiles, action.OriginalPath)
+				result.FailureCount++
+				break
+			}
+			result.RestoredFiles = append(result.RestoredFiles, action.TargetPath)
 			result.SuccessCount++
-			result.RestoredSize += entry.Size
+			result.RestoredSize += entry.fileSize()
+
+			// Directory attributes are applied once their children are restored
+			if entry.Kind() == EntryTypeDir {
+				restoredDirs = append(restoredDirs, target)
+			} else {
+				result.MetadataIssues = append(result.MetadataIssues, applyAttributes(target)...)
+			}
 		}
-		result.TotalSize += entry.Size
 
 		// Send progress update
 		progress := RestoreProgress{
-			SessionID:      sessionID,
+			SessionID:      result.SessionID,
 			CurrentFile:    entry.OriginalPath,
 			FilesProcessed: i + 1,
-			TotalFiles:     len(session.Entries),
-			Progress:       float64(i+1) / float64(len(session.Entries)) * 100,
+			TotalFiles:     len(entries),
+			Progress:       float64(i+1) / float64(len(entries)) * 100,
 			ElapsedTime:    time.Since(startTime),
 			CurrentSize:    result.RestoredSize,
 			TotalSize:      result.TotalSize,
@@ -156,7 +215,7 @@ func (rm *RestoreManager) RestoreSession(sessionID string, overwrite bool) (*Res
 		// Calculate estimated time
 		if i > 0 {
 			avgTimePerFile := time.Since(startTime) / time.Duration(i+1)
-			remainingFiles := len(session.Entries) - (i + 1)
+			remainingFiles := len(entries) - (i + 1)
 			progress.EstimatedTime = avgTimePerFile * time.Duration(remainingFiles)
 		}
 
@@ -167,117 +226,75 @@ func (rm *RestoreManager) RestoreSession(sessionID string, overwrite bool) (*Res
 		}
 	}
 
+	result.Actions = actions
 	result.EndTime = time.Now()
 	result.Status = "completed"
 
 	return result, nil
 }
 
-// RestoreFiles restores specific files from a backup session
-func (rm *RestoreManager) RestoreFiles(sessionID string, filePaths []string, overwrite bool) (*RestoreResult, error) {
-	if rm.IsRestoring() {
-		return nil, fmt.Errorf("restore already in progress")
-	}
-
-	rm.setRestoring(true)
-	defer rm.setRestoring(false)
-
+// planRestore selects the entries of a session to restore and decides the action for each
+func (rm *RestoreManager) planRestore(sessionID string, options RestoreOptions) (*RestoreResult, []BackupEntry, []RestoreAction, error) {
 	// Get the backup session
 	session, err := rm.backupManager.GetSession(sessionID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get backup session: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to get backup session: %w", err)
 	}
 
-	result := &RestoreResult{
-		SessionID:     sessionID,
-		StartTime:     time.Now(),
-		TotalFiles:    len(filePaths),
-		Status:        "in_progress",
-		RestoredFiles: make([]string, 0),
-		FailedFiles:   make([]string, 0),
-	}
-
-	// Create a map for quick lookup
-	entryMap := make(map[string]BackupEntry)
-	for _, entry := range session.Entries {
-		entryMap[entry.OriginalPath] = entry
+	entries := session.Entries
+	var missing []string
+	if len(options.Paths) > 0 {
+		entries, missing = selectEntries(session, options.Paths)
 	}
 
-	startTime := time.Now()
-
-	for i, filePath := range filePaths {
-		// Check for stop signal
-		select {
-		case <-rm.stopChan:
-			result.Status = "cancelled"
-			result.EndTime = time.Now()
-			return result, fmt.Errorf("restore cancelled by user")
-		default:
-		}
-
-		entry, exists := entryMap[filePath]
-		if !exists {
-			result.FailedFiles = append(result.FailedFiles, filePath)
-			result.FailureCount++
-			continue
-		}
-
-		if !entry.Success {
-			result.FailedFiles = append(result.FailedFiles, filePath)
-			result.FailureCount++
-			continue
-		}
-
-		// Restore the file
-		if err := rm.restoreSingleFile(entry, overwrite); err != nil {
-			result.FailedFiles = append(result.FailedFiles, filePath)
-			result.FailureCount++
-		} else {
-			result.RestoredFiles = append(result.RestoredFiles, filePath)
-			result.SuccessCount++
-			result.RestoredSize += entry.Size
-		}
-		result.TotalSize += entry.Size
-
-		// Send progress update
-		progress := RestoreProgress{
-			SessionID:      sessionID,
-			CurrentFile:    filePath,
-			FilesProcessed: i + 1,
-			TotalFiles:     len(filePaths),
-			Progress:       float64(i+1) / float64(len(filePaths)) * 100,
-			ElapsedTime:    time.Since(startTime),
-			CurrentSize:    result.RestoredSize,
-			TotalSize:      result.TotalSize,
-		}
-
-		// Calculate estimated time
-		if i > 0 {
-			avgTimePerFile := time.Since(startTime) / time.Duration(i+1)
-			remainingFiles := len(filePaths) - (i + 1)
-			progress.EstimatedTime = avgTimePerFile * time.Duration(remainingFiles)
-		}
+	result, actions := planEntries(entries, missing, options)
+	result.SessionID = sessionID
+	return result, entries, actions, nil
+}
 
-		select {
-		case rm.progressChan <- progress:
-		default:
-			// Channel is full, skip this update
-		}
+// planEntries decides the action for each entry and prepares the result
+func planEntries(entries []BackupEntry, missing []string, options RestoreOptions) (*RestoreResult, []RestoreAction) {
+	planner := newRestorePlanner(options)
+	result := &RestoreResult{
+		TotalFiles:    len(entries) + len(missing),
+		Strategy:      planner.options.Conflict,
+		TargetRoot:    options.TargetRoot,
+		RestoredFiles: make([]string, 0),
+		FailedFiles:   append(make([]string, 0), missing...),
+		FailureCount:  len(missing),
+		SkippedFiles:  make([]string, 0),
 	}
 
-	result.EndTime = time.Now()
-	result.Status = "completed"
+	actions := make([]RestoreAction, len(entries))
+	for i, entry := range entries {
+		actions[i] = planner.plan(entry)
+		result.TotalSize += entry.fileSize()
+	}
 
-	return result, nil
+	return result, actions
 }
 
-// restoreSingleFile restores a single file from backup
+// restoreSingleFile restores a single file, directory or symlink from backup
 func (rm *RestoreManager) restoreSingleFile(entry BackupEntry, overwrite bool) error {
+	switch entry.Kind() {
+	case EntryTypeDir:
+		return restoreDirectory(entry, overwrite)
+	case EntryTypeSymlink:
+		return restoreSymlink(entry, overwrite)
+	}
+
 	// Check if destination file exists
-	if _, err := os.Stat(entry.OriginalPath); err == nil {
+	if info, err := os.Lstat(entry.OriginalPath); err == nil {
 		if !overwrite {
 			return fmt.Errorf("file already exists and overwrite is disabled: %s", entry.OriginalPath)
 		}
+		if info.IsDir() {
+			return fmt.Errorf("a directory exists at %s", entry.OriginalPath)
+		}
+		// Replace rather than write through an existing symlink
+		if err := os.Remove(entry.OriginalPath); err != nil {
+			return fmt.Errorf("failed to replace %s: %w", entry.OriginalPath, err)
+		}
 	}
 
 	// Ensure destination directory exists
@@ -287,7 +304,7 @@ func (rm *RestoreManager) restoreSingleFile(entry BackupEntry, overwrite bool) e
 	}
 
 	// Copy file from backup to original location
-	if err := rm.copyFile(entry.BackupPath, entry.OriginalPath); err != nil {
+	if err := rm.backupManager.extractBlob(entry, entry.OriginalPath); err != nil {
 		return fmt.Errorf("failed to copy file from backup: %w", err)
 	}
 
@@ -307,38 +324,6 @@ func (rm *RestoreManager) restoreSingleFile(entry BackupEntry, overwrite bool) e
 	return nil
 }
 
-// copyFile copies a file from source to destination
-func (rm *RestoreManager) copyFile(src, dst string) error {
-	sourceFile, err := os.Open(src)
-	if err != nil {
-		return fmt.Errorf("failed to open source file: %w", err)
-	}
-	defer sourceFile.Close()
-
-	destFile, err := os.Create(dst)
-	if err != nil {
-		return fmt.Errorf("failed to create destination file: %w", err)
-	}
-	defer destFile.Close()
-
-	_, err = io.Copy(destFile, sourceFile)
-	if err != nil {
-		return fmt.Errorf("failed to copy file content: %w", err)
-	}
-
-	// Copy file permissions
-	sourceInfo, err := sourceFile.Stat()
-	if err != nil {
-		return fmt.Errorf("failed to get source file info: %w", err)
-	}
-
-	if err := destFile.Chmod(sourceInfo.Mode()); err != nil {
-		return fmt.Errorf("failed to set destination file permissions: %w", err)
-	}
-
-	return nil
-}
-
 // GetRestoreableFiles returns a list of files that can be restored from a session
 func (rm *RestoreManager) GetRestoreableFiles(sessionID string) ([]string, error) {
 	session, err := rm.backupManager.GetSession(sessionID)
@@ -358,37 +343,42 @@ func (rm *RestoreManager) GetRestoreableFiles(sessionID string) ([]string, error
 
 // PreviewRestore shows what files would be restored without actually restoring them
 func (rm *RestoreManager) PreviewRestore(sessionID string) (*RestoreResult, error) {
-	session, err := rm.backupManager.GetSession(sessionID)
-	if err != nil {
+	return rm.PreviewRestoreWithOptions(sessionID, RestoreOptions{})
+}
+
+// PreviewRestoreWithOptions reports the action each entry would get from
+// Restore with the same options, without changing anything
+func (rm *RestoreManager) PreviewRestoreWithOptions(sessionID string, options RestoreOptions) (*RestoreResult, error) {
+	if err := ValidateConflictStrategy(options.Conflict); err != nil {
 		return nil, err
 	}
 
-	result := &RestoreResult{
-		SessionID:     sessionID,
-		TotalFiles:    len(session.Entries),
-		Status:        "preview",
-		RestoredFiles: make([]string, 0),
-		FailedFiles:   make([]string, 0),
+	result, _, actions, err := rm.planRestore(sessionID, options)
+	if err != nil {
+		return nil, err
 	}
+	return previewResult(result, actions), nil
+}
 
-	for _, entry := range session.Entries {
-		if !entry.Success {
-			result.FailedFiles = append(result.FailedFiles, entry.OriginalPath)
-			result.FailureCount++
-			continue
-		}
-
-		// Check if file would conflict
-		if _, err := os.Stat(entry.OriginalPath); err == nil {
-			result.FailedFiles = append(result.FailedFiles, entry.OriginalPath+" (would conflict)")
+// previewResult summarizes planned actions without executing them
+func previewResult(result *RestoreResult, actions []RestoreAction) *RestoreResult {
+	result.Status = "preview"
+	result.Actions = actions
+
+	for _, action := range actions {
+		switch action.Action {
+		case RestoreActionSkip:
+			result.SkippedFiles = append(result.SkippedFiles, action.OriginalPath)
+			result.SkippedCount++
+		case RestoreActionFail:
+			result.FailedFiles = append(result.FailedFiles, action.OriginalPath)
 			result.FailureCount++
-		} else {
-			result.RestoredFiles = append(result.RestoredFiles, entry.OriginalPath)
+		default:
+			result.RestoredFiles = append(result.RestoredFiles, action.TargetPath)
 			result.SuccessCount++
-			result.RestoredSize += entry.Size
+			result.RestoredSize += action.Size
 		}
-		result.TotalSize += entry.Size
 	}
 
-	return result, nil
+	return result
 }
@@ -0,0 +1,232 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// ConflictStrategy decides what happens when a restore target already exists
+type ConflictStrategy string
+
+const (
+	// ConflictSkip leaves existing files untouched
+	ConflictSkip ConflictStrategy = "skip"
+	// ConflictOverwrite replaces existing files with the backed up version
+	ConflictOverwrite ConflictStrategy = "overwrite"
+	// ConflictKeepNewer replaces existing files only if the backed up version is newer
+	ConflictKeepNewer ConflictStrategy = "keep_newer"
+	// ConflictRename restores next to existing files under a suffixed name
+	ConflictRename ConflictStrategy = "rename"
+	// ConflictFailFast aborts the restore before anything is written if any target exists
+	ConflictFailFast ConflictStrategy = "fail_fast"
+)
+
+// Actions planned for a single entry
+const (
+	RestoreActionCreate    = "create"
+	RestoreActionOverwrite = "overwrite"
+	RestoreActionMerge     = "merge" // Directory restored into an existing directory
+	RestoreActionRename    = "rename"
+	RestoreActionSkip      = "skip"
+	RestoreActionFail      = "fail"
+)
+
+// renamedRestoreSuffix is inserted before the extension of renamed restore targets
+const renamedRestoreSuffix = ".restored"
+
+// RestoreOptions controls which entries are restored and where
+type RestoreOptions struct {
+	Paths      []string         `json:"paths,omitempty"` // Original paths to restore, all entries if empty
+	Conflict   ConflictStrategy `json:"conflict"`        // Defaults to ConflictSkip
+	TargetRoot string           `json:"target_root,omitempty"`
+}
+
+// RestoreAction describes what a restore does, or would do, with one entry
+type RestoreAction struct {
+	OriginalPath string    `json:"original_path"`
+	TargetPath   string    `json:"target_path"`
+	Type         EntryType `json:"type"`
+	Size         int64     `json:"size"`
+	Action       string    `json:"action"`
+	Conflict     bool      `json:"conflict"`
+	Reason       string    `json:"reason,omitempty"`
+	SessionID    string    `json:"session_id,omitempty"` // Set when entries come from several sessions
+}
+
+// ValidateConflictStrategy checks that strategy is one of the known strategies
+func ValidateConflictStrategy(strategy ConflictStrategy) error {
+	switch strategy {
+	case "", ConflictSkip, ConflictOverwrite, ConflictKeepNewer, ConflictRename, ConflictFailFast:
+		return nil
+	}
+	return fmt.Errorf("unknown conflict strategy: %s", strategy)
+}
+
+// conflictStrategy maps the legacy overwrite flag to a strategy
+func conflictStrategy(overwrite bool) ConflictStrategy {
+	if overwrite {
+		return ConflictOverwrite
+	}
+	return ConflictSkip
+}
+
+// restoreTarget maps an original path below targetRoot, keeping the full
+// original path so entries from different locations cannot collide
+func restoreTarget(originalPath, targetRoot string) string {
+	if targetRoot == "" {
+		return originalPath
+	}
+	volume := filepath.VolumeName(originalPath)
+	rest := originalPath[len(volume):]
+	volume = strings.Trim(strings.ReplaceAll(volume, ":", ""), `\/`)
+	return filepath.Join(targetRoot, volume, rest)
+}
+
+// restorePlanner decides the action for each entry in session order, so that
+// renamed or skipped directories carry their descendants along
+type restorePlanner struct {
+	options    RestoreOptions
+	redirected map[string]string // Renamed directory target -> actual target
+	skipped    map[string]string // Skipped directory target -> reason
+	claimed    map[string]bool   // Rename targets already handed out
+}
+
+func newRestorePlanner(options RestoreOptions) *restorePlanner {
+	if options.Conflict == "" {
+		options.Conflict = ConflictSkip
+	}
+	return &restorePlanner{
+		options:    options,
+		redirected: make(map[string]string),
+		skipped:    make(map[string]string),
+		claimed:    make(map[string]bool),
+	}
+}
+
+// plan decides the action for a single entry
+func (p *restorePlanner) plan(entry BackupEntry) RestoreAction {
+	action := RestoreAction{
+		OriginalPath: entry.OriginalPath,
+		TargetPath:   restoreTarget(entry.OriginalPath, p.options.TargetRoot),
+		Type:         entry.Kind(),
+		Size:         entry.fileSize(),
+		Action:       RestoreActionCreate,
+	}
+
+	if !entry.Success {
+		action.Action = RestoreActionFail
+		action.Reason = entry.Error
+		return action
+	}
+
+	// Follow renamed or skipped ancestors
+	for dir := filepath.Dir(action.TargetPath); ; dir = filepath.Dir(dir) {
+		if reason, ok := p.skipped[dir]; ok {
+			action.Action = RestoreActionSkip
+			action.Reason = reason
+			p.markSkipped(action, reason)
+			return action
+		}
+		if renamed, ok := p.redirected[dir]; ok {
+			action.TargetPath = renamed + action.TargetPath[len(dir):]
+			break
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+
+	existing, err := os.Lstat(action.TargetPath)
+	if err != nil {
+		// A missing parent, or a file where a replaced parent will be, means nothing to conflict with
+		if !os.IsNotExist(err) && !errors.Is(err, syscall.ENOTDIR) {
+			action.Action = RestoreActionFail
+			action.Reason = fmt.Sprintf("failed to check target: %v", err)
+		}
+		return action
+	}
+
+	if entry.Kind() == EntryTypeDir && existing.IsDir() {
+		action.Action = RestoreActionMerge
+		return action
+	}
+
+	action.Conflict = true
+	switch p.options.Conflict {
+	case ConflictOverwrite:
+		p.overwrite(&action, existing)
+	case ConflictKeepNewer:
+		attrs := entryAttributes(entry)
+		switch {
+		case attrs == nil || attrs.ModTime.IsZero():
+			action.Action = RestoreActionSkip
+			action.Reason = "backup has no modification time to compare"
+		case attrs.ModTime.After(existing.ModTime()):
+			p.overwrite(&action, existing)
+			if action.Action == RestoreActionOverwrite {
+				action.Reason = "backup is newer than existing file"
+			}
+		default:
+			action.Action = RestoreActionSkip
+			action.Reason = "existing file is newer or the same age"
+		}
+	case ConflictRename:
+		renamed := p.renamedTarget(action.TargetPath)
+		if entry.Kind() == EntryTypeDir {
+			p.redirected[action.TargetPath] = renamed
+		}
+		action.TargetPath = renamed
+		action.Action = RestoreActionRename
+	case ConflictFailFast:
+		action.Action = RestoreActionFail
+		action.Reason = "target already exists"
+	default:
+		action.Action = RestoreActionSkip
+		action.Reason = "target already exists"
+	}
+
+	if action.Action == RestoreActionSkip {
+		p.markSkipped(action, action.Reason)
+	}
+	return action
+}
+
+// overwrite plans replacing an existing target. Existing directories are
+// never removed to make room for a file or symlink.
+func (p *restorePlanner) overwrite(action *RestoreAction, existing os.FileInfo) {
+	if existing.IsDir() {
+		action.Action = RestoreActionFail
+		action.Reason = "a directory exists at the target"
+		return
+	}
+	action.Action = RestoreActionOverwrite
+}
+
+// markSkipped makes descendants of a skipped directory skip as well
+func (p *restorePlanner) markSkipped(action RestoreAction, reason string) {
+	if action.Type == EntryTypeDir {
+		p.skipped[action.TargetPath] = "parent directory skipped: " + reason
+	}
+}
+
+// renamedTarget returns the first free "<name>.restored[-N]<ext>" path next to target
+func (p *restorePlanner) renamedTarget(target string) string {
+	dir, fileName := filepath.Split(target)
+	ext := filepath.Ext(fileName)
+	name := fileName[:len(fileName)-len(ext)]
+	for counter := 1; ; counter++ {
+		suffix := renamedRestoreSuffix
+		if counter > 1 {
+			suffix = fmt.Sprintf("%s-%d", renamedRestoreSuffix, counter)
+		}
+		candidate := filepath.Join(dir, name+suffix+ext)
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) && !p.claimed[candidate] {
+			p.claimed[candidate] = true
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRestoreConflictStrategies(t *testing.T) {
+	testDir := t.TempDir()
+	older := filepath.Join(testDir, "older.txt")  // Existing file older than the backup
+	newer := filepath.Join(testDir, "newer.txt")  // Existing file newer than the backup
+	missing := filepath.Join(testDir, "gone.txt") // Deleted after the backup
+	olderRenamed := filepath.Join(testDir, "older.restored.txt")
+	newerRenamed := filepath.Join(testDir, "newer.restored.txt")
+	cacheDir := filepath.Join(testDir, "cache") // Directory replaced by a file
+	child := filepath.Join(cacheDir, "entry.bin")
+
+	writeFile := func(path, content string, mtime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("Failed to set times: %v", err)
+		}
+	}
+	readFile := func(path string) string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "<missing>"
+		}
+		return string(data)
+	}
+
+	backupTime := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	os.MkdirAll(cacheDir, 0755)
+	writeFile(older, "backup", backupTime)
+	writeFile(newer, "backup", backupTime)
+	writeFile(missing, "backup", backupTime)
+	writeFile(child, "backup", backupTime)
+	os.Chtimes(cacheDir, backupTime, backupTime)
+
+	system, err := NewBackupSystemWithDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create backup system: %v", err)
+	}
+	session, err := system.BackupFiles([]string{older, newer, missing, cacheDir}, "restore_test")
+	if err != nil || session.FailureCount != 0 {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	// reset recreates the state the restores run against
+	reset := func() {
+		for _, path := range []string{older, newer, missing, cacheDir} {
+			os.RemoveAll(path)
+		}
+		for _, path := range []string{olderRenamed, newerRenamed, cacheDir + ".restored"} {
+			os.RemoveAll(path)
+		}
+		writeFile(older, "local", backupTime.Add(-time.Hour))
+		writeFile(newer, "local", backupTime.Add(time.Hour))
+		writeFile(cacheDir, "local", backupTime.Add(time.Hour))
+	}
+
+	cases := []struct {
+		strategy ConflictStrategy
+		want     map[string]string
+		skipped  int
+	}{
+		{ConflictSkip, map[string]string{older: "local", newer: "local", missing: "backup", cacheDir: "local", child: "<missing>"}, 4},
+		{ConflictOverwrite, map[string]string{older: "backup", newer: "backup", missing: "backup", child: "backup"}, 0},
+		{ConflictKeepNewer, map[string]string{older: "backup", newer: "local", missing: "backup", cacheDir: "local"}, 3},
+		{ConflictRename, map[string]string{
+			older: "local", olderRenamed: "backup",
+			newer: "local", newerRenamed: "backup",
+			missing: "backup", cacheDir: "local",
+			filepath.Join(cacheDir+".restored", "entry.bin"): "backup",
+		}, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(string(tc.strategy), func(t *testing.T) {
+			reset()
+			options := RestoreOptions{Conflict: tc.strategy}
+
+			preview, err := system.PreviewRestore(session.SessionID, options)
+			if err != nil {
+				t.Fatalf("Preview failed: %v", err)
+			}
+
+			result, err := system.Restore(session.SessionID, options)
+			if err != nil || result.FailureCount != 0 {
+				t.Fatalf("Restore failed: %v (%+v)", err, result)
+			}
+			if result.SkippedCount != tc.skipped || preview.SkippedCount != tc.skipped {
+				t.Errorf("Skipped %d (preview %d), want %d", result.SkippedCount, preview.SkippedCount, tc.skipped)
+			}
+			for i, action := range preview.Actions {
+				if action.Action != result.Actions[i].Action || action.TargetPath != result.Actions[i].TargetPath {
+					t.Errorf("Preview %+v does not match restore %+v", action, result.Actions[i])
+				}
+			}
+			for path, content := range tc.want {
+				if got := readFile(path); got != content {
+					t.Errorf("%s: got %q, want %q", filepath.Base(path), got, content)
+				}
+			}
+		})
+	}
+
+	t.Run(string(ConflictFailFast), func(t *testing.T) {
+		reset()
+		os.Remove(missing)
+
+		result, err := system.Restore(session.SessionID, RestoreOptions{Conflict: ConflictFailFast})
+		if err == nil || result.Status != "conflict" {
+			t.Fatalf("Expected restore to abort on conflict, got %v (%+v)", err, result)
+		}
+		if _, err := os.Stat(missing); !os.IsNotExist(err) {
+			t.Error("Fail-fast restore must not write any file")
+		}
+	})
+
+	t.Run("AlternateRoot", func(t *testing.T) {
+		reset()
+		targetRoot := t.TempDir()
+
+		result, err := system.Restore(session.SessionID, RestoreOptions{
+			Paths:      []string{older, cacheDir},
+			Conflict:   ConflictFailFast,
+			TargetRoot: targetRoot,
+		})
+		if err != nil || result.SuccessCount != 3 {
+			t.Fatalf("Restore failed: %v (%+v)", err, result)
+		}
+		if got := readFile(restoreTarget(child, targetRoot)); got != "backup" {
+			t.Errorf("Restored child: got %q", got)
+		}
+		if got := readFile(older); got != "local" {
+			t.Error("Original location must not be touched")
+		}
+		if !filepath.IsAbs(result.RestoredFiles[0]) || filepath.Dir(restoreTarget(older, targetRoot)) == filepath.Dir(older) {
+			t.Errorf("Unexpected target path %s", result.RestoredFiles[0])
+		}
+	})
+
+	if _, err := system.Restore(session.SessionID, RestoreOptions{Conflict: "merge"}); err == nil {
+		t.Error("Unknown strategy should be rejected")
+	}
+}
@@ -0,0 +1,291 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// RetentionPolicy decides which backup sessions are kept. Sessions are pruned
+// when they are older than MaxAge, or - if any grandfather-father-son rule is
+// set - when no keep rule selects them. MaxTotalSize and MaxSessions are hard
+// limits enforced by pruning the oldest sessions. The newest KeepLast sessions
+// and the most recent session overall are never pruned.
+type RetentionPolicy struct {
+	MaxAge       time.Duration `json:"max_age"`        // 0 keeps sessions regardless of age
+	MaxTotalSize int64         `json:"max_total_size"` // Bytes stored on disk, 0 for no limit
+	MaxSessions  int           `json:"max_sessions"`   // 0 for no limit
+	KeepLast     int           `json:"keep_last"`
+	KeepDaily    int           `json:"keep_daily"`
+	KeepWeekly   int           `json:"keep_weekly"`
+	KeepMonthly  int           `json:"keep_monthly"`
+	KeepYearly   int           `json:"keep_yearly"`
+	// AutoApply prunes sessions automatically after every backup
+	AutoApply bool `json:"auto_apply"`
+}
+
+// RetentionDecision explains what the policy decided for a single session
+type RetentionDecision struct {
+	SessionID string    `json:"session_id"`
+	StartTime time.Time `json:"start_time"`
+	Size      int64     `json:"size"`
+	Keep      bool      `json:"keep"`
+	Reasons   []string  `json:"reasons"`
+}
+
+// RetentionPlan is the result of evaluating a retention policy
+type RetentionPlan struct {
+	Policy        RetentionPolicy     `json:"policy"`
+	EvaluatedAt   time.Time           `json:"evaluated_at"`
+	Decisions     []RetentionDecision `json:"decisions"` // Newest session first
+	PruneSessions []string            `json:"prune_sessions"`
+	KeptCount     int                 `json:"kept_count"`
+	KeptSize      int64               `json:"kept_size"`
+	PrunedCount   int                 `json:"pruned_count"`
+	PrunedSize    int64               `json:"pruned_size"`
+	Applied       bool                `json:"applied"`
+	Warnings      []string            `json:"warnings,omitempty"`
+}
+
+// hasGFSRules reports whether any grandfather-father-son rule is set
+func (p RetentionPolicy) hasGFSRules() bool {
+	return p.KeepDaily > 0 || p.KeepWeekly > 0 || p.KeepMonthly > 0 || p.KeepYearly > 0
+}
+
+// Evaluate decides which sessions to keep without changing anything
+func (p RetentionPolicy) Evaluate(sessions []BackupSession, now time.Time) *RetentionPlan {
+	plan := &RetentionPlan{Policy: p, EvaluatedAt: now}
+
+	sorted := append([]BackupSession(nil), sessions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].StartTime.After(sorted[j].StartTime)
+	})
+
+	decisions := make([]RetentionDecision, len(sorted))
+	pinned := make([]bool, len(sorted))    // Never pruned
+	protected := make([]bool, len(sorted)) // Kept unless a hard limit requires otherwise
+	for i, session := range sorted {
+		decisions[i] = RetentionDecision{
+			SessionID: session.SessionID,
+			StartTime: session.StartTime,
+			Size:      sessionStoredSize(session),
+			Keep:      true,
+		}
+	}
+
+	keepLast := max(p.KeepLast, 1)
+	for i := 0; i < len(sorted) && i < keepLast; i++ {
+		pinned[i] = true
+		if i == 0 {
+			decisions[i].Reasons = append(decisions[i].Reasons, "most recent backup")
+		} else {
+			decisions[i].Reasons = append(decisions[i].Reasons, fmt.Sprintf("one of the last %d backups", keepLast))
+		}
+	}
+
+	// Grandfather-father-son: keep the newest session of each of the latest N periods
+	gfsRules := []struct {
+		name   string
+		count  int
+		bucket func(time.Time) string
+	}{
+		{"daily", p.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{"weekly", p.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{"monthly", p.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }},
+		{"yearly", p.KeepYearly, func(t time.Time) string { return t.Format("2006") }},
+	}
+	for _, rule := range gfsRules {
+		if rule.count <= 0 {
+			continue
+		}
+		kept, lastBucket := 0, ""
+		for i, session := range sorted {
+			if kept >= rule.count {
+				break
+			}
+			bucket := rule.bucket(session.StartTime.Local())
+			if bucket == lastBucket {
+				continue
+			}
+			lastBucket = bucket
+			kept++
+			protected[i] = true
+			decisions[i].Reasons = append(decisions[i].Reasons, fmt.Sprintf("%s backup for %s", rule.name, bucket))
+		}
+	}
+
+	prune := func(i int, reason string) {
+		decisions[i].Keep = false
+		decisions[i].Reasons = append(decisions[i].Reasons, reason)
+	}
+
+	for i, session := range sorted {
+		if pinned[i] || protected[i] {
+			continue
+		}
+		switch {
+		case p.MaxAge > 0 && now.Sub(session.StartTime) > p.MaxAge:
+			prune(i, fmt.Sprintf("older than %s", formatRetentionAge(p.MaxAge)))
+		case p.hasGFSRules():
+			prune(i, "not selected by any keep rule")
+		}
+	}
+
+	// Hard limits: prune the oldest remaining sessions, unprotected ones first
+	keptCount, keptSize := 0, int64(0)
+	for _, decision := range decisions {
+		if decision.Keep {
+			keptCount++
+			keptSize += decision.Size
+		}
+	}
+	overLimit := func() bool {
+		return (p.MaxSessions > 0 && keptCount > p.MaxSessions) || (p.MaxTotalSize > 0 && keptSize > p.MaxTotalSize)
+	}
+	for _, allowProtected := range []bool{false, true} {
+		for i := len(sorted) - 1; i >= 0 && overLimit(); i-- {
+			if !decisions[i].Keep || pinned[i] || (protected[i] && !allowProtected) {
+				continue
+			}
+			if p.MaxSessions > 0 && keptCount > p.MaxSessions {
+				prune(i, fmt.Sprintf("exceeds maximum of %d sessions", p.MaxSessions))
+			} else {
+				prune(i, fmt.Sprintf("exceeds maximum backup size of %d bytes", p.MaxTotalSize))
+			}
+			keptCount--
+			keptSize -= decisions[i].Size
+		}
+	}
+	if overLimit() {
+		plan.Warnings = append(plan.Warnings, "retention limits cannot be met without pruning the most recent backups")
+	}
+
+	for _, decision := range decisions {
+		if decision.Keep {
+			plan.KeptCount++
+			plan.KeptSize += decision.Size
+		} else {
+			plan.PrunedCount++
+			plan.PrunedSize += decision.Size
+			plan.PruneSessions = append(plan.PruneSessions, decision.SessionID)
+		}
+	}
+	plan.Decisions = decisions
+
+	return plan
+}
+
+// SetRetentionPolicy sets the policy used by ApplyRetention and, if AutoApply is set, after every backup
+func (bm *BackupManager) SetRetentionPolicy(policy *RetentionPolicy) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	bm.retention = policy
+}
+
+// GetRetentionPolicy returns the configured retention policy, or nil if none is set
+func (bm *BackupManager) GetRetentionPolicy() *RetentionPolicy {
+	bm.mu.RLock()
+	defer bm.mu.RUnlock()
+	return bm.retention
+}
+
+// PreviewRetention reports which sessions the configured policy would prune
+func (bm *BackupManager) PreviewRetention() (*RetentionPlan, error) {
+	policy := bm.GetRetentionPolicy()
+	if policy == nil {
+		return nil, fmt.Errorf("no retention policy configured")
+	}
+
+	manifest, err := bm.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+	return policy.Evaluate(manifest.Sessions, time.Now()), nil
+}
+
+// ApplyRetention prunes the sessions selected by the configured policy
+func (bm *BackupManager) ApplyRetention() (*RetentionPlan, error) {
+	plan, err := bm.PreviewRetention()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(plan.PruneSessions) > 0 {
+		if err := bm.deleteSessions(plan.PruneSessions); err != nil {
+			return plan, fmt.Errorf("failed to prune backup sessions: %w", err)
+		}
+	}
+	plan.Applied = true
+	return plan, nil
+}
+
+// deleteSessions removes sessions and their blobs from the store and the remote backend
+func (bm *BackupManager) deleteSessions(sessionIDs []string) error {
+	manifest, err := bm.loadManifest()
+	if err != nil {
+		return err
+	}
+
+	remove := make(map[string]bool, len(sessionIDs))
+	for _, id := range sessionIDs {
+		remove[id] = true
+	}
+
+	var remainingSessions []BackupSession
+	var sessionsToDelete []BackupSession
+	for _, session := range manifest.Sessions {
+		if remove[session.SessionID] {
+			sessionsToDelete = append(sessionsToDelete, session)
+		} else {
+			remainingSessions = append(remainingSessions, session)
+		}
+	}
+
+	// Delete backup files for removed sessions
+	for _, session := range sessionsToDelete {
+		sessionDir := filepath.Join(bm.backupDir, "files", session.SessionID)
+		if err := os.RemoveAll(sessionDir); err != nil {
+			return fmt.Errorf("failed to remove session directory %s: %w", sessionDir, err)
+		}
+	}
+
+	// Update manifest
+	manifest.Sessions = remainingSessions
+	manifest.LastUpdated = time.Now()
+	manifest.TotalSessions = len(remainingSessions)
+
+	// Recalculate totals
+	manifest.TotalFiles = 0
+	manifest.TotalSize = 0
+	for _, session := range remainingSessions {
+		manifest.TotalFiles += session.TotalFiles
+		manifest.TotalSize += session.TotalSize
+	}
+
+	if err := bm.SaveManifest(manifest); err != nil {
+		return err
+	}
+
+	return bm.removeRemoteSessions(sessionsToDelete)
+}
+
+// sessionStoredSize returns the bytes a session occupies in the store
+func sessionStoredSize(session BackupSession) int64 {
+	if session.StoredSize > 0 {
+		return session.StoredSize
+	}
+	return session.BackupSize
+}
+
+// formatRetentionAge formats an age in days where possible
+func formatRetentionAge(age time.Duration) string {
+	if age%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%d days", int(age/(24*time.Hour)))
+	}
+	return age.String()
+}
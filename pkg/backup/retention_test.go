@@ -0,0 +1,133 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// dailySessions returns one session per day going back from now, newest first
+func dailySessions(now time.Time, days int, size int64) []BackupSession {
+	sessions := make([]BackupSession, days)
+	for i := range sessions {
+		sessions[i] = BackupSession{
+			SessionID:  fmt.Sprintf("day_%02d", i),
+			StartTime:  now.AddDate(0, 0, -i),
+			StoredSize: size,
+		}
+	}
+	return sessions
+}
+
+func TestRetentionPolicyEvaluate(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.Local)
+	sessions := dailySessions(now, 60, 100)
+
+	cases := []struct {
+		name   string
+		policy RetentionPolicy
+		kept   []string
+	}{
+		{
+			name:   "MaxAge",
+			policy: RetentionPolicy{MaxAge: 3 * 24 * time.Hour},
+			kept:   []string{"day_00", "day_01", "day_02", "day_03"},
+		},
+		{
+			name:   "KeepLastOverridesAge",
+			policy: RetentionPolicy{MaxAge: 24 * time.Hour, KeepLast: 3},
+			kept:   []string{"day_00", "day_01", "day_02"},
+		},
+		{
+			name:   "MaxSessions",
+			policy: RetentionPolicy{MaxSessions: 2},
+			kept:   []string{"day_00", "day_01"},
+		},
+		{
+			name:   "MaxTotalSize",
+			policy: RetentionPolicy{MaxTotalSize: 250},
+			kept:   []string{"day_00", "day_01"},
+		},
+		{
+			// 2024-03-31 is a Sunday: weekly picks the newest session of each ISO week
+			name:   "GrandfatherFatherSon",
+			policy: RetentionPolicy{KeepDaily: 2, KeepWeekly: 3, KeepMonthly: 3},
+			kept:   []string{"day_00", "day_01", "day_07", "day_14", "day_31"},
+		},
+		{
+			name:   "NewestAlwaysKept",
+			policy: RetentionPolicy{MaxTotalSize: 10},
+			kept:   []string{"day_00"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			plan := tc.policy.Evaluate(sessions, now)
+
+			var kept []string
+			for _, decision := range plan.Decisions {
+				if decision.Keep {
+					kept = append(kept, decision.SessionID)
+				}
+				if !decision.Keep && len(decision.Reasons) == 0 {
+					t.Errorf("Pruned session %s has no reason", decision.SessionID)
+				}
+			}
+			sort.Strings(kept)
+			if !reflect.DeepEqual(kept, tc.kept) {
+				t.Errorf("Kept %v, want %v", kept, tc.kept)
+			}
+			if plan.PrunedCount+plan.KeptCount != len(sessions) || len(plan.PruneSessions) != plan.PrunedCount {
+				t.Errorf("Inconsistent plan counts: %+v", plan)
+			}
+		})
+	}
+}
+
+func TestAutomaticRetention(t *testing.T) {
+	testDir := t.TempDir()
+	manager, err := NewBackupManagerWithDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create backup manager: %v", err)
+	}
+	manager.SetRetentionPolicy(&RetentionPolicy{MaxSessions: 2, AutoApply: true})
+
+	var sessions []*BackupSession
+	for i := 0; i < 3; i++ {
+		file := filepath.Join(testDir, fmt.Sprintf("cache_%d.tmp", i))
+		if err := os.WriteFile(file, []byte("cached"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		session, err := manager.BackupFiles([]string{file}, "retention_test")
+		if err != nil {
+			t.Fatalf("Backup failed: %v", err)
+		}
+		sessions = append(sessions, session)
+
+		// Session IDs have second resolution; make start times distinct
+		time.Sleep(1100 * time.Millisecond)
+	}
+
+	last := sessions[len(sessions)-1]
+	if last.RetentionError != "" || !reflect.DeepEqual(last.PrunedSessions, []string{sessions[0].SessionID}) {
+		t.Fatalf("Expected oldest session to be pruned, got %v (%s)", last.PrunedSessions, last.RetentionError)
+	}
+
+	remaining, err := manager.ListSessions()
+	if err != nil || len(remaining) != 2 {
+		t.Fatalf("Expected 2 remaining sessions, got %d (%v)", len(remaining), err)
+	}
+	if _, err := os.Stat(filepath.Join(manager.GetBackupDir(), "files", sessions[0].SessionID)); !os.IsNotExist(err) {
+		t.Error("Pruned session files should be removed")
+	}
+
+	plan, err := manager.PreviewRetention()
+	if err != nil || plan.PrunedCount != 0 || plan.Applied {
+		t.Errorf("Expected empty preview after pruning, got %+v (%v)", plan, err)
+	}
+}
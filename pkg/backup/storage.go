@@ -0,0 +1,250 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cache_app/internal/ui"
+)
+
+// partialUploadSuffix marks incomplete uploads in file backends
+const partialUploadSuffix = ".ccpartial-"
+
+// ErrObjectNotFound is returned when a key does not exist in a storage backend
+var ErrObjectNotFound = errors.New("object not found")
+
+// StorageBackend stores backup blobs and manifests under slash-separated keys
+type StorageBackend interface {
+	// Name identifies the backend and its location, e.g. "s3://bucket/prefix"
+	Name() string
+	// Upload stores size bytes from src under key, resuming an interrupted
+	// upload of the same source fingerprint where the backend supports it
+	Upload(key string, src UploadSource) error
+	// Open returns a reader for the object stored under key
+	Open(key string) (io.ReadCloser, error)
+	// Stat returns information about the object stored under key
+	Stat(key string) (*ObjectInfo, error)
+	// List returns all objects whose key starts with prefix
+	List(prefix string) ([]ObjectInfo, error)
+	// Delete removes the object stored under key
+	Delete(key string) error
+}
+
+// UploadSource describes the content of an upload
+type UploadSource struct {
+	Reader io.ReaderAt
+	Size   int64
+	// Fingerprint identifies the source version so partial uploads of stale content are discarded
+	Fingerprint string
+}
+
+// ObjectInfo describes an object in a storage backend
+type ObjectInfo struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// permanentStorageError marks an error as not worth retrying
+func permanentStorageError(err error) error {
+	return ui.NewErrorBuilder().
+		Type(ui.ErrorTypeBackup).
+		Severity(ui.ErrorSeverityMedium).
+		Code("STORAGE_ERROR").
+		Message(err.Error()).
+		OriginalError(err).
+		UserMessage("The backup storage rejected the request").
+		Retryable(false).
+		Build()
+}
+
+// FileBackend stores objects as files below a root directory
+type FileBackend struct {
+	root    string
+	network bool
+}
+
+// NewLocalBackend creates a backend storing objects in a local directory
+func NewLocalBackend(root string) (*FileBackend, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory %s: %w", root, err)
+	}
+	return &FileBackend{root: root}, nil
+}
+
+// NewNetworkPathBackend creates a backend on a mounted network share. The
+// root must already exist so an unmounted share is not silently replaced by
+// a local directory; writes are synced before they are considered complete.
+func NewNetworkPathBackend(root string) (*FileBackend, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("network path %s is not available: %w", root, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("network path %s is not a directory", root)
+	}
+	return &FileBackend{root: root, network: true}, nil
+}
+
+// Name returns the location of the backend
+func (fb *FileBackend) Name() string {
+	return "file://" + filepath.ToSlash(fb.root)
+}
+
+// objectPath maps a key to a path below the root, rejecting keys that escape it
+func (fb *FileBackend) objectPath(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" || clean != "/"+key {
+		return "", permanentStorageError(fmt.Errorf("invalid storage key %q", key))
+	}
+	return filepath.Join(fb.root, filepath.FromSlash(clean[1:])), nil
+}
+
+// Upload writes the object to a partial file, resuming from its current
+// length when the fingerprint matches, and renames it into place when complete
+func (fb *FileBackend) Upload(key string, src UploadSource) error {
+	target, err := fb.objectPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", key, err)
+	}
+
+	partial := target + partialUploadSuffix + fingerprintSuffix(src.Fingerprint)
+
+	file, err := os.OpenFile(partial, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open partial upload %s: %w", partial, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat partial upload %s: %w", partial, err)
+	}
+	offset := info.Size()
+	if offset > src.Size {
+		offset = 0
+	}
+	if err := file.Truncate(offset); err != nil {
+		return fmt.Errorf("failed to truncate partial upload %s: %w", partial, err)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek partial upload %s: %w", partial, err)
+	}
+
+	if _, err := io.Copy(file, io.NewSectionReader(src.Reader, offset, src.Size-offset)); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	if fb.network {
+		if err := file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync %s: %w", key, err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to finish %s: %w", key, err)
+	}
+
+	if err := os.Rename(partial, target); err != nil {
+		return fmt.Errorf("failed to move %s into place: %w", key, err)
+	}
+
+	// Partial uploads of older source versions are no longer resumable
+	if stale, _ := filepath.Glob(target + partialUploadSuffix + "*"); len(stale) > 0 {
+		for _, p := range stale {
+			os.Remove(p)
+		}
+	}
+	return nil
+}
+
+// Open returns a reader for the object stored under key
+func (fb *FileBackend) Open(key string) (io.ReadCloser, error) {
+	target, err := fb.objectPath(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(target)
+	if os.IsNotExist(err) {
+		return nil, permanentStorageError(fmt.Errorf("%w: %s", ErrObjectNotFound, key))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", key, err)
+	}
+	return file, nil
+}
+
+// Stat returns information about the object stored under key
+func (fb *FileBackend) Stat(key string) (*ObjectInfo, error) {
+	target, err := fb.objectPath(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(target)
+	if os.IsNotExist(err) {
+		return nil, permanentStorageError(fmt.Errorf("%w: %s", ErrObjectNotFound, key))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", key, err)
+	}
+	return &ObjectInfo{Key: key, Size: info.Size(), LastModified: info.ModTime()}, nil
+}
+
+// List returns all complete objects whose key starts with prefix
+func (fb *FileBackend) List(prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := filepath.Walk(fb.root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || strings.Contains(info.Name(), partialUploadSuffix) {
+			return nil
+		}
+		rel, err := filepath.Rel(fb.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, ObjectInfo{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// Delete removes the object stored under key
+func (fb *FileBackend) Delete(key string) error {
+	target, err := fb.objectPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// fingerprintSuffix shortens a fingerprint into a file name safe suffix
+func fingerprintSuffix(fingerprint string) string {
+	sum := sha256.Sum256([]byte(fingerprint))
+	return hex.EncodeToString(sum[:8])
+}
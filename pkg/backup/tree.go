@@ -0,0 +1,211 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EntryType describes what kind of filesystem object a backup entry holds
+type EntryType string
+
+const (
+	EntryTypeFile    EntryType = "file"
+	EntryTypeDir     EntryType = "dir"
+	EntryTypeSymlink EntryType = "symlink"
+)
+
+// Kind returns the entry type, treating entries from older manifests as files
+func (e BackupEntry) Kind() EntryType {
+	if e.Type == "" {
+		return EntryTypeFile
+	}
+	return e.Type
+}
+
+// hasBlob reports whether the entry has stored file content
+func (e BackupEntry) hasBlob() bool {
+	return e.Success && e.Kind() == EntryTypeFile
+}
+
+// fileSize returns the content size of file entries and zero for directories and symlinks
+func (e BackupEntry) fileSize() int64 {
+	if e.Kind() != EntryTypeFile {
+		return 0
+	}
+	return e.Size
+}
+
+// backupPath backs up a file, symlink or directory tree. Directories produce
+// an entry for the directory itself followed by one entry per descendant, with
+// blobs stored below the session directory in the same relative layout. The
+// directory entry only succeeds when every descendant was backed up.
+func (bm *BackupManager) backupPath(originalPath, sessionDir, operation string, linkOriginals bool) []BackupEntry {
+	info, err := os.Lstat(originalPath)
+	if err != nil {
+		return []BackupEntry{bm.failedEntry(originalPath, operation, fmt.Sprintf("failed to get file info: %v", err))}
+	}
+
+	target := uniqueBackupPath(filepath.Join(sessionDir, filepath.Base(originalPath)))
+	root := bm.backupSingleFile(originalPath, target, operation, info, "", linkOriginals)
+	if !info.IsDir() {
+		return []BackupEntry{root}
+	}
+
+	entries := []BackupEntry{root}
+	failed := 0
+	var treeSize int64
+
+	walkErr := filepath.Walk(originalPath, func(path string, info os.FileInfo, err error) error {
+		if path == originalPath {
+			return err
+		}
+		if err != nil {
+			entries = append(entries, bm.failedEntry(path, operation, fmt.Sprintf("failed to read: %v", err)))
+			failed++
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(originalPath, path)
+		if err != nil {
+			return err
+		}
+
+		backupPath := filepath.Join(target, rel)
+		if !info.IsDir() {
+			backupPath = uniqueBackupPath(backupPath)
+		}
+		entry := bm.backupSingleFile(path, backupPath, operation, info, originalPath, linkOriginals)
+		entries = append(entries, entry)
+		if !entry.Success {
+			failed++
+		} else if entry.Kind() == EntryTypeFile {
+			treeSize += entry.Size
+		}
+		return nil
+	})
+
+	entries[0].Size = treeSize
+	switch {
+	case walkErr != nil:
+		entries[0].Success = false
+		entries[0].Error = fmt.Sprintf("failed to walk directory: %v", walkErr)
+	case failed > 0:
+		entries[0].Success = false
+		entries[0].Error = fmt.Sprintf("%d entries in directory could not be backed up", failed)
+	}
+
+	return entries
+}
+
+// failedEntry creates an entry recording a backup failure
+func (bm *BackupManager) failedEntry(originalPath, operation, message string) BackupEntry {
+	return BackupEntry{
+		OriginalPath: originalPath,
+		BackupTime:   time.Now(),
+		Operation:    operation,
+		Error:        message,
+	}
+}
+
+// uniqueBackupPath appends a numeric suffix until path does not collide with an existing blob
+func uniqueBackupPath(path string) string {
+	dir, fileName := filepath.Split(path)
+	candidate := path
+	for counter := 1; backupPathTaken(candidate); counter++ {
+		ext := filepath.Ext(fileName)
+		name := fileName[:len(fileName)-len(ext)]
+		candidate = filepath.Join(dir, fmt.Sprintf("%s_%d%s", name, counter, ext))
+	}
+	return candidate
+}
+
+// selectEntries returns the entries for the requested paths, including every
+// descendant of requested directories, and the paths that have no entry
+func selectEntries(session *BackupSession, paths []string) ([]BackupEntry, []string) {
+	var selected []BackupEntry
+	var missing []string
+	seen := make(map[int]bool)
+
+	for _, requested := range paths {
+		found := false
+		prefix := strings.TrimSuffix(requested, string(filepath.Separator)) + string(filepath.Separator)
+		for i, entry := range session.Entries {
+			if entry.OriginalPath != requested && !strings.HasPrefix(entry.OriginalPath, prefix) {
+				continue
+			}
+			found = true
+			if !seen[i] {
+				seen[i] = true
+				selected = append(selected, entry)
+			}
+		}
+		if !found {
+			missing = append(missing, requested)
+		}
+	}
+
+	return selected, missing
+}
+
+// restoreDirectory recreates a directory entry. Existing directories are merged into.
+func restoreDirectory(entry BackupEntry, overwrite bool) error {
+	if info, err := os.Lstat(entry.OriginalPath); err == nil {
+		if info.IsDir() {
+			return nil
+		}
+		if !overwrite {
+			return fmt.Errorf("file already exists and overwrite is disabled: %s", entry.OriginalPath)
+		}
+		if err := os.Remove(entry.OriginalPath); err != nil {
+			return fmt.Errorf("failed to replace %s: %w", entry.OriginalPath, err)
+		}
+	}
+
+	// Owner write access is needed to restore children; the recorded mode is applied afterwards
+	if err := os.MkdirAll(entry.OriginalPath, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return nil
+}
+
+// restoreSymlink recreates a symbolic link entry
+func restoreSymlink(entry BackupEntry, overwrite bool) error {
+	if _, err := os.Lstat(entry.OriginalPath); err == nil {
+		if !overwrite {
+			return fmt.Errorf("file already exists and overwrite is disabled: %s", entry.OriginalPath)
+		}
+		if err := os.Remove(entry.OriginalPath); err != nil {
+			return fmt.Errorf("failed to replace %s: %w", entry.OriginalPath, err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(entry.OriginalPath), 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+	if err := os.Symlink(entry.LinkTarget, entry.OriginalPath); err != nil {
+		return fmt.Errorf("failed to create symlink: %w", err)
+	}
+	return nil
+}
+
+// applyDirectoryAttributes reapplies the recorded attributes of restored
+// directories, deepest first so that restoring a child does not change the
+// timestamps of an already processed parent and read-only parents do not
+// block their children
+func applyDirectoryAttributes(dirs []BackupEntry) []MetadataIssue {
+	sort.Slice(dirs, func(i, j int) bool {
+		return len(dirs[i].OriginalPath) > len(dirs[j].OriginalPath)
+	})
+	var issues []MetadataIssue
+	for _, dir := range dirs {
+		issues = append(issues, applyAttributes(dir)...)
+	}
+	return issues
+}
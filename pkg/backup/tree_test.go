@@ -0,0 +1,146 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// backupAndRemove backs up paths for deletion and removes the originals the way
+// the deletion engine does
+func backupAndRemove(t *testing.T, system *BackupSystem, paths []string, operation string) *BackupSession {
+	t.Helper()
+	session, err := system.BackupFilesForDeletion(paths, operation)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	for _, path := range paths {
+		if err := os.RemoveAll(path); err != nil {
+			t.Fatalf("Failed to remove %s: %v", path, err)
+		}
+	}
+	return session
+}
+
+// snapshotTree describes every object below root as "type mode [content|target]"
+func snapshotTree(t *testing.T, root string) map[string]string {
+	t.Helper()
+	snapshot := make(map[string]string)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		desc := info.Mode().String()
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, _ := os.Readlink(path)
+			desc += " -> " + target
+		case info.Mode().IsRegular():
+			data, _ := os.ReadFile(path)
+			desc += " " + string(data)
+		}
+		snapshot[rel] = desc
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to snapshot %s: %v", root, err)
+	}
+	return snapshot
+}
+
+func TestDirectoryBackupPreservesTree(t *testing.T) {
+	testDir := t.TempDir()
+	cacheDir := filepath.Join(testDir, "app", "Cache")
+
+	mustMkdir := func(path string, mode os.FileMode) {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", path, err)
+		}
+		os.Chmod(path, mode)
+	}
+	mustWrite := func(path, content string, mode os.FileMode) {
+		if err := os.WriteFile(path, []byte(content), mode); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+		os.Chmod(path, mode)
+	}
+
+	mustMkdir(filepath.Join(cacheDir, "nested", "deeper"), 0755)
+	mustMkdir(filepath.Join(cacheDir, "empty"), 0700)
+	mustWrite(filepath.Join(cacheDir, "index"), "index data", 0644)
+	mustWrite(filepath.Join(cacheDir, "nested", "index"), "same name, different dir", 0600)
+	mustWrite(filepath.Join(cacheDir, "nested", "deeper", "run.sh"), "#!/bin/sh\n", 0755)
+	if err := os.Symlink("nested/index", filepath.Join(cacheDir, "latest")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	os.Chmod(filepath.Join(cacheDir, "nested"), 0750)
+
+	// A second root with the same base name must not collide in the store
+	otherCache := filepath.Join(testDir, "other", "Cache")
+	mustMkdir(otherCache, 0755)
+	mustWrite(filepath.Join(otherCache, "index"), "other index", 0644)
+
+	before := snapshotTree(t, cacheDir)
+	otherBefore := snapshotTree(t, otherCache)
+
+	system, err := NewBackupSystemWithDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create backup system: %v", err)
+	}
+
+	deleted := backupAndRemove(t, system, []string{cacheDir, otherCache}, "tree_test")
+	if _, err := os.Stat(cacheDir); !os.IsNotExist(err) {
+		t.Fatal("Cache directory should have been deleted")
+	}
+
+	session, err := system.GetSession(deleted.SessionID)
+	if err != nil {
+		t.Fatalf("Failed to load session: %v", err)
+	}
+	if session.FailureCount != 0 || session.TotalFiles != len(session.Entries) {
+		t.Errorf("Unexpected session counts: %+v", session)
+	}
+	if session.BackupSize != int64(len("index data")+len("same name, different dir")+len("#!/bin/sh\n")+len("other index")) {
+		t.Errorf("Unexpected backup size %d", session.BackupSize)
+	}
+	for _, entry := range session.Entries {
+		if entry.hasBlob() && !strings.Contains(filepath.ToSlash(entry.BackupPath), "/Cache") {
+			t.Errorf("Blob %s does not preserve the directory layout", entry.BackupPath)
+		}
+	}
+
+	t.Run("RestoreFiles", func(t *testing.T) {
+		result, err := system.RestoreFiles(session.SessionID, []string{cacheDir}, false)
+		if err != nil || result.FailureCount != 0 {
+			t.Fatalf("Restore failed: %v (%+v)", err, result)
+		}
+
+		after := snapshotTree(t, cacheDir)
+		if len(after) != len(before) {
+			t.Fatalf("Restored tree has %d entries, want %d: %v", len(after), len(before), after)
+		}
+		for path, desc := range before {
+			if after[path] != desc {
+				t.Errorf("%s: restored %q, want %q", path, after[path], desc)
+			}
+		}
+		if _, err := os.Stat(otherCache); !os.IsNotExist(err) {
+			t.Error("Only the requested directory should be restored")
+		}
+	})
+
+	t.Run("RestoreSession", func(t *testing.T) {
+		result, err := system.RestoreSession(session.SessionID, true)
+		if err != nil || result.FailureCount != 0 {
+			t.Fatalf("Restore failed: %v (%+v)", err, result)
+		}
+		after := snapshotTree(t, otherCache)
+		for path, desc := range otherBefore {
+			if after[path] != desc {
+				t.Errorf("%s: restored %q, want %q", path, after[path], desc)
+			}
+		}
+	})
+}
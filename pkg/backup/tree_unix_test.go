@@ -0,0 +1,36 @@
+//go:build linux || darwin
+
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestDirectoryBackupWithUnsupportedEntry(t *testing.T) {
+	cacheDir := filepath.Join(t.TempDir(), "Cache")
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	os.WriteFile(filepath.Join(cacheDir, "data"), []byte("data"), 0644)
+	if err := syscall.Mkfifo(filepath.Join(cacheDir, "pipe"), 0644); err != nil {
+		t.Skipf("FIFOs not supported: %v", err)
+	}
+
+	system, err := NewBackupSystemWithDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create backup system: %v", err)
+	}
+
+	// A directory that cannot be backed up completely must not count as backed up,
+	// which keeps the deletion engine from removing it
+	session, err := system.BackupFilesForDeletion([]string{cacheDir}, "tree_test")
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if session.Entries[0].OriginalPath != cacheDir || session.Entries[0].Success {
+		t.Errorf("Directory with unsupported entries should not be backed up, got %+v", session.Entries[0])
+	}
+}
@@ -0,0 +1,141 @@
+package budget
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"cache_app/pkg/deletion"
+	"cache_app/pkg/recommend"
+	"cache_app/pkg/safety"
+)
+
+// Budget caps the size of a location
+type Budget struct {
+	Path     string `json:"path"`
+	MaxBytes int64  `json:"max_bytes"`
+}
+
+// Eviction is a file chosen to bring a location under its budget
+type Eviction struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	LastUsed time.Time `json:"last_used"` // Later of the access and modification times
+	Level    string    `json:"level"`
+}
+
+// Report describes how a location is brought under its budget
+type Report struct {
+	Path                string     `json:"path"`
+	BudgetBytes         int64      `json:"budget_bytes"`
+	SizeBefore          int64      `json:"size_before"`
+	SizeAfter           int64      `json:"size_after"` // Projected for dry runs
+	OverBudget          bool       `json:"over_budget"`
+	WithinBudget        bool       `json:"within_budget"` // SizeAfter fits the budget
+	Evicted             []Eviction `json:"evicted"`
+	EvictedBytes        int64      `json:"evicted_bytes"`
+	SkippedRisky        int        `json:"skipped_risky"`
+	SkippedRiskyBytes   int64      `json:"skipped_risky_bytes"`
+	SkippedUnclassified int        `json:"skipped_unclassified"`
+	DryRun              bool       `json:"dry_run"`
+	DeletedCount        int        `json:"deleted_count"`
+	FailedCount         int        `json:"failed_count"`
+	BackupSessionID     string     `json:"backup_session_id,omitempty"`
+	QuarantineBatchID   string     `json:"quarantine_batch_id,omitempty"`
+	Errors              []string   `json:"errors,omitempty"`
+}
+
+// Plan selects the least recently used files of a location until what remains
+// fits the budget. Risky and unclassified files are never evicted.
+func Plan(budget Budget, location recommend.ScannedLocation) *Report {
+	report := &Report{
+		Path:        budget.Path,
+		BudgetBytes: budget.MaxBytes,
+		DryRun:      true,
+		Evicted:     []Eviction{},
+	}
+
+	var candidates []Eviction
+	for _, file := range location.Files {
+		if file.IsDir {
+			continue
+		}
+		report.SizeBefore += file.Size
+		switch {
+		case file.Classification == nil:
+			report.SkippedUnclassified++
+		case file.Classification.Level == safety.Risky:
+			report.SkippedRisky++
+			report.SkippedRiskyBytes += file.Size
+		default:
+			candidates = append(candidates, Eviction{
+				Path:     file.Path,
+				Size:     file.Size,
+				LastUsed: lastUsed(file),
+				Level:    file.Classification.Level.String(),
+			})
+		}
+	}
+
+	report.SizeAfter = report.SizeBefore
+	report.OverBudget = report.SizeBefore > budget.MaxBytes
+	if report.OverBudget {
+		// Least recently used first; among equals, larger files free the budget sooner
+		sort.Slice(candidates, func(i, j int) bool {
+			if !candidates[i].LastUsed.Equal(candidates[j].LastUsed) {
+				return candidates[i].LastUsed.Before(candidates[j].LastUsed)
+			}
+			if candidates[i].Size != candidates[j].Size {
+				return candidates[i].Size > candidates[j].Size
+			}
+			return candidates[i].Path < candidates[j].Path
+		})
+		for _, candidate := range candidates {
+			if report.SizeAfter <= budget.MaxBytes {
+				break
+			}
+			report.Evicted = append(report.Evicted, candidate)
+			report.EvictedBytes += candidate.Size
+			report.SizeAfter -= candidate.Size
+		}
+	}
+	report.WithinBudget = report.SizeAfter <= budget.MaxBytes
+	return report
+}
+
+// Files returns the paths of the evicted files
+func (r *Report) Files() []string {
+	files := make([]string, 0, len(r.Evicted))
+	for _, eviction := range r.Evicted {
+		files = append(files, eviction.Path)
+	}
+	return files
+}
+
+// Record updates the report with the outcome of deleting the evicted files
+func (r *Report) Record(result *deletion.DeletionResult, err error) {
+	r.DryRun = false
+	if result != nil {
+		r.DeletedCount = result.DeletedCount
+		r.FailedCount = result.FailedCount
+		r.BackupSessionID = result.BackupSessionID
+		r.QuarantineBatchID = result.QuarantineBatchID
+		r.SizeAfter = r.SizeBefore - result.DeletedSize
+		r.WithinBudget = r.SizeAfter <= r.BudgetBytes
+		for _, path := range result.SkippedFiles {
+			r.Errors = append(r.Errors, fmt.Sprintf("skipped %s", path))
+		}
+	}
+	if err != nil {
+		r.Errors = append(r.Errors, err.Error())
+	}
+}
+
+// lastUsed returns when a file was last used. Access times are often not
+// updated on every read, so the later of access and modification counts.
+func lastUsed(file recommend.ScannedFile) time.Time {
+	if file.LastAccessed.After(file.LastModified) {
+		return file.LastAccessed
+	}
+	return file.LastModified
+}
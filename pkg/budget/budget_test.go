@@ -0,0 +1,70 @@
+package budget
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"cache_app/pkg/deletion"
+	"cache_app/pkg/recommend"
+	"cache_app/pkg/safety"
+)
+
+func TestPlan(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	file := func(path string, size int64, level safety.SafetyLevel, modifiedDays, accessedDays int) recommend.ScannedFile {
+		return recommend.ScannedFile{
+			Path:           path,
+			Size:           size,
+			LastModified:   now.AddDate(0, 0, -modifiedDays),
+			LastAccessed:   now.AddDate(0, 0, -accessedDays),
+			Classification: &safety.SafetyClassification{Level: level},
+		}
+	}
+	location := recommend.ScannedLocation{Path: "/cache/pip", Files: []recommend.ScannedFile{
+		{Path: "/cache/pip/wheels", IsDir: true},
+		file("/cache/pip/wheels/recent", 400, safety.Safe, 30, 1),
+		file("/cache/pip/wheels/stale", 300, safety.Safe, 60, 60),
+		file("/cache/pip/wheels/rewritten", 200, safety.Caution, 2, 90), // Modified after its last access
+		file("/cache/pip/selfcheck.json", 500, safety.Risky, 100, 100),
+		{Path: "/cache/pip/unreadable", Size: 50},
+	}}
+
+	t.Run("EvictsLeastRecentlyUsed", func(t *testing.T) {
+		report := Plan(Budget{Path: "~/.cache/pip", MaxBytes: 1000}, location)
+		if report.SizeBefore != 1450 || !report.OverBudget {
+			t.Fatalf("Expected 1450 bytes over budget, got %+v", report)
+		}
+		if len(report.Evicted) != 2 || report.Evicted[0].Path != "/cache/pip/wheels/stale" || report.Evicted[1].Path != "/cache/pip/wheels/rewritten" {
+			t.Fatalf("Expected stale then rewritten to be evicted, got %+v", report.Evicted)
+		}
+		if report.SizeAfter != 950 || !report.WithinBudget || report.EvictedBytes != 500 {
+			t.Errorf("Expected 950 bytes within budget, got %+v", report)
+		}
+		if report.SkippedRisky != 1 || report.SkippedRiskyBytes != 500 || report.SkippedUnclassified != 1 {
+			t.Errorf("Expected the risky and unclassified files to be skipped, got %+v", report)
+		}
+	})
+
+	t.Run("RiskyFilesKeepLocationOverBudget", func(t *testing.T) {
+		report := Plan(Budget{MaxBytes: 100}, location)
+		if len(report.Evicted) != 3 || report.WithinBudget || report.SizeAfter != 550 {
+			t.Errorf("Expected every non-risky file evicted and still over budget, got %+v", report)
+		}
+	})
+
+	t.Run("UnderBudget", func(t *testing.T) {
+		report := Plan(Budget{MaxBytes: 2000}, location)
+		if report.OverBudget || len(report.Evicted) != 0 || !report.WithinBudget {
+			t.Errorf("Expected nothing evicted, got %+v", report)
+		}
+	})
+
+	t.Run("Record", func(t *testing.T) {
+		report := Plan(Budget{MaxBytes: 1000}, location)
+		report.Record(&deletion.DeletionResult{DeletedCount: 1, FailedCount: 1, DeletedSize: 300}, errors.New("partial failure"))
+		if report.DryRun || report.SizeAfter != 1150 || report.WithinBudget || len(report.Errors) != 1 {
+			t.Errorf("Expected the partial deletion to be recorded, got %+v", report)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Category groups catalog locations the way cache_locations.json does
+type Category string
+
+const (
+	CategorySystem      Category = "system"
+	CategoryUser        Category = "user"
+	CategoryApplication Category = "application"
+	CategorySpecial     Category = "special"
+)
+
+// Guidance is the cleanup recommendation the catalog gives for a location
+type Guidance string
+
+const (
+	GuidanceSafeToClean Guidance = "safe_to_clean"
+	GuidanceReview      Guidance = "review_before_cleaning"
+	GuidanceDoNotClean  Guidance = "do_not_clean"
+	GuidanceUnknown     Guidance = "unknown"
+)
+
+// Location is a known cache location from the catalog
+type Location struct {
+	ID             string   `json:"id"`
+	Name           string   `json:"name"`
+	Path           string   `json:"path"`
+	Description    string   `json:"description"`
+	Permissions    string   `json:"permissions"`
+	SafetyLevel    string   `json:"safety_level"` // "safe", "moderate", "dangerous"
+	Recommendation string   `json:"recommendation"`
+	FilePatterns   []string `json:"file_patterns"`
+	SizeEstimate   string   `json:"size_estimate"`
+	CleanupRisk    string   `json:"cleanup_risk"` // "low", "medium", "high"
+	Application    string   `json:"application,omitempty"`
+	BundleID       string   `json:"bundle_id,omitempty"`
+	Category       Category `json:"category"`
+}
+
+// Catalog is the parsed location catalog
+type Catalog struct {
+	Locations []Location
+	guidance  map[string]Guidance
+}
+
+// catalogFile mirrors the layout of cache_locations.json
+type catalogFile struct {
+	SystemCaches           []Location `json:"system_caches"`
+	UserCaches             []Location `json:"user_caches"`
+	ApplicationCaches      []Location `json:"application_caches"`
+	SpecialLocations       []Location `json:"special_locations"`
+	CleanupRecommendations struct {
+		SafeToClean          []string `json:"safe_to_clean"`
+		ReviewBeforeCleaning []string `json:"review_before_cleaning"`
+		DoNotClean           []string `json:"do_not_clean"`
+	} `json:"cleanup_recommendations"`
+}
+
+// Load reads a catalog file such as cache_locations.json
+func Load(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read location catalog: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses catalog JSON
+func Parse(data []byte) (*Catalog, error) {
+	var file catalogFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse location catalog: %w", err)
+	}
+
+	c := &Catalog{guidance: make(map[string]Guidance)}
+	for _, group := range []struct {
+		category  Category
+		locations []Location
+	}{
+		{CategorySystem, file.SystemCaches},
+		{CategoryUser, file.UserCaches},
+		{CategoryApplication, file.ApplicationCaches},
+		{CategorySpecial, file.SpecialLocations},
+	} {
+		for _, location := range group.locations {
+			location.Category = group.category
+			c.Locations = append(c.Locations, location)
+		}
+	}
+
+	recommendations := file.CleanupRecommendations
+	for guidance, ids := range map[Guidance][]string{
+		GuidanceSafeToClean: recommendations.SafeToClean,
+		GuidanceReview:      recommendations.ReviewBeforeCleaning,
+		GuidanceDoNotClean:  recommendations.DoNotClean,
+	} {
+		for _, id := range ids {
+			c.guidance[id] = guidance
+		}
+	}
+	return c, nil
+}
+
+// Get returns the location with the given ID
+func (c *Catalog) Get(id string) (*Location, bool) {
+	for i := range c.Locations {
+		if c.Locations[i].ID == id {
+			return &c.Locations[i], true
+		}
+	}
+	return nil, false
+}
+
+// Match returns the most specific location containing path, or nil
+func (c *Catalog) Match(path string) *Location {
+	path = filepath.Clean(path)
+
+	var best *Location
+	bestLen := -1
+	for i := range c.Locations {
+		root := c.Locations[i].ExpandedPath()
+		if root == "" {
+			continue
+		}
+		if path != root && !strings.HasPrefix(path, root+string(filepath.Separator)) {
+			continue
+		}
+		if len(root) > bestLen {
+			best, bestLen = &c.Locations[i], len(root)
+		}
+	}
+	return best
+}
+
+// Guidance returns the catalog's cleanup recommendation for a location
+func (c *Catalog) Guidance(id string) Guidance {
+	if guidance, ok := c.guidance[id]; ok {
+		return guidance
+	}
+	return GuidanceUnknown
+}
+
+// ExpandedPath returns the location path with ~ expanded and without a trailing separator
+func (l *Location) ExpandedPath() string {
+	path := l.Path
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	return filepath.Clean(path)
+}
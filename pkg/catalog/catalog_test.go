@@ -0,0 +1,60 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testCatalog = `{
+  "user_caches": [
+    {"id": "user_library_caches", "name": "User Library Caches", "path": "~/Library/Caches/", "safety_level": "safe", "cleanup_risk": "low"}
+  ],
+  "application_caches": [
+    {"id": "chrome_cache", "name": "Google Chrome Cache", "path": "~/Library/Caches/Google/Chrome/", "safety_level": "safe",
+     "cleanup_risk": "low", "application": "Google Chrome", "bundle_id": "com.google.Chrome"}
+  ],
+  "special_locations": [
+    {"id": "kernel_cache", "name": "Kernel Extensions Cache", "path": "/System/Library/Extensions.kextcache", "safety_level": "dangerous", "cleanup_risk": "high"}
+  ],
+  "cleanup_recommendations": {
+    "safe_to_clean": ["user_library_caches", "chrome_cache"],
+    "do_not_clean": ["kernel_cache"]
+  }
+}`
+
+func TestCatalog(t *testing.T) {
+	c, err := Parse([]byte(testCatalog))
+	if err != nil {
+		t.Fatalf("Failed to parse catalog: %v", err)
+	}
+	if len(c.Locations) != 3 {
+		t.Fatalf("Expected 3 locations, got %d", len(c.Locations))
+	}
+
+	chrome, ok := c.Get("chrome_cache")
+	if !ok || chrome.Category != CategoryApplication || chrome.BundleID != "com.google.Chrome" {
+		t.Errorf("Unexpected chrome location %+v", chrome)
+	}
+	if c.Guidance("kernel_cache") != GuidanceDoNotClean || c.Guidance("missing") != GuidanceUnknown {
+		t.Error("Unexpected cleanup guidance")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	caches := filepath.Join(home, "Library", "Caches")
+	for path, expected := range map[string]string{
+		filepath.Join(caches, "Google", "Chrome", "Default", "Cache", "data_1"): "chrome_cache",
+		filepath.Join(caches, "Google", "Chrome"):                               "chrome_cache",
+		filepath.Join(caches, "com.example.app", "cache.db"):                    "user_library_caches",
+		filepath.Join(caches+"-old", "file"):                                    "",
+		"/System/Library/Extensions.kextcache":                                  "kernel_cache",
+	} {
+		location := c.Match(path)
+		if expected == "" && location != nil || expected != "" && (location == nil || location.ID != expected) {
+			t.Errorf("Match(%s) = %+v, expected %q", path, location, expected)
+		}
+	}
+}
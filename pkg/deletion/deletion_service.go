@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 
@@ -22,6 +23,13 @@ type DeletionService struct {
 	progressTracker *ProgressTracker // Optional progress tracker for external monitoring
 	activeOperations map[string]bool // Track active operations by ID
 	operationsMu     sync.RWMutex    // Mutex for activeOperations
+	quarantine       *Quarantine     // Holding area for DeletionModeQuarantine, nil if unavailable
+	trash            *FreedesktopTrash // Trash for DeletionModeTrash, nil if unavailable
+	inUse            *InUseChecker     // Detects files used by running processes, nil to skip
+	classifier       *safety.SafetyClassifier // Rates files during validation, nil for the defaults
+	pathRules        *safety.PathRules        // User allow and deny rules, nil for none
+	plans            map[string]*issuedPlan   // Plans created by this service, by ID
+	plansMu          sync.Mutex               // Mutex for plans
 }
 
 // DeletionProgress represents progress information during deletion operations
@@ -47,6 +55,8 @@ type DeletionRequest struct {
 	Operation   string   `json:"operation"`
 	ForceDelete bool     `json:"force_delete"` // Skip safety checks
 	DryRun      bool     `json:"dry_run"`      // Don't actually delete
+	Mode        DeletionMode `json:"mode,omitempty"` // Defaults to DeletionModeBackup
+	Atomic      bool     `json:"atomic"`       // All or nothing: restore deleted files if any deletion fails
 }
 
 // DeletionResult represents the result of a deletion operation
@@ -62,7 +72,13 @@ type DeletionResult struct {
 	TotalSize       int64     `json:"total_size"`
 	BackedUpSize    int64     `json:"backed_up_size"`
 	DeletedSize     int64     `json:"deleted_size"`
+	Mode            DeletionMode `json:"mode"` // Strategy that removed the files
 	BackupSessionID string    `json:"backup_session_id"`
+	QuarantineBatchID string  `json:"quarantine_batch_id,omitempty"`
+	QuarantineExpiresAt *time.Time `json:"quarantine_expires_at,omitempty"`
+	TrashedItems    []TrashedItem `json:"trashed_items,omitempty"`
+	Rollback        *RollbackResult `json:"rollback,omitempty"` // Set when an atomic deletion was rolled back
+	Plan            *DeletionPlan `json:"plan,omitempty"` // Set for dry runs
 	Status          string    `json:"status"`
 	Error           string    `json:"error,omitempty"`
 	DeletedFiles    []string  `json:"deleted_files"`
@@ -79,18 +95,27 @@ type SafetyCheckResult struct {
 	BlockedFiles  []string `json:"blocked_files"`
 	RiskyFiles    []string `json:"risky_files"`
 	SafeFiles     []string `json:"safe_files"`
+	BlockReasons  map[string]string `json:"block_reasons"` // Why each blocked or risky file was held back
+	InUse         map[string]string `json:"in_use,omitempty"` // Files used by running processes and by whom
+	Denied        map[string]string `json:"denied,omitempty"` // Files denied by a user path rule and its pattern
 	TotalSize     int64    `json:"total_size"`
 	EstimatedTime time.Duration `json:"estimated_time"`
 }
 
 // NewDeletionService creates a new deletion service instance
 func NewDeletionService(backupSystem *backup.BackupSystem) *DeletionService {
+	return NewDeletionServiceWithLogger(backupSystem, NewDeletionLogger())
+}
+
+// NewDeletionServiceWithLogger creates a deletion service that writes to the given logger
+func NewDeletionServiceWithLogger(backupSystem *backup.BackupSystem, logger *DeletionLogger) *DeletionService {
 	return &DeletionService{
 		backupSystem: backupSystem,
 		progressChan: make(chan DeletionProgress, 100),
 		stopChan:     make(chan bool, 1),
-		logger:       NewDeletionLogger(),
+		logger:       logger,
 		activeOperations: make(map[string]bool),
+		plans:            make(map[string]*issuedPlan),
 	}
 }
 
@@ -112,6 +137,69 @@ func (ds *DeletionService) StopDeletion() {
 	}
 }
 
+// SetQuarantine sets the holding area used for DeletionModeQuarantine
+func (ds *DeletionService) SetQuarantine(quarantine *Quarantine) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.quarantine = quarantine
+}
+
+// GetQuarantine returns the quarantine holding area, or nil if none is set
+func (ds *DeletionService) GetQuarantine() *Quarantine {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.quarantine
+}
+
+// SetTrash sets the trash used for DeletionModeTrash
+func (ds *DeletionService) SetTrash(trash *FreedesktopTrash) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.trash = trash
+}
+
+// GetTrash returns the trash, or nil if none is set
+func (ds *DeletionService) GetTrash() *FreedesktopTrash {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.trash
+}
+
+// SetInUseChecker sets the detection of files used by running processes
+func (ds *DeletionService) SetInUseChecker(checker *InUseChecker) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.inUse = checker
+}
+
+// GetInUseChecker returns the in-use detection, or nil if none is set
+func (ds *DeletionService) GetInUseChecker() *InUseChecker {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.inUse
+}
+
+// SetSafetyClassifier sets the classifier rating files during validation
+func (ds *DeletionService) SetSafetyClassifier(classifier *safety.SafetyClassifier) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.classifier = classifier
+}
+
+// SetPathRules sets the user allow and deny path rules
+func (ds *DeletionService) SetPathRules(rules *safety.PathRules) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.pathRules = rules
+}
+
+// GetPathRules returns the user path rules, or nil if none are set
+func (ds *DeletionService) GetPathRules() *safety.PathRules {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.pathRules
+}
+
 // IsDeleting returns whether a deletion is currently in progress
 func (ds *DeletionService) IsDeleting() bool {
 	ds.mu.RLock()
@@ -165,6 +253,7 @@ func (ds *DeletionService) ValidateDeletionRequest(request *DeletionRequest) (*S
 		BlockedFiles: make([]string, 0),
 		RiskyFiles:   make([]string, 0),
 		SafeFiles:    make([]string, 0),
+		BlockReasons: make(map[string]string),
 	}
 
 	startTime := time.Now()
@@ -182,10 +271,24 @@ func (ds *DeletionService) ValidateDeletionRequest(request *DeletionRequest) (*S
 		info, err := os.Stat(filePath)
 		if err != nil {
 			result.BlockedFiles = append(result.BlockedFiles, filePath)
+			result.BlockReasons[filePath] = "file not found"
 			result.Warnings = append(result.Warnings, fmt.Sprintf("File not found: %s", filePath))
 			continue
 		}
 
+		// User deny rules block even forced deletions
+		if action, rule := ds.GetPathRules().Decide(filePath, info.IsDir()); action == safety.RuleDeny {
+			if result.Denied == nil {
+				result.Denied = make(map[string]string)
+			}
+			result.Denied[filePath] = rule.Pattern
+			result.BlockedFiles = append(result.BlockedFiles, filePath)
+			result.BlockReasons[filePath] = "denied by path rule " + rule.Pattern
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Denied by path rule %s: %s", rule.Pattern, filePath))
+			result.IsSafe = false
+			continue
+		}
+
 		// Get file size
 		fileSize := int64(0)
 		if !info.IsDir() {
@@ -196,6 +299,7 @@ func (ds *DeletionService) ValidateDeletionRequest(request *DeletionRequest) (*S
 		// Check file permissions
 		if !ds.checkFilePermissions(filePath, info) {
 			result.BlockedFiles = append(result.BlockedFiles, filePath)
+			result.BlockReasons[filePath] = "insufficient permissions"
 			result.Warnings = append(result.Warnings, fmt.Sprintf("Insufficient permissions: %s", filePath))
 			continue
 		}
@@ -206,6 +310,7 @@ func (ds *DeletionService) ValidateDeletionRequest(request *DeletionRequest) (*S
 			switch safetyLevel {
 			case "Risky":
 				result.RiskyFiles = append(result.RiskyFiles, filePath)
+				result.BlockReasons[filePath] = "classified as risky, force delete required"
 				result.Warnings = append(result.Warnings, fmt.Sprintf("Risky file detected: %s", filePath))
 				if !request.ForceDelete {
 					result.IsSafe = false
@@ -223,11 +328,14 @@ func (ds *DeletionService) ValidateDeletionRequest(request *DeletionRequest) (*S
 		// Check for system critical files
 		if ds.isSystemCritical(filePath) {
 			result.BlockedFiles = append(result.BlockedFiles, filePath)
+			result.BlockReasons[filePath] = "system critical path"
 			result.Warnings = append(result.Warnings, fmt.Sprintf("System critical file blocked: %s", filePath))
 			result.IsSafe = false
 		}
 	}
 
+	ds.checkInUse(result)
+
 	result.TotalSize = totalSize
 	result.EstimatedTime = time.Since(startTime) * time.Duration(len(request.Files))
 
@@ -243,6 +351,45 @@ func (ds *DeletionService) ValidateDeletionRequest(request *DeletionRequest) (*S
 	return result, nil
 }
 
+// checkInUse warns about or blocks the validated files that running processes use
+func (ds *DeletionService) checkInUse(result *SafetyCheckResult) {
+	checker := ds.GetInUseChecker()
+	if checker == nil || checker.Action() == InUseOff {
+		return
+	}
+
+	candidates := append(append([]string{}, result.SafeFiles...), result.RiskyFiles...)
+	inUse, err := checker.Check(candidates)
+	if err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Could not fully check for files in use: %v", err))
+	}
+	if len(inUse) == 0 {
+		return
+	}
+
+	result.InUse = make(map[string]string, len(inUse))
+	for _, filePath := range candidates {
+		usage, ok := inUse[filePath]
+		if !ok {
+			continue
+		}
+		reason := usage.Reason()
+		result.InUse[filePath] = reason
+		if checker.Action() == InUseWarn {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("File in use, %s: %s", reason, filePath))
+			continue
+		}
+
+		if !slices.Contains(result.BlockedFiles, filePath) {
+			result.BlockedFiles = append(result.BlockedFiles, filePath)
+		}
+		result.BlockReasons[filePath] = "in use, close the application first: " + reason
+		result.SafeFiles = slices.DeleteFunc(result.SafeFiles, func(path string) bool { return path == filePath })
+		result.Warnings = append(result.Warnings, fmt.Sprintf("File in use blocked, %s: %s", reason, filePath))
+		result.IsSafe = false
+	}
+}
+
 // DeleteFilesWithBackup safely deletes files after creating mandatory backups
 func (ds *DeletionService) DeleteFilesWithBackup(request *DeletionRequest) (*DeletionResult, error) {
 	return ds.DeleteFilesWithBackupAndTracker(request, nil)
@@ -309,6 +456,11 @@ func (ds *DeletionService) DeleteFilesWithBackupAndTracker(request *DeletionRequ
 	// Add validation warnings to result
 	result.Warnings = append(result.Warnings, safetyResult.Warnings...)
 
+	// Dry runs return a plan that can be reviewed and executed later with ExecutePlan
+	if request.DryRun || request.Mode == DeletionModeDryRun {
+		result.Plan = ds.buildPlan(request, safetyResult)
+	}
+
 	// Check if deletion is safe
 	if !safetyResult.IsSafe && !request.ForceDelete {
 		result.Status = "blocked"
@@ -321,6 +473,22 @@ func (ds *DeletionService) DeleteFilesWithBackupAndTracker(request *DeletionRequ
 	filesToDelete := safetyResult.SafeFiles
 	if request.ForceDelete {
 		filesToDelete = request.Files
+		// Force overrides classification, but denied files stay untouched and files
+		// in use stay blocked until their application is closed
+		inUse := ds.GetInUseChecker()
+		holdInUse := inUse != nil && inUse.Action() == InUseBlock && len(safetyResult.InUse) > 0
+		if holdInUse || len(safetyResult.Denied) > 0 {
+			filesToDelete = make([]string, 0, len(request.Files))
+			for _, filePath := range request.Files {
+				_, used := safetyResult.InUse[filePath]
+				if _, denied := safetyResult.Denied[filePath]; denied || (used && holdInUse) {
+					result.SkippedFiles = append(result.SkippedFiles, filePath)
+					result.SkippedCount++
+					continue
+				}
+				filesToDelete = append(filesToDelete, filePath)
+			}
+		}
 	}
 
 	if len(filesToDelete) == 0 {
@@ -331,176 +499,15 @@ func (ds *DeletionService) DeleteFilesWithBackupAndTracker(request *DeletionRequ
 		return result, nil
 	}
 
-	// Step 2: Create mandatory backup
-	progress = DeletionProgress{
-		Operation:      request.Operation,
-		Status:         "backing_up",
-		Message:        "Creating mandatory backup...",
-		BackupProgress: 0,
-	}
-	ds.sendProgress(progress)
-	if tracker != nil {
-		tracker.SetStatus("backing_up", "Creating mandatory backup...")
-		tracker.SetBackupProgress(0, "Creating mandatory backup...")
-	}
-
-	backupSession, err := ds.backupSystem.BackupFiles(filesToDelete, request.Operation)
+	strategy, err := ds.newStrategy(request, tracker)
 	if err != nil {
 		result.Status = "failed"
-		result.Error = fmt.Sprintf("backup failed: %v", err)
+		result.Error = err.Error()
 		result.EndTime = time.Now()
-		ds.logger.LogError("Backup failed", err, map[string]interface{}{
-			"operation": request.Operation,
-			"files":     filesToDelete,
-		})
-		return result, fmt.Errorf("mandatory backup failed: %w", err)
-	}
-
-	result.BackupSessionID = backupSession.SessionID
-	result.BackedUpCount = backupSession.SuccessCount
-	result.BackedUpSize = backupSession.BackupSize
-
-	ds.logger.LogInfo("Backup created successfully", map[string]interface{}{
-		"session_id":    backupSession.SessionID,
-		"backed_up":     backupSession.SuccessCount,
-		"backup_size":   backupSession.BackupSize,
-	})
-
-	progress = DeletionProgress{
-		Operation:      request.Operation,
-		Status:         "backup_complete",
-		Message:        "Backup completed, starting deletion...",
-		BackupProgress: 100,
-	}
-	ds.sendProgress(progress)
-	if tracker != nil {
-		tracker.SetStatus("backup_complete", "Backup completed, starting deletion...")
-		tracker.SetBackupProgress(100, "Backup completed, starting deletion...")
-	}
-
-	// Step 3: Delete files (if not dry run)
-	if !request.DryRun {
-		startTime := time.Now()
-		for i, filePath := range filesToDelete {
-			// Check for stop signal
-			select {
-			case <-ds.stopChan:
-				result.Status = "cancelled"
-				result.EndTime = time.Now()
-				ds.logger.LogInfo("Deletion cancelled by user", map[string]interface{}{
-					"files_processed": i,
-					"total_files":     len(filesToDelete),
-				})
-				return result, fmt.Errorf("deletion cancelled by user")
-			default:
-			}
-
-			// Find the corresponding backup entry
-			var backupEntry *backup.BackupEntry
-			for _, entry := range backupSession.Entries {
-				if entry.OriginalPath == filePath {
-					backupEntry = &entry
-					break
-				}
-			}
-
-			// Only delete if backup was successful
-			if backupEntry != nil && backupEntry.Success {
-				if err := ds.deleteSingleFile(filePath); err != nil {
-					result.FailedFiles = append(result.FailedFiles, filePath)
-					result.FailedCount++
-					ds.logger.LogError("Failed to delete file", err, map[string]interface{}{
-						"file_path": filePath,
-					})
-				} else {
-					result.DeletedFiles = append(result.DeletedFiles, filePath)
-					result.DeletedCount++
-					result.DeletedSize += backupEntry.Size
-					ds.logger.LogInfo("File deleted successfully", map[string]interface{}{
-						"file_path": filePath,
-						"size":      backupEntry.Size,
-					})
-				}
-			} else {
-				result.FailedFiles = append(result.FailedFiles, filePath)
-				result.FailedCount++
-				ds.logger.LogWarning("Skipped deletion due to backup failure", map[string]interface{}{
-					"file_path": filePath,
-				})
-			}
-
-			result.TotalSize += backupEntry.Size
-
-			// Send progress update
-			progress := DeletionProgress{
-				Operation:         request.Operation,
-				CurrentFile:       filePath,
-				FilesProcessed:    i + 1,
-				TotalFiles:        len(filesToDelete),
-				Progress:          float64(i+1) / float64(len(filesToDelete)) * 100,
-				ElapsedTime:       time.Since(startTime),
-				CurrentSize:       result.DeletedSize,
-				TotalSize:         result.TotalSize,
-				BackupProgress:    100,
-				DeletionProgress:  float64(i+1) / float64(len(filesToDelete)) * 100,
-				Status:            "deleting",
-				Message:           fmt.Sprintf("Deleting file %d of %d", i+1, len(filesToDelete)),
-			}
-
-			// Calculate estimated time
-			if i > 0 {
-				avgTimePerFile := time.Since(startTime) / time.Duration(i+1)
-				remainingFiles := len(filesToDelete) - (i + 1)
-				progress.EstimatedTime = avgTimePerFile * time.Duration(remainingFiles)
-			}
-
-			ds.sendProgress(progress)
-			
-			// Update progress tracker if available
-			if tracker != nil {
-				tracker.SetFileProgress(filePath, i+1, len(filesToDelete), result.DeletedSize, result.TotalSize)
-			}
-		}
-	} else {
-		// Dry run - just log what would be deleted
-		ds.logger.LogInfo("Dry run completed", map[string]interface{}{
-			"files_to_delete": filesToDelete,
-			"total_size":      result.TotalSize,
-		})
-		result.DeletedFiles = filesToDelete
-		result.DeletedCount = len(filesToDelete)
-	}
-
-	result.EndTime = time.Now()
-	result.Status = "completed"
-
-	// Send final progress update
-	finalProgress := DeletionProgress{
-		Operation:      request.Operation,
-		Status:         "completed",
-		Message:        fmt.Sprintf("Deletion completed: %d files deleted", result.DeletedCount),
-		FilesProcessed: result.DeletedCount,
-		TotalFiles:     result.TotalFiles,
-		Progress:       100,
-		BackupProgress: 100,
-		DeletionProgress: 100,
-	}
-	ds.sendProgress(finalProgress)
-	
-	if tracker != nil {
-		tracker.Complete(fmt.Sprintf("Deletion completed: %d files deleted", result.DeletedCount))
+		return result, err
 	}
 
-	ds.logger.LogInfo("Deletion operation completed", map[string]interface{}{
-		"operation":      request.Operation,
-		"deleted_count":  result.DeletedCount,
-		"failed_count":   result.FailedCount,
-		"skipped_count":  result.SkippedCount,
-		"deleted_size":   result.DeletedSize,
-		"duration":       result.EndTime.Sub(result.StartTime),
-	})
-
-	return result, nil
+	return ds.runStrategy(strategy, request, filesToDelete, result, tracker)
 }
 
 // RestoreFromBackup restores files from the most recent backup session
@@ -542,6 +549,31 @@ func (ds *DeletionService) GetBackupSessions() ([]backup.BackupSession, error) {
 	return ds.backupSystem.ListSessions()
 }
 
+// VerifyDeletionIntegrity checks that the files of a deletion are gone and that their backups are intact
+func (ds *DeletionService) VerifyDeletionIntegrity(sessionID string) (bool, []string, error) {
+	session, err := ds.backupSystem.GetSession(sessionID)
+	if err != nil {
+		return false, nil, err
+	}
+
+	valid, problems, err := ds.backupSystem.VerifyBackupIntegrity(sessionID)
+	if err != nil {
+		return false, nil, err
+	}
+
+	for _, entry := range session.Entries {
+		if !entry.Success || entry.RootPath != "" {
+			continue
+		}
+		if _, err := os.Lstat(entry.OriginalPath); err == nil {
+			problems = append(problems, fmt.Sprintf("original file still exists: %s", entry.OriginalPath))
+			valid = false
+		}
+	}
+
+	return valid, problems, nil
+}
+
 // Helper methods
 
 func (ds *DeletionService) checkFilePermissions(filePath string, info os.FileInfo) bool {
@@ -568,19 +600,32 @@ func (ds *DeletionService) classifyFileSafety(filePath string, info os.FileInfo)
 		Path:         filePath,
 		Size:         info.Size(),
 		LastModified: info.ModTime(),
-		LastAccessed: getLastAccessTime(info),
+		LastAccessed: backup.LastAccessTime(info),
 		IsDir:        info.IsDir(),
 		Permissions:  info.Mode().String(),
 	}
 
 	// Classify the file
-	classifier := safety.NewDefaultSafetyClassifier()
+	ds.mu.RLock()
+	classifier := ds.classifier
+	ds.mu.RUnlock()
+	if classifier == nil {
+		classifier = safety.NewDefaultSafetyClassifier()
+	}
 	classification := classifier.ClassifyFile(fileMetadata)
 
-	return string(classification.Level)
+	return classification.Level.String()
 }
 
 func (ds *DeletionService) isSystemCritical(filePath string) bool {
+	// User rules override the built in list
+	switch action, _ := ds.GetPathRules().Match(filePath); action {
+	case safety.RuleDeny:
+		return true
+	case safety.RuleAllow:
+		return false
+	}
+
 	criticalPaths := []string{
 		"/System",
 		"/usr",
@@ -624,10 +669,3 @@ func (ds *DeletionService) sendProgress(progress DeletionProgress) {
 		// Channel is full, skip this update
 	}
 }
-
-// getLastAccessTime gets the last access time from file info
-func getLastAccessTime(info os.FileInfo) time.Time {
-	// On macOS, we can use the access time from the file info
-	// This is a simplified implementation
-	return info.ModTime()
-}
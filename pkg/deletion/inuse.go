@@ -0,0 +1,272 @@
+package deletion
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// InUseAction decides what validation does with files in use by a running process
+type InUseAction string
+
+const (
+	InUseOff   InUseAction = "off"
+	InUseWarn  InUseAction = "warn"
+	InUseBlock InUseAction = "block" // Hold the files back until the application is closed
+)
+
+// ParseInUseAction parses the settings value of an InUseAction
+func ParseInUseAction(value string) (InUseAction, error) {
+	switch action := InUseAction(strings.ToLower(value)); action {
+	case InUseOff, InUseWarn, InUseBlock:
+		return action, nil
+	case "":
+		return InUseBlock, nil
+	}
+	return "", fmt.Errorf("unknown in-use action: %s", value)
+}
+
+// ProcessInfo identifies a running process
+type ProcessInfo struct {
+	PID        int    `json:"pid"`
+	Name       string `json:"name"`
+	Executable string `json:"executable,omitempty"`
+}
+
+// OwnerApp is the application that owns a cache location
+type OwnerApp struct {
+	Name     string `json:"name"`
+	BundleID string `json:"bundle_id,omitempty"`
+}
+
+// InUse explains why a path is in use
+type InUse struct {
+	Path      string        `json:"path"`
+	Processes []ProcessInfo `json:"processes"`     // Processes holding files under the path open or running the owner
+	App       *OwnerApp     `json:"app,omitempty"` // Set when the owning application is running
+}
+
+// Reason describes the processes using the path
+func (iu InUse) Reason() string {
+	names := make([]string, 0, len(iu.Processes))
+	for _, process := range iu.Processes {
+		names = append(names, fmt.Sprintf("%s (pid %d)", process.Name, process.PID))
+	}
+	if iu.App != nil {
+		return fmt.Sprintf("%s is running: %s", iu.App.Name, strings.Join(names, ", "))
+	}
+	return "open in " + strings.Join(names, ", ")
+}
+
+// InUseChecker finds files held open by running processes and cache locations
+// whose owning application is running
+type InUseChecker struct {
+	mu        sync.RWMutex
+	action    InUseAction
+	owner     func(path string) *OwnerApp
+	openFiles func() (map[string][]ProcessInfo, error)
+	processes func() ([]ProcessInfo, error)
+}
+
+// NewInUseChecker creates a checker. owner maps a path to the application
+// owning its location and may be nil.
+func NewInUseChecker(action InUseAction, owner func(path string) *OwnerApp) *InUseChecker {
+	return &InUseChecker{
+		action:    action,
+		owner:     owner,
+		openFiles: listOpenFiles,
+		processes: listProcesses,
+	}
+}
+
+// Action returns what validation does with files in use
+func (c *InUseChecker) Action() InUseAction {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.action
+}
+
+// SetAction changes what validation does with files in use
+func (c *InUseChecker) SetAction(action InUseAction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.action = action
+}
+
+// Check returns the paths that are in use, keyed by path. A path is in use
+// when a process holds it or a file below it open, or when the application
+// owning its location is running. Detection that is unavailable on this
+// system is reported in the error alongside whatever could be detected.
+func (c *InUseChecker) Check(paths []string) (map[string]InUse, error) {
+	found := make(map[string]InUse)
+	if len(paths) == 0 {
+		return found, nil
+	}
+
+	requested := make(map[string]string, len(paths)) // Cleaned path to requested path
+	for _, path := range paths {
+		requested[filepath.Clean(path)] = path
+	}
+
+	var errs []string
+	openFiles, err := c.openFiles()
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("failed to list open files: %v", err))
+	}
+	for openPath, processes := range openFiles {
+		// Match the open file and every directory above it
+		for dir := filepath.Clean(openPath); ; dir = filepath.Dir(dir) {
+			if path, ok := requested[dir]; ok {
+				inUse := found[path]
+				inUse.Path = path
+				inUse.Processes = appendProcesses(inUse.Processes, processes)
+				found[path] = inUse
+			}
+			if parent := filepath.Dir(dir); parent == dir {
+				break
+			}
+		}
+	}
+
+	if c.owner != nil {
+		var running []ProcessInfo
+		listed := false
+		for _, path := range paths {
+			app := c.owner(path)
+			if app == nil {
+				continue
+			}
+			if !listed {
+				listed = true
+				if running, err = c.processes(); err != nil {
+					errs = append(errs, fmt.Sprintf("failed to list processes: %v", err))
+				}
+			}
+			var matching []ProcessInfo
+			for _, process := range running {
+				if app.matches(process) {
+					matching = append(matching, process)
+				}
+			}
+			if len(matching) > 0 {
+				inUse := found[path]
+				inUse.Path = path
+				inUse.App = app
+				inUse.Processes = appendProcesses(inUse.Processes, matching)
+				found[path] = inUse
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return found, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return found, nil
+}
+
+// matches reports whether a process belongs to the application, by its name,
+// its .app bundle or the last part of its bundle identifier
+func (app *OwnerApp) matches(process ProcessInfo) bool {
+	name := strings.ToLower(process.Name)
+	executable := strings.ToLower(process.Executable)
+	appName := strings.ToLower(app.Name)
+	if appName != "" && (name == appName || strings.Contains(executable, "/"+appName+".app/")) {
+		return true
+	}
+
+	bundleID := strings.ToLower(app.BundleID)
+	if prefix, ok := strings.CutSuffix(bundleID, ".*"); ok {
+		// Vendor wildcards such as com.adobe.* match any of the vendor's apps
+		vendor := prefix[strings.LastIndex(prefix, ".")+1:]
+		return vendor != "" && strings.Contains(executable, vendor)
+	}
+	if last := bundleID[strings.LastIndex(bundleID, ".")+1:]; last != "" {
+		return name == last || strings.HasSuffix(executable, "/"+last)
+	}
+	return false
+}
+
+// appendProcesses adds the processes not already listed
+func appendProcesses(list []ProcessInfo, processes []ProcessInfo) []ProcessInfo {
+	for _, process := range processes {
+		seen := false
+		for _, existing := range list {
+			if existing.PID == process.PID {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			list = append(list, process)
+		}
+	}
+	return list
+}
+
+// lsofOpenFiles lists open files with lsof
+func lsofOpenFiles() (map[string][]ProcessInfo, error) {
+	output, err := exec.Command("lsof", "-n", "-P", "-F", "pcn").Output()
+	if err != nil && len(output) == 0 {
+		// lsof exits non-zero when some processes cannot be inspected
+		return nil, err
+	}
+	return parseLsof(string(output)), nil
+}
+
+// parseLsof parses lsof -F pcn output into open paths and their processes
+func parseLsof(output string) map[string][]ProcessInfo {
+	openFiles := make(map[string][]ProcessInfo)
+	self := os.Getpid()
+	var process ProcessInfo
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		switch value := line[1:]; line[0] {
+		case 'p':
+			pid, _ := strconv.Atoi(value)
+			process = ProcessInfo{PID: pid}
+		case 'c':
+			process.Name = value
+		case 'n':
+			if process.PID != self && filepath.IsAbs(value) {
+				openFiles[value] = append(openFiles[value], process)
+			}
+		}
+	}
+	return openFiles
+}
+
+// psProcesses lists running processes with ps
+func psProcesses() ([]ProcessInfo, error) {
+	output, err := exec.Command("ps", "-axo", "pid=,comm=").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parsePs(string(output)), nil
+}
+
+// parsePs parses ps -axo pid=,comm= output, where comm may be a path with spaces
+func parsePs(output string) []ProcessInfo {
+	var processes []ProcessInfo
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		command := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), fields[0]))
+		processes = append(processes, ProcessInfo{PID: pid, Name: filepath.Base(command), Executable: command})
+	}
+	return processes
+}
@@ -0,0 +1,70 @@
+package deletion
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// procDir is where the kernel lists processes
+const procDir = "/proc"
+
+// listOpenFiles lists the files held open by processes through /proc/*/fd,
+// falling back to lsof when /proc is unavailable
+func listOpenFiles() (map[string][]ProcessInfo, error) {
+	if _, err := os.Stat(filepath.Join(procDir, "self", "fd")); err != nil {
+		return lsofOpenFiles()
+	}
+	return procOpenFiles(procDir), nil
+}
+
+// procOpenFiles reads the file descriptors of every process it may inspect
+func procOpenFiles(dir string) map[string][]ProcessInfo {
+	openFiles := make(map[string][]ProcessInfo)
+	for _, process := range procProcesses(dir) {
+		fdDir := filepath.Join(dir, strconv.Itoa(process.PID), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue // Other users' processes
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !filepath.IsAbs(target) {
+				continue // Sockets, pipes and anonymous inodes
+			}
+			target = strings.TrimSuffix(target, " (deleted)")
+			openFiles[target] = appendProcesses(openFiles[target], []ProcessInfo{process})
+		}
+	}
+	return openFiles
+}
+
+// listProcesses lists running processes from /proc
+func listProcesses() ([]ProcessInfo, error) {
+	return procProcesses(procDir), nil
+}
+
+// procProcesses lists the processes in a /proc directory, except this one
+func procProcesses(dir string) []ProcessInfo {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	self := os.Getpid()
+	var processes []ProcessInfo
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		comm, err := os.ReadFile(filepath.Join(dir, entry.Name(), "comm"))
+		if err != nil {
+			continue
+		}
+		executable, _ := os.Readlink(filepath.Join(dir, entry.Name(), "exe"))
+		processes = append(processes, ProcessInfo{PID: pid, Name: strings.TrimSpace(string(comm)), Executable: executable})
+	}
+	return processes
+}
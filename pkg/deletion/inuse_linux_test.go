@@ -0,0 +1,36 @@
+package deletion
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestProcOpenFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "held.db")
+	if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// The child holds the file open as its standard input
+	child := exec.Command("sleep", "30")
+	child.Stdin = file
+	if err := child.Start(); err != nil {
+		t.Skipf("Cannot start child process: %v", err)
+	}
+	defer func() {
+		child.Process.Kill()
+		child.Wait()
+	}()
+
+	holders := procOpenFiles(procDir)[path]
+	if len(holders) != 1 || holders[0].PID != child.Process.Pid || holders[0].Name != "sleep" {
+		t.Errorf("Expected only the child to hold the file, got %+v", holders)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package deletion
+
+// listOpenFiles lists the files held open by processes with lsof
+func listOpenFiles() (map[string][]ProcessInfo, error) {
+	return lsofOpenFiles()
+}
+
+// listProcesses lists running processes with ps
+func listProcesses() ([]ProcessInfo, error) {
+	return psProcesses()
+}
@@ -0,0 +1,122 @@
+package deletion
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInUseChecker(t *testing.T) {
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	browserDir := filepath.Join(t.TempDir(), "Google", "Chrome")
+	openFile := filepath.Join(cacheDir, "held", "index")
+	idleFile := filepath.Join(cacheDir, "idle.bin")
+	browserFile := filepath.Join(browserDir, "data_0")
+	for _, path := range []string{openFile, idleFile, browserFile} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("cache"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	newChecker := func(action InUseAction) *InUseChecker {
+		checker := NewInUseChecker(action, func(path string) *OwnerApp {
+			if strings.HasPrefix(path, browserDir) {
+				return &OwnerApp{Name: "Google Chrome", BundleID: "com.google.Chrome"}
+			}
+			return nil
+		})
+		checker.openFiles = func() (map[string][]ProcessInfo, error) {
+			return map[string][]ProcessInfo{openFile: {{PID: 42, Name: "indexer"}}}, nil
+		}
+		checker.processes = func() ([]ProcessInfo, error) {
+			return []ProcessInfo{
+				{PID: 7, Name: "Google Chrome", Executable: "/Applications/Google Chrome.app/Contents/MacOS/Google Chrome"},
+				{PID: 8, Name: "bash", Executable: "/bin/bash"},
+			}, nil
+		}
+		return checker
+	}
+
+	t.Run("Check", func(t *testing.T) {
+		inUse, err := newChecker(InUseBlock).Check([]string{filepath.Dir(openFile), idleFile, browserFile})
+		if err != nil {
+			t.Fatalf("Failed to check files in use: %v", err)
+		}
+		if len(inUse) != 2 {
+			t.Fatalf("Expected the held directory and the browser file in use, got %+v", inUse)
+		}
+		if held := inUse[filepath.Dir(openFile)]; len(held.Processes) != 1 || held.Processes[0].PID != 42 || held.App != nil {
+			t.Errorf("Expected the directory to be held by the indexer, got %+v", held)
+		}
+		if browser := inUse[browserFile]; browser.App == nil || len(browser.Processes) != 1 || browser.Processes[0].PID != 7 {
+			t.Errorf("Expected the browser file to be used by running Chrome, got %+v", browser)
+		}
+	})
+
+	t.Run("BlockInValidation", func(t *testing.T) {
+		ds := newTestDeletionService(t)
+		ds.SetInUseChecker(newChecker(InUseBlock))
+
+		result, err := ds.ValidateDeletionRequest(&DeletionRequest{Files: []string{openFile, idleFile, browserFile}})
+		if err != nil {
+			t.Fatalf("Failed to validate: %v", err)
+		}
+		if result.IsSafe || len(result.InUse) != 2 || len(result.SafeFiles) != 1 || result.SafeFiles[0] != idleFile {
+			t.Errorf("Expected the files in use to be blocked, got %+v", result)
+		}
+		if !strings.Contains(result.BlockReasons[browserFile], "Google Chrome is running") {
+			t.Errorf("Expected the running application as block reason, got %q", result.BlockReasons[browserFile])
+		}
+
+		// Force deletion still leaves files in use alone
+		deletion, err := ds.DeleteFilesWithBackup(&DeletionRequest{Files: []string{openFile, idleFile}, ForceDelete: true, Operation: "in_use"})
+		if err != nil {
+			t.Fatalf("Failed to delete: %v", err)
+		}
+		if deletion.DeletedCount != 1 || len(deletion.SkippedFiles) != 1 || deletion.SkippedFiles[0] != openFile {
+			t.Errorf("Expected only the idle file to be deleted, got %+v", deletion)
+		}
+		if _, err := os.Stat(openFile); err != nil {
+			t.Errorf("Expected the file in use to remain: %v", err)
+		}
+	})
+
+	t.Run("Warn", func(t *testing.T) {
+		ds := newTestDeletionService(t)
+		ds.SetInUseChecker(newChecker(InUseWarn))
+
+		result, err := ds.ValidateDeletionRequest(&DeletionRequest{Files: []string{openFile}})
+		if err != nil {
+			t.Fatalf("Failed to validate: %v", err)
+		}
+		if !result.IsSafe || len(result.BlockedFiles) != 0 || len(result.InUse) != 1 || len(result.Warnings) == 0 {
+			t.Errorf("Expected a warning only, got %+v", result)
+		}
+	})
+}
+
+func TestParseProcessListings(t *testing.T) {
+	openFiles := parseLsof("p100\ncSafari\nf12\nn/Users/me/Library/Caches/com.apple.Safari/Cache.db\nf13\nnTCP 1.2.3.4:443\np200\ncmds\nn/Users/me/Library/Caches/com.apple.Safari/Cache.db\n")
+	processes := openFiles["/Users/me/Library/Caches/com.apple.Safari/Cache.db"]
+	if len(openFiles) != 1 || len(processes) != 2 || processes[0].Name != "Safari" || processes[1].PID != 200 {
+		t.Errorf("Unexpected lsof parse: %+v", openFiles)
+	}
+
+	running := parsePs("  311 /Applications/Visual Studio Code.app/Contents/MacOS/Electron\n  1 /sbin/launchd\n")
+	if len(running) != 2 || running[0].PID != 311 || running[0].Name != "Electron" {
+		t.Fatalf("Unexpected ps parse: %+v", running)
+	}
+	if !(&OwnerApp{Name: "Visual Studio Code"}).matches(running[0]) {
+		t.Error("Expected VS Code to be recognized by its bundle path")
+	}
+	if (&OwnerApp{Name: "Spotify", BundleID: "com.spotify.client"}).matches(running[1]) {
+		t.Error("Expected launchd not to match Spotify")
+	}
+	if !(&OwnerApp{Name: "Firefox", BundleID: "org.mozilla.firefox"}).matches(ProcessInfo{Name: "firefox"}) {
+		t.Error("Expected firefox to match its bundle identifier")
+	}
+}
@@ -0,0 +1,57 @@
+package deletion
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cache_app/pkg/safety"
+)
+
+func TestPathRulesInValidation(t *testing.T) {
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	indexFile := filepath.Join(cacheDir, "IdeaIC", "index", "shard")
+	logFile := filepath.Join(cacheDir, "IdeaIC", "log", "idea.log")
+	for _, path := range []string{indexFile, logFile} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("cache"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rules, err := safety.NewPathRules([]safety.PathRule{
+		{Pattern: filepath.ToSlash(cacheDir) + "/*/index", Action: safety.RuleDeny},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := newTestDeletionService(t)
+	ds.SetPathRules(rules)
+
+	parent := filepath.Join(cacheDir, "IdeaIC")
+	result, err := ds.ValidateDeletionRequest(&DeletionRequest{Files: []string{indexFile, logFile, parent}})
+	if err != nil {
+		t.Fatalf("Failed to validate: %v", err)
+	}
+	if result.IsSafe || len(result.Denied) != 2 || len(result.SafeFiles) != 1 || result.SafeFiles[0] != logFile {
+		t.Errorf("Expected the index and its parent to be denied, got %+v", result)
+	}
+	if !strings.Contains(result.BlockReasons[parent], "denied by path rule") {
+		t.Errorf("Expected the rule as block reason, got %q", result.BlockReasons[parent])
+	}
+
+	// A deny rule holds even against force
+	deletion, err := ds.DeleteFilesWithBackup(&DeletionRequest{Files: []string{indexFile, logFile}, ForceDelete: true, Operation: "path_rules"})
+	if err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if deletion.DeletedCount != 1 || len(deletion.SkippedFiles) != 1 || deletion.SkippedFiles[0] != indexFile {
+		t.Errorf("Expected only the log to be deleted, got %+v", deletion)
+	}
+	if _, err := os.Stat(indexFile); err != nil {
+		t.Errorf("Expected the denied file to remain: %v", err)
+	}
+}
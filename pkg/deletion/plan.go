@@ -0,0 +1,420 @@
+package deletion
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// PlanAction is what executing a plan does with a path
+type PlanAction string
+
+const (
+	PlanActionBackupAndDelete PlanAction = "backup_and_delete"
+	PlanActionDelete          PlanAction = "delete"
+	PlanActionQuarantine      PlanAction = "quarantine"
+	PlanActionTrash           PlanAction = "trash"
+	PlanActionBlock           PlanAction = "block" // Held back by validation, never executed
+)
+
+// Estimates used for DeletionPlan.EstimatedDuration
+const (
+	planEntryCost     = 500 * time.Microsecond // Removing or moving a single entry
+	planBackupCost    = 2 * time.Millisecond   // Extra per-file work of a backup
+	planCopyBandwidth = 200 << 20              // Bytes per second copied into backups
+)
+
+// PlanItem describes what happens to a single requested path
+type PlanItem struct {
+	Path      string     `json:"path"`
+	Action    PlanAction `json:"action"`
+	Reason    string     `json:"reason,omitempty"` // Why a path is blocked
+	IsDir     bool       `json:"is_dir"`
+	Entries   int        `json:"entries"`    // Files and directories under the path, including itself
+	Size      int64      `json:"size"`       // Apparent size of all regular files
+	DiskBytes int64      `json:"disk_bytes"` // Allocated bytes released once the path is gone
+	ModTime   time.Time  `json:"mod_time"`   // Checked before execution to detect changes
+}
+
+// DeletionPlan is the reviewable outcome of a dry run. It is serializable and
+// can be executed unchanged with ExecutePlan until it expires.
+type DeletionPlan struct {
+	ID          string       `json:"id"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Operation   string       `json:"operation"`
+	Mode        DeletionMode `json:"mode"` // Mode the plan executes with
+	ForceDelete bool         `json:"force_delete"`
+	Atomic      bool         `json:"atomic"`
+	Items       []PlanItem   `json:"items"`
+	Blocked     bool         `json:"blocked"` // Validation blocks the whole request
+	BlockReason string       `json:"block_reason,omitempty"`
+
+	TotalSize           int64         `json:"total_size"`
+	PredictedFreedBytes int64         `json:"predicted_freed_bytes"` // On disk, released by execution
+	DeferredFreedBytes  int64         `json:"deferred_freed_bytes"`  // Released once quarantine is purged or the trash emptied
+	SharedBytes         int64         `json:"shared_bytes"`          // Kept alive by hard links outside the plan
+	BackupSpaceRequired int64         `json:"backup_space_required"` // Upper bound, compression and reflinks reduce it
+	EstimatedDuration   time.Duration `json:"estimated_duration"`
+	Warnings            []string      `json:"warnings"`
+}
+
+// issuedPlanTTL is how long the service remembers a plan it created
+const issuedPlanTTL = 24 * time.Hour
+
+// issuedPlan is what the service remembers of a plan it created, so that
+// executing it does not depend on the copy sent back by a client
+type issuedPlan struct {
+	createdAt   time.Time
+	mode        DeletionMode
+	forceDelete bool
+	atomic      bool
+	items       map[string]bool // Planned paths, true for blocked ones
+}
+
+// fileID identifies an inode for hard link accounting
+type fileID struct {
+	dev, ino uint64
+}
+
+// planInode tracks how many links of an inode a plan removes
+type planInode struct {
+	links   uint64
+	removed uint64
+	bytes   int64
+	owner   int // Index of the first item that references the inode
+}
+
+// Files returns the paths the plan acts on
+func (p *DeletionPlan) Files() []string {
+	files := make([]string, 0, len(p.Items))
+	for _, item := range p.Items {
+		if item.Action != PlanActionBlock {
+			files = append(files, item.Path)
+		}
+	}
+	return files
+}
+
+// PlanDeletion validates a request and returns its plan without backing up or removing anything
+func (ds *DeletionService) PlanDeletion(request *DeletionRequest) (*DeletionPlan, error) {
+	safetyResult, err := ds.ValidateDeletionRequest(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate deletion request: %w", err)
+	}
+
+	// The mode must be usable when the plan is executed
+	mode := request.Mode
+	if mode == DeletionModeDryRun {
+		mode = DeletionModeBackup
+	}
+	if _, err := ds.newStrategy(&DeletionRequest{Mode: mode, Atomic: request.Atomic}, nil); err != nil {
+		return nil, err
+	}
+	return ds.buildPlan(request, safetyResult), nil
+}
+
+// ExecutePlan carries out a plan exactly as reviewed. Paths that changed or
+// disappeared since planning are skipped rather than re-evaluated. Only plans
+// created by this service within issuedPlanTTL are accepted. Plans may come
+// back edited from a client, so the paths are validated again and the
+// service's own record of the plan wins: paths blocked at planning time or
+// now are refused, as are paths the plan did not contain.
+func (ds *DeletionService) ExecutePlan(plan *DeletionPlan, tracker *ProgressTracker) (*DeletionResult, error) {
+	if plan == nil {
+		return nil, fmt.Errorf("no deletion plan given")
+	}
+	if plan.Blocked {
+		return nil, fmt.Errorf("deletion plan %s is blocked: %s", plan.ID, plan.BlockReason)
+	}
+
+	issued := ds.issuedPlan(plan.ID)
+	if issued == nil {
+		return nil, fmt.Errorf("deletion plan %s is unknown or expired, create a new plan", plan.ID)
+	}
+	mode, forceDelete, atomic := issued.mode, issued.forceDelete, issued.atomic
+
+	var candidates, changed []string
+	refused := make(map[string]string)
+	for _, item := range plan.Items {
+		if item.Action == PlanActionBlock {
+			continue
+		}
+		if blocked, planned := issued.items[item.Path]; !planned {
+			refused[item.Path] = "not part of the plan"
+			continue
+		} else if blocked {
+			refused[item.Path] = "blocked when the plan was created"
+			continue
+		}
+		if item.changed() {
+			changed = append(changed, item.Path)
+			continue
+		}
+		candidates = append(candidates, item.Path)
+	}
+
+	var files []string
+	if len(candidates) > 0 {
+		safetyResult, err := ds.ValidateDeletionRequest(&DeletionRequest{
+			Files:       candidates,
+			Operation:   plan.Operation,
+			ForceDelete: forceDelete,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to validate deletion plan: %w", err)
+		}
+		if !safetyResult.IsSafe && !forceDelete {
+			return nil, fmt.Errorf("deletion plan %s is blocked: deletion blocked due to safety concerns, force delete required", plan.ID)
+		}
+		for _, path := range append(safetyResult.BlockedFiles, safetyResult.RiskyFiles...) {
+			refused[path] = "blocked now: " + safetyResult.BlockReasons[path]
+		}
+		for _, path := range candidates {
+			if _, ok := refused[path]; !ok {
+				files = append(files, path)
+			}
+		}
+	}
+
+	ds.logger.LogInfo("Executing deletion plan", map[string]interface{}{
+		"plan_id":   plan.ID,
+		"operation": plan.Operation,
+		"mode":      plan.Mode,
+		"files":     len(files),
+		"changed":   len(changed),
+		"refused":   len(refused),
+	})
+
+	// The files passed validation just now, so they are deleted as listed
+	result, err := ds.DeleteFilesWithBackupAndTracker(&DeletionRequest{
+		Files:       files,
+		Operation:   plan.Operation,
+		ForceDelete: true,
+		Mode:        mode,
+		Atomic:      atomic,
+	}, tracker)
+	if result != nil {
+		skipped := len(changed)
+		for _, path := range changed {
+			result.SkippedFiles = append(result.SkippedFiles, path)
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Changed since plan %s, skipped: %s", plan.ID, path))
+		}
+		for _, item := range plan.Items {
+			if reason, ok := refused[item.Path]; ok && item.Action != PlanActionBlock {
+				skipped++
+				result.SkippedFiles = append(result.SkippedFiles, item.Path)
+				result.Warnings = append(result.Warnings, fmt.Sprintf("Refused by plan %s, %s: %s", plan.ID, reason, item.Path))
+			}
+		}
+		result.SkippedCount += skipped
+		result.TotalFiles += skipped
+	}
+	return result, err
+}
+
+// rememberPlan records a plan the service created, forgetting expired ones
+func (ds *DeletionService) rememberPlan(plan *DeletionPlan) {
+	issued := &issuedPlan{
+		createdAt:   plan.CreatedAt,
+		mode:        plan.Mode,
+		forceDelete: plan.ForceDelete,
+		atomic:      plan.Atomic,
+		items:       make(map[string]bool, len(plan.Items)),
+	}
+	for _, item := range plan.Items {
+		issued.items[item.Path] = issued.items[item.Path] || item.Action == PlanActionBlock
+	}
+
+	ds.plansMu.Lock()
+	defer ds.plansMu.Unlock()
+	for id, old := range ds.plans {
+		if time.Since(old.createdAt) > issuedPlanTTL {
+			delete(ds.plans, id)
+		}
+	}
+	ds.plans[plan.ID] = issued
+}
+
+// issuedPlan returns the record of a plan the service created, nil if unknown
+// or expired
+func (ds *DeletionService) issuedPlan(id string) *issuedPlan {
+	ds.plansMu.Lock()
+	defer ds.plansMu.Unlock()
+	issued := ds.plans[id]
+	if issued == nil || time.Since(issued.createdAt) > issuedPlanTTL {
+		return nil
+	}
+	return issued
+}
+
+// changed reports whether the path no longer matches the plan
+func (item *PlanItem) changed() bool {
+	info, err := os.Lstat(item.Path)
+	if err != nil || info.IsDir() != item.IsDir || !info.ModTime().Equal(item.ModTime) {
+		return true
+	}
+	return info.Mode().IsRegular() && info.Size() != item.Size
+}
+
+// buildPlan turns a validated request into a plan. Files blocked by validation
+// are never acted on, even when the request is forced.
+func (ds *DeletionService) buildPlan(request *DeletionRequest, safetyResult *SafetyCheckResult) *DeletionPlan {
+	mode := request.Mode
+	if mode == "" || mode == DeletionModeDryRun {
+		mode = DeletionModeBackup
+	}
+
+	plan := &DeletionPlan{
+		ID:          fmt.Sprintf("plan_%d", time.Now().UnixNano()),
+		CreatedAt:   time.Now(),
+		Operation:   request.Operation,
+		Mode:        mode,
+		ForceDelete: request.ForceDelete,
+		Atomic:      request.Atomic,
+		Items:       make([]PlanItem, 0, len(request.Files)),
+		Warnings:    append([]string{}, safetyResult.Warnings...),
+	}
+	if !safetyResult.IsSafe && !request.ForceDelete {
+		plan.Blocked = true
+		plan.BlockReason = "deletion blocked due to safety concerns, force delete required"
+	}
+
+	blocked := make(map[string]bool, len(safetyResult.BlockedFiles)+len(safetyResult.RiskyFiles))
+	for _, path := range safetyResult.BlockedFiles {
+		blocked[path] = true
+	}
+	for _, path := range safetyResult.RiskyFiles {
+		blocked[path] = true
+	}
+
+	var quarantineRoot os.FileInfo
+	if quarantine := ds.GetQuarantine(); mode == DeletionModeQuarantine && quarantine != nil {
+		quarantineRoot, _ = os.Stat(quarantine.GetRoot())
+	}
+
+	inodes := make(map[fileID]*planInode)
+	for _, path := range request.Files {
+		if blocked[path] {
+			plan.Items = append(plan.Items, PlanItem{Path: path, Action: PlanActionBlock, Reason: safetyResult.BlockReasons[path]})
+			continue
+		}
+
+		info, err := os.Lstat(path)
+		if err != nil {
+			plan.Items = append(plan.Items, PlanItem{Path: path, Action: PlanActionBlock, Reason: "file not found"})
+			continue
+		}
+		plan.Items = append(plan.Items, PlanItem{Path: path, Action: planAction(mode), IsDir: info.IsDir(), ModTime: info.ModTime()})
+		index := len(plan.Items) - 1
+
+		// Quarantining across devices copies the data into the quarantine root
+		copied := mode == DeletionModeBackup
+		if quarantineRoot != nil {
+			rootID, _, _, ok := diskUsage(quarantineRoot)
+			pathID, _, _, ok2 := diskUsage(info)
+			copied = ok && ok2 && rootID.dev != pathID.dev
+		}
+
+		size, entries := measurePlanItem(path, index, plan, inodes)
+		plan.Items[index].Size, plan.Items[index].Entries = size, entries
+		plan.TotalSize += size
+
+		plan.EstimatedDuration += time.Duration(entries) * planEntryCost
+		if copied {
+			plan.BackupSpaceRequired += size
+			plan.EstimatedDuration += time.Duration(entries)*planBackupCost +
+				time.Duration(float64(size)/planCopyBandwidth*float64(time.Second))
+		}
+	}
+
+	// An inode is only released when every one of its links is removed
+	for _, inode := range inodes {
+		if inode.removed < inode.links {
+			plan.SharedBytes += inode.bytes
+			continue
+		}
+		plan.Items[inode.owner].DiskBytes += inode.bytes
+	}
+	for _, item := range plan.Items {
+		switch item.Action {
+		case PlanActionBackupAndDelete, PlanActionDelete:
+			plan.PredictedFreedBytes += item.DiskBytes
+		case PlanActionQuarantine, PlanActionTrash:
+			plan.DeferredFreedBytes += item.DiskBytes
+		}
+	}
+	if plan.SharedBytes > 0 {
+		plan.Warnings = append(plan.Warnings,
+			fmt.Sprintf("%d bytes stay on disk because the files have hard links outside the plan", plan.SharedBytes))
+	}
+
+	ds.rememberPlan(plan)
+	ds.logger.LogInfo("Deletion plan created", map[string]interface{}{
+		"plan_id":               plan.ID,
+		"operation":             plan.Operation,
+		"mode":                  plan.Mode,
+		"items":                 len(plan.Items),
+		"blocked":               plan.Blocked,
+		"predicted_freed_bytes": plan.PredictedFreedBytes,
+		"backup_space_required": plan.BackupSpaceRequired,
+	})
+
+	return plan
+}
+
+// measurePlanItem walks a path without following symlinks and returns the
+// apparent size of its regular files and its entry count. Allocated bytes are
+// credited to the item directly or, for regular files, through inodes.
+func measurePlanItem(path string, index int, plan *DeletionPlan, inodes map[fileID]*planInode) (int64, int) {
+	var size int64
+	entries := 0
+	filepath.WalkDir(path, func(walkPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entries++
+
+		id, links, bytes, ok := diskUsage(info)
+		if !ok {
+			bytes = info.Size()
+		}
+		if !info.Mode().IsRegular() {
+			plan.Items[index].DiskBytes += bytes
+			return nil
+		}
+		size += info.Size()
+		if !ok || links <= 1 {
+			plan.Items[index].DiskBytes += bytes
+			return nil
+		}
+
+		inode, seen := inodes[id]
+		if !seen {
+			inode = &planInode{links: links, bytes: bytes, owner: index}
+			inodes[id] = inode
+		}
+		inode.removed++
+		return nil
+	})
+	return size, entries
+}
+
+// planAction maps a deletion mode to the action applied to each path
+func planAction(mode DeletionMode) PlanAction {
+	switch mode {
+	case DeletionModeNoBackup:
+		return PlanActionDelete
+	case DeletionModeQuarantine:
+		return PlanActionQuarantine
+	case DeletionModeTrash:
+		return PlanActionTrash
+	default:
+		return PlanActionBackupAndDelete
+	}
+}
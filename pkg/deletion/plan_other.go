@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package deletion
+
+import "os"
+
+// diskUsage is unavailable without a Unix stat; plans fall back to apparent sizes
+func diskUsage(info os.FileInfo) (fileID, uint64, int64, bool) {
+	return fileID{}, 0, 0, false
+}
@@ -0,0 +1,214 @@
+//go:build linux || darwin
+
+package deletion
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cache_app/pkg/safety"
+)
+
+func TestDeletionPlan(t *testing.T) {
+	cacheDir := t.TempDir()
+	content := strings.Repeat("x", 8192)
+	write := func(name string) string {
+		path := filepath.Join(cacheDir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		return path
+	}
+	single := write("single.bin")
+	tree := filepath.Dir(filepath.Dir(write("tree/nested/entry.bin")))
+	linked := write("linked.bin")
+	linkedCopy := filepath.Join(cacheDir, "linked-copy.bin")
+	shared := write("shared.bin")
+	changed := write("changed.bin")
+	missing := filepath.Join(cacheDir, "missing.bin")
+	for link, target := range map[string]string{linkedCopy: linked, filepath.Join(t.TempDir(), "outside.bin"): shared} {
+		if err := os.Link(target, link); err != nil {
+			t.Fatalf("Failed to create hard link: %v", err)
+		}
+	}
+	files := []string{single, tree, linked, linkedCopy, shared, changed, missing}
+
+	ds := newTestDeletionService(t)
+	result, err := ds.DeleteFilesWithBackup(&DeletionRequest{
+		Files:       files,
+		Operation:   "plan_test",
+		ForceDelete: true,
+		DryRun:      true,
+	})
+	if err != nil || result.Plan == nil {
+		t.Fatalf("Dry run failed: %v (%+v)", err, result)
+	}
+	if result.BackupSessionID != "" {
+		t.Errorf("Dry run must not create backup %s", result.BackupSessionID)
+	}
+	for _, path := range files[:len(files)-1] {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Dry run must not remove %s: %v", path, err)
+		}
+	}
+
+	plan := result.Plan
+	if plan.Mode != DeletionModeBackup || plan.Blocked {
+		t.Errorf("Unexpected plan mode %s, blocked %v", plan.Mode, plan.Blocked)
+	}
+	if last := plan.Items[len(plan.Items)-1]; last.Action != PlanActionBlock || last.Reason != "file not found" {
+		t.Errorf("Expected the missing file to be blocked, got %+v", last)
+	}
+	if len(plan.Files()) != len(files)-1 {
+		t.Errorf("Expected %d files to act on, got %v", len(files)-1, plan.Files())
+	}
+	if plan.TotalSize != 6*8192 || plan.BackupSpaceRequired != plan.TotalSize {
+		t.Errorf("Unexpected sizes: total %d, backup %d", plan.TotalSize, plan.BackupSpaceRequired)
+	}
+
+	// Both links of linked.bin are in the plan, shared.bin keeps a link elsewhere
+	byPath := make(map[string]PlanItem)
+	for _, item := range plan.Items {
+		byPath[item.Path] = item
+	}
+	if byPath[linked].DiskBytes+byPath[linkedCopy].DiskBytes != byPath[single].DiskBytes {
+		t.Errorf("Hard linked file should be freed exactly once: %+v %+v", byPath[linked], byPath[linkedCopy])
+	}
+	if byPath[shared].DiskBytes != 0 || plan.SharedBytes != byPath[single].DiskBytes {
+		t.Errorf("File linked outside the plan must not be freed: %+v, shared %d", byPath[shared], plan.SharedBytes)
+	}
+	if byPath[tree].Entries != 3 || !byPath[tree].IsDir {
+		t.Errorf("Unexpected directory item %+v", byPath[tree])
+	}
+	if plan.PredictedFreedBytes == 0 || plan.EstimatedDuration <= 0 {
+		t.Errorf("Expected freed bytes and a duration estimate, got %+v", plan)
+	}
+
+	// A plan survives serialization and executes as reviewed
+	data, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatalf("Failed to marshal plan: %v", err)
+	}
+	var decoded DeletionPlan
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal plan: %v", err)
+	}
+
+	os.WriteFile(changed, []byte("rewritten"), 0644)
+	executed, err := ds.ExecutePlan(&decoded, nil)
+	if err != nil || executed.Status != "completed" {
+		t.Fatalf("Plan execution failed: %v (%+v)", err, executed)
+	}
+	if executed.DeletedCount != 5 || executed.FailedCount != 0 || executed.SkippedCount != 1 || executed.SkippedFiles[0] != changed {
+		t.Errorf("Expected 5 deleted and the changed file skipped, got %+v", executed)
+	}
+	if executed.BackupSessionID == "" {
+		t.Error("Executing a backup plan should create a backup")
+	}
+	if _, err := os.Stat(changed); err != nil {
+		t.Errorf("Changed file must be kept: %v", err)
+	}
+	if _, err := os.Stat(single); !os.IsNotExist(err) {
+		t.Error("Planned file should have been deleted")
+	}
+}
+
+func TestBlockedDeletionPlan(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file.bin")
+	os.WriteFile(file, []byte("content"), 0644)
+
+	ds := newTestDeletionService(t)
+	plan := ds.buildPlan(&DeletionRequest{Files: []string{file}}, &SafetyCheckResult{
+		RiskyFiles:   []string{file},
+		BlockReasons: map[string]string{file: "classified as risky, force delete required"},
+	})
+	if !plan.Blocked || plan.Items[0].Action != PlanActionBlock || plan.Items[0].Reason == "" {
+		t.Fatalf("Expected a blocked plan, got %+v", plan)
+	}
+	if _, err := ds.ExecutePlan(plan, nil); err == nil {
+		t.Error("Blocked plans must not execute")
+	}
+
+	if _, err := ds.PlanDeletion(&DeletionRequest{Files: []string{file}, Mode: DeletionModeQuarantine}); err == nil {
+		t.Error("Planning must fail when the mode is unavailable")
+	}
+
+	// Plans the service did not create, or no longer remembers, are refused
+	unknown := &DeletionPlan{ID: "plan_unknown", Mode: DeletionModeNoBackup, Items: []PlanItem{{
+		Path: file, Action: PlanActionDelete,
+	}}}
+	if _, err := ds.ExecutePlan(unknown, nil); err == nil || !strings.Contains(err.Error(), "create a new plan") {
+		t.Errorf("Expected unknown plan to be refused, got %v", err)
+	}
+	expired := ds.buildPlan(&DeletionRequest{Files: []string{file}, Mode: DeletionModeNoBackup}, &SafetyCheckResult{IsSafe: true})
+	ds.plans[expired.ID].createdAt = time.Now().Add(-issuedPlanTTL - time.Minute)
+	if _, err := ds.ExecutePlan(expired, nil); err == nil || !strings.Contains(err.Error(), "unknown or expired") {
+		t.Errorf("Expected expired plan to be refused, got %v", err)
+	}
+
+	// Executing a stale plan skips everything
+	stale := ds.buildPlan(&DeletionRequest{Files: []string{file}, Mode: DeletionModeNoBackup}, &SafetyCheckResult{IsSafe: true})
+	os.Chtimes(file, time.Now().Add(time.Hour), time.Now().Add(time.Hour))
+	result, err := ds.ExecutePlan(stale, nil)
+	if err != nil || result.SkippedCount != 1 || result.DeletedCount != 0 {
+		t.Errorf("Expected the stale file to be skipped, got %+v (%v)", result, err)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("Stale plan must not remove files: %v", err)
+	}
+}
+
+func TestTamperedDeletionPlan(t *testing.T) {
+	dir := t.TempDir()
+	risky := filepath.Join(dir, "risky.bin")
+	safe := filepath.Join(dir, "safe.bin")
+	denied := filepath.Join(dir, "denied.bin")
+	for _, file := range []string{risky, safe, denied} {
+		os.WriteFile(file, []byte("content"), 0644)
+	}
+
+	ds := newTestDeletionService(t)
+	plan := ds.buildPlan(&DeletionRequest{Files: []string{risky, safe, denied}, ForceDelete: true}, &SafetyCheckResult{
+		RiskyFiles:   []string{risky},
+		BlockReasons: map[string]string{risky: "classified as risky, force delete required"},
+	})
+	if plan.Blocked || plan.Items[0].Action != PlanActionBlock {
+		t.Fatalf("Expected a forced plan with a blocked item, got %+v", plan)
+	}
+
+	// A client unblocks the risky item, adds a path and skips the backup
+	data, _ := json.Marshal(plan)
+	var tampered DeletionPlan
+	json.Unmarshal(data, &tampered)
+	tampered.Items[0].Action = PlanActionDelete
+	tampered.Items = append(tampered.Items, PlanItem{Path: "/etc/passwd", Action: PlanActionDelete})
+	tampered.Mode = DeletionModeNoBackup
+
+	// A deny rule added after planning blocks a path now
+	rules, err := safety.NewPathRules([]safety.PathRule{{Pattern: denied, Action: safety.RuleDeny}}, "")
+	if err != nil {
+		t.Fatalf("Failed to compile path rules: %v", err)
+	}
+	ds.SetPathRules(rules)
+
+	result, err := ds.ExecutePlan(&tampered, nil)
+	if err != nil {
+		t.Fatalf("Plan execution failed: %v", err)
+	}
+	if result.DeletedCount != 1 || result.SkippedCount != 3 || result.BackupSessionID == "" {
+		t.Errorf("Expected only the safe file deleted with a backup, got %+v", result)
+	}
+	for _, file := range []string{risky, denied} {
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("Refused file %s must be kept: %v", file, err)
+		}
+	}
+	if _, err := os.Stat(safe); !os.IsNotExist(err) {
+		t.Error("Safe file should have been deleted")
+	}
+}
@@ -0,0 +1,18 @@
+//go:build linux || darwin
+
+package deletion
+
+import (
+	"os"
+	"syscall"
+)
+
+// diskUsage returns the inode, link count and allocated bytes of a file
+func diskUsage(info os.FileInfo) (fileID, uint64, int64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, 0, 0, false
+	}
+	id := fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}
+	return id, uint64(stat.Nlink), int64(stat.Blocks) * 512, true
+}
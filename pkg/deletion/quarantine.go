@@ -0,0 +1,568 @@
+package deletion
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DeletionMode selects how DeletionService removes files
+type DeletionMode string
+
+const (
+	// DeletionModeBackup copies files into the backup store and then deletes them
+	DeletionModeBackup DeletionMode = "backup"
+	// DeletionModeQuarantine moves files into a holding area from which they can be put back
+	DeletionModeQuarantine DeletionMode = "quarantine"
+)
+
+// How a quarantined item was moved
+const (
+	QuarantineMethodRename = "rename"
+	QuarantineMethodCopy   = "copy" // Source was on another device
+)
+
+const quarantineBatchFile = "batch.json"
+
+// errSourceNotRemoved is returned by moveFile when the destination holds a
+// complete copy but the source could not be removed afterwards
+var errSourceNotRemoved = errors.New("source not removed after copying")
+
+// DefaultQuarantineHoldPeriod is how long quarantined files are kept before they are purged
+const DefaultQuarantineHoldPeriod = 7 * 24 * time.Hour
+
+// Quarantine keeps deleted files in a holding area for a limited time. Files
+// are renamed into the area, which is atomic and needs no extra space when it
+// is on the same filesystem, and copied otherwise.
+type Quarantine struct {
+	root       string
+	holdPeriod time.Duration
+	mu         sync.Mutex
+	now        func() time.Time
+	stopPurge  chan struct{}
+}
+
+// QuarantineItem is a single file or directory in a quarantine batch
+type QuarantineItem struct {
+	OriginalPath string `json:"original_path"`
+	StoredPath   string `json:"stored_path"`
+	Size         int64  `json:"size"`
+	IsDir        bool   `json:"is_dir"`
+	Method       string `json:"method,omitempty"`
+	Success      bool   `json:"success"`
+	Restored     bool   `json:"restored,omitempty"`
+	Error        string `json:"error,omitempty"` // A warning when Success is set
+}
+
+// QuarantineBatch groups the items quarantined by one deletion operation
+type QuarantineBatch struct {
+	ID        string           `json:"id"`
+	Operation string           `json:"operation"`
+	CreatedAt time.Time        `json:"created_at"`
+	ExpiresAt time.Time        `json:"expires_at"`
+	TotalSize int64            `json:"total_size"`
+	Items     []QuarantineItem `json:"items"`
+}
+
+// QuarantineUndoResult reports which items of a batch were put back
+type QuarantineUndoResult struct {
+	BatchID       string   `json:"batch_id"`
+	RestoredFiles []string `json:"restored_files"`
+	FailedFiles   []string `json:"failed_files"`
+	Errors        []string `json:"errors"`
+	Remaining     int      `json:"remaining"` // Items still held in quarantine
+}
+
+// NewQuarantine creates a quarantine area in root. A non-positive holdPeriod uses the default.
+func NewQuarantine(root string, holdPeriod time.Duration) (*Quarantine, error) {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+	if holdPeriod <= 0 {
+		holdPeriod = DefaultQuarantineHoldPeriod
+	}
+	return &Quarantine{root: root, holdPeriod: holdPeriod, now: time.Now}, nil
+}
+
+// GetRoot returns the quarantine directory
+func (q *Quarantine) GetRoot() string {
+	return q.root
+}
+
+// SetHoldPeriod changes how long newly quarantined batches are kept
+func (q *Quarantine) SetHoldPeriod(holdPeriod time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if holdPeriod <= 0 {
+		holdPeriod = DefaultQuarantineHoldPeriod
+	}
+	q.holdPeriod = holdPeriod
+}
+
+// Add moves paths into a new batch. The batch file is rewritten after every
+// item so that an interrupted run can still be undone. onItem is called after
+// each path and stops the batch early by returning false.
+func (q *Quarantine) Add(paths []string, operation string, onItem func(index int, item QuarantineItem) bool) (*QuarantineBatch, error) {
+	batch, err := q.begin(operation)
+	if err != nil {
+		return nil, err
+	}
+	defer q.finish(batch)
+
+	for i, path := range paths {
+		item, err := q.addItem(batch, path)
+		if err != nil {
+			return batch, err
+		}
+		if onItem != nil && !onItem(i, item) {
+			break
+		}
+	}
+	return batch, nil
+}
+
+// begin creates an empty batch
+func (q *Quarantine) begin(operation string) (*QuarantineBatch, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	batch := &QuarantineBatch{
+		ID:        fmt.Sprintf("quarantine_%d", now.UnixNano()),
+		Operation: operation,
+		CreatedAt: now,
+		ExpiresAt: now.Add(q.holdPeriod),
+		Items:     make([]QuarantineItem, 0),
+	}
+
+	if err := os.MkdirAll(filepath.Join(q.root, batch.ID, "items"), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create quarantine batch: %w", err)
+	}
+	return batch, nil
+}
+
+// addItem moves path into the batch and saves the batch file. The returned
+// error is only set when the batch file could not be written.
+func (q *Quarantine) addItem(batch *QuarantineBatch, path string) (QuarantineItem, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	item := QuarantineItem{
+		OriginalPath: path,
+		StoredPath:   filepath.Join(q.root, batch.ID, "items", fmt.Sprintf("%06d_%s", len(batch.Items), filepath.Base(path))),
+	}
+
+	if info, err := os.Lstat(path); err != nil {
+		item.Error = fmt.Sprintf("failed to get file info: %v", err)
+	} else {
+		item.IsDir = info.IsDir()
+		item.Size = treeSize(path, info)
+		method, err := moveFile(path, item.StoredPath)
+		item.Method = method
+		if errors.Is(err, errSourceNotRemoved) {
+			// The copy is complete, so it is held like any other item
+			item.Error = err.Error()
+			item.Success = true
+			batch.TotalSize += item.Size
+		} else if err != nil {
+			item.Error = err.Error()
+		} else {
+			item.Success = true
+			batch.TotalSize += item.Size
+		}
+	}
+
+	batch.Items = append(batch.Items, item)
+	return item, q.saveBatch(batch)
+}
+
+// finish removes the batch again when nothing in it is held
+func (q *Quarantine) finish(batch *QuarantineBatch) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !batch.hasHeldItems() {
+		os.RemoveAll(filepath.Join(q.root, batch.ID))
+	}
+}
+
+// hasHeldItems reports whether any item of the batch is still in quarantine
+func (b *QuarantineBatch) hasHeldItems() bool {
+	for _, item := range b.Items {
+		if item.Success && !item.Restored {
+			return true
+		}
+	}
+	return false
+}
+
+// Undo moves the items of a batch back to their original locations. Items
+// whose original path has been recreated are left in quarantine.
+func (q *Quarantine) Undo(batchID string) (*QuarantineUndoResult, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	batch, err := q.loadBatch(batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &QuarantineUndoResult{
+		BatchID:       batchID,
+		RestoredFiles: make([]string, 0),
+		FailedFiles:   make([]string, 0),
+		Errors:        make([]string, 0),
+	}
+
+	for i := range batch.Items {
+		item := &batch.Items[i]
+		if !item.Success || item.Restored {
+			continue
+		}
+
+		err := func() error {
+			if _, err := os.Lstat(item.OriginalPath); err == nil {
+				return fmt.Errorf("%s already exists", item.OriginalPath)
+			}
+			if err := os.MkdirAll(filepath.Dir(item.OriginalPath), 0755); err != nil {
+				return fmt.Errorf("failed to create parent directory: %w", err)
+			}
+			// A stored copy left behind is removed with the batch
+			if _, err := moveFile(item.StoredPath, item.OriginalPath); err != nil && !errors.Is(err, errSourceNotRemoved) {
+				return err
+			}
+			return nil
+		}()
+		if err != nil {
+			result.FailedFiles = append(result.FailedFiles, item.OriginalPath)
+			result.Errors = append(result.Errors, err.Error())
+			result.Remaining++
+			continue
+		}
+
+		item.Restored = true
+		result.RestoredFiles = append(result.RestoredFiles, item.OriginalPath)
+	}
+
+	if result.Remaining == 0 {
+		if err := os.RemoveAll(filepath.Join(q.root, batchID)); err != nil {
+			return result, fmt.Errorf("failed to remove quarantine batch: %w", err)
+		}
+		return result, nil
+	}
+	return result, q.saveBatch(batch)
+}
+
+// List returns all batches in quarantine, newest first
+func (q *Quarantine) List() ([]QuarantineBatch, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.listBatches()
+}
+
+// Purge permanently deletes a batch
+func (q *Quarantine) Purge(batchID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, err := q.loadBatch(batchID); err != nil {
+		return err
+	}
+	if err := removeBatchDir(filepath.Join(q.root, batchID)); err != nil {
+		return fmt.Errorf("failed to purge quarantine batch: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpired permanently deletes batches whose holding period has ended.
+// A batch that cannot be removed does not stop the others from being purged;
+// the failures are returned together.
+func (q *Quarantine) PurgeExpired() ([]string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	batches, err := q.listBatches()
+	if err != nil {
+		return nil, err
+	}
+
+	now := q.now()
+	var purged []string
+	var errs []error
+	for _, batch := range batches {
+		if now.Before(batch.ExpiresAt) {
+			continue
+		}
+		if err := removeBatchDir(filepath.Join(q.root, batch.ID)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to purge quarantine batch %s: %w", batch.ID, err))
+			continue
+		}
+		purged = append(purged, batch.ID)
+	}
+	return purged, errors.Join(errs...)
+}
+
+// removeBatchDir removes a batch directory, making its directories writable
+// first since quarantined trees keep their original read-only modes
+func removeBatchDir(dir string) error {
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.IsDir() {
+			os.Chmod(path, 0700)
+		}
+		return nil
+	})
+	return os.RemoveAll(dir)
+}
+
+// StartAutoPurge purges expired batches every interval until StopAutoPurge is called
+func (q *Quarantine) StartAutoPurge(interval time.Duration, onError func(error)) {
+	q.mu.Lock()
+	if q.stopPurge != nil {
+		q.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	q.stopPurge = stop
+	q.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := q.PurgeExpired(); err != nil && onError != nil {
+				onError(err)
+			}
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopAutoPurge stops the automatic purge started by StartAutoPurge
+func (q *Quarantine) StopAutoPurge() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.stopPurge != nil {
+		close(q.stopPurge)
+		q.stopPurge = nil
+	}
+}
+
+// listBatches reads every batch file in the quarantine area
+func (q *Quarantine) listBatches() ([]QuarantineBatch, error) {
+	dirEntries, err := os.ReadDir(q.root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quarantine directory: %w", err)
+	}
+
+	var batches []QuarantineBatch
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		batch, err := q.loadBatch(dirEntry.Name())
+		if err != nil {
+			continue
+		}
+		batches = append(batches, *batch)
+	}
+
+	sort.Slice(batches, func(i, j int) bool {
+		return batches[i].CreatedAt.After(batches[j].CreatedAt)
+	})
+	return batches, nil
+}
+
+// loadBatch reads the batch file of a batch
+func (q *Quarantine) loadBatch(batchID string) (*QuarantineBatch, error) {
+	if batchID == "" || filepath.Base(batchID) != batchID {
+		return nil, fmt.Errorf("invalid quarantine batch ID: %s", batchID)
+	}
+
+	data, err := os.ReadFile(filepath.Join(q.root, batchID, quarantineBatchFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("quarantine batch %s not found", batchID)
+		}
+		return nil, fmt.Errorf("failed to read quarantine batch: %w", err)
+	}
+
+	var batch QuarantineBatch
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return nil, fmt.Errorf("failed to parse quarantine batch: %w", err)
+	}
+	return &batch, nil
+}
+
+// saveBatch atomically writes the batch file of a batch
+func (q *Quarantine) saveBatch(batch *QuarantineBatch) error {
+	data, err := json.MarshalIndent(batch, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal quarantine batch: %w", err)
+	}
+
+	path := filepath.Join(q.root, batch.ID, quarantineBatchFile)
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		return fmt.Errorf("failed to write quarantine batch: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to write quarantine batch: %w", err)
+	}
+	return nil
+}
+
+// moveFile renames src to dst, copying and then removing src when they are on
+// different devices. If src cannot be removed after copying, the error wraps
+// errSourceNotRemoved and dst is kept.
+func moveFile(src, dst string) (string, error) {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return QuarantineMethodRename, nil
+	}
+	if !isCrossDevice(err) {
+		return QuarantineMethodRename, fmt.Errorf("failed to move %s: %w", src, err)
+	}
+
+	if err := copyTree(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return QuarantineMethodCopy, fmt.Errorf("failed to copy %s across devices: %w", src, err)
+	}
+	if err := os.RemoveAll(src); err != nil {
+		return QuarantineMethodCopy, fmt.Errorf("%w: %s: %v", errSourceNotRemoved, src, err)
+	}
+	return QuarantineMethodCopy, nil
+}
+
+// isCrossDevice reports whether a rename failed because source and destination are on different devices
+func isCrossDevice(err error) bool {
+	if errors.Is(err, syscall.EXDEV) {
+		return true
+	}
+	// Windows reports ERROR_NOT_SAME_DEVICE instead
+	var errno syscall.Errno
+	return runtime.GOOS == "windows" && errors.As(err, &errno) && errno == 17
+}
+
+// copyTree copies a file, symlink or directory tree, preserving modes and modification times
+func copyTree(src, dst string) error {
+	var dirs []string
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			if err := os.MkdirAll(target, 0700); err != nil {
+				return err
+			}
+			dirs = append(dirs, path)
+			return nil
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case !info.Mode().IsRegular():
+			return fmt.Errorf("unsupported file type: %s", path)
+		}
+
+		if err := copyRegularFile(path, target, info.Mode().Perm()); err != nil {
+			return err
+		}
+		return os.Chtimes(target, info.ModTime(), info.ModTime())
+	})
+	if err != nil {
+		return err
+	}
+
+	// Apply directory modes and times after their contents are written, deepest first
+	for i := len(dirs) - 1; i >= 0; i-- {
+		info, err := os.Stat(dirs[i])
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, dirs[i])
+		target := filepath.Join(dst, rel)
+		os.Chmod(target, info.Mode().Perm())
+		os.Chtimes(target, info.ModTime(), info.ModTime())
+	}
+	return nil
+}
+
+// copyRegularFile copies the content of a regular file
+func copyRegularFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// treeSize returns the size of a file or the total size of the files below a directory
+func treeSize(path string, info os.FileInfo) int64 {
+	if !info.IsDir() {
+		return info.Size()
+	}
+	var size int64
+	filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// UndoQuarantine moves the files of a quarantine batch back to their original locations
+func (ds *DeletionService) UndoQuarantine(batchID string) (*QuarantineUndoResult, error) {
+	if ds.IsDeleting() {
+		return nil, fmt.Errorf("deletion in progress, cannot undo quarantine")
+	}
+
+	quarantine := ds.GetQuarantine()
+	if quarantine == nil {
+		return nil, fmt.Errorf("quarantine is not available")
+	}
+
+	result, err := quarantine.Undo(batchID)
+	if err != nil {
+		ds.logger.LogError("Quarantine undo failed", err, map[string]interface{}{"batch_id": batchID})
+		return result, err
+	}
+
+	ds.logger.LogInfo("Quarantine undo completed", map[string]interface{}{
+		"batch_id":       batchID,
+		"restored_count": len(result.RestoredFiles),
+		"failed_count":   len(result.FailedFiles),
+	})
+	return result, nil
+}
@@ -0,0 +1,167 @@
+package deletion
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cache_app/pkg/backup"
+)
+
+// newTestDeletionService creates a deletion service that keeps its backups and logs in temporary directories
+func newTestDeletionService(t *testing.T) *DeletionService {
+	t.Helper()
+	backupSystem, err := backup.NewBackupSystemWithDir(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create backup system: %v", err)
+	}
+	ds := NewDeletionServiceWithLogger(backupSystem, &DeletionLogger{
+		logFile: filepath.Join(t.TempDir(), "deletion.log"),
+		maxLogs: 100,
+	})
+	return ds
+}
+
+func TestQuarantineDeletionAndUndo(t *testing.T) {
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	file := filepath.Join(cacheDir, "blob.bin")
+	tree := filepath.Join(cacheDir, "tree")
+	if err := os.MkdirAll(filepath.Join(tree, "nested"), 0755); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	os.WriteFile(file, []byte("blob"), 0644)
+	os.WriteFile(filepath.Join(tree, "nested", "entry"), []byte("nested entry"), 0644)
+
+	quarantine, err := NewQuarantine(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create quarantine: %v", err)
+	}
+	ds := newTestDeletionService(t)
+	ds.SetQuarantine(quarantine)
+
+	result, err := ds.DeleteFilesWithBackup(&DeletionRequest{
+		Files:       []string{file, tree},
+		Operation:   "quarantine_test",
+		ForceDelete: true,
+		Mode:        DeletionModeQuarantine,
+	})
+	if err != nil || result.DeletedCount != 2 {
+		t.Fatalf("Quarantine failed: %v (%+v)", err, result)
+	}
+	if result.BackupSessionID != "" || result.QuarantineBatchID == "" {
+		t.Errorf("Expected a quarantine batch and no backup session, got %+v", result)
+	}
+	if result.DeletedSize != int64(len("blob")+len("nested entry")) {
+		t.Errorf("Unexpected deleted size %d", result.DeletedSize)
+	}
+	for _, path := range []string{file, tree} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("%s should have been moved to quarantine", path)
+		}
+	}
+
+	batches, err := quarantine.List()
+	if err != nil || len(batches) != 1 || batches[0].Items[0].Method != QuarantineMethodRename {
+		t.Fatalf("Expected one renamed batch, got %+v (%v)", batches, err)
+	}
+
+	// Recreated paths are not overwritten by undo
+	os.WriteFile(file, []byte("new"), 0644)
+	undo, err := ds.UndoQuarantine(result.QuarantineBatchID)
+	if err != nil || len(undo.RestoredFiles) != 1 || undo.Remaining != 1 {
+		t.Fatalf("Expected partial undo, got %+v (%v)", undo, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tree, "nested", "entry")); string(data) != "nested entry" {
+		t.Errorf("Directory not restored, got %q", data)
+	}
+
+	os.Remove(file)
+	undo, err = ds.UndoQuarantine(result.QuarantineBatchID)
+	if err != nil || len(undo.RestoredFiles) != 1 || undo.Remaining != 0 {
+		t.Fatalf("Expected remaining item to be restored, got %+v (%v)", undo, err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "blob" {
+		t.Errorf("File not restored, got %q", data)
+	}
+	if batches, _ := quarantine.List(); len(batches) != 0 {
+		t.Error("Fully restored batch should be removed")
+	}
+}
+
+func TestQuarantinePurgeExpired(t *testing.T) {
+	dir := t.TempDir()
+	quarantine, err := NewQuarantine(filepath.Join(dir, "quarantine"), 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create quarantine: %v", err)
+	}
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	quarantine.now = func() time.Time { return now }
+
+	// Quarantined trees keep read-only directories, which must not stop a purge
+	readOnly := filepath.Join(dir, "read-only")
+	os.MkdirAll(filepath.Join(readOnly, "nested"), 0755)
+	os.WriteFile(filepath.Join(readOnly, "nested", "entry"), []byte("entry"), 0644)
+	os.Chmod(filepath.Join(readOnly, "nested"), 0555)
+	os.Chmod(readOnly, 0555)
+	if _, err := quarantine.Add([]string{readOnly}, "purge_test", nil); err != nil {
+		t.Fatalf("Failed to quarantine read-only tree: %v", err)
+	}
+	now = now.Add(time.Second)
+
+	var batchIDs []string
+	for i, name := range []string{"old", "new"} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(name), 0644)
+		batch, err := quarantine.Add([]string{path}, "purge_test", nil)
+		if err != nil || !batch.Items[0].Success {
+			t.Fatalf("Failed to quarantine %s: %v", name, err)
+		}
+		batchIDs = append(batchIDs, batch.ID)
+		now = now.Add(time.Duration(i+1) * 12 * time.Hour)
+	}
+
+	// 36 hours after the first batches and 24 hours after the last
+	purged, err := quarantine.PurgeExpired()
+	if err != nil || len(purged) != 3 {
+		t.Fatalf("Expected all batches to expire, got %v (%v)", purged, err)
+	}
+
+	now = now.Add(-time.Hour)
+	os.WriteFile(filepath.Join(dir, "kept"), []byte("kept"), 0644)
+	batch, _ := quarantine.Add([]string{filepath.Join(dir, "kept")}, "purge_test", nil)
+	if purged, _ := quarantine.PurgeExpired(); len(purged) != 0 {
+		t.Errorf("Batch within its holding period was purged: %v", purged)
+	}
+	if err := quarantine.Purge(batch.ID); err != nil {
+		t.Errorf("Manual purge failed: %v", err)
+	}
+	if _, err := quarantine.Undo(batchIDs[0]); err == nil {
+		t.Error("Undo of a purged batch should fail")
+	}
+}
+
+func TestQuarantineCopyFallback(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "tree")
+	os.MkdirAll(filepath.Join(src, "sub"), 0750)
+	os.WriteFile(filepath.Join(src, "sub", "file"), []byte("content"), 0600)
+	if err := os.Symlink("sub/file", filepath.Join(src, "link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "copy")
+	if err := copyTree(src, dst); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dst, "link")); string(data) != "content" {
+		t.Errorf("Copied symlink resolves to %q", data)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "sub")); err != nil || info.Mode().Perm() != 0750 {
+		t.Errorf("Directory mode not preserved: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "sub", "file")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("File mode not preserved: %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package deletion
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"cache_app/pkg/backup"
+)
+
+// RollbackResult reports how files deleted by a failed atomic deletion were
+// restored from the session backup
+type RollbackResult struct {
+	Reason        string        `json:"reason"`    // Failure or cancellation that triggered the rollback
+	Completed     bool          `json:"completed"` // Every affected path is back in place
+	RestoredFiles []string      `json:"restored_files"`
+	FailedFiles   []string      `json:"failed_files"`
+	Error         string        `json:"error,omitempty"`
+	Duration      time.Duration `json:"duration"`
+}
+
+// unbackedFiles returns the files of an atomic request without a successful backup entry
+func unbackedFiles(session *backup.BackupSession, files []string) []string {
+	backedUp := make(map[string]bool, len(session.Entries))
+	for _, entry := range session.Entries {
+		if entry.Success {
+			backedUp[entry.OriginalPath] = true
+		}
+	}
+
+	var missing []string
+	for _, file := range files {
+		if !backedUp[file] {
+			missing = append(missing, file)
+		}
+	}
+	return missing
+}
+
+// rollbackDeletion restores paths from the session backup after an atomic
+// deletion failed or was cancelled. Paths that still exist, such as a
+// directory that was only partly removed, are merged without overwriting.
+func (ds *DeletionService) rollbackDeletion(request *DeletionRequest, sessionID string, paths []string, reason string, tracker *ProgressTracker) *RollbackResult {
+	rollback := &RollbackResult{
+		Reason:        reason,
+		RestoredFiles: make([]string, 0),
+		FailedFiles:   make([]string, 0),
+	}
+	startTime := time.Now()
+
+	ds.sendProgress(DeletionProgress{
+		Operation: request.Operation,
+		Status:    "rolling_back",
+		Message:   "Restoring deleted files from backup...",
+	})
+	if tracker != nil {
+		tracker.SetStatus("rolling_back", "Restoring deleted files from backup...")
+	}
+
+	ds.logger.LogWarning("Rolling back atomic deletion", map[string]interface{}{
+		"operation":  request.Operation,
+		"session_id": sessionID,
+		"reason":     reason,
+		"files":      paths,
+	})
+
+	if len(paths) > 0 {
+		restoreResult, err := ds.backupSystem.Restore(sessionID, backup.RestoreOptions{
+			Paths:    paths,
+			Conflict: backup.ConflictSkip,
+		})
+		if err != nil {
+			rollback.Error = err.Error()
+		}
+		if restoreResult != nil {
+			rollback.RestoredFiles = append(rollback.RestoredFiles, restoreResult.RestoredFiles...)
+			rollback.FailedFiles = append(rollback.FailedFiles, restoreResult.FailedFiles...)
+		}
+	}
+
+	// The rollback is only complete when every affected path exists again
+	for _, path := range paths {
+		if _, err := os.Lstat(path); err != nil && !slices.Contains(rollback.FailedFiles, path) {
+			rollback.FailedFiles = append(rollback.FailedFiles, path)
+		}
+	}
+	rollback.Completed = rollback.Error == "" && len(rollback.FailedFiles) == 0
+	rollback.Duration = time.Since(startTime)
+
+	if rollback.Completed {
+		ds.logger.LogInfo("Rollback completed", map[string]interface{}{
+			"session_id": sessionID,
+			"restored":   len(rollback.RestoredFiles),
+		})
+	} else {
+		ds.logger.LogError("Rollback incomplete", fmt.Errorf("%d files not restored", len(rollback.FailedFiles)), map[string]interface{}{
+			"session_id":   sessionID,
+			"failed_files": rollback.FailedFiles,
+			"error":        rollback.Error,
+		})
+	}
+
+	return rollback
+}
+
+// failAtomicDeletion rolls back the files removed so far plus the file that
+// was being removed, and records the outcome in result
+func (ds *DeletionService) failAtomicDeletion(strategy DeletionStrategy, result *DeletionResult, current, reason, status string) (*DeletionResult, error) {
+	paths := append([]string{}, result.DeletedFiles...)
+	if current != "" {
+		paths = append(paths, current)
+	}
+
+	result.Rollback = strategy.(rollbackStrategy).Rollback(paths, reason)
+	result.Status = status
+	result.EndTime = time.Now()
+	if result.Rollback.Completed {
+		result.Error = fmt.Sprintf("%s; all files were restored", reason)
+		return result, fmt.Errorf("%s, deletion rolled back", reason)
+	}
+	result.Error = fmt.Sprintf("%s; rollback incomplete, %d files could not be restored from backup %s",
+		reason, len(result.Rollback.FailedFiles), result.BackupSessionID)
+	return result, fmt.Errorf("%s, rollback incomplete", reason)
+}
@@ -0,0 +1,84 @@
+package deletion
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAtomicDeletionRollsBack(t *testing.T) {
+	cacheDir := t.TempDir()
+	first := filepath.Join(cacheDir, "first.bin")
+	tree := filepath.Join(cacheDir, "tree")
+	dangling := filepath.Join(cacheDir, "dangling")
+	last := filepath.Join(cacheDir, "last.bin")
+	os.MkdirAll(filepath.Join(tree, "nested"), 0755)
+	os.WriteFile(first, []byte("first"), 0644)
+	os.WriteFile(filepath.Join(tree, "nested", "entry"), []byte("entry"), 0644)
+	os.WriteFile(last, []byte("last"), 0644)
+	// Backed up as a symlink, but deleting it fails because its target is gone
+	if err := os.Symlink(filepath.Join(cacheDir, "missing"), dangling); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	ds := newTestDeletionService(t)
+	result, err := ds.DeleteFilesWithBackup(&DeletionRequest{
+		Files:       []string{first, tree, dangling, last},
+		Operation:   "atomic_test",
+		ForceDelete: true,
+		Atomic:      true,
+	})
+	if err == nil || result.Status != "failed" {
+		t.Fatalf("Expected the deletion to fail, got %+v (%v)", result, err)
+	}
+	if result.Rollback == nil || !result.Rollback.Completed {
+		t.Fatalf("Expected a completed rollback, got %+v", result.Rollback)
+	}
+	if len(result.Rollback.RestoredFiles) == 0 {
+		t.Error("Expected deleted files to be restored")
+	}
+
+	if data, _ := os.ReadFile(first); string(data) != "first" {
+		t.Errorf("File not rolled back, got %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tree, "nested", "entry")); string(data) != "entry" {
+		t.Errorf("Directory not rolled back, got %q", data)
+	}
+	if data, _ := os.ReadFile(last); string(data) != "last" {
+		t.Errorf("File after the failure should be untouched, got %q", data)
+	}
+	if _, err := os.Lstat(dangling); err != nil {
+		t.Errorf("Failed file should still exist: %v", err)
+	}
+}
+
+func TestAtomicDeletionRequiresBackup(t *testing.T) {
+	cacheDir := t.TempDir()
+	present := filepath.Join(cacheDir, "present.bin")
+	os.WriteFile(present, []byte("present"), 0644)
+
+	ds := newTestDeletionService(t)
+	result, err := ds.DeleteFilesWithBackup(&DeletionRequest{
+		Files:       []string{present, filepath.Join(cacheDir, "missing.bin")},
+		Operation:   "atomic_test",
+		ForceDelete: true,
+		Atomic:      true,
+	})
+	if err == nil || result.FailedCount != 1 || result.DeletedCount != 0 {
+		t.Fatalf("Expected the deletion to abort before deleting, got %+v (%v)", result, err)
+	}
+	if _, err := os.Stat(present); err != nil {
+		t.Errorf("No file should be deleted when a backup failed: %v", err)
+	}
+
+	_, err = ds.DeleteFilesWithBackup(&DeletionRequest{
+		Files:       []string{present},
+		Operation:   "atomic_test",
+		ForceDelete: true,
+		Atomic:      true,
+		Mode:        DeletionModeQuarantine,
+	})
+	if err == nil {
+		t.Error("Atomic deletion should be rejected outside backup mode")
+	}
+}
@@ -0,0 +1,444 @@
+package deletion
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"cache_app/pkg/backup"
+)
+
+const (
+	// DeletionModeNoBackup deletes files without a backup. It is not offered in
+	// the settings and has to be requested explicitly.
+	DeletionModeNoBackup DeletionMode = "no_backup"
+	// DeletionModeDryRun reports what would be deleted without touching anything
+	DeletionModeDryRun DeletionMode = "dry_run"
+)
+
+// DeletionStrategy removes the files of a validated deletion request. The
+// engine in DeleteFilesWithBackupAndTracker drives every strategy the same way:
+// Prepare once, Remove per file with progress and cancellation between files,
+// then Finish, which also runs when the run stops early.
+type DeletionStrategy interface {
+	// Mode identifies the strategy
+	Mode() DeletionMode
+	// Status is the progress status reported while files are removed
+	Status() string
+	// Prepare runs before the first file is removed
+	Prepare(files []string, result *DeletionResult) error
+	// Remove removes a single path and returns its size in bytes
+	Remove(path string, result *DeletionResult) (int64, error)
+	// Finish runs after the last file
+	Finish(result *DeletionResult)
+}
+
+// rollbackStrategy is implemented by strategies that can undo removed files,
+// which atomic requests require
+type rollbackStrategy interface {
+	Rollback(paths []string, reason string) *RollbackResult
+}
+
+// newStrategy creates the strategy for a request. Dry runs always use the
+// dry-run strategy so that nothing is backed up or removed.
+func (ds *DeletionService) newStrategy(request *DeletionRequest, tracker *ProgressTracker) (DeletionStrategy, error) {
+	mode := request.Mode
+	if mode == "" {
+		mode = DeletionModeBackup
+	}
+
+	var strategy DeletionStrategy
+	switch mode {
+	case DeletionModeBackup:
+		strategy = &backupStrategy{ds: ds, request: request, tracker: tracker}
+	case DeletionModeNoBackup:
+		strategy = &noBackupStrategy{ds: ds}
+	case DeletionModeQuarantine:
+		quarantine := ds.GetQuarantine()
+		if quarantine == nil {
+			return nil, fmt.Errorf("quarantine is not available")
+		}
+		strategy = &quarantineStrategy{ds: ds, request: request, quarantine: quarantine}
+	case DeletionModeTrash:
+		trash := ds.GetTrash()
+		if trash == nil {
+			return nil, fmt.Errorf("trash is not available")
+		}
+		strategy = &trashStrategy{trash: trash}
+	case DeletionModeDryRun:
+	default:
+		return nil, fmt.Errorf("unknown deletion mode: %s", mode)
+	}
+
+	if request.DryRun || mode == DeletionModeDryRun {
+		return &dryRunStrategy{}, nil
+	}
+	if _, ok := strategy.(rollbackStrategy); request.Atomic && !ok {
+		return nil, fmt.Errorf("atomic deletion is not supported in %s mode", mode)
+	}
+	return strategy, nil
+}
+
+// backupStrategy copies files into the backup store and deletes the originals
+// whose backup succeeded
+type backupStrategy struct {
+	ds      *DeletionService
+	request *DeletionRequest
+	tracker *ProgressTracker
+	session *backup.BackupSession
+	entries map[string]backup.BackupEntry
+}
+
+func (s *backupStrategy) Mode() DeletionMode { return DeletionModeBackup }
+
+func (s *backupStrategy) Status() string { return "deleting" }
+
+func (s *backupStrategy) Prepare(files []string, result *DeletionResult) error {
+	s.ds.sendProgress(DeletionProgress{
+		Operation:      s.request.Operation,
+		Status:         "backing_up",
+		Message:        "Creating mandatory backup...",
+		BackupProgress: 0,
+	})
+	if s.tracker != nil {
+		s.tracker.SetStatus("backing_up", "Creating mandatory backup...")
+		s.tracker.SetBackupProgress(0, "Creating mandatory backup...")
+	}
+
+	session, err := s.ds.backupSystem.BackupFilesForDeletion(files, s.request.Operation)
+	if err != nil {
+		if session != nil {
+			s.session = session
+			s.unlinkOriginals()
+		}
+		s.ds.logger.LogError("Backup failed", err, map[string]interface{}{
+			"operation": s.request.Operation,
+			"files":     files,
+		})
+		return fmt.Errorf("mandatory backup failed: %w", err)
+	}
+
+	s.session = session
+	s.entries = make(map[string]backup.BackupEntry, len(session.Entries))
+	for _, entry := range session.Entries {
+		s.entries[entry.OriginalPath] = entry
+	}
+
+	result.BackupSessionID = session.SessionID
+	result.BackedUpCount = session.SuccessCount
+	result.BackedUpSize = session.BackupSize
+
+	s.ds.logger.LogInfo("Backup created successfully", map[string]interface{}{
+		"session_id":  session.SessionID,
+		"backed_up":   session.SuccessCount,
+		"backup_size": session.BackupSize,
+	})
+
+	// Atomic deletions only start when there is a backup to roll back to
+	if s.request.Atomic {
+		if missing := unbackedFiles(session, files); len(missing) > 0 {
+			result.FailedFiles = append(result.FailedFiles, missing...)
+			result.FailedCount += len(missing)
+			s.unlinkOriginals()
+			return fmt.Errorf("atomic deletion aborted: backup failed for %d files", len(missing))
+		}
+	}
+
+	s.ds.sendProgress(DeletionProgress{
+		Operation:      s.request.Operation,
+		Status:         "backup_complete",
+		Message:        "Backup completed, starting deletion...",
+		BackupProgress: 100,
+	})
+	if s.tracker != nil {
+		s.tracker.SetStatus("backup_complete", "Backup completed, starting deletion...")
+		s.tracker.SetBackupProgress(100, "Backup completed, starting deletion...")
+	}
+	return nil
+}
+
+func (s *backupStrategy) Remove(path string, result *DeletionResult) (int64, error) {
+	entry, ok := s.entries[path]
+	if !ok || !entry.Success {
+		return entry.Size, fmt.Errorf("skipped deletion due to backup failure")
+	}
+	return entry.Size, s.ds.deleteSingleFile(path)
+}
+
+// Finish breaks the hard links between the backup and the originals that
+// were not removed, which happens on cancellation and on failed removals
+func (s *backupStrategy) Finish(result *DeletionResult) {
+	s.unlinkOriginals()
+}
+
+// unlinkOriginals gives hardlinked blobs of kept originals a copy of their own
+func (s *backupStrategy) unlinkOriginals() {
+	if s.session == nil {
+		return
+	}
+	if err := s.ds.backupSystem.UnlinkOriginals(s.session); err != nil {
+		s.ds.logger.LogError("Failed to unlink backup from originals", err, map[string]interface{}{
+			"session_id": s.session.SessionID,
+		})
+	}
+}
+
+func (s *backupStrategy) Rollback(paths []string, reason string) *RollbackResult {
+	return s.ds.rollbackDeletion(s.request, s.session.SessionID, paths, reason, s.tracker)
+}
+
+// noBackupStrategy deletes files permanently
+type noBackupStrategy struct {
+	ds *DeletionService
+}
+
+func (s *noBackupStrategy) Mode() DeletionMode { return DeletionModeNoBackup }
+
+func (s *noBackupStrategy) Status() string { return "deleting" }
+
+func (s *noBackupStrategy) Prepare(files []string, result *DeletionResult) error { return nil }
+
+func (s *noBackupStrategy) Remove(path string, result *DeletionResult) (int64, error) {
+	size := pathSize(path)
+	return size, s.ds.deleteSingleFile(path)
+}
+
+func (s *noBackupStrategy) Finish(result *DeletionResult) {}
+
+// quarantineStrategy moves files into one quarantine batch
+type quarantineStrategy struct {
+	ds         *DeletionService
+	request    *DeletionRequest
+	quarantine *Quarantine
+	batch      *QuarantineBatch
+}
+
+func (s *quarantineStrategy) Mode() DeletionMode { return DeletionModeQuarantine }
+
+func (s *quarantineStrategy) Status() string { return "quarantining" }
+
+func (s *quarantineStrategy) Prepare(files []string, result *DeletionResult) error {
+	// Expired batches are purged before adding new ones
+	purged, err := s.quarantine.PurgeExpired()
+	if err != nil {
+		s.ds.logger.LogWarning("Failed to purge expired quarantine", map[string]interface{}{"error": err.Error()})
+	}
+	if len(purged) > 0 {
+		s.ds.logger.LogInfo("Purged expired quarantine batches", map[string]interface{}{"batches": purged})
+	}
+
+	batch, err := s.quarantine.begin(s.request.Operation)
+	if err != nil {
+		return fmt.Errorf("quarantine failed: %w", err)
+	}
+	s.batch = batch
+	return nil
+}
+
+func (s *quarantineStrategy) Remove(path string, result *DeletionResult) (int64, error) {
+	item, err := s.quarantine.addItem(s.batch, path)
+	if err != nil {
+		return item.Size, err
+	}
+	if !item.Success {
+		return item.Size, fmt.Errorf("%s", item.Error)
+	}
+	if item.Error != "" {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Quarantined but not fully removed: %s", item.Error))
+	}
+	return item.Size, nil
+}
+
+func (s *quarantineStrategy) Finish(result *DeletionResult) {
+	s.quarantine.finish(s.batch)
+	if s.batch.hasHeldItems() {
+		result.QuarantineBatchID = s.batch.ID
+		result.QuarantineExpiresAt = &s.batch.ExpiresAt
+	}
+}
+
+// trashStrategy moves files to the freedesktop trash
+type trashStrategy struct {
+	trash *FreedesktopTrash
+}
+
+func (s *trashStrategy) Mode() DeletionMode { return DeletionModeTrash }
+
+func (s *trashStrategy) Status() string { return "trashing" }
+
+func (s *trashStrategy) Prepare(files []string, result *DeletionResult) error { return nil }
+
+func (s *trashStrategy) Remove(path string, result *DeletionResult) (int64, error) {
+	item, err := s.trash.Trash(path)
+	if err != nil {
+		return 0, err
+	}
+	result.TrashedItems = append(result.TrashedItems, *item)
+	return item.Size, nil
+}
+
+func (s *trashStrategy) Finish(result *DeletionResult) {}
+
+// dryRunStrategy reports every file as deleted without backing up or removing anything
+type dryRunStrategy struct{}
+
+func (s *dryRunStrategy) Mode() DeletionMode { return DeletionModeDryRun }
+
+func (s *dryRunStrategy) Status() string { return "simulating" }
+
+func (s *dryRunStrategy) Prepare(files []string, result *DeletionResult) error { return nil }
+
+func (s *dryRunStrategy) Remove(path string, result *DeletionResult) (int64, error) {
+	if _, err := os.Lstat(path); err != nil {
+		return 0, fmt.Errorf("failed to get file info: %w", err)
+	}
+	return pathSize(path), nil
+}
+
+func (s *dryRunStrategy) Finish(result *DeletionResult) {}
+
+// pathSize returns the size of a file or the total size of a directory tree
+func pathSize(path string) int64 {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0
+	}
+	return treeSize(path, info)
+}
+
+// runStrategy removes files with strategy, reporting progress after every
+// file and honouring cancellation between files. Atomic requests roll back
+// everything removed so far on the first failure or on cancellation.
+func (ds *DeletionService) runStrategy(strategy DeletionStrategy, request *DeletionRequest, files []string, result *DeletionResult, tracker *ProgressTracker) (*DeletionResult, error) {
+	result.Mode = strategy.Mode()
+	status := strategy.Status()
+	label := progressLabel(status)
+
+	if err := strategy.Prepare(files, result); err != nil {
+		result.Status = "failed"
+		result.Error = err.Error()
+		result.EndTime = time.Now()
+		return result, err
+	}
+
+	ds.sendProgress(DeletionProgress{
+		Operation:      request.Operation,
+		Status:         status,
+		Message:        fmt.Sprintf("%s %d files...", label, len(files)),
+		BackupProgress: 100,
+	})
+	if tracker != nil {
+		tracker.SetStatus(status, fmt.Sprintf("%s %d files...", label, len(files)))
+	}
+
+	startTime := time.Now()
+	for i, filePath := range files {
+		// Check for stop signal
+		select {
+		case <-ds.stopChan:
+			strategy.Finish(result)
+			ds.logger.LogInfo("Deletion cancelled by user", map[string]interface{}{
+				"files_processed": i,
+				"total_files":     len(files),
+			})
+			if request.Atomic {
+				return ds.failAtomicDeletion(strategy, result, "", "deletion cancelled by user", "cancelled")
+			}
+			result.Status = "cancelled"
+			result.EndTime = time.Now()
+			return result, fmt.Errorf("deletion cancelled by user")
+		default:
+		}
+
+		size, err := strategy.Remove(filePath, result)
+		result.TotalSize += size
+		if err != nil {
+			result.FailedFiles = append(result.FailedFiles, filePath)
+			result.FailedCount++
+			ds.logger.LogError("Failed to delete file", err, map[string]interface{}{
+				"file_path": filePath,
+				"mode":      result.Mode,
+			})
+			if request.Atomic {
+				strategy.Finish(result)
+				return ds.failAtomicDeletion(strategy, result, filePath, fmt.Sprintf("failed to delete %s: %v", filePath, err), "failed")
+			}
+		} else {
+			result.DeletedFiles = append(result.DeletedFiles, filePath)
+			result.DeletedCount++
+			result.DeletedSize += size
+			ds.logger.LogInfo("File deleted successfully", map[string]interface{}{
+				"file_path": filePath,
+				"size":      size,
+				"mode":      result.Mode,
+			})
+		}
+
+		// Send progress update
+		progress := DeletionProgress{
+			Operation:        request.Operation,
+			CurrentFile:      filePath,
+			FilesProcessed:   i + 1,
+			TotalFiles:       len(files),
+			Progress:         float64(i+1) / float64(len(files)) * 100,
+			ElapsedTime:      time.Since(startTime),
+			CurrentSize:      result.DeletedSize,
+			TotalSize:        result.TotalSize,
+			BackupProgress:   100,
+			DeletionProgress: float64(i+1) / float64(len(files)) * 100,
+			Status:           status,
+			Message:          fmt.Sprintf("%s file %d of %d", label, i+1, len(files)),
+		}
+
+		// Calculate estimated time
+		if i > 0 {
+			avgTimePerFile := time.Since(startTime) / time.Duration(i+1)
+			remainingFiles := len(files) - (i + 1)
+			progress.EstimatedTime = avgTimePerFile * time.Duration(remainingFiles)
+		}
+
+		ds.sendProgress(progress)
+		if tracker != nil {
+			tracker.SetFileProgress(filePath, i+1, len(files), result.DeletedSize, result.TotalSize)
+		}
+	}
+	strategy.Finish(result)
+
+	result.EndTime = time.Now()
+	result.Status = "completed"
+
+	ds.sendProgress(DeletionProgress{
+		Operation:        request.Operation,
+		Status:           "completed",
+		Message:          fmt.Sprintf("Deletion completed: %d files deleted", result.DeletedCount),
+		FilesProcessed:   result.DeletedCount,
+		TotalFiles:       result.TotalFiles,
+		Progress:         100,
+		BackupProgress:   100,
+		DeletionProgress: 100,
+	})
+	if tracker != nil {
+		tracker.Complete(fmt.Sprintf("Deletion completed: %d files deleted", result.DeletedCount))
+	}
+
+	ds.logger.LogInfo("Deletion operation completed", map[string]interface{}{
+		"operation":     request.Operation,
+		"mode":          result.Mode,
+		"deleted_count": result.DeletedCount,
+		"failed_count":  result.FailedCount,
+		"skipped_count": result.SkippedCount,
+		"deleted_size":  result.DeletedSize,
+		"duration":      result.EndTime.Sub(result.StartTime),
+	})
+
+	return result, nil
+}
+
+// progressLabel capitalizes a progress status for messages
+func progressLabel(status string) string {
+	if status == "" {
+		return status
+	}
+	return strings.ToUpper(status[:1]) + status[1:]
+}
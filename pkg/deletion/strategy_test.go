@@ -0,0 +1,158 @@
+package deletion
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDeletionStrategies(t *testing.T) {
+	tests := []struct {
+		mode      DeletionMode
+		dryRun    bool
+		removed   bool // Files are gone afterwards
+		backedUp  bool // A backup session is created
+		configure func(t *testing.T, ds *DeletionService)
+	}{
+		{mode: DeletionModeBackup, removed: true, backedUp: true},
+		{mode: "", removed: true, backedUp: true},
+		{mode: DeletionModeNoBackup, removed: true},
+		{mode: DeletionModeQuarantine, removed: true, configure: func(t *testing.T, ds *DeletionService) {
+			quarantine, err := NewQuarantine(t.TempDir(), time.Hour)
+			if err != nil {
+				t.Fatalf("Failed to create quarantine: %v", err)
+			}
+			ds.SetQuarantine(quarantine)
+		}},
+		{mode: DeletionModeTrash, removed: true, configure: func(t *testing.T, ds *DeletionService) {
+			if !trashSupported {
+				t.Skip("freedesktop trash not supported on this platform")
+			}
+			trash, err := NewFreedesktopTrashAt(filepath.Join(t.TempDir(), "Trash"))
+			if err != nil {
+				t.Fatalf("Failed to create trash: %v", err)
+			}
+			ds.SetTrash(trash)
+		}},
+		{mode: DeletionModeDryRun},
+		{mode: DeletionModeBackup, dryRun: true},
+	}
+
+	for _, tt := range tests {
+		name := string(tt.mode)
+		if name == "" {
+			name = "default"
+		}
+		if tt.dryRun {
+			name += "_dry_run"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			cacheDir := t.TempDir()
+			file := filepath.Join(cacheDir, "file.bin")
+			tree := filepath.Join(cacheDir, "tree")
+			missing := filepath.Join(cacheDir, "missing.bin")
+			os.MkdirAll(tree, 0755)
+			os.WriteFile(file, []byte("12345"), 0644)
+			os.WriteFile(filepath.Join(tree, "entry"), []byte("123"), 0644)
+
+			ds := newTestDeletionService(t)
+			if tt.configure != nil {
+				tt.configure(t, ds)
+			}
+
+			// The missing file cannot be backed up or removed; it must fail without stopping the run
+			result, err := ds.DeleteFilesWithBackup(&DeletionRequest{
+				Files:       []string{file, missing, tree},
+				Operation:   "strategy_test",
+				ForceDelete: true,
+				DryRun:      tt.dryRun,
+				Mode:        tt.mode,
+			})
+			if err != nil || result.Status != "completed" {
+				t.Fatalf("Deletion failed: %v (%+v)", err, result)
+			}
+
+			expectedMode := tt.mode
+			if expectedMode == "" {
+				expectedMode = DeletionModeBackup
+			}
+			if tt.dryRun {
+				expectedMode = DeletionModeDryRun
+			}
+			if result.Mode != expectedMode {
+				t.Errorf("Expected mode %s, got %s", expectedMode, result.Mode)
+			}
+
+			if result.DeletedCount != 2 || result.FailedCount != 1 || result.FailedFiles[0] != missing {
+				t.Errorf("Expected 2 deleted and the missing file failed, got %+v", result)
+			}
+			if result.DeletedSize != 8 {
+				t.Errorf("Expected 8 bytes deleted, got %d", result.DeletedSize)
+			}
+			if (result.BackupSessionID != "") != tt.backedUp {
+				t.Errorf("Unexpected backup session %q", result.BackupSessionID)
+			}
+
+			for _, path := range []string{file, tree} {
+				_, err := os.Lstat(path)
+				if tt.removed && !os.IsNotExist(err) {
+					t.Errorf("%s should have been removed", path)
+				}
+				if !tt.removed && err != nil {
+					t.Errorf("%s should have been left in place: %v", path, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDeletionStrategyErrors(t *testing.T) {
+	ds := newTestDeletionService(t)
+	file := filepath.Join(t.TempDir(), "file.bin")
+	os.WriteFile(file, []byte("content"), 0644)
+
+	for _, request := range []*DeletionRequest{
+		{Files: []string{file}, ForceDelete: true, Mode: "shred"},
+		{Files: []string{file}, ForceDelete: true, Mode: DeletionModeQuarantine},
+		{Files: []string{file}, ForceDelete: true, Mode: DeletionModeNoBackup, Atomic: true},
+	} {
+		result, err := ds.DeleteFilesWithBackup(request)
+		if err == nil || result.Status != "failed" {
+			t.Errorf("Expected %s request to fail, got %+v", request.Mode, result)
+		}
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("Rejected requests must not remove files: %v", err)
+	}
+}
+
+func TestDeletionCancellation(t *testing.T) {
+	ds := newTestDeletionService(t)
+	if ds.IsDeleting() {
+		t.Error("Deletion service should not be deleting initially")
+	}
+	// Stopping while idle is a no-op
+	ds.StopDeletion()
+
+	file := filepath.Join(t.TempDir(), "file.bin")
+	os.WriteFile(file, []byte("content"), 0644)
+
+	ds.stopChan <- true
+	result, err := ds.DeleteFilesWithBackup(&DeletionRequest{
+		Files:       []string{file},
+		Operation:   "cancel_test",
+		ForceDelete: true,
+		Mode:        DeletionModeNoBackup,
+	})
+	if err == nil || result.Status != "cancelled" || result.DeletedCount != 0 {
+		t.Fatalf("Expected a cancelled deletion, got %+v (%v)", result, err)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("Cancelled deletion must not remove files: %v", err)
+	}
+	if ds.IsDeleting() {
+		t.Error("Deletion state should be reset after a run")
+	}
+}
@@ -0,0 +1,57 @@
+//go:build linux || darwin
+
+package deletion
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"cache_app/pkg/backup"
+)
+
+func TestCancelledDeletionUnlinksBackup(t *testing.T) {
+	ds := newTestDeletionService(t)
+	dir := t.TempDir()
+	files := []string{filepath.Join(dir, "a.bin"), filepath.Join(dir, "b.bin")}
+	for _, file := range files {
+		os.WriteFile(file, []byte("content of "+file), 0644)
+	}
+
+	// The backup completes before the engine sees the stop signal
+	ds.stopChan <- true
+	result, err := ds.DeleteFilesWithBackup(&DeletionRequest{
+		Files:       files,
+		Operation:   "unlink_test",
+		ForceDelete: true,
+	})
+	if err == nil || result.Status != "cancelled" {
+		t.Fatalf("Expected a cancelled deletion, got %+v (%v)", result, err)
+	}
+
+	session, err := ds.backupSystem.GetSession(result.BackupSessionID)
+	if err != nil {
+		t.Fatalf("Failed to load session: %v", err)
+	}
+	for _, entry := range session.Entries {
+		if entry.CopyStrategy == backup.CopyStrategyHardlink {
+			t.Errorf("Backup of kept %s is still recorded as hardlinked", entry.OriginalPath)
+		}
+	}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatalf("Cancelled deletion must not remove files: %v", err)
+		}
+		if links := info.Sys().(*syscall.Stat_t).Nlink; links != 1 {
+			t.Errorf("Expected %s to have 1 link after the run, got %d", file, links)
+		}
+	}
+
+	// Writing to a kept original must not change its backup
+	os.WriteFile(files[0], []byte("changed"), 0644)
+	if valid, problems, err := ds.backupSystem.VerifyBackupIntegrity(result.BackupSessionID); err != nil || !valid {
+		t.Errorf("Backup changed with its original: %v %v", err, problems)
+	}
+}
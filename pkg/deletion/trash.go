@@ -0,0 +1,320 @@
+package deletion
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DeletionModeTrash moves files to the freedesktop.org Trash
+const DeletionModeTrash DeletionMode = "trash"
+
+const (
+	trashInfoExt        = ".trashinfo"
+	trashInfoHeader     = "[Trash Info]"
+	trashDeletionLayout = "2006-01-02T15:04:05"
+)
+
+// FreedesktopTrash moves files to the Trash as described by the freedesktop.org
+// Trash specification. Files on the same device as the home trash go to
+// $XDG_DATA_HOME/Trash; files on other mounts go to $topdir/.Trash/$uid when an
+// administrator has set up a shared sticky .Trash directory, and to
+// $topdir/.Trash-$uid otherwise.
+type FreedesktopTrash struct {
+	homeTrash string
+	uid       int
+	now       func() time.Time
+}
+
+// TrashedItem describes a file that was moved to a trash directory
+type TrashedItem struct {
+	OriginalPath string    `json:"original_path"`
+	TrashDir     string    `json:"trash_dir"`
+	Name         string    `json:"name"` // File name below TrashDir/files
+	DeletionDate time.Time `json:"deletion_date"`
+	Size         int64     `json:"size"`
+}
+
+// FilePath returns the location of the trashed file
+func (item TrashedItem) FilePath() string {
+	return filepath.Join(item.TrashDir, "files", item.Name)
+}
+
+// InfoPath returns the location of the .trashinfo file of the item
+func (item TrashedItem) InfoPath() string {
+	return filepath.Join(item.TrashDir, "info", item.Name+trashInfoExt)
+}
+
+// NewFreedesktopTrash uses the home trash in $XDG_DATA_HOME, or ~/.local/share if unset
+func NewFreedesktopTrash() (*FreedesktopTrash, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home directory: %w", err)
+		}
+		dataHome = filepath.Join(homeDir, ".local", "share")
+	}
+	return NewFreedesktopTrashAt(filepath.Join(dataHome, "Trash"))
+}
+
+// NewFreedesktopTrashAt uses homeTrash as the home trash directory
+func NewFreedesktopTrashAt(homeTrash string) (*FreedesktopTrash, error) {
+	if !trashSupported {
+		return nil, fmt.Errorf("the freedesktop trash is not supported on this platform")
+	}
+	trash := &FreedesktopTrash{homeTrash: homeTrash, uid: os.Getuid(), now: time.Now}
+	if err := ensureTrashDir(homeTrash); err != nil {
+		return nil, err
+	}
+	return trash, nil
+}
+
+// GetHomeTrash returns the home trash directory
+func (t *FreedesktopTrash) GetHomeTrash() string {
+	return t.homeTrash
+}
+
+// Trash moves path to the trash directory for its mount point
+func (t *FreedesktopTrash) Trash(path string) (*TrashedItem, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	trashDir, topDir, err := t.trashDirFor(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Paths in top directory trashes are stored relative to the top directory
+	infoPath := path
+	if topDir != "" {
+		if rel, err := filepath.Rel(topDir, path); err == nil {
+			infoPath = rel
+		}
+	}
+
+	item := &TrashedItem{
+		OriginalPath: path,
+		TrashDir:     trashDir,
+		DeletionDate: t.now(),
+		Size:         treeSize(path, info),
+	}
+	if item.Name, err = reserveTrashName(trashDir, filepath.Base(path), infoPath, item.DeletionDate); err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(path, item.FilePath()); err != nil {
+		os.Remove(item.InfoPath())
+		return nil, fmt.Errorf("failed to move %s to trash: %w", path, err)
+	}
+	return item, nil
+}
+
+// Restore moves a trashed item back to its original location
+func (t *FreedesktopTrash) Restore(item TrashedItem) error {
+	if _, err := os.Lstat(item.OriginalPath); err == nil {
+		return fmt.Errorf("%s already exists", item.OriginalPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(item.OriginalPath), 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory: %w", err)
+	}
+	if err := os.Rename(item.FilePath(), item.OriginalPath); err != nil {
+		return fmt.Errorf("failed to restore %s from trash: %w", item.OriginalPath, err)
+	}
+	if err := os.Remove(item.InfoPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove trash info: %w", err)
+	}
+	return nil
+}
+
+// List returns the items in the home trash, most recently deleted first
+func (t *FreedesktopTrash) List() ([]TrashedItem, error) {
+	infoDir := filepath.Join(t.homeTrash, "info")
+	dirEntries, err := os.ReadDir(infoDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trash: %w", err)
+	}
+
+	var items []TrashedItem
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), trashInfoExt) {
+			continue
+		}
+		item, err := readTrashInfo(t.homeTrash, "", strings.TrimSuffix(dirEntry.Name(), trashInfoExt))
+		if err != nil {
+			continue
+		}
+		items = append(items, *item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletionDate.After(items[j].DeletionDate)
+	})
+	return items, nil
+}
+
+// trashDirFor picks the trash directory for path and returns the top directory
+// of its mount point when that is not the home trash
+func (t *FreedesktopTrash) trashDirFor(path string) (string, string, error) {
+	homeInfo, err := os.Stat(t.homeTrash)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to access home trash: %w", err)
+	}
+	parentInfo, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get parent directory info: %w", err)
+	}
+	if sameDevice(homeInfo, parentInfo) {
+		return t.homeTrash, "", nil
+	}
+
+	topDir := mountTopDir(filepath.Dir(path), parentInfo)
+
+	// $topdir/.Trash/$uid, only if .Trash is a real sticky directory
+	shared := filepath.Join(topDir, ".Trash")
+	if info, err := os.Lstat(shared); err == nil && info.IsDir() && info.Mode()&os.ModeSticky != 0 {
+		trashDir := filepath.Join(shared, fmt.Sprint(t.uid))
+		if err := ensureTrashDir(trashDir); err == nil {
+			return trashDir, topDir, nil
+		}
+	}
+
+	// $topdir/.Trash-$uid
+	trashDir := filepath.Join(topDir, fmt.Sprintf(".Trash-%d", t.uid))
+	if err := ensureTrashDir(trashDir); err != nil {
+		return "", "", fmt.Errorf("no usable trash directory on the device of %s: %w", path, err)
+	}
+	return trashDir, topDir, nil
+}
+
+// mountTopDir returns the top directory of the mount point containing dir
+func mountTopDir(dir string, info os.FileInfo) string {
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		parentInfo, err := os.Stat(parent)
+		if err != nil || !sameDevice(info, parentInfo) {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// ensureTrashDir creates a trash directory with its files and info subdirectories.
+// Existing trash directories must be real directories, not symlinks.
+func ensureTrashDir(trashDir string) error {
+	if info, err := os.Lstat(trashDir); err == nil && !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", trashDir)
+	}
+	for _, dir := range []string{trashDir, filepath.Join(trashDir, "files"), filepath.Join(trashDir, "info")} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("failed to create trash directory: %w", err)
+		}
+	}
+	return nil
+}
+
+// reserveTrashName atomically creates the .trashinfo file for a free name,
+// which claims that name in the trash directory
+func reserveTrashName(trashDir, base, originalPath string, deletionDate time.Time) (string, error) {
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	if stem == "" {
+		stem, ext = base, ""
+	}
+
+	content := fmt.Sprintf("%s\nPath=%s\nDeletionDate=%s\n",
+		trashInfoHeader, escapeTrashPath(originalPath), deletionDate.Format(trashDeletionLayout))
+
+	for counter := 1; counter < 10000; counter++ {
+		name := base
+		if counter > 1 {
+			name = fmt.Sprintf("%s.%d%s", stem, counter, ext)
+		}
+		if _, err := os.Lstat(filepath.Join(trashDir, "files", name)); err == nil {
+			continue
+		}
+
+		file, err := os.OpenFile(filepath.Join(trashDir, "info", name+trashInfoExt), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to create trash info: %w", err)
+		}
+		_, err = file.WriteString(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(file.Name())
+			return "", fmt.Errorf("failed to write trash info: %w", err)
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("no free name for %s in %s", base, trashDir)
+}
+
+// readTrashInfo parses the .trashinfo file of name in trashDir. Relative paths
+// are resolved against topDir, or the parent of trashDir if topDir is empty.
+func readTrashInfo(trashDir, topDir, name string) (*TrashedItem, error) {
+	item := TrashedItem{TrashDir: trashDir, Name: name}
+
+	file, err := os.Open(item.InfoPath())
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != trashInfoHeader {
+		return nil, fmt.Errorf("invalid trash info: %s", item.InfoPath())
+	}
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Path":
+			path, err := url.PathUnescape(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trash info path: %w", err)
+			}
+			if !filepath.IsAbs(path) {
+				if topDir == "" {
+					topDir = filepath.Dir(trashDir)
+				}
+				path = filepath.Join(topDir, path)
+			}
+			item.OriginalPath = path
+		case "DeletionDate":
+			if date, err := time.ParseInLocation(trashDeletionLayout, value, time.Local); err == nil {
+				item.DeletionDate = date
+			}
+		}
+	}
+	if item.OriginalPath == "" {
+		return nil, fmt.Errorf("trash info without path: %s", item.InfoPath())
+	}
+	return &item, scanner.Err()
+}
+
+// escapeTrashPath percent-encodes a path the way URL paths are encoded
+func escapeTrashPath(path string) string {
+	return (&url.URL{Path: filepath.ToSlash(path)}).EscapedPath()
+}
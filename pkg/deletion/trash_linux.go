@@ -0,0 +1,15 @@
+package deletion
+
+import (
+	"os"
+	"syscall"
+)
+
+const trashSupported = true
+
+// sameDevice reports whether two files are on the same device
+func sameDevice(a, b os.FileInfo) bool {
+	statA, okA := a.Sys().(*syscall.Stat_t)
+	statB, okB := b.Sys().(*syscall.Stat_t)
+	return okA && okB && statA.Dev == statB.Dev
+}
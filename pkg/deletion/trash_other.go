@@ -0,0 +1,13 @@
+//go:build !linux
+
+package deletion
+
+import "os"
+
+// The freedesktop trash is only used on Linux; macOS and Windows have their own trash
+const trashSupported = false
+
+// sameDevice is not needed where the freedesktop trash is unsupported
+func sameDevice(a, b os.FileInfo) bool {
+	return false
+}
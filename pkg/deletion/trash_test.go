@@ -0,0 +1,96 @@
+//go:build linux
+
+package deletion
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTrashDeletionMode(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	trash, err := NewFreedesktopTrash()
+	if err != nil {
+		t.Fatalf("Failed to create trash: %v", err)
+	}
+
+	cacheDir := filepath.Join(t.TempDir(), "my cache")
+	os.MkdirAll(filepath.Join(cacheDir, "other"), 0755)
+	first := filepath.Join(cacheDir, "data.bin")
+	second := filepath.Join(cacheDir, "other", "data.bin")
+	os.WriteFile(first, []byte("first"), 0644)
+	os.WriteFile(second, []byte("second"), 0644)
+
+	ds := newTestDeletionService(t)
+	ds.SetTrash(trash)
+	result, err := ds.DeleteFilesWithBackup(&DeletionRequest{
+		Files:       []string{first, second},
+		Operation:   "trash_test",
+		ForceDelete: true,
+		Mode:        DeletionModeTrash,
+	})
+	if err != nil || result.DeletedCount != 2 || len(result.TrashedItems) != 2 {
+		t.Fatalf("Trash failed: %v (%+v)", err, result)
+	}
+	if result.BackupSessionID != "" {
+		t.Errorf("Trash mode should not create a backup session, got %s", result.BackupSessionID)
+	}
+
+	// The second file with the same name gets a unique trash name
+	names := []string{result.TrashedItems[0].Name, result.TrashedItems[1].Name}
+	if names[0] != "data.bin" || names[1] != "data.2.bin" {
+		t.Errorf("Unexpected trash names %v", names)
+	}
+
+	info, err := os.ReadFile(result.TrashedItems[0].InfoPath())
+	if err != nil {
+		t.Fatalf("Missing trash info: %v", err)
+	}
+	lines := strings.Split(string(info), "\n")
+	if lines[0] != "[Trash Info]" || !strings.Contains(lines[1], "/my%20cache/data.bin") || !strings.HasPrefix(lines[2], "DeletionDate=") {
+		t.Errorf("Unexpected trash info:\n%s", info)
+	}
+
+	items, err := trash.List()
+	if err != nil || len(items) != 2 {
+		t.Fatalf("Expected two items in trash, got %+v (%v)", items, err)
+	}
+	for _, item := range items {
+		if item.OriginalPath != first && item.OriginalPath != second {
+			t.Errorf("Unexpected original path %s", item.OriginalPath)
+		}
+	}
+
+	if err := trash.Restore(result.TrashedItems[1]); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if data, _ := os.ReadFile(second); string(data) != "second" {
+		t.Errorf("File not restored, got %q", data)
+	}
+	if _, err := os.Stat(result.TrashedItems[1].InfoPath()); !os.IsNotExist(err) {
+		t.Error("Trash info should be removed after restore")
+	}
+}
+
+func TestTrashInfoRelativePath(t *testing.T) {
+	topDir := t.TempDir()
+	trashDir := filepath.Join(topDir, ".Trash-1000")
+	if err := ensureTrashDir(trashDir); err != nil {
+		t.Fatalf("Failed to create trash directory: %v", err)
+	}
+	os.WriteFile(filepath.Join(trashDir, "info", "file.trashinfo"),
+		[]byte("[Trash Info]\nPath=projects/a%20b/file\nDeletionDate=2024-05-01T10:20:30\n"), 0600)
+
+	item, err := readTrashInfo(trashDir, "", "file")
+	if err != nil {
+		t.Fatalf("Failed to read trash info: %v", err)
+	}
+	if item.OriginalPath != filepath.Join(topDir, "projects", "a b", "file") {
+		t.Errorf("Relative path not resolved against the top directory: %s", item.OriginalPath)
+	}
+	if item.DeletionDate.Year() != 2024 || item.DeletionDate.Second() != 30 {
+		t.Errorf("Unexpected deletion date %v", item.DeletionDate)
+	}
+}
@@ -0,0 +1,237 @@
+package devcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// Timeouts for the tools' own commands
+const (
+	discoverTimeout = 10 * time.Second
+	cleanTimeout    = 10 * time.Minute
+)
+
+// Cleanup methods reported in a CleanResult
+const (
+	MethodNative = "native" // The tool's own clean command
+	MethodFiles  = "files"  // File deletion fallback
+)
+
+// ErrNoNativeClean is returned by Provider.Clean when the tool cannot clean a cache itself
+var ErrNoNativeClean = errors.New("no native clean command available")
+
+// Cache is a cache directory owned by a developer tool
+type Cache struct {
+	Provider      string `json:"provider"`
+	Kind          string `json:"kind"` // Identifies the cache within its provider, such as "build"
+	Name          string `json:"name"`
+	Path          string `json:"path"`
+	Size          int64  `json:"size"`
+	FileCount     int    `json:"file_count"`
+	Installed     bool   `json:"installed"`                // The tool was found on PATH
+	NativeCommand string `json:"native_command,omitempty"` // Used instead of deleting files when installed
+}
+
+// CleanResult describes how a cache was cleaned
+type CleanResult struct {
+	Provider    string        `json:"provider"`
+	Kind        string        `json:"kind"`
+	Path        string        `json:"path"`
+	Method      string        `json:"method"`
+	Command     string        `json:"command,omitempty"`
+	NativeError string        `json:"native_error,omitempty"` // Why the fallback was used
+	SizeBefore  int64         `json:"size_before"`
+	SizeAfter   int64         `json:"size_after"`
+	FreedBytes  int64         `json:"freed_bytes"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// Provider contributes the caches of one developer tool
+type Provider interface {
+	// Name identifies the tool, such as "go"
+	Name() string
+	// Discover returns the tool's cache directories, whether or not they exist
+	Discover(sys *System) []Cache
+	// Clean cleans a cache with the tool's own command, or returns ErrNoNativeClean
+	Clean(sys *System, cache Cache) (command string, err error)
+	// FallbackPaths returns what to delete when the tool cannot clean a cache itself
+	FallbackPaths(cache Cache) []string
+}
+
+// System gives providers access to the environment and the tools on PATH
+type System struct {
+	GOOS     string
+	Home     string
+	Getenv   func(key string) string
+	LookPath func(file string) (string, error)
+	Run      func(timeout time.Duration, name string, args ...string) ([]byte, error) // Returns stdout
+}
+
+// NewSystem returns the System of the current process
+func NewSystem() (*System, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return &System{
+		GOOS:     runtime.GOOS,
+		Home:     home,
+		Getenv:   os.Getenv,
+		LookPath: exec.LookPath,
+		Run: func(timeout time.Duration, name string, args ...string) ([]byte, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			return exec.CommandContext(ctx, name, args...).Output()
+		},
+	}, nil
+}
+
+// userCacheDir returns the per-user cache directory of the platform
+func (sys *System) userCacheDir() string {
+	switch sys.GOOS {
+	case "darwin":
+		return filepath.Join(sys.Home, "Library", "Caches")
+	case "windows":
+		if dir := sys.Getenv("LocalAppData"); dir != "" {
+			return dir
+		}
+		return filepath.Join(sys.Home, "AppData", "Local")
+	}
+	if dir := sys.Getenv("XDG_CACHE_HOME"); filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(sys.Home, ".cache")
+}
+
+// Manager discovers and cleans developer tool caches
+type Manager struct {
+	sys       *System
+	providers []Provider
+	remove    func(paths []string) error
+}
+
+// NewManager creates a manager for the built-in providers. remove deletes the
+// fallback paths; nil removes them directly.
+func NewManager(sys *System, remove func(paths []string) error) *Manager {
+	if remove == nil {
+		remove = RemovePaths
+	}
+	return &Manager{sys: sys, providers: Providers(), remove: remove}
+}
+
+// Discover returns the existing caches of every provider with their sizes
+func (m *Manager) Discover() []Cache {
+	var caches []Cache
+	for _, provider := range m.providers {
+		for _, cache := range provider.Discover(m.sys) {
+			if info, err := os.Stat(cache.Path); err != nil || !info.IsDir() {
+				continue
+			}
+			cache.Size, cache.FileCount, _ = DirSize(cache.Path)
+			caches = append(caches, cache)
+		}
+	}
+	return caches
+}
+
+// Clean cleans a cache with its tool's command, deleting files when the tool
+// is missing, has no clean command or its command fails
+func (m *Manager) Clean(providerName, kind string) (*CleanResult, error) {
+	var provider Provider
+	var cache *Cache
+	for _, candidate := range m.providers {
+		if candidate.Name() == providerName {
+			provider = candidate
+			break
+		}
+	}
+	if provider != nil {
+		for _, discovered := range provider.Discover(m.sys) {
+			if discovered.Kind == kind {
+				cache = &discovered
+				break
+			}
+		}
+	}
+	if cache == nil {
+		return nil, fmt.Errorf("unknown developer cache: %s/%s", providerName, kind)
+	}
+	if _, err := os.Stat(cache.Path); err != nil {
+		return nil, fmt.Errorf("failed to access %s: %w", cache.Path, err)
+	}
+
+	start := time.Now()
+	result := &CleanResult{Provider: providerName, Kind: kind, Path: cache.Path, Method: MethodNative}
+	result.SizeBefore, _, _ = DirSize(cache.Path)
+
+	command, err := provider.Clean(m.sys, *cache)
+	result.Command = command
+	if err != nil {
+		if !errors.Is(err, ErrNoNativeClean) {
+			result.NativeError = err.Error()
+		}
+		result.Method = MethodFiles
+		var paths []string
+		for _, path := range provider.FallbackPaths(*cache) {
+			if _, statErr := os.Lstat(path); statErr == nil {
+				paths = append(paths, path)
+			}
+		}
+		err = nil
+		if len(paths) > 0 {
+			err = m.remove(paths)
+		}
+	}
+
+	result.SizeAfter, _, _ = DirSize(cache.Path)
+	result.FreedBytes = max(result.SizeBefore-result.SizeAfter, 0)
+	result.Duration = time.Since(start)
+	if err != nil {
+		return result, fmt.Errorf("failed to clean %s: %w", cache.Name, err)
+	}
+	return result, nil
+}
+
+// DirSize returns the total size and number of the regular files under path
+func DirSize(path string) (int64, int, error) {
+	var size int64
+	var count int
+	err := filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip what cannot be read
+		}
+		if entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+				count++
+			}
+		}
+		return nil
+	})
+	return size, count, err
+}
+
+// RemovePaths deletes the paths, first making read-only directories such as
+// the Go module cache writable
+func RemovePaths(paths []string) error {
+	var errs []error
+	for _, path := range paths {
+		filepath.WalkDir(path, func(dir string, entry fs.DirEntry, err error) error {
+			if err == nil && entry.IsDir() {
+				os.Chmod(dir, 0700)
+			}
+			return nil
+		})
+		if err := os.RemoveAll(path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove %s: %w", path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
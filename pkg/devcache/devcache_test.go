@@ -0,0 +1,181 @@
+package devcache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSystem is a System where only the given tools are installed
+type fakeSystem struct {
+	System
+	installed map[string]bool
+	outputs   map[string]string // Command line to stdout
+	failing   map[string]bool   // Command lines that fail
+	run       []string
+}
+
+func newFakeSystem(t *testing.T, installed ...string) *fakeSystem {
+	home := t.TempDir()
+	fake := &fakeSystem{installed: make(map[string]bool), outputs: make(map[string]string), failing: make(map[string]bool)}
+	for _, tool := range installed {
+		fake.installed[tool] = true
+	}
+	fake.System = System{
+		GOOS:   "linux",
+		Home:   home,
+		Getenv: func(string) string { return "" },
+		LookPath: func(file string) (string, error) {
+			if fake.installed[file] {
+				return filepath.Join("/usr/bin", file), nil
+			}
+			return "", errors.New("not found")
+		},
+		Run: func(_ time.Duration, name string, args ...string) ([]byte, error) {
+			command := commandLine(name, args)
+			fake.run = append(fake.run, command)
+			if fake.failing[command] {
+				return nil, errors.New("exit status 1")
+			}
+			return []byte(fake.outputs[command]), nil
+		},
+	}
+	return fake
+}
+
+func writeFile(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	fake := newFakeSystem(t, "go", "pip3")
+	buildCache := filepath.Join(fake.Home, "gocache")
+	pipCache := filepath.Join(fake.Home, "pipcache")
+	fake.outputs["go env GOCACHE GOMODCACHE"] = buildCache + "\n" + filepath.Join(fake.Home, "gomod") + "\n"
+	fake.outputs["pip3 cache dir"] = pipCache + "\n"
+	writeFile(t, filepath.Join(buildCache, "00", "a-d"), 100)
+	writeFile(t, filepath.Join(buildCache, "01", "b-d"), 50)
+	writeFile(t, filepath.Join(pipCache, "http", "c"), 10)
+	writeFile(t, filepath.Join(fake.Home, ".cargo", "registry", "cache", "crate.crate"), 20)
+
+	caches := make(map[string]Cache)
+	for _, cache := range NewManager(&fake.System, nil).Discover() {
+		caches[cache.Provider+"/"+cache.Kind] = cache
+	}
+	if len(caches) != 3 {
+		t.Fatalf("Expected the go build, pip and cargo registry caches, got %+v", caches)
+	}
+
+	if build := caches["go/build"]; build.Path != buildCache || build.Size != 150 || build.FileCount != 2 ||
+		!build.Installed || build.NativeCommand != "go clean -cache" {
+		t.Errorf("Unexpected go build cache: %+v", build)
+	}
+	if pip := caches["pip/cache"]; pip.Path != pipCache || pip.NativeCommand != "pip3 cache purge" {
+		t.Errorf("Unexpected pip cache: %+v", pip)
+	}
+	if registry := caches["cargo/registry"]; registry.Installed || registry.NativeCommand != "" || registry.Size != 20 {
+		t.Errorf("Unexpected cargo registry cache: %+v", registry)
+	}
+}
+
+func TestClean(t *testing.T) {
+	t.Run("Native", func(t *testing.T) {
+		fake := newFakeSystem(t, "npm")
+		writeFile(t, filepath.Join(fake.Home, ".npm", "_cacache", "index"), 100)
+
+		result, err := NewManager(&fake.System, func([]string) error {
+			t.Error("Expected no files to be deleted")
+			return nil
+		}).Clean("npm", "cache")
+		if err != nil {
+			t.Fatalf("Failed to clean npm cache: %v", err)
+		}
+		if result.Method != MethodNative || result.Command != "npm cache clean --force" || fake.run[len(fake.run)-1] != result.Command {
+			t.Errorf("Expected npm to clean its own cache, got %+v (ran %v)", result, fake.run)
+		}
+	})
+
+	t.Run("FallbackWhenNotInstalled", func(t *testing.T) {
+		fake := newFakeSystem(t)
+		registry := filepath.Join(fake.Home, ".cargo", "registry")
+		writeFile(t, filepath.Join(registry, "cache", "crate.crate"), 100)
+		writeFile(t, filepath.Join(registry, "src", "crate", "lib.rs"), 50)
+		writeFile(t, filepath.Join(registry, "index", "config.json"), 10)
+
+		result, err := NewManager(&fake.System, nil).Clean("cargo", "registry")
+		if err != nil {
+			t.Fatalf("Failed to clean cargo registry: %v", err)
+		}
+		if result.Method != MethodFiles || result.FreedBytes != 150 || result.SizeAfter != 10 {
+			t.Errorf("Expected the downloaded crates to be deleted, got %+v", result)
+		}
+		if _, err := os.Stat(filepath.Join(registry, "index", "config.json")); err != nil {
+			t.Errorf("Expected the index to be kept: %v", err)
+		}
+	})
+
+	t.Run("FallbackWhenCommandFails", func(t *testing.T) {
+		fake := newFakeSystem(t, "go")
+		buildCache := filepath.Join(fake.Home, ".cache", "go-build")
+		writeFile(t, filepath.Join(buildCache, "00", "a-d"), 100)
+		fake.failing["go clean -cache"] = true
+
+		var removed []string
+		result, err := NewManager(&fake.System, func(paths []string) error {
+			removed = paths
+			return RemovePaths(paths)
+		}).Clean("go", "build")
+		if err != nil {
+			t.Fatalf("Failed to clean go build cache: %v", err)
+		}
+		if result.Method != MethodFiles || !strings.Contains(result.NativeError, "go clean -cache") || result.FreedBytes != 100 {
+			t.Errorf("Expected the fallback after the failed command, got %+v", result)
+		}
+		if len(removed) != 1 || removed[0] != filepath.Join(buildCache, "00") {
+			t.Errorf("Expected the cache contents to be removed, got %v", removed)
+		}
+	})
+
+	t.Run("PrepareBeforeFallback", func(t *testing.T) {
+		fake := newFakeSystem(t, "gradle")
+		writeFile(t, filepath.Join(fake.Home, ".gradle", "caches", "modules-2", "jar"), 100)
+
+		if _, err := NewManager(&fake.System, nil).Clean("gradle", "caches"); err != nil {
+			t.Fatalf("Failed to clean gradle caches: %v", err)
+		}
+		if len(fake.run) != 1 || fake.run[0] != "gradle --stop" {
+			t.Errorf("Expected the gradle daemons to be stopped, ran %v", fake.run)
+		}
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		fake := newFakeSystem(t)
+		if _, err := NewManager(&fake.System, nil).Clean("go", "nonexistent"); err == nil {
+			t.Error("Expected an unknown cache to be rejected")
+		}
+	})
+}
+
+func TestRemovePathsReadOnly(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mod", "example.com@v1.0.0")
+	writeFile(t, filepath.Join(dir, "go.mod"), 10)
+	if err := os.Chmod(dir, 0555); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RemovePaths([]string{filepath.Dir(dir)}); err != nil {
+		t.Fatalf("Failed to remove read-only module: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Expected the module to be removed, got %v", err)
+	}
+}
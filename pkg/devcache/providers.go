@@ -0,0 +1,257 @@
+package devcache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// toolProvider is a Provider described by the tool's commands and cache layout
+type toolProvider struct {
+	name     string
+	tools    []string                               // Executables, the first found on PATH is used
+	discover func(sys *System, tool string) []Cache // tool is empty when it is not installed
+	commands map[string][]string                    // Native clean arguments by cache kind
+	prepare  []string                               // Run before deleting files, such as stopping daemons
+	fallback map[string][]string                    // Subpaths deleted by cache kind; the cache's contents if absent
+}
+
+// Providers returns the built-in developer tool providers
+func Providers() []Provider {
+	return []Provider{
+		&toolProvider{
+			name:     "go",
+			tools:    []string{"go"},
+			discover: discoverGo,
+			commands: map[string][]string{"build": {"clean", "-cache"}, "modules": {"clean", "-modcache"}},
+		},
+		&toolProvider{
+			name:  "npm",
+			tools: []string{"npm"},
+			discover: single("cache", "npm cache", []string{"config", "get", "cache"}, "npm_config_cache", func(sys *System) string {
+				if sys.GOOS == "windows" {
+					return filepath.Join(sys.userCacheDir(), "npm-cache")
+				}
+				return filepath.Join(sys.Home, ".npm")
+			}),
+			commands: map[string][]string{"cache": {"cache", "clean", "--force"}},
+			fallback: map[string][]string{"cache": {"_cacache"}}, // Keeps logs and npx installs
+		},
+		&toolProvider{
+			name:  "yarn",
+			tools: []string{"yarn"},
+			discover: single("cache", "Yarn cache", []string{"cache", "dir"}, "YARN_CACHE_FOLDER", func(sys *System) string {
+				switch sys.GOOS {
+				case "darwin":
+					return filepath.Join(sys.userCacheDir(), "Yarn")
+				case "windows":
+					return filepath.Join(sys.userCacheDir(), "Yarn", "Cache")
+				}
+				return filepath.Join(sys.userCacheDir(), "yarn")
+			}),
+			commands: map[string][]string{"cache": {"cache", "clean"}},
+		},
+		&toolProvider{
+			name:  "pip",
+			tools: []string{"pip3", "pip"},
+			discover: single("cache", "pip cache", []string{"cache", "dir"}, "PIP_CACHE_DIR", func(sys *System) string {
+				if sys.GOOS == "windows" {
+					return filepath.Join(sys.userCacheDir(), "pip", "Cache")
+				}
+				return filepath.Join(sys.userCacheDir(), "pip")
+			}),
+			commands: map[string][]string{"cache": {"cache", "purge"}},
+		},
+		&toolProvider{
+			name:     "cargo",
+			tools:    []string{"cargo"},
+			discover: discoverCargo,
+			// Stable cargo has no command to clean its global cache. The index is
+			// kept so builds do not have to refetch it.
+			fallback: map[string][]string{"registry": {"cache", "src"}, "git": {"checkouts", "db"}},
+		},
+		&toolProvider{
+			name:  "gradle",
+			tools: []string{"gradle"},
+			discover: func(sys *System, _ string) []Cache {
+				home := absPath(sys.Getenv("GRADLE_USER_HOME"))
+				if home == "" {
+					home = filepath.Join(sys.Home, ".gradle")
+				}
+				return []Cache{{Kind: "caches", Name: "Gradle caches", Path: filepath.Join(home, "caches")}}
+			},
+			// Daemons hold locks inside the caches and must not see them disappear
+			prepare: []string{"--stop"},
+		},
+		&toolProvider{
+			name:  "maven",
+			tools: []string{"mvn"},
+			discover: func(sys *System, _ string) []Cache {
+				return []Cache{{Kind: "repository", Name: "Maven local repository", Path: filepath.Join(sys.Home, ".m2", "repository")}}
+			},
+		},
+	}
+}
+
+// Name returns the provider name
+func (p *toolProvider) Name() string {
+	return p.name
+}
+
+// Discover returns the caches of the tool
+func (p *toolProvider) Discover(sys *System) []Cache {
+	tool := p.tool(sys)
+	caches := p.discover(sys, tool)
+	for i := range caches {
+		caches[i].Provider = p.name
+		caches[i].Path = filepath.Clean(caches[i].Path)
+		caches[i].Installed = tool != ""
+		if args, ok := p.commands[caches[i].Kind]; ok && tool != "" {
+			caches[i].NativeCommand = commandLine(tool, args)
+		}
+	}
+	return caches
+}
+
+// Clean runs the tool's clean command for the cache
+func (p *toolProvider) Clean(sys *System, cache Cache) (string, error) {
+	tool := p.tool(sys)
+	if tool == "" {
+		return "", ErrNoNativeClean
+	}
+
+	args, ok := p.commands[cache.Kind]
+	if !ok {
+		if p.prepare != nil {
+			// Best effort: the files are deleted either way
+			sys.Run(cleanTimeout, tool, p.prepare...)
+		}
+		return "", ErrNoNativeClean
+	}
+
+	command := commandLine(tool, args)
+	if _, err := sys.Run(cleanTimeout, tool, args...); err != nil {
+		return command, fmt.Errorf("failed to run %s: %w", command, err)
+	}
+	return command, nil
+}
+
+// FallbackPaths returns the paths deleted when the tool cannot clean the cache
+func (p *toolProvider) FallbackPaths(cache Cache) []string {
+	var paths []string
+	if subpaths, ok := p.fallback[cache.Kind]; ok {
+		for _, subpath := range subpaths {
+			paths = append(paths, filepath.Join(cache.Path, subpath))
+		}
+		return paths
+	}
+
+	entries, err := os.ReadDir(cache.Path)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		paths = append(paths, filepath.Join(cache.Path, entry.Name()))
+	}
+	return paths
+}
+
+// tool returns the path of the tool's executable, or "" when it is not installed
+func (p *toolProvider) tool(sys *System) string {
+	for _, name := range p.tools {
+		if path, err := sys.LookPath(name); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// single discovers a tool's only cache by asking the tool, then the
+// environment variable that overrides it, then the platform default
+func single(kind, name string, query []string, envVar string, fallback func(sys *System) string) func(sys *System, tool string) []Cache {
+	return func(sys *System, tool string) []Cache {
+		path := ""
+		if tool != "" {
+			path = queryPath(sys, tool, query...)
+		}
+		if path == "" && filepath.IsAbs(sys.Getenv(envVar)) {
+			path = sys.Getenv(envVar)
+		}
+		if path == "" {
+			path = fallback(sys)
+		}
+		return []Cache{{Kind: kind, Name: name, Path: path}}
+	}
+}
+
+// discoverGo finds the build and module caches, asking go env when go is installed
+func discoverGo(sys *System, tool string) []Cache {
+	var buildCache, moduleCache string
+	if tool != "" {
+		if output, err := sys.Run(discoverTimeout, tool, "env", "GOCACHE", "GOMODCACHE"); err == nil {
+			if lines := strings.Split(strings.TrimSpace(string(output)), "\n"); len(lines) == 2 {
+				buildCache, moduleCache = absPath(lines[0]), absPath(lines[1])
+			}
+		}
+	}
+
+	if buildCache == "" {
+		buildCache = absPath(sys.Getenv("GOCACHE"))
+	}
+	if buildCache == "" {
+		buildCache = filepath.Join(sys.userCacheDir(), "go-build")
+	}
+	if moduleCache == "" {
+		moduleCache = absPath(sys.Getenv("GOMODCACHE"))
+	}
+	if moduleCache == "" {
+		gopath := filepath.SplitList(sys.Getenv("GOPATH"))
+		if len(gopath) > 0 && filepath.IsAbs(gopath[0]) {
+			moduleCache = filepath.Join(gopath[0], "pkg", "mod")
+		} else {
+			moduleCache = filepath.Join(sys.Home, "go", "pkg", "mod")
+		}
+	}
+
+	return []Cache{
+		{Kind: "build", Name: "Go build cache", Path: buildCache},
+		{Kind: "modules", Name: "Go module cache", Path: moduleCache},
+	}
+}
+
+// discoverCargo finds the registry and git caches under CARGO_HOME
+func discoverCargo(sys *System, _ string) []Cache {
+	home := absPath(sys.Getenv("CARGO_HOME"))
+	if home == "" {
+		home = filepath.Join(sys.Home, ".cargo")
+	}
+	return []Cache{
+		{Kind: "registry", Name: "Cargo registry cache", Path: filepath.Join(home, "registry")},
+		{Kind: "git", Name: "Cargo git cache", Path: filepath.Join(home, "git")},
+	}
+}
+
+// queryPath runs a tool command that prints a directory
+func queryPath(sys *System, tool string, args ...string) string {
+	output, err := sys.Run(discoverTimeout, tool, args...)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	return absPath(lines[len(lines)-1])
+}
+
+// absPath returns the trimmed path if it is absolute, or ""
+func absPath(path string) string {
+	path = strings.TrimSpace(path)
+	if !filepath.IsAbs(path) {
+		return ""
+	}
+	return path
+}
+
+// commandLine formats a command for display
+func commandLine(tool string, args []string) string {
+	return strings.Join(append([]string{filepath.Base(tool)}, args...), " ")
+}
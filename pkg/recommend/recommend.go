@@ -0,0 +1,335 @@
+package recommend
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"cache_app/pkg/catalog"
+	"cache_app/pkg/safety"
+)
+
+// Weights of the ranking score within a safety level
+const (
+	confidenceWeight     = 0.40
+	regenerabilityWeight = 0.35
+	ageWeight            = 0.25
+	ageHorizon           = 90 * 24 * time.Hour // Unused this long counts as fully stale
+)
+
+// Reasons files are left out of a recommendation
+const (
+	ExcludedRisky         = "risky"
+	ExcludedAboveMaxLevel = "above_max_level"
+	ExcludedLowConfidence = "low_confidence"
+	ExcludedProtected     = "protected_location" // Catalog says do not clean, or the location is dangerous
+	ExcludedUnclassified  = "unclassified"
+)
+
+// ScannedFile is a file or directory reported by a cache scan
+type ScannedFile struct {
+	Path           string
+	Size           int64
+	IsDir          bool
+	LastModified   time.Time
+	LastAccessed   time.Time
+	Classification *safety.SafetyClassification
+}
+
+// ScannedLocation is a scanned cache location with its files
+type ScannedLocation struct {
+	ID    string
+	Name  string
+	Path  string // Expanded root the files were found under
+	Files []ScannedFile
+}
+
+// Options controls what a recommendation may contain
+type Options struct {
+	TargetBytes   int64              // Space to free, 0 proposes every eligible file
+	MaxLevel      safety.SafetyLevel // Highest level proposed; Risky files are never proposed
+	MinConfidence int                // Files classified with less confidence are skipped
+}
+
+// Item is a file or directory proposed for cleanup
+type Item struct {
+	Path           string   `json:"path"`
+	IsDir          bool     `json:"is_dir"`
+	LocationID     string   `json:"location_id"`
+	LocationName   string   `json:"location_name"`
+	Size           int64    `json:"size"`
+	FileCount      int      `json:"file_count"`
+	Level          string   `json:"level"`
+	Confidence     int      `json:"confidence"`
+	AgeDays        int      `json:"age_days"`       // Days since last modification or access
+	Regenerability float64  `json:"regenerability"` // 0-1, how readily the data comes back
+	Score          float64  `json:"score"`          // Rank within the safety level
+	Justification  string   `json:"justification"`
+	Reasons        []string `json:"reasons"`
+
+	level       safety.SafetyLevel
+	regenerates string // Why the data comes back, from the catalog
+	dir         string // Top-level directory under the location root, empty for files at the root
+}
+
+// Recommendation is the proposed set of items to free the requested space
+type Recommendation struct {
+	GeneratedAt   time.Time      `json:"generated_at"`
+	TargetBytes   int64          `json:"target_bytes"`
+	SelectedBytes int64          `json:"selected_bytes"`
+	GoalReached   bool           `json:"goal_reached"`
+	Items         []Item         `json:"items"`
+	Candidates    int            `json:"candidates"` // Eligible files considered
+	Excluded      map[string]int `json:"excluded"`   // Files left out by reason
+}
+
+// Engine ranks scanned files and proposes the least risky set to clean
+type Engine struct {
+	catalog *catalog.Catalog
+	now     func() time.Time
+}
+
+// NewEngine creates a recommendation engine backed by a location catalog
+func NewEngine(locations *catalog.Catalog) *Engine {
+	if locations == nil {
+		locations = &catalog.Catalog{}
+	}
+	return &Engine{catalog: locations, now: time.Now}
+}
+
+// Recommend proposes files and directories to clean until TargetBytes is
+// reached, taking Safe items before Caution items and, within a level, the
+// most confident, most regenerable and oldest first. Directories replace their
+// files when every file in them is proposed.
+func (e *Engine) Recommend(locations []ScannedLocation, options Options) *Recommendation {
+	if options.MaxLevel > safety.Caution {
+		options.MaxLevel = safety.Caution
+	}
+
+	recommendation := &Recommendation{
+		GeneratedAt: e.now(),
+		TargetBytes: options.TargetBytes,
+		Items:       make([]Item, 0),
+		Excluded:    make(map[string]int),
+	}
+
+	var candidates []Item
+	dirFiles := make(map[string]int) // Scanned files per top-level directory
+	for _, location := range locations {
+		for _, file := range location.Files {
+			if file.IsDir {
+				continue
+			}
+			dir := topLevelDir(location.Path, file.Path)
+			if dir != "" {
+				dirFiles[dir]++
+			}
+
+			item, excluded := e.evaluate(location, file, options)
+			if excluded != "" {
+				recommendation.Excluded[excluded]++
+				continue
+			}
+			item.dir = dir
+			candidates = append(candidates, item)
+		}
+	}
+	recommendation.Candidates = len(candidates)
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.level != b.level {
+			return a.level < b.level
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Size != b.Size {
+			return a.Size > b.Size
+		}
+		return a.Path < b.Path
+	})
+
+	var selected []Item
+	for _, item := range candidates {
+		if options.TargetBytes > 0 && recommendation.SelectedBytes >= options.TargetBytes {
+			break
+		}
+		selected = append(selected, item)
+		recommendation.SelectedBytes += item.Size
+	}
+	recommendation.GoalReached = recommendation.SelectedBytes >= options.TargetBytes
+	recommendation.Items = collapseDirectories(selected, dirFiles)
+	return recommendation
+}
+
+// evaluate scores a file, or returns why it is excluded
+func (e *Engine) evaluate(location ScannedLocation, file ScannedFile, options Options) (Item, string) {
+	classification := file.Classification
+	switch {
+	case classification == nil:
+		return Item{}, ExcludedUnclassified
+	case classification.Level == safety.Risky:
+		return Item{}, ExcludedRisky
+	case classification.Level > options.MaxLevel:
+		return Item{}, ExcludedAboveMaxLevel
+	case classification.Confidence < options.MinConfidence:
+		return Item{}, ExcludedLowConfidence
+	}
+
+	entry, ok := e.catalog.Get(location.ID)
+	if !ok {
+		entry = e.catalog.Match(file.Path)
+	}
+	regenerability, regenerates, protected := e.regenerability(entry)
+	if protected {
+		return Item{}, ExcludedProtected
+	}
+
+	lastUsed := file.LastModified
+	if file.LastAccessed.After(lastUsed) {
+		lastUsed = file.LastAccessed
+	}
+	age := e.now().Sub(lastUsed)
+	if age < 0 {
+		age = 0
+	}
+	ageFactor := float64(age) / float64(ageHorizon)
+	if ageFactor > 1 {
+		ageFactor = 1
+	}
+
+	item := Item{
+		Path:           file.Path,
+		LocationID:     location.ID,
+		LocationName:   location.Name,
+		Size:           file.Size,
+		FileCount:      1,
+		Level:          classification.Level.String(),
+		Confidence:     classification.Confidence,
+		AgeDays:        int(age.Hours() / 24),
+		Regenerability: regenerability,
+		Reasons:        classification.Reasons,
+		level:          classification.Level,
+		regenerates:    regenerates,
+	}
+	item.Score = confidenceWeight*float64(classification.Confidence)/100 +
+		regenerabilityWeight*regenerability + ageWeight*ageFactor
+	item.Justification = fmt.Sprintf("%s with %d%% confidence; %s; last used %d days ago",
+		item.Level, item.Confidence, regenerates, item.AgeDays)
+	if len(item.Reasons) > 0 {
+		item.Justification += "; " + strings.Join(item.Reasons, "; ")
+	}
+	return item, ""
+}
+
+// regenerability rates how readily data in a catalog location comes back after
+// cleaning, describes it, and reports whether the location must not be cleaned
+func (e *Engine) regenerability(entry *catalog.Location) (float64, string, bool) {
+	if entry == nil {
+		return 0.3, "location is not in the catalog", false
+	}
+	if entry.SafetyLevel == "dangerous" || entry.CleanupRisk == "high" {
+		return 0, "", true
+	}
+
+	description := entry.Name
+	if entry.Recommendation != "" {
+		description += ": " + entry.Recommendation
+	}
+	switch e.catalog.Guidance(entry.ID) {
+	case catalog.GuidanceDoNotClean:
+		return 0, "", true
+	case catalog.GuidanceSafeToClean:
+		return 1, description, false
+	case catalog.GuidanceReview:
+		return 0.5, description, false
+	}
+	if entry.SafetyLevel == "safe" {
+		return 0.8, description, false
+	}
+	return 0.4, description, false
+}
+
+// topLevelDir returns the directory directly under root that contains path
+func topLevelDir(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	first, _, found := strings.Cut(rel, string(filepath.Separator))
+	if !found {
+		return ""
+	}
+	return filepath.Join(root, first)
+}
+
+// collapseDirectories replaces the files of a top-level directory with the
+// directory itself when every scanned file in it was selected
+func collapseDirectories(selected []Item, dirFiles map[string]int) []Item {
+	selectedPerDir := make(map[string]int)
+	for _, item := range selected {
+		if item.dir != "" {
+			selectedPerDir[item.dir]++
+		}
+	}
+
+	items := make([]Item, 0, len(selected))
+	dirs := make(map[string]int) // Index of the collapsed directory in items
+	for _, item := range selected {
+		if item.dir == "" || selectedPerDir[item.dir] < dirFiles[item.dir] {
+			items = append(items, item)
+			continue
+		}
+
+		index, ok := dirs[item.dir]
+		if !ok {
+			dirs[item.dir] = len(items)
+			items = append(items, Item{
+				Path:           item.dir,
+				IsDir:          true,
+				LocationID:     item.LocationID,
+				LocationName:   item.LocationName,
+				Level:          item.Level,
+				Confidence:     item.Confidence,
+				AgeDays:        item.AgeDays,
+				Regenerability: item.Regenerability,
+				Score:          item.Score,
+				Reasons:        append([]string{}, item.Reasons...),
+				level:          item.level,
+				regenerates:    item.regenerates,
+				dir:            item.dir,
+			})
+			index = len(items) - 1
+		}
+
+		// A directory is only as safe as its riskiest file
+		dir := &items[index]
+		dir.Size += item.Size
+		dir.FileCount++
+		if item.level > dir.level {
+			dir.level, dir.Level = item.level, item.Level
+		}
+		dir.Confidence = min(dir.Confidence, item.Confidence)
+		dir.AgeDays = min(dir.AgeDays, item.AgeDays)
+		dir.Regenerability = min(dir.Regenerability, item.Regenerability)
+		dir.Score = min(dir.Score, item.Score)
+		// Only reasons every file shares describe the directory
+		dir.Reasons = slices.DeleteFunc(dir.Reasons, func(reason string) bool {
+			return !slices.Contains(item.Reasons, reason)
+		})
+	}
+
+	for _, index := range dirs {
+		dir := &items[index]
+		dir.Justification = fmt.Sprintf("All %d files are recommended; %s with at least %d%% confidence; %s; last used %d days ago",
+			dir.FileCount, dir.Level, dir.Confidence, dir.regenerates, dir.AgeDays)
+		if len(dir.Reasons) > 0 {
+			dir.Justification += "; " + strings.Join(dir.Reasons, "; ")
+		}
+	}
+	return items
+}
@@ -0,0 +1,97 @@
+package recommend
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cache_app/pkg/catalog"
+	"cache_app/pkg/safety"
+)
+
+func TestRecommend(t *testing.T) {
+	locations, err := catalog.Parse([]byte(`{
+	  "user_caches": [
+	    {"id": "browser", "name": "Browser Cache", "path": "/cache/browser", "safety_level": "safe", "cleanup_risk": "low",
+	     "recommendation": "Safe to clean - regenerated on demand"},
+	    {"id": "mail", "name": "Mail", "path": "/cache/mail", "safety_level": "moderate", "cleanup_risk": "medium"},
+	    {"id": "fonts", "name": "Fonts", "path": "/cache/fonts", "safety_level": "dangerous", "cleanup_risk": "high"}
+	  ],
+	  "cleanup_recommendations": {"safe_to_clean": ["browser"], "review_before_cleaning": ["mail"]}
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to parse catalog: %v", err)
+	}
+
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	file := func(path string, size int64, level safety.SafetyLevel, confidence, ageDays int) ScannedFile {
+		return ScannedFile{
+			Path:         path,
+			Size:         size,
+			LastModified: now.Add(-time.Duration(ageDays) * 24 * time.Hour),
+			Classification: &safety.SafetyClassification{
+				Level: level, Confidence: confidence, Reasons: []string{"Located in temporary directory"},
+			},
+		}
+	}
+	scans := []ScannedLocation{
+		{ID: "browser", Name: "Browser Cache", Path: "/cache/browser", Files: []ScannedFile{
+			{Path: "/cache/browser/Default", IsDir: true},
+			file("/cache/browser/Default/a", 400, safety.Safe, 90, 60),
+			file("/cache/browser/Default/b", 100, safety.Safe, 80, 60),
+			file("/cache/browser/recent", 300, safety.Safe, 70, 1),
+		}},
+		{ID: "mail", Name: "Mail", Path: "/cache/mail", Files: []ScannedFile{
+			file("/cache/mail/index", 1000, safety.Caution, 90, 200),
+			file("/cache/mail/store", 5000, safety.Risky, 90, 200),
+			{Path: "/cache/mail/unreadable", Size: 10},
+		}},
+		{ID: "fonts", Name: "Fonts", Path: "/cache/fonts", Files: []ScannedFile{
+			file("/cache/fonts/font.cache", 2000, safety.Safe, 90, 200),
+		}},
+	}
+
+	engine := NewEngine(locations)
+	engine.now = func() time.Time { return now }
+
+	t.Run("SafeFirst", func(t *testing.T) {
+		recommendation := engine.Recommend(scans, Options{TargetBytes: 450, MaxLevel: safety.Caution})
+		if !recommendation.GoalReached || recommendation.SelectedBytes != 500 {
+			t.Fatalf("Expected 500 bytes selected, got %+v", recommendation)
+		}
+		// Both files of Default are selected, so the directory replaces them
+		if len(recommendation.Items) != 1 || recommendation.Items[0].Path != filepath.FromSlash("/cache/browser/Default") ||
+			!recommendation.Items[0].IsDir || recommendation.Items[0].FileCount != 2 {
+			t.Fatalf("Expected the Default directory, got %+v", recommendation.Items)
+		}
+		justification := recommendation.Items[0].Justification
+		if !strings.Contains(justification, "regenerated on demand") || !strings.Contains(justification, "Located in temporary directory") {
+			t.Errorf("Justification lacks catalog or classifier reasons: %s", justification)
+		}
+
+		excluded := recommendation.Excluded
+		if excluded[ExcludedRisky] != 1 || excluded[ExcludedProtected] != 1 || excluded[ExcludedUnclassified] != 1 {
+			t.Errorf("Unexpected exclusions %v", excluded)
+		}
+	})
+
+	t.Run("CautionWhenSafeIsExhausted", func(t *testing.T) {
+		recommendation := engine.Recommend(scans, Options{TargetBytes: 1500, MaxLevel: safety.Caution})
+		var paths []string
+		for _, item := range recommendation.Items {
+			paths = append(paths, filepath.ToSlash(item.Path))
+		}
+		expected := "/cache/browser/Default,/cache/browser/recent,/cache/mail/index"
+		if strings.Join(paths, ",") != expected || !recommendation.GoalReached {
+			t.Errorf("Expected %s, got %v", expected, paths)
+		}
+	})
+
+	t.Run("TargetOutOfReach", func(t *testing.T) {
+		recommendation := engine.Recommend(scans, Options{TargetBytes: 1 << 20, MaxLevel: safety.Safe})
+		if recommendation.GoalReached || recommendation.SelectedBytes != 800 || recommendation.Excluded[ExcludedAboveMaxLevel] != 1 {
+			t.Errorf("Expected only Safe files and an unreached goal, got %+v", recommendation)
+		}
+	})
+}
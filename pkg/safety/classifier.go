@@ -37,6 +37,8 @@ type SafetyClassification struct {
 	Confidence  int         `json:"confidence"`  // 0-100 percentage
 	Explanation string      `json:"explanation"` // Human-readable explanation
 	Reasons     []string    `json:"reasons"`     // List of specific reasons for the classification
+	ContentType string      `json:"content_type,omitempty"` // Sniffed MIME type of user content
+	Signals     []Signal    `json:"signals"`                // Structured evidence, contributions adding up to the confidence
 }
 
 // MarshalJSON customizes JSON serialization for SafetyClassification
@@ -51,6 +53,41 @@ func (sc SafetyClassification) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// UnmarshalJSON reads a SafetyClassification written by MarshalJSON
+func (sc *SafetyClassification) UnmarshalJSON(data []byte) error {
+	type Alias SafetyClassification
+	aux := &struct {
+		Level string `json:"level"`
+		*Alias
+	}{
+		Alias: (*Alias)(sc),
+	}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+
+	level, err := ParseSafetyLevel(aux.Level)
+	if err != nil {
+		return err
+	}
+	sc.Level = level
+	return nil
+}
+
+// ParseSafetyLevel parses the string form of a SafetyLevel
+func ParseSafetyLevel(value string) (SafetyLevel, error) {
+	switch value {
+	case "Safe":
+		return Safe, nil
+	case "Caution":
+		return Caution, nil
+	case "Risky":
+		return Risky, nil
+	default:
+		return Safe, fmt.Errorf("invalid safety level: %s", value)
+	}
+}
+
 // FileMetadata represents the metadata needed for safety classification
 type FileMetadata struct {
 	Name         string
@@ -66,6 +103,9 @@ type FileMetadata struct {
 type SafetyClassifier struct {
 	// Configuration for classification rules
 	config ClassificationConfig
+	
+	// Reads git working trees when dev files are protected
+	git *GitInspector
 }
 
 // ClassificationConfig holds configuration for the classification rules
@@ -85,6 +125,24 @@ type ClassificationConfig struct {
 	
 	// Development cache patterns that need caution
 	DevCachePatterns []string
+	
+	// Sniff file contents to recognize documents, images, archives and databases
+	SniffContent bool
+	
+	// Folders where users save content, the only places content is sniffed;
+	// images and archives elsewhere in caches are ordinary cache data
+	UserContentPatterns []string
+	
+	// Check git working trees for uncommitted, untracked, stashed and unpushed work
+	ProtectDevFiles bool
+	
+	// User allow and deny rules, overriding every other check
+	PathRules *PathRules
+	
+	// Confidence points per signal and the confidence needed for each level
+	Weights           Weights
+	SafeConfidence    int
+	CautionConfidence int
 }
 
 // DefaultConfig returns a default configuration for the classifier
@@ -120,14 +178,37 @@ func DefaultConfig() ClassificationConfig {
 			".gradle",
 			".m2/",
 		},
+		UserContentPatterns: []string{
+			"download",
+			"attachment",
+			"desktop",
+			"documents",
+			"inbox",
+			"received",
+		},
+		SniffContent:      true,
+		ProtectDevFiles:   true,
+		Weights:           DefaultWeights(),
+		SafeConfidence:    70,
+		CautionConfidence: 40,
 	}
 }
 
 // NewSafetyClassifier creates a new safety classifier with the given configuration
 func NewSafetyClassifier(config ClassificationConfig) *SafetyClassifier {
-	return &SafetyClassifier{
+	if config.Weights == nil {
+		config.Weights = DefaultWeights()
+	}
+	if config.SafeConfidence == 0 && config.CautionConfidence == 0 {
+		config.SafeConfidence, config.CautionConfidence = 70, 40
+	}
+	sc := &SafetyClassifier{
 		config: config,
 	}
+	if config.ProtectDevFiles {
+		sc.git = NewGitInspector()
+	}
+	return sc
 }
 
 // NewDefaultSafetyClassifier creates a new safety classifier with default configuration
@@ -137,94 +218,124 @@ func NewDefaultSafetyClassifier() *SafetyClassifier {
 
 // ClassifyFile analyzes a file and returns its safety classification
 func (sc *SafetyClassifier) ClassifyFile(file FileMetadata) SafetyClassification {
-	var reasons []string
-	var confidence int
 	var level SafetyLevel
+	levelSet := false // Safe is the zero level, so a set level is tracked separately
+
+	// User path rules decide on their own, a deny always winning
+	if action, rule := sc.config.PathRules.Decide(file.Path, file.IsDir); action != "" {
+		return sc.ruleClassification(action, rule)
+	}
 
 	// Start with base confidence
-	confidence = 50 // Base confidence
+	e := newEvidence(sc.config.Weights)
 	
 	// Check file age
 	age := time.Since(file.LastModified)
 	if age > sc.config.SafeAgeThreshold {
-		reasons = append(reasons, fmt.Sprintf("File is %d days old (safe threshold: %d days)", int(age.Hours()/24), int(sc.config.SafeAgeThreshold.Hours()/24)))
-		confidence += 20
+		e.add(SignalOldAge, "", fmt.Sprintf("File is %d days old (safe threshold: %d days)", int(age.Hours()/24), int(sc.config.SafeAgeThreshold.Hours()/24)))
 	} else if age < sc.config.CautionAgeThreshold {
-		reasons = append(reasons, fmt.Sprintf("File is recent (%d days old, caution threshold: %d days)", int(age.Hours()/24), int(sc.config.CautionAgeThreshold.Hours()/24)))
-		confidence -= 15
+		e.add(SignalRecent, "", fmt.Sprintf("File is recent (%d days old, caution threshold: %d days)", int(age.Hours()/24), int(sc.config.CautionAgeThreshold.Hours()/24)))
 	}
 
 	// Check file size
 	if file.Size > sc.config.LargeFileThreshold {
-		reasons = append(reasons, fmt.Sprintf("Large file size: %.2f MB (threshold: %.2f MB)", float64(file.Size)/(1024*1024), float64(sc.config.LargeFileThreshold)/(1024*1024)))
-		confidence -= 10
+		e.add(SignalLargeSize, "", fmt.Sprintf("Large file size: %.2f MB (threshold: %.2f MB)", float64(file.Size)/(1024*1024), float64(sc.config.LargeFileThreshold)/(1024*1024)))
 	} else if file.Size < 1024 { // Less than 1KB
-		reasons = append(reasons, "Very small file size, likely safe to delete")
-		confidence += 5
+		e.add(SignalSmallSize, "", "Very small file size, likely safe to delete")
 	}
 
 	// Check if it's a system-critical location
 	if sc.isSystemCritical(file.Path) {
-		reasons = append(reasons, "Located in system-critical directory")
-		confidence -= 30
-		level = Risky
+		e.add(SignalSystemCritical, "sets Risky", "Located in system-critical directory")
+		level, levelSet = Risky, true
 	}
 
-	// Check if it's a temporary directory
+	// Check if it's a temporary directory, which only adds confidence so that
+	// recent or large temporary files can still end up Caution
 	if sc.isTempDirectory(file.Path) {
-		reasons = append(reasons, "Located in temporary directory")
-		confidence += 25
-		if level != Risky {
-			level = Safe
-		}
+		e.add(SignalTempDirectory, "", "Located in temporary directory")
 	}
 
-	// Check if it's a development cache
-	if sc.isDevCache(file.Path, file.Name) {
-		reasons = append(reasons, "Development cache detected")
-		confidence -= 5
+	// Check if it's inside a git working tree, where deleting could lose unpushed
+	// work, and otherwise if it's a development cache
+	var git *GitReport
+	if sc.git != nil {
+		if git = sc.git.Inspect(file.Path, file.IsDir); git != nil && git.Ignored {
+			git = nil // Ignored files are build output like any other dev cache
+		}
+	}
+	if git != nil && git.HasUnpushedWork() {
+		e.add(SignalGitUnpushed, "sets Risky", git.Reasons()...)
+		level, levelSet = Risky, true
+	} else if git != nil && (git.Tracked || git.InGitDir) {
+		if git.InGitDir {
+			e.add(SignalGitTracked, "sets Caution unless Risky", fmt.Sprintf("Git metadata of repository %s, everything is pushed", git.Root))
+		} else {
+			e.add(SignalGitTracked, "sets Caution unless Risky", fmt.Sprintf("Tracked by git repository %s with no local changes", git.Root))
+		}
 		if level != Risky {
-			level = Caution
+			level, levelSet = Caution, true
+		}
+	} else if sc.isDevCache(file.Path, file.Name) {
+		e.add(SignalDevCache, "sets Caution unless Risky", "Development cache detected")
+		if level != Risky {
+			level, levelSet = Caution, true
 		}
 	}
 
 	// Check file permissions (read-only files might be more critical)
 	if strings.Contains(file.Permissions, "r--") && !strings.Contains(file.Permissions, "rw") {
-		reasons = append(reasons, "Read-only file, may be system-critical")
-		confidence -= 5
+		e.add(SignalReadOnly, "", "Read-only file, may be system-critical")
 	}
 
 	// Determine final level if not already set
-	if level == 0 { // Not set yet
-		if confidence >= 70 {
+	if !levelSet {
+		if e.confidence >= sc.config.SafeConfidence {
 			level = Safe
-		} else if confidence >= 40 {
+		} else if e.confidence >= sc.config.CautionConfidence {
 			level = Caution
 		} else {
 			level = Risky
 		}
 	}
 
-	// Ensure confidence is within bounds
-	if confidence > 100 {
-		confidence = 100
-	} else if confidence < 0 {
-		confidence = 0
+	// Check the content, user data saved into a cache location is worth keeping
+	var contentType string
+	if sc.config.SniffContent && !file.IsDir && file.Size > 0 && sc.isUserContentFolder(file.Path) {
+		if mimeType, kind := DetectContent(file.Path); kind != ContentUnknown {
+			contentType = mimeType
+			e.add(SignalUserContent, "one level riskier", fmt.Sprintf("Content is a %s (%s), likely user data", kind, mimeType))
+			if level < Risky {
+				level++
+			}
+		}
 	}
 
+	// Ensure confidence is within bounds
+	e.bound()
+
 	// Generate explanation
-	explanation := sc.generateExplanation(level, confidence, reasons)
+	explanation := sc.generateExplanation(level, e.confidence, e.reasons)
 
 	return SafetyClassification{
 		Level:       level,
-		Confidence:  confidence,
+		Confidence:  e.confidence,
 		Explanation: explanation,
-		Reasons:     reasons,
+		Reasons:     e.reasons,
+		ContentType: contentType,
+		Signals:     e.signals,
 	}
 }
 
 // isSystemCritical checks if the file path is in a system-critical location
 func (sc *SafetyClassifier) isSystemCritical(path string) bool {
+	switch action, _ := sc.config.PathRules.Match(path); action {
+	case RuleDeny:
+		return true
+	case RuleAllow:
+		return false
+	}
+	
 	normalizedPath := strings.ToLower(filepath.Clean(path))
 	
 	for _, criticalPath := range sc.config.SystemCriticalPaths {
@@ -263,6 +374,21 @@ func (sc *SafetyClassifier) isTempDirectory(path string) bool {
 	return false
 }
 
+// isUserContentFolder checks if the file is in a folder where users save content
+func (sc *SafetyClassifier) isUserContentFolder(path string) bool {
+	dir := strings.ToLower(filepath.Dir(filepath.Clean(path)))
+	
+	for _, segment := range strings.Split(filepath.ToSlash(dir), "/") {
+		for _, pattern := range sc.config.UserContentPatterns {
+			if strings.Contains(segment, strings.ToLower(pattern)) {
+				return true
+			}
+		}
+	}
+	
+	return false
+}
+
 // isDevCache checks if the file is a development cache
 func (sc *SafetyClassifier) isDevCache(path, name string) bool {
 	normalizedPath := strings.ToLower(filepath.Clean(path))
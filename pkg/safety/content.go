@@ -0,0 +1,75 @@
+package safety
+
+import (
+	"strings"
+
+	"github.com/wailsapp/mimetype"
+)
+
+// ContentKind groups detected content types by how valuable they are to the user
+type ContentKind string
+
+const (
+	ContentUnknown  ContentKind = ""
+	ContentDocument ContentKind = "document"
+	ContentImage    ContentKind = "image"
+	ContentArchive  ContentKind = "archive"
+	ContentDatabase ContentKind = "database"
+)
+
+// contentKinds maps MIME types to their kind. Office formats are zip files, so
+// detected types are looked up from the most specific one up to their parents.
+var contentKinds = map[string]ContentKind{
+	"application/pdf":               ContentDocument,
+	"application/postscript":        ContentDocument,
+	"application/msword":            ContentDocument,
+	"application/vnd.ms-excel":      ContentDocument,
+	"application/vnd.ms-powerpoint": ContentDocument,
+	"application/epub+zip":          ContentDocument,
+	"text/rtf":                      ContentDocument,
+	"application/vnd.sqlite3":       ContentDatabase,
+	"application/x-msaccess":        ContentDatabase,
+	"application/zip":               ContentArchive,
+	"application/gzip":              ContentArchive,
+	"application/x-tar":             ContentArchive,
+	"application/x-7z-compressed":   ContentArchive,
+	"application/x-rar-compressed":  ContentArchive,
+	"application/x-bzip2":           ContentArchive,
+	"application/x-xz":              ContentArchive,
+	"application/zstd":              ContentArchive,
+}
+
+// contentKindPrefixes classify whole families of MIME types
+var contentKindPrefixes = []struct {
+	prefix string
+	kind   ContentKind
+}{
+	{"application/vnd.openxmlformats-officedocument.", ContentDocument},
+	{"application/vnd.oasis.opendocument.", ContentDocument},
+	{"image/", ContentImage},
+}
+
+// DetectContent sniffs the magic bytes at the start of a file and returns its
+// MIME type and kind. Files that cannot be read are ContentUnknown.
+func DetectContent(path string) (string, ContentKind) {
+	mime, err := mimetype.DetectFile(path)
+	if err != nil {
+		return "", ContentUnknown
+	}
+	return mime.String(), contentKindOf(mime)
+}
+
+// contentKindOf returns the kind of the most specific known type in the MIME hierarchy
+func contentKindOf(mime *mimetype.MIME) ContentKind {
+	for m := mime; m != nil; m = m.Parent() {
+		if kind, ok := contentKinds[m.String()]; ok {
+			return kind
+		}
+		for _, family := range contentKindPrefixes {
+			if strings.HasPrefix(m.String(), family.prefix) {
+				return family.kind
+			}
+		}
+	}
+	return ContentUnknown
+}
@@ -0,0 +1,91 @@
+package safety
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestContentSniffing(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "cache", "Downloads")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]struct {
+		data []byte
+		kind ContentKind
+	}{
+		"report.bin":  {[]byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n1 0 obj\n"), ContentDocument},
+		"photo.tmp":   {[]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), ContentImage},
+		"bundle.dat":  {[]byte("PK\x03\x04\x14\x00\x00\x00\x08\x00"), ContentArchive},
+		"history":     {append([]byte("SQLite format 3\x00"), make([]byte, 84)...), ContentDatabase},
+		"entry.cache": {[]byte{0x01, 0x02, 0x03, 0x04, 0xfe, 0xff, 0x00, 0x10}, ContentUnknown},
+	}
+
+	classifier := NewDefaultSafetyClassifier()
+	old := time.Now().AddDate(0, 0, -60)
+	for name, file := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, file.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, kind := DetectContent(path); kind != file.kind {
+			t.Errorf("Expected %s to be detected as %q, got %q", name, file.kind, kind)
+		}
+
+		classification := classifier.ClassifyFile(FileMetadata{
+			Name:         name,
+			Path:         path,
+			Size:         int64(len(file.data)),
+			LastModified: old,
+			Permissions:  "-rw-r--r--",
+		})
+		// Old small files in a cache location are Safe unless they hold user content
+		// saved into a downloads-like folder
+		expected := Safe
+		if file.kind != ContentUnknown {
+			expected = Caution
+		}
+		if classification.Level != expected {
+			t.Errorf("Expected %s to be %s, got %s: %v", name, expected, classification.Level, classification.Reasons)
+		}
+		if (classification.ContentType != "") != (file.kind != ContentUnknown) {
+			t.Errorf("Unexpected content type for %s: %q", name, classification.ContentType)
+		}
+	}
+
+	// Media that caches hold by design stays Safe
+	for _, name := range []string{
+		filepath.Join(".cache", "thumbnails", "normal", "5d41402abc4b2a76.png"),
+		filepath.Join(".npm", "_cacache", "content-v2", "sha512", "ab", "cd12"),
+	} {
+		path := filepath.Join(root, name)
+		data := files["photo.tmp"].data
+		if filepath.Ext(name) == "" {
+			data = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00")
+		}
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		classification := classifier.ClassifyFile(FileMetadata{
+			Name:         filepath.Base(path),
+			Path:         path,
+			Size:         int64(len(data)),
+			LastModified: old,
+			Permissions:  "-rw-r--r--",
+		})
+		if classification.Level != Safe || classification.ContentType != "" {
+			t.Errorf("Expected cache media %s to stay Safe, got %s: %v", name, classification.Level, classification.Reasons)
+		}
+	}
+
+	config := DefaultConfig()
+	config.SniffContent = false
+	path := filepath.Join(dir, "report.bin")
+	if classification := NewSafetyClassifier(config).ClassifyFile(FileMetadata{Name: "report.bin", Path: path, Size: 20, LastModified: old}); classification.Level != Safe {
+		t.Errorf("Expected no content check when sniffing is disabled, got %s", classification.Level)
+	}
+}
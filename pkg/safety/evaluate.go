@@ -0,0 +1,136 @@
+package safety
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LabeledPath is a path with the level a person judged it to be
+type LabeledPath struct {
+	Path  string `json:"path"`
+	Level string `json:"level"` // Safe, Caution or Risky
+}
+
+// LevelMetrics measures how well the classifier finds one level
+type LevelMetrics struct {
+	Level     string  `json:"level"`
+	Support   int     `json:"support"`   // Paths labeled with the level
+	Predicted int     `json:"predicted"` // Paths classified as the level
+	Correct   int     `json:"correct"`
+	Precision float64 `json:"precision"` // Correct share of the predicted, 0 when none were
+	Recall    float64 `json:"recall"`    // Correct share of the labeled, 0 when none were
+}
+
+// Misclassification is a path whose level differs from its label
+type Misclassification struct {
+	Path       string   `json:"path"`
+	Expected   string   `json:"expected"`
+	Got        string   `json:"got"`
+	Confidence int      `json:"confidence"`
+	Signals    []Signal `json:"signals"`
+}
+
+// Evaluation reports the classifier's accuracy on a labeled corpus
+type Evaluation struct {
+	Total              int                       `json:"total"`
+	Evaluated          int                       `json:"evaluated"` // Paths that could be read and classified
+	Correct            int                       `json:"correct"`
+	Accuracy           float64                   `json:"accuracy"`
+	Levels             []LevelMetrics            `json:"levels"`
+	Confusion          map[string]map[string]int `json:"confusion"` // Expected level to classified level to count
+	Misclassifications []Misclassification       `json:"misclassifications,omitempty"`
+	Errors             []string                  `json:"errors,omitempty"`
+}
+
+// LoadCorpus reads a JSON array of labeled paths
+func LoadCorpus(path string) ([]LabeledPath, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus: %w", err)
+	}
+	var corpus []LabeledPath
+	if err := json.Unmarshal(data, &corpus); err != nil {
+		return nil, fmt.Errorf("failed to parse corpus: %w", err)
+	}
+	return corpus, nil
+}
+
+// FileMetadataFor reads the metadata the classifier needs from the file system
+func FileMetadataFor(path string) (FileMetadata, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return FileMetadata{}, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	return FileMetadata{
+		Name:         filepath.Base(path),
+		Path:         path,
+		Size:         info.Size(),
+		LastModified: info.ModTime(),
+		IsDir:        info.IsDir(),
+		Permissions:  info.Mode().String(),
+	}, nil
+}
+
+// Evaluate classifies every path of the corpus and reports precision and
+// recall per level. Paths that cannot be read or are badly labeled are
+// reported as errors and left out of the metrics.
+func (sc *SafetyClassifier) Evaluate(corpus []LabeledPath) *Evaluation {
+	levels := []SafetyLevel{Safe, Caution, Risky}
+	evaluation := &Evaluation{
+		Total:     len(corpus),
+		Confusion: make(map[string]map[string]int),
+	}
+	for _, level := range levels {
+		evaluation.Confusion[level.String()] = make(map[string]int)
+	}
+
+	for _, labeled := range corpus {
+		expected, err := ParseSafetyLevel(labeled.Level)
+		if err != nil {
+			evaluation.Errors = append(evaluation.Errors, fmt.Sprintf("%s: %v", labeled.Path, err))
+			continue
+		}
+		file, err := FileMetadataFor(labeled.Path)
+		if err != nil {
+			evaluation.Errors = append(evaluation.Errors, err.Error())
+			continue
+		}
+
+		classification := sc.ClassifyFile(file)
+		evaluation.Evaluated++
+		evaluation.Confusion[expected.String()][classification.Level.String()]++
+		if classification.Level == expected {
+			evaluation.Correct++
+			continue
+		}
+		evaluation.Misclassifications = append(evaluation.Misclassifications, Misclassification{
+			Path:       labeled.Path,
+			Expected:   expected.String(),
+			Got:        classification.Level.String(),
+			Confidence: classification.Confidence,
+			Signals:    classification.Signals,
+		})
+	}
+
+	if evaluation.Evaluated > 0 {
+		evaluation.Accuracy = float64(evaluation.Correct) / float64(evaluation.Evaluated)
+	}
+	for _, level := range levels {
+		metrics := LevelMetrics{Level: level.String()}
+		for _, other := range levels {
+			metrics.Support += evaluation.Confusion[level.String()][other.String()]
+			metrics.Predicted += evaluation.Confusion[other.String()][level.String()]
+		}
+		metrics.Correct = evaluation.Confusion[level.String()][level.String()]
+		if metrics.Predicted > 0 {
+			metrics.Precision = float64(metrics.Correct) / float64(metrics.Predicted)
+		}
+		if metrics.Support > 0 {
+			metrics.Recall = float64(metrics.Correct) / float64(metrics.Support)
+		}
+		evaluation.Levels = append(evaluation.Levels, metrics)
+	}
+	return evaluation
+}
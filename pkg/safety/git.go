@@ -0,0 +1,733 @@
+package safety
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxGitWalkEntries bounds how many entries are checked below a directory
+const maxGitWalkEntries = 20000
+
+// GitReport describes where a path lies in a git working tree and the work
+// there that has not been committed or pushed
+type GitReport struct {
+	Root      string   `json:"root"`
+	InGitDir  bool     `json:"in_git_dir"` // The path is, holds or is inside the repository metadata
+	Tracked   bool     `json:"tracked"`
+	Ignored   bool     `json:"ignored"`
+	Modified  []string `json:"modified,omitempty"`  // Tracked files changed or deleted in the working tree, relative to Root
+	Untracked []string `json:"untracked,omitempty"` // Files neither tracked nor ignored, relative to Root
+	Stashes   int      `json:"stashes,omitempty"`
+	Unpushed  []string `json:"unpushed,omitempty"` // Local branches without an up to date upstream
+}
+
+// HasUnpushedWork reports whether deleting the path could lose work that exists nowhere else
+func (r *GitReport) HasUnpushedWork() bool {
+	return len(r.Modified) > 0 || len(r.Untracked) > 0 || r.Stashes > 0 || len(r.Unpushed) > 0
+}
+
+// Reasons describes the unpushed work, one reason per kind
+func (r *GitReport) Reasons() []string {
+	var reasons []string
+	if len(r.Modified) > 0 {
+		reasons = append(reasons, fmt.Sprintf("Uncommitted changes in git repository %s: %s", r.Root, summarizePaths(r.Modified)))
+	}
+	if len(r.Untracked) > 0 {
+		reasons = append(reasons, fmt.Sprintf("Untracked files in git repository %s: %s", r.Root, summarizePaths(r.Untracked)))
+	}
+	if r.Stashes > 0 {
+		reasons = append(reasons, fmt.Sprintf("Git repository %s has %d stash(es)", r.Root, r.Stashes))
+	}
+	if len(r.Unpushed) > 0 {
+		reasons = append(reasons, fmt.Sprintf("Git repository %s has unpushed branches: %s", r.Root, strings.Join(r.Unpushed, ", ")))
+	}
+	return reasons
+}
+
+// summarizePaths lists the first few paths and counts the rest
+func summarizePaths(paths []string) string {
+	if len(paths) <= 3 {
+		return strings.Join(paths, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(paths[:3], ", "), len(paths)-3)
+}
+
+// GitInspector reads git repositories directly from their .git directory,
+// without running git or touching the network. Repositories are cached until
+// their index changes.
+type GitInspector struct {
+	mu    sync.Mutex
+	roots map[string]string // Directory to its working tree root, empty when outside any
+	repos map[string]*gitRepo
+}
+
+// NewGitInspector creates an inspector with empty caches
+func NewGitInspector() *GitInspector {
+	return &GitInspector{
+		roots: make(map[string]string),
+		repos: make(map[string]*gitRepo),
+	}
+}
+
+// Inspect returns the git state of path, or nil when it is not inside a
+// working tree or the repository cannot be read
+func (gi *GitInspector) Inspect(path string, isDir bool) *GitReport {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+
+	gi.mu.Lock()
+	defer gi.mu.Unlock()
+
+	root := gi.workTreeRoot(path)
+	if root == "" {
+		return nil
+	}
+	repo, err := gi.repo(root)
+	if err != nil {
+		return nil
+	}
+
+	report := &GitReport{Root: root}
+	rel, _ := filepath.Rel(root, path)
+	rel = filepath.ToSlash(rel)
+	if rel == "." || rel == ".git" || strings.HasPrefix(rel, ".git/") {
+		// The path holds or is inside the repository itself, so everything in it is at stake
+		report.InGitDir = true
+		report.Stashes = repo.stashes()
+		report.Unpushed = repo.unpushedBranches()
+		if rel != "." {
+			return report
+		}
+	}
+
+	if !isDir {
+		entry, tracked := repo.index[rel]
+		switch {
+		case tracked:
+			report.Tracked = true
+			if repo.modified(rel, entry) {
+				report.Modified = append(report.Modified, rel)
+			}
+		case repo.ignored(rel, false):
+			report.Ignored = true
+		default:
+			report.Untracked = append(report.Untracked, rel)
+		}
+		return report
+	}
+
+	if rel != "." && repo.ignored(rel, true) {
+		report.Ignored = true
+		return report
+	}
+	prefix := rel + "/"
+	if rel == "." {
+		prefix = ""
+	}
+	for i := sort.SearchStrings(repo.paths, prefix); i < len(repo.paths) && strings.HasPrefix(repo.paths[i], prefix); i++ {
+		name := repo.paths[i]
+		report.Tracked = true
+		if repo.modified(name, repo.index[name]) {
+			report.Modified = append(report.Modified, name)
+		}
+	}
+	report.Untracked = repo.untracked(path, prefix)
+	return report
+}
+
+// workTreeRoot finds the working tree containing path, caching every directory on the way
+func (gi *GitInspector) workTreeRoot(path string) string {
+	var visited []string
+	root := ""
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if cached, ok := gi.roots[dir]; ok {
+			root = cached
+			break
+		}
+		visited = append(visited, dir)
+		if filepath.Base(dir) == ".git" {
+			if info, err := os.Stat(dir); err == nil && info.IsDir() {
+				root = filepath.Dir(dir)
+				break
+			}
+		}
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			root = dir
+			break
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+	for _, dir := range visited {
+		gi.roots[dir] = root
+	}
+	return root
+}
+
+// repo returns the cached repository at root, reloading it when its index changed
+func (gi *GitInspector) repo(root string) (*gitRepo, error) {
+	gitDir, commonDir, err := resolveGitDir(root)
+	if err != nil {
+		return nil, err
+	}
+	var stamp time.Time
+	var size int64
+	if info, err := os.Stat(filepath.Join(gitDir, "index")); err == nil {
+		stamp, size = info.ModTime(), info.Size()
+	}
+	if repo, ok := gi.repos[root]; ok && repo.indexTime.Equal(stamp) && repo.indexSize == size {
+		return repo, nil
+	}
+
+	repo := &gitRepo{
+		root:      root,
+		gitDir:    gitDir,
+		commonDir: commonDir,
+		config:    readGitConfig(filepath.Join(commonDir, "config")),
+		indexTime: stamp,
+		indexSize: size,
+		ignores:   make(map[string][]ignorePattern),
+	}
+	repo.hashSize = sha1.Size
+	if strings.EqualFold(repo.config["extensions.objectformat"], "sha256") {
+		repo.hashSize = sha256.Size
+	}
+	if repo.index, err = readGitIndex(filepath.Join(gitDir, "index"), repo.hashSize); err != nil {
+		return nil, err
+	}
+	for name := range repo.index {
+		repo.paths = append(repo.paths, name)
+	}
+	sort.Strings(repo.paths)
+	gi.repos[root] = repo
+	return repo, nil
+}
+
+// resolveGitDir returns the git directory of a working tree and the common
+// directory holding its refs and config, which differ for linked worktrees
+func resolveGitDir(root string) (string, string, error) {
+	gitDir := filepath.Join(root, ".git")
+	info, err := os.Stat(gitDir)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to stat git directory: %w", err)
+	}
+	if !info.IsDir() {
+		data, err := os.ReadFile(gitDir)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read git file: %w", err)
+		}
+		if !strings.HasPrefix(string(data), "gitdir:") {
+			return "", "", fmt.Errorf("invalid git file: %s", gitDir)
+		}
+		gitDir = strings.TrimSpace(strings.TrimPrefix(string(data), "gitdir:"))
+		if !filepath.IsAbs(gitDir) {
+			gitDir = filepath.Join(root, gitDir)
+		}
+	}
+
+	commonDir := gitDir
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = strings.TrimSpace(string(data))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+	}
+	return gitDir, filepath.Clean(commonDir), nil
+}
+
+// indexEntry is the cached stat data and object id of a tracked file
+type indexEntry struct {
+	mtime    time.Time
+	size     uint32
+	hash     []byte
+	conflict bool
+}
+
+// gitRepo is the parsed state of one repository
+type gitRepo struct {
+	root      string
+	gitDir    string
+	commonDir string
+	config    map[string]string
+	hashSize  int
+	index     map[string]indexEntry
+	paths     []string // Sorted index paths
+	indexTime time.Time
+	indexSize int64
+	ignores   map[string][]ignorePattern // Patterns by the directory they apply to, relative to root
+}
+
+// readGitIndex parses the entries of an index file in version 2, 3 or 4.
+// A missing index is an empty repository.
+func readGitIndex(path string, hashSize int) (map[string]indexEntry, error) {
+	entries := make(map[string]indexEntry)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git index: %w", err)
+	}
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return nil, fmt.Errorf("invalid git index: %s", path)
+	}
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("unsupported git index version %d", version)
+	}
+	count := int(binary.BigEndian.Uint32(data[8:12]))
+
+	offset := 12
+	previous := ""
+	fixed := 40 + hashSize + 2 // Stat data, object id and flags
+	for i := 0; i < count; i++ {
+		if offset+fixed > len(data) {
+			return nil, fmt.Errorf("truncated git index: %s", path)
+		}
+		entry := data[offset:]
+		mtime := time.Unix(int64(binary.BigEndian.Uint32(entry[8:12])), int64(binary.BigEndian.Uint32(entry[12:16])))
+		size := binary.BigEndian.Uint32(entry[36:40])
+		hash := entry[40 : 40+hashSize]
+		flags := binary.BigEndian.Uint16(entry[40+hashSize : fixed])
+		pos := fixed
+		if version >= 3 && flags&0x4000 != 0 {
+			pos += 2 // Extended flags
+		}
+
+		var name string
+		if version == 4 {
+			// Paths are compressed against the previous entry
+			strip, n := readIndexVarint(entry[pos:])
+			if n == 0 || strip > len(previous) {
+				return nil, fmt.Errorf("invalid path in git index: %s", path)
+			}
+			pos += n
+			end := bytes.IndexByte(entry[pos:], 0)
+			if end < 0 {
+				return nil, fmt.Errorf("truncated git index: %s", path)
+			}
+			name = previous[:len(previous)-strip] + string(entry[pos:pos+end])
+			pos += end + 1
+		} else {
+			end := bytes.IndexByte(entry[pos:], 0)
+			if end < 0 {
+				return nil, fmt.Errorf("truncated git index: %s", path)
+			}
+			name = string(entry[pos : pos+end])
+			pos = (pos + end + 8) &^ 7 // Entries are NUL padded to a multiple of 8 bytes
+		}
+		offset += pos
+		previous = name
+
+		existing, seen := entries[name]
+		entries[name] = indexEntry{
+			mtime:    mtime,
+			size:     size,
+			hash:     append([]byte(nil), hash...),
+			conflict: flags&0x3000 != 0 || (seen && existing.conflict),
+		}
+	}
+	return entries, nil
+}
+
+// readIndexVarint decodes the offset encoding used by index version 4
+func readIndexVarint(data []byte) (int, int) {
+	value := 0
+	for i, c := range data {
+		if i > 0 {
+			value++
+		}
+		value = value<<7 | int(c&0x7f)
+		if c&0x80 == 0 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// modified reports whether a tracked file differs from the index, comparing
+// contents when the stat data changed
+func (r *gitRepo) modified(name string, entry indexEntry) bool {
+	if entry.conflict {
+		return true
+	}
+	path := filepath.Join(r.root, filepath.FromSlash(name))
+	info, err := os.Lstat(path)
+	if err != nil {
+		return true // Deleted
+	}
+	if info.IsDir() {
+		return false // Submodules are repositories of their own
+	}
+	if uint32(info.Size()) != entry.size {
+		return true
+	}
+	if info.ModTime().Equal(entry.mtime) || (entry.mtime.Nanosecond() == 0 && info.ModTime().Unix() == entry.mtime.Unix()) {
+		return false
+	}
+	hash, err := r.hashBlob(path, info)
+	return err != nil || !bytes.Equal(hash, entry.hash)
+}
+
+// hashBlob computes the object id git would store for a file
+func (r *gitRepo) hashBlob(path string, info os.FileInfo) ([]byte, error) {
+	var h hash.Hash = sha1.New()
+	if r.hashSize == sha256.Size {
+		h = sha256.New()
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		// Symbolic links are stored as their target
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(h, "blob %d\x00%s", len(target), target)
+		return h.Sum(nil), nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	fmt.Fprintf(h, "blob %d\x00", info.Size())
+	if _, err := io.Copy(h, file); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// untracked walks dir and returns the files that are neither tracked nor ignored
+func (r *gitRepo) untracked(dir, prefix string) []string {
+	var untracked []string
+	checked := 0
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || path == dir {
+			return nil
+		}
+		if checked++; checked > maxGitWalkEntries {
+			return filepath.SkipAll
+		}
+		rel, _ := filepath.Rel(r.root, path)
+		rel = filepath.ToSlash(rel)
+		if entry.IsDir() {
+			if entry.Name() == ".git" || r.ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			if _, err := os.Lstat(filepath.Join(path, ".git")); err == nil && !r.hasTrackedPrefix(rel+"/") {
+				return filepath.SkipDir // A nested repository
+			}
+			return nil
+		}
+		if _, tracked := r.index[rel]; !tracked && !r.ignored(rel, false) {
+			untracked = append(untracked, rel)
+		}
+		return nil
+	})
+	return untracked
+}
+
+// hasTrackedPrefix reports whether any tracked path starts with prefix
+func (r *gitRepo) hasTrackedPrefix(prefix string) bool {
+	i := sort.SearchStrings(r.paths, prefix)
+	return i < len(r.paths) && strings.HasPrefix(r.paths[i], prefix)
+}
+
+// stashes counts the entries of the stash reflog
+func (r *gitRepo) stashes() int {
+	data, err := os.ReadFile(filepath.Join(r.commonDir, "logs", "refs", "stash"))
+	if err == nil {
+		return bytes.Count(bytes.TrimSpace(data), []byte("\n")) + 1
+	}
+	if r.resolveRef("refs/stash") != "" {
+		return 1
+	}
+	return 0
+}
+
+// unpushedBranches lists local branches that have no upstream or point
+// elsewhere than their upstream
+func (r *gitRepo) unpushedBranches() []string {
+	refs := r.refs()
+	var unpushed []string
+	for ref, id := range refs {
+		branch, ok := strings.CutPrefix(ref, "refs/heads/")
+		if !ok {
+			continue
+		}
+		remote := r.config["branch."+branch+".remote"]
+		merge := r.config["branch."+branch+".merge"]
+		if remote == "" || merge == "" {
+			unpushed = append(unpushed, branch+" (no upstream)")
+			continue
+		}
+		upstream := merge
+		if remote != "." {
+			upstream = "refs/remotes/" + remote + "/" + strings.TrimPrefix(merge, "refs/heads/")
+		}
+		if refs[upstream] != id {
+			unpushed = append(unpushed, branch)
+		}
+	}
+	sort.Strings(unpushed)
+	return unpushed
+}
+
+// refs reads the loose and packed refs, loose refs taking precedence
+func (r *gitRepo) refs() map[string]string {
+	refs := make(map[string]string)
+	if file, err := os.Open(filepath.Join(r.commonDir, "packed-refs")); err == nil {
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "" || line[0] == '#' || line[0] == '^' {
+				continue
+			}
+			if id, ref, ok := strings.Cut(line, " "); ok {
+				refs[ref] = id
+			}
+		}
+		file.Close()
+	}
+	refsDir := filepath.Join(r.commonDir, "refs")
+	filepath.WalkDir(refsDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(r.commonDir, path)
+		if id := r.resolveRef(filepath.ToSlash(rel)); id != "" {
+			refs[filepath.ToSlash(rel)] = id
+		}
+		return nil
+	})
+	return refs
+}
+
+// resolveRef returns the object id of a loose ref, following symbolic refs
+func (r *gitRepo) resolveRef(ref string) string {
+	for depth := 0; depth < 5; depth++ {
+		data, err := os.ReadFile(filepath.Join(r.commonDir, filepath.FromSlash(ref)))
+		if err != nil {
+			return ""
+		}
+		value := strings.TrimSpace(string(data))
+		target, symbolic := strings.CutPrefix(value, "ref: ")
+		if !symbolic {
+			if _, err := hex.DecodeString(value); err != nil {
+				return ""
+			}
+			return value
+		}
+		ref = target
+	}
+	return ""
+}
+
+// readGitConfig reads a git config file into section.subsection.key values.
+// Section and key names are lower case; subsections keep their case.
+func readGitConfig(path string) map[string]string {
+	config := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config
+	}
+	section := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			header := strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
+			name, sub, hasSub := strings.Cut(header, " ")
+			section = strings.ToLower(name)
+			if hasSub {
+				section += "." + strings.Trim(strings.TrimSpace(sub), `"`)
+			}
+			continue
+		}
+		key, value, _ := strings.Cut(line, "=")
+		config[section+"."+strings.ToLower(strings.TrimSpace(key))] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return config
+}
+
+// ignorePattern is one line of a .gitignore file
+type ignorePattern struct {
+	regex   *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignored reports whether a path relative to the root is ignored by
+// info/exclude or the .gitignore files above it. Like git, nothing below an
+// ignored directory can be re-included.
+func (r *gitRepo) ignored(rel string, isDir bool) bool {
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		last := i == len(parts)-1
+		if r.matchIgnore(strings.Join(parts[:i+1], "/"), !last || isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchIgnore applies the patterns of every directory above rel, the deepest
+// and latest matching pattern deciding
+func (r *gitRepo) matchIgnore(rel string, isDir bool) bool {
+	dirs := []string{""}
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		dirs = append(dirs, strings.Join(parts[:i], "/"))
+	}
+
+	ignored := false
+	for _, dir := range dirs {
+		sub := rel
+		if dir != "" {
+			sub = strings.TrimPrefix(rel, dir+"/")
+		}
+		for _, pattern := range r.ignorePatterns(dir) {
+			if pattern.dirOnly && !isDir {
+				continue
+			}
+			if pattern.regex.MatchString(sub) {
+				ignored = !pattern.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// ignorePatterns returns the patterns applying to a directory relative to the root.
+// The root also gets info/exclude, which ranks below its .gitignore.
+func (r *gitRepo) ignorePatterns(dir string) []ignorePattern {
+	if patterns, ok := r.ignores[dir]; ok {
+		return patterns
+	}
+	var patterns []ignorePattern
+	if dir == "" {
+		patterns = readIgnoreFile(filepath.Join(r.commonDir, "info", "exclude"))
+	}
+	patterns = append(patterns, readIgnoreFile(filepath.Join(r.root, filepath.FromSlash(dir), ".gitignore"))...)
+	r.ignores[dir] = patterns
+	return patterns
+}
+
+// readIgnoreFile parses the patterns of a .gitignore or exclude file
+func readIgnoreFile(path string) []ignorePattern {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var patterns []ignorePattern
+	for _, line := range strings.Split(string(data), "\n") {
+		if pattern, ok := parseIgnorePattern(line); ok {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// parseIgnorePattern compiles a gitignore line into a regular expression
+// matched against paths relative to the directory of the file
+func parseIgnorePattern(line string) (ignorePattern, bool) {
+	line = strings.TrimRight(line, "\r")
+	if !strings.HasSuffix(line, `\ `) {
+		line = strings.TrimRight(line, " ")
+	}
+	if line == "" || line[0] == '#' {
+		return ignorePattern{}, false
+	}
+
+	var pattern ignorePattern
+	if line[0] == '!' {
+		pattern.negate = true
+		line = line[1:]
+	} else if line[0] == '\\' && len(line) > 1 && (line[1] == '!' || line[1] == '#') {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		pattern.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignorePattern{}, false
+	}
+
+	// Patterns with a slash other than at the end are relative to the
+	// directory of the file, the others match a name at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; c {
+		case '*':
+			if i+1 < len(line) && line[i+1] == '*' {
+				switch {
+				case i+2 < len(line) && line[i+2] == '/':
+					expr.WriteString("(?:.*/)?")
+					i += 2
+				default:
+					expr.WriteString(".*")
+					i++
+				}
+				continue
+			}
+			expr.WriteString("[^/]*")
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(line[i+1:], ']')
+			if end < 0 {
+				expr.WriteString(`\[`)
+				continue
+			}
+			class := line[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(line) {
+				i++
+				expr.WriteString(regexp.QuoteMeta(string(line[i])))
+			}
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+
+	regex, err := regexp.Compile(expr.String())
+	if err != nil {
+		return ignorePattern{}, false
+	}
+	pattern.regex = regex
+	return pattern, true
+}
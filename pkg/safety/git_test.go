@@ -0,0 +1,131 @@
+package safety
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGitInspector(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	write := func(path, content string) string {
+		t.Helper()
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	run("init", "-q", "-b", "main")
+	write(".gitignore", "build/\n*.log\n!keep.log\n/docs/**/*.tmp\n")
+	edited := write("src/main.go", "package main\n")
+	touched := write("src/util.go", "package main\n\nfunc util() {}\n")
+	clean := write("README.md", "readme\n")
+	run("add", ".")
+	run("commit", "-q", "-m", "initial")
+
+	write("src/main.go", "package main\n\nfunc main() {}\n")
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(touched, later, later); err != nil {
+		t.Fatal(err)
+	}
+	untracked := write("notes/todo.txt", "todo\n")
+	ignored := write("build/out.bin", "binary")
+	ignoredLog := write("debug.log", "log")
+	keptLog := write("keep.log", "log")
+	ignoredTmp := write("docs/a/b/draft.tmp", "draft")
+
+	inspector := NewGitInspector()
+	check := func(name, path string, isDir bool, expect func(*GitReport) bool) {
+		t.Helper()
+		if report := inspector.Inspect(path, isDir); report == nil || !expect(report) {
+			t.Errorf("Unexpected report for %s: %+v", name, report)
+		}
+	}
+	check("edited file", edited, false, func(r *GitReport) bool { return r.Tracked && len(r.Modified) == 1 })
+	check("touched file", touched, false, func(r *GitReport) bool { return r.Tracked && !r.HasUnpushedWork() })
+	check("clean file", clean, false, func(r *GitReport) bool { return r.Tracked && !r.HasUnpushedWork() })
+	check("untracked file", untracked, false, func(r *GitReport) bool { return !r.Tracked && len(r.Untracked) == 1 })
+	check("ignored directory", filepath.Dir(ignored), true, func(r *GitReport) bool { return r.Ignored })
+	check("ignored file", ignored, false, func(r *GitReport) bool { return r.Ignored })
+	check("ignored log", ignoredLog, false, func(r *GitReport) bool { return r.Ignored })
+	check("re-included log", keptLog, false, func(r *GitReport) bool { return !r.Ignored && len(r.Untracked) == 1 })
+	check("anchored glob", ignoredTmp, false, func(r *GitReport) bool { return r.Ignored })
+	check("source directory", filepath.Join(root, "src"), true, func(r *GitReport) bool {
+		return r.Tracked && len(r.Modified) == 1 && r.Modified[0] == "src/main.go" && len(r.Untracked) == 0
+	})
+	check("git directory", filepath.Join(root, ".git", "objects"), true, func(r *GitReport) bool {
+		return r.InGitDir && len(r.Unpushed) == 1 && r.Unpushed[0] == "main (no upstream)" && r.Stashes == 0
+	})
+	if report := inspector.Inspect(t.TempDir(), true); report != nil {
+		t.Errorf("Expected no report outside a working tree, got %+v", report)
+	}
+
+	// Stashing the edit leaves the working tree clean but the stash behind
+	run("stash", "-q")
+	run("update-index", "--index-version", "4")
+	check("stashed file", edited, false, func(r *GitReport) bool { return r.Tracked && !r.HasUnpushedWork() })
+	check("repository root", root, true, func(r *GitReport) bool {
+		return r.InGitDir && r.Stashes == 1 && len(r.Modified) == 0 && len(r.Untracked) == 2
+	})
+
+	classifier := NewDefaultSafetyClassifier()
+	classification := classifier.ClassifyFile(FileMetadata{Name: "todo.txt", Path: untracked, Size: 5, LastModified: time.Now().AddDate(0, 0, -60)})
+	if classification.Level != Risky || !strings.Contains(strings.Join(classification.Reasons, "\n"), "Untracked files in git repository") {
+		t.Errorf("Expected an untracked file to be risky, got %s: %v", classification.Level, classification.Reasons)
+	}
+	classification = classifier.ClassifyFile(FileMetadata{Name: "out.bin", Path: ignored, Size: 6, LastModified: time.Now().AddDate(0, 0, -60)})
+	if classification.Level == Risky || !strings.Contains(strings.Join(classification.Reasons, "\n"), "Development cache detected") {
+		t.Errorf("Expected ignored build output to be a development cache, got %s: %v", classification.Level, classification.Reasons)
+	}
+}
+
+func TestParseIgnorePattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		match   bool
+	}{
+		{"*.log", "a/b/debug.log", false, true},
+		{"/*.log", "a/debug.log", false, false},
+		{"build/", "build", false, false},
+		{"build/", "src/build", true, true},
+		{"docs/*.md", "docs/a.md", false, true},
+		{"docs/*.md", "x/docs/a.md", false, false},
+		{"**/cache", "a/b/cache", true, true},
+		{"out/**", "out/a/b", false, true},
+		{"a/**/z", "a/z", false, true},
+		{"file[0-9].txt", "file7.txt", false, true},
+		{"file[!0-9].txt", "file7.txt", false, false},
+	}
+	for _, c := range cases {
+		pattern, ok := parseIgnorePattern(c.pattern)
+		if !ok {
+			t.Fatalf("Failed to parse %q", c.pattern)
+		}
+		if match := (!pattern.dirOnly || c.isDir) && pattern.regex.MatchString(c.path); match != c.match {
+			t.Errorf("Expected %q matching %q to be %v", c.pattern, c.path, c.match)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package safety
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// RuleAction is what a user path rule does with the paths it matches
+type RuleAction string
+
+const (
+	RuleAllow RuleAction = "allow" // Always treat as safe to delete
+	RuleDeny  RuleAction = "deny"  // Never touch, wins over any allow rule
+)
+
+// PathRule is a user rule for paths matching a glob pattern. Patterns are
+// anchored: they start with / or ~/ and match the whole path of a file or of
+// a directory above it. * and ? do not cross a /, ** matches any number of
+// directories.
+type PathRule struct {
+	Pattern string     `json:"pattern"`
+	Action  RuleAction `json:"action"`
+	Note    string     `json:"note,omitempty"`
+}
+
+// RuleExplanation tells which user rules match a path and which one decides
+type RuleExplanation struct {
+	Path        string     `json:"path"`
+	Decision    RuleAction `json:"decision,omitempty"`     // Empty when no rule applies
+	Rule        *PathRule  `json:"rule,omitempty"`         // The deciding rule
+	Matches     []PathRule `json:"matches,omitempty"`      // Rules matching the path or a directory above it
+	DeniedBelow []PathRule `json:"denied_below,omitempty"` // Deny rules that can match inside the path
+}
+
+// compiledRule is a rule with its pattern split into path segments
+type compiledRule struct {
+	rule     PathRule
+	segments []string
+}
+
+// PathRules matches paths against user allow and deny rules. A nil
+// *PathRules has no rules.
+type PathRules struct {
+	rules []compiledRule
+}
+
+// NewPathRules compiles rules, expanding ~ to home
+func NewPathRules(rules []PathRule, home string) (*PathRules, error) {
+	pr := &PathRules{}
+	for _, rule := range rules {
+		segments, err := compilePathPattern(rule.Pattern, home)
+		if err != nil {
+			return nil, err
+		}
+		if rule.Action != RuleAllow && rule.Action != RuleDeny {
+			return nil, fmt.Errorf("invalid action %q for path rule %s", rule.Action, rule.Pattern)
+		}
+		pr.rules = append(pr.rules, compiledRule{rule: rule, segments: segments})
+	}
+	return pr, nil
+}
+
+// compilePathPattern expands and splits an anchored pattern into segments
+func compilePathPattern(pattern, home string) ([]string, error) {
+	expanded := pattern
+	if expanded == "~" || strings.HasPrefix(expanded, "~/") {
+		if home == "" {
+			return nil, fmt.Errorf("cannot expand ~ in path rule %s without a home directory", pattern)
+		}
+		expanded = filepath.ToSlash(home) + strings.TrimPrefix(expanded, "~")
+	}
+	expanded = filepath.ToSlash(expanded)
+	if !strings.HasPrefix(expanded, "/") && !filepath.IsAbs(filepath.FromSlash(expanded)) {
+		return nil, fmt.Errorf("path rule %s must start with / or ~/", pattern)
+	}
+
+	segments := splitPath(path.Clean(expanded))
+	for _, segment := range segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob in path rule %s: %w", pattern, err)
+		}
+	}
+	return segments, nil
+}
+
+// splitPath splits a slash separated path into its segments
+func splitPath(p string) []string {
+	return strings.Split(strings.TrimSuffix(p, "/"), "/")
+}
+
+// Match returns the rule deciding a path by itself: the first deny rule
+// matching it or a directory above it, otherwise the first such allow rule
+func (pr *PathRules) Match(p string) (RuleAction, *PathRule) {
+	explanation := pr.explain(p, false)
+	return explanation.Decision, explanation.Rule
+}
+
+// Decide is Match that also denies a directory when a deny rule can match
+// anything inside it, since deleting the directory would delete that too
+func (pr *PathRules) Decide(p string, isDir bool) (RuleAction, *PathRule) {
+	explanation := pr.explain(p, isDir)
+	return explanation.Decision, explanation.Rule
+}
+
+// Explain lists the rules applying to a path and the one deciding it
+func (pr *PathRules) Explain(p string) RuleExplanation {
+	return pr.explain(p, true)
+}
+
+// explain matches every rule, looking inside the path when below is set
+func (pr *PathRules) explain(p string, below bool) RuleExplanation {
+	explanation := RuleExplanation{Path: p}
+	if pr == nil || len(pr.rules) == 0 {
+		return explanation
+	}
+	if abs, err := filepath.Abs(p); err == nil {
+		p = abs
+	}
+	segments := splitPath(path.Clean(filepath.ToSlash(p)))
+
+	for _, compiled := range pr.rules {
+		if matchAncestorOrSelf(compiled.segments, segments) {
+			explanation.Matches = append(explanation.Matches, compiled.rule)
+		} else if below && compiled.rule.Action == RuleDeny && matchBelow(compiled.segments, segments) {
+			explanation.DeniedBelow = append(explanation.DeniedBelow, compiled.rule)
+		}
+	}
+
+	for i, rule := range explanation.Matches {
+		if rule.Action == RuleDeny {
+			explanation.Decision, explanation.Rule = RuleDeny, &explanation.Matches[i]
+			return explanation
+		}
+	}
+	if len(explanation.DeniedBelow) > 0 {
+		explanation.Decision, explanation.Rule = RuleDeny, &explanation.DeniedBelow[0]
+		return explanation
+	}
+	if len(explanation.Matches) > 0 {
+		explanation.Decision, explanation.Rule = RuleAllow, &explanation.Matches[0]
+	}
+	return explanation
+}
+
+// matchAncestorOrSelf reports whether the pattern matches the path or a directory above it
+func matchAncestorOrSelf(pattern, segments []string) bool {
+	for i := 1; i <= len(segments); i++ {
+		if matchSegments(pattern, segments[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches whole paths, ** standing for any number of segments
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	matched, _ := path.Match(pattern[0], segments[0])
+	return matched && matchSegments(pattern[1:], segments[1:])
+}
+
+// matchBelow reports whether the pattern can match a path inside the directory
+func matchBelow(pattern, segments []string) bool {
+	if len(segments) == 0 {
+		return len(pattern) > 0
+	}
+	if len(pattern) == 0 {
+		return false
+	}
+	if pattern[0] == "**" {
+		return true
+	}
+	matched, _ := path.Match(pattern[0], segments[0])
+	return matched && matchBelow(pattern[1:], segments[1:])
+}
+
+// ruleClassification is the classification a user rule imposes
+func (sc *SafetyClassifier) ruleClassification(action RuleAction, rule *PathRule) SafetyClassification {
+	level := Safe
+	reason := "Allowed by path rule " + rule.Pattern
+	if action == RuleDeny {
+		level = Risky
+		reason = "Denied by path rule " + rule.Pattern
+	}
+	if rule.Note != "" {
+		reason += " (" + rule.Note + ")"
+	}
+	reasons := []string{reason}
+	return SafetyClassification{
+		Level:       level,
+		Confidence:  100,
+		Explanation: sc.generateExplanation(level, 100, reasons),
+		Reasons:     reasons,
+		Signals:     []Signal{{Name: SignalPathRule, Reason: reason, Contribution: 100, Level: "sets " + level.String()}},
+	}
+}
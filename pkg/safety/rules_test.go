@@ -0,0 +1,100 @@
+package safety
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPathRules(t *testing.T) {
+	home := "/home/dev"
+	rules, err := NewPathRules([]PathRule{
+		{Pattern: "/var/tmp/build-*", Action: RuleAllow},
+		{Pattern: "~/.cache/JetBrains/*/index", Action: RuleDeny, Note: "IDE indexes"},
+		{Pattern: "~/.cache/**", Action: RuleAllow},
+		{Pattern: "/var/tmp/build-*/keep", Action: RuleDeny},
+	}, home)
+	if err != nil {
+		t.Fatalf("Failed to compile rules: %v", err)
+	}
+
+	cases := []struct {
+		path     string
+		isDir    bool
+		decision RuleAction
+		pattern  string
+	}{
+		{"/var/tmp/build-42/out", true, RuleAllow, "/var/tmp/build-*"},
+		{"/var/tmp/build-42/out/a.o", false, RuleAllow, "/var/tmp/build-*"},
+		{"/var/tmp/build-42/keep/state", false, RuleDeny, "/var/tmp/build-*/keep"},
+		{"/var/tmp/other", true, "", ""},
+		{"/home/dev/.cache/JetBrains/IdeaIC2024.1/index/shard", false, RuleDeny, "~/.cache/JetBrains/*/index"},
+		{"/home/dev/.cache/JetBrains/IdeaIC2024.1/log/idea.log", false, RuleAllow, "~/.cache/**"},
+		// Deleting a directory would delete the denied paths inside it
+		{"/home/dev/.cache/JetBrains", true, RuleDeny, "~/.cache/JetBrains/*/index"},
+		{"/home/dev/.cache/JetBrains", false, RuleAllow, "~/.cache/**"},
+		{"/home/dev/.cachefoo", false, "", ""},
+	}
+	for _, c := range cases {
+		decision, rule := rules.Decide(c.path, c.isDir)
+		if decision != c.decision || (rule == nil) != (c.pattern == "") || (rule != nil && rule.Pattern != c.pattern) {
+			t.Errorf("Expected %s to be decided %q by %q, got %q by %+v", c.path, c.decision, c.pattern, decision, rule)
+		}
+	}
+
+	explanation := rules.Explain("/var/tmp/build-42")
+	if explanation.Decision != RuleDeny || len(explanation.Matches) != 1 || len(explanation.DeniedBelow) != 1 {
+		t.Errorf("Expected the allowed directory to be denied for the path inside it, got %+v", explanation)
+	}
+	if action, _ := rules.Match("/var/tmp/build-42"); action != RuleAllow {
+		t.Errorf("Expected the directory itself to be allowed, got %q", action)
+	}
+
+	var none *PathRules
+	if decision, rule := none.Decide("/tmp", true); decision != "" || rule != nil {
+		t.Error("Expected no decision without rules")
+	}
+	for _, pattern := range []string{"relative/*", "~/[a-"} {
+		if _, err := NewPathRules([]PathRule{{Pattern: pattern, Action: RuleDeny}}, home); err == nil {
+			t.Errorf("Expected %q to be rejected", pattern)
+		}
+	}
+}
+
+func TestPathRulesOverrideClassification(t *testing.T) {
+	dir := t.TempDir()
+	denied := filepath.Join(dir, "index", "shard")
+	allowed := filepath.Join(dir, "build-1", "photo")
+	for path, data := range map[string][]byte{denied: []byte("x"), allowed: []byte("\x89PNG\r\n\x1a\n")} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := DefaultConfig()
+	rules, err := NewPathRules([]PathRule{
+		{Pattern: filepath.ToSlash(dir) + "/**", Action: RuleAllow},
+		{Pattern: filepath.ToSlash(dir) + "/index", Action: RuleDeny},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.PathRules = rules
+	classifier := NewSafetyClassifier(config)
+
+	old := time.Now().AddDate(0, 0, -60)
+	if classification := classifier.ClassifyFile(FileMetadata{Name: "shard", Path: denied, Size: 1, LastModified: old}); classification.Level != Risky || classification.Confidence != 100 {
+		t.Errorf("Expected the denied file to be risky, got %+v", classification)
+	}
+	// An allow rule overrides the content check that would flag the image
+	if classification := classifier.ClassifyFile(FileMetadata{Name: "photo", Path: allowed, Size: 8, LastModified: old}); classification.Level != Safe {
+		t.Errorf("Expected the allowed file to be safe, got %+v", classification)
+	}
+	if !classifier.isSystemCritical(denied) {
+		t.Error("Expected a denied path to be treated as system critical")
+	}
+}
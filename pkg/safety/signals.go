@@ -0,0 +1,128 @@
+package safety
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Signal names, also the keys of Weights
+const (
+	SignalBase           = "base"
+	SignalOldAge         = "old_age"
+	SignalRecent         = "recent"
+	SignalLargeSize      = "large_size"
+	SignalSmallSize      = "small_size"
+	SignalSystemCritical = "system_critical"
+	SignalTempDirectory  = "temp_directory"
+	SignalGitUnpushed    = "git_unpushed"
+	SignalGitTracked     = "git_tracked"
+	SignalDevCache       = "dev_cache"
+	SignalReadOnly       = "read_only"
+	SignalUserContent    = "user_content"
+	SignalPathRule       = "path_rule" // Decides alone, not weighted
+	SignalBounds         = "bounds"    // Keeps the confidence within 0-100, not weighted
+)
+
+// Signal is one piece of evidence behind a classification. The contributions
+// of all signals add up to the confidence.
+type Signal struct {
+	Name         string `json:"name"`
+	Reason       string `json:"reason"`
+	Weight       int    `json:"weight"`          // Configured confidence points of the signal
+	Contribution int    `json:"contribution"`    // Points it actually added to the confidence
+	Level        string `json:"level,omitempty"` // How it moved the level, if it did
+}
+
+// Weights are the confidence points each signal adds, keyed by signal name
+type Weights map[string]int
+
+// DefaultWeights returns the weights the classifier was calibrated with
+func DefaultWeights() Weights {
+	return Weights{
+		SignalBase:           50,
+		SignalOldAge:         20,
+		SignalRecent:         -15,
+		SignalLargeSize:      -10,
+		SignalSmallSize:      5,
+		SignalSystemCritical: -30,
+		SignalTempDirectory:  25,
+		SignalGitUnpushed:    -30,
+		SignalGitTracked:     -10,
+		SignalDevCache:       -5,
+		SignalReadOnly:       -5,
+		SignalUserContent:    -15,
+	}
+}
+
+// With returns a copy of the weights with overrides applied. Overrides must
+// name a weighted signal and stay within -100 to 100.
+func (w Weights) With(overrides map[string]int) (Weights, error) {
+	merged := make(Weights, len(w))
+	for name, weight := range w {
+		merged[name] = weight
+	}
+
+	var unknown []string
+	for name, weight := range overrides {
+		if _, ok := w[name]; !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		if weight < -100 || weight > 100 {
+			return nil, fmt.Errorf("weight of %s must be between -100 and 100", name)
+		}
+		merged[name] = weight
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown classifier signals: %s", strings.Join(unknown, ", "))
+	}
+	return merged, nil
+}
+
+// evidence collects the signals firing while a file is classified
+type evidence struct {
+	weights    Weights
+	signals    []Signal
+	reasons    []string
+	confidence int
+}
+
+// newEvidence starts from the base confidence
+func newEvidence(weights Weights) *evidence {
+	base := weights[SignalBase]
+	return &evidence{
+		weights:    weights,
+		signals:    []Signal{{Name: SignalBase, Reason: "Base confidence", Weight: base, Contribution: base}},
+		confidence: base,
+	}
+}
+
+// add records a signal and its reasons, adding its weight to the confidence
+func (e *evidence) add(name, level string, reasons ...string) {
+	weight := e.weights[name]
+	e.confidence += weight
+	e.signals = append(e.signals, Signal{
+		Name:         name,
+		Reason:       strings.Join(reasons, "; "),
+		Weight:       weight,
+		Contribution: weight,
+		Level:        level,
+	})
+	e.reasons = append(e.reasons, reasons...)
+}
+
+// bound keeps the confidence within 0-100, recording the correction
+func (e *evidence) bound() {
+	correction := 0
+	if e.confidence > 100 {
+		correction = 100 - e.confidence
+	} else if e.confidence < 0 {
+		correction = -e.confidence
+	}
+	if correction != 0 {
+		e.confidence += correction
+		e.signals = append(e.signals, Signal{Name: SignalBounds, Reason: "Confidence kept within 0-100", Contribution: correction})
+	}
+}
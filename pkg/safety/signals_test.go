@@ -0,0 +1,120 @@
+package safety
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignalsAndWeights(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := FileMetadata{Name: "entry", Path: filepath.Join(dir, "entry"), Size: 10, LastModified: time.Now().AddDate(0, 0, -60)}
+
+	classification := NewDefaultSafetyClassifier().ClassifyFile(file)
+	total := 0
+	names := make([]string, 0, len(classification.Signals))
+	for _, signal := range classification.Signals {
+		total += signal.Contribution
+		names = append(names, signal.Name)
+	}
+	// 50 base + 20 old + 5 small + 25 temporary
+	if got := strings.Join(names, ","); got != "base,old_age,small_size,temp_directory" {
+		t.Errorf("Unexpected signals: %s", got)
+	}
+	if classification.Level != Safe || classification.Confidence != 100 || total != classification.Confidence {
+		t.Errorf("Expected contributions adding up to the confidence, got %d of %+v", total, classification)
+	}
+
+	weights, err := DefaultWeights().With(map[string]int{SignalTempDirectory: -40})
+	if err != nil {
+		t.Fatalf("Failed to override weights: %v", err)
+	}
+	config := DefaultConfig()
+	config.Weights = weights
+	classification = NewSafetyClassifier(config).ClassifyFile(file)
+	if classification.Level != Risky || classification.Confidence != 35 {
+		t.Errorf("Expected a negative temporary directory weight to make the file risky, got %+v", classification)
+	}
+	// Confidence beyond 100 is cut back by a bounds signal
+	config.Weights, _ = DefaultWeights().With(map[string]int{SignalBase: 80})
+	classification = NewSafetyClassifier(config).ClassifyFile(file)
+	last := classification.Signals[len(classification.Signals)-1]
+	if classification.Confidence != 100 || last.Name != SignalBounds || last.Contribution != -30 {
+		t.Errorf("Expected the confidence to be bounded, got %+v", classification)
+	}
+	if DefaultWeights()[SignalTempDirectory] != 25 {
+		t.Error("Expected overrides to leave the defaults alone")
+	}
+
+	// A recent temporary file is rated by its confidence, 50 + 25 - 15 + 5,
+	// and no signal claims to have set its level
+	recent := file
+	recent.LastModified = time.Now()
+	classification = NewDefaultSafetyClassifier().ClassifyFile(recent)
+	if classification.Level != Caution || classification.Confidence != 65 {
+		t.Errorf("Expected a recent temporary file to be Caution at 65, got %+v", classification)
+	}
+	for _, signal := range classification.Signals {
+		if signal.Level != "" {
+			t.Errorf("Signal %s claims %q but the level comes from the confidence", signal.Name, signal.Level)
+		}
+	}
+
+	if _, err := DefaultWeights().With(map[string]int{"gut_feeling": 10}); err == nil {
+		t.Error("Expected an unknown signal to be rejected")
+	}
+	if _, err := DefaultWeights().With(map[string]int{SignalOldAge: 101}); err == nil {
+		t.Error("Expected an out of range weight to be rejected")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	old := time.Now().AddDate(0, 0, -60)
+	write := func(name string, data []byte, modified time.Time) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	corpus := []LabeledPath{
+		{Path: write("old.bin", []byte("cache data"), old), Level: "Safe"},
+		{Path: write("recent.bin", []byte("cache data"), time.Now()), Level: "Caution"},
+		{Path: write("node_modules/pkg/index.js", []byte("cache data"), old), Level: "Safe"}, // Classified Caution
+		{Path: write("Downloads/photo.bin", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), time.Now()), Level: "Risky"},
+		{Path: filepath.Join(dir, "missing"), Level: "Safe"},
+		{Path: filepath.Join(dir, "old.bin"), Level: "Fine"},
+	}
+
+	evaluation := NewDefaultSafetyClassifier().Evaluate(corpus)
+	if evaluation.Total != 6 || evaluation.Evaluated != 4 || evaluation.Correct != 3 || len(evaluation.Errors) != 2 {
+		t.Fatalf("Unexpected evaluation: %+v", evaluation)
+	}
+	if evaluation.Accuracy != 0.75 || evaluation.Confusion["Safe"]["Caution"] != 1 {
+		t.Errorf("Unexpected accuracy or confusion: %+v", evaluation)
+	}
+	if len(evaluation.Misclassifications) != 1 || evaluation.Misclassifications[0].Got != "Caution" || len(evaluation.Misclassifications[0].Signals) == 0 {
+		t.Errorf("Expected the dev cache to be misclassified, got %+v", evaluation.Misclassifications)
+	}
+
+	expected := map[string][2]float64{"Safe": {1, 0.5}, "Caution": {0.5, 1}, "Risky": {1, 1}}
+	for _, metrics := range evaluation.Levels {
+		if want := expected[metrics.Level]; metrics.Precision != want[0] || metrics.Recall != want[1] {
+			t.Errorf("Expected %s precision %.2f and recall %.2f, got %+v", metrics.Level, want[0], want[1], metrics)
+		}
+	}
+}
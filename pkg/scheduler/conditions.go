@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+)
+
+// QuietHours is a daily window in which automatic cleanups do not start.
+// A window whose end is before its start spans midnight.
+type QuietHours struct {
+	Start time.Duration // Offset from midnight
+	End   time.Duration
+}
+
+// ParseQuietHours parses "HH:MM" start and end times. Empty values disable quiet hours.
+func ParseQuietHours(start, end string) (*QuietHours, error) {
+	if start == "" && end == "" {
+		return nil, nil
+	}
+
+	var offsets [2]time.Duration
+	for i, value := range []string{start, end} {
+		parsed, err := time.Parse("15:04", value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quiet hours time %q: %w", value, err)
+		}
+		offsets[i] = time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute
+	}
+	return &QuietHours{Start: offsets[0], End: offsets[1]}, nil
+}
+
+// Contains reports whether t falls within the quiet hours
+func (q *QuietHours) Contains(t time.Time) bool {
+	if q == nil || q.Start == q.End {
+		return false
+	}
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if q.Start < q.End {
+		return offset >= q.Start && offset < q.End
+	}
+	return offset >= q.Start || offset < q.End
+}
+
+// PowerState describes the power source where the platform reports it
+type PowerState struct {
+	Known     bool `json:"known"`      // False when no power information is available
+	OnBattery bool `json:"on_battery"` // Running without external power
+	Percent   int  `json:"percent"`    // Battery charge, -1 if unknown
+}
+
+// ReadPowerState returns the current power state
+func ReadPowerState() PowerState {
+	return readPowerState()
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FreeSpace is not implemented on this platform, so free space triggers never fire
+func FreeSpace(path string) (uint64, error) {
+	return 0, fmt.Errorf("failed to read free space of %s: %w", path, errors.ErrUnsupported)
+}
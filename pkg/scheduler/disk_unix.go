@@ -0,0 +1,18 @@
+//go:build linux || darwin
+
+package scheduler
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// FreeSpace returns the bytes available to unprivileged users on the volume containing path
+func FreeSpace(path string) (uint64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("failed to read free space of %s: %w", path, err)
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+
+package scheduler
+
+import "os"
+
+// processAlive reports whether a process with the given pid exists
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
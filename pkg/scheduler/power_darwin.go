@@ -0,0 +1,36 @@
+package scheduler
+
+import (
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pmsetPercent matches the charge in pmset output, such as "85%;"
+var pmsetPercent = regexp.MustCompile(`(\d+)%`)
+
+// readPowerState asks pmset for the power source
+func readPowerState() PowerState {
+	output, err := exec.Command("pmset", "-g", "batt").Output()
+	if err != nil {
+		return PowerState{Percent: -1}
+	}
+	return parsePmset(string(output))
+}
+
+// parsePmset parses the output of pmset -g batt
+func parsePmset(output string) PowerState {
+	state := PowerState{Percent: -1}
+	switch {
+	case strings.Contains(output, "'AC Power'"):
+		state.Known = true
+	case strings.Contains(output, "'Battery Power'"):
+		state.Known = true
+		state.OnBattery = true
+	}
+	if match := pmsetPercent.FindStringSubmatch(output); match != nil {
+		state.Percent, _ = strconv.Atoi(match[1])
+	}
+	return state
+}
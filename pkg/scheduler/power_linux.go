@@ -0,0 +1,62 @@
+package scheduler
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// powerSupplyDir is where the kernel lists power supplies
+const powerSupplyDir = "/sys/class/power_supply"
+
+// readPowerState reads the power supplies from sysfs
+func readPowerState() PowerState {
+	return readPowerSupplies(powerSupplyDir)
+}
+
+// readPowerSupplies derives the power state from a power_supply directory.
+// A machine is on battery when it has a battery and no online mains supply.
+func readPowerSupplies(dir string) PowerState {
+	state := PowerState{Percent: -1}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return state
+	}
+
+	hasBattery, onMains := false, false
+	for _, entry := range entries {
+		read := func(name string) string {
+			data, _ := os.ReadFile(filepath.Join(dir, entry.Name(), name))
+			return strings.TrimSpace(string(data))
+		}
+
+		switch read("type") {
+		case "Mains", "USB":
+			state.Known = true
+			if read("online") == "1" {
+				onMains = true
+			}
+		case "Battery":
+			if read("scope") == "Device" {
+				continue // Peripheral batteries such as mice
+			}
+			state.Known = true
+			hasBattery = true
+			if percent, err := strconv.Atoi(read("capacity")); err == nil {
+				state.Percent = percent
+			}
+			if read("status") == "Discharging" {
+				state.OnBattery = true
+			}
+		}
+	}
+
+	if hasBattery && !onMains {
+		state.OnBattery = true
+	}
+	if onMains {
+		state.OnBattery = false
+	}
+	return state
+}
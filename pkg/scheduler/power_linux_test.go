@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadPowerSupplies(t *testing.T) {
+	dir := t.TempDir()
+	supply := func(name string, attributes map[string]string) {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		for attribute, value := range attributes {
+			if err := os.WriteFile(filepath.Join(dir, name, attribute), []byte(value+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	supply("AC", map[string]string{"type": "Mains", "online": "0"})
+	supply("BAT0", map[string]string{"type": "Battery", "capacity": "42", "status": "Discharging"})
+	supply("hid-mouse", map[string]string{"type": "Battery", "scope": "Device", "capacity": "5"})
+
+	state := readPowerSupplies(dir)
+	if !state.Known || !state.OnBattery || state.Percent != 42 {
+		t.Errorf("Expected discharging at 42%%, got %+v", state)
+	}
+
+	supply("AC", map[string]string{"online": "1"})
+	if state := readPowerSupplies(dir); state.OnBattery {
+		t.Errorf("Expected mains power, got %+v", state)
+	}
+
+	if state := readPowerSupplies(filepath.Join(dir, "missing")); state.Known {
+		t.Errorf("Expected an unknown state without sysfs, got %+v", state)
+	}
+}
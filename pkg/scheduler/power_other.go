@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package scheduler
+
+// readPowerState reports an unknown power state where it cannot be read
+func readPowerState() PowerState {
+	return PowerState{Percent: -1}
+}
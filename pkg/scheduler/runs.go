@@ -0,0 +1,130 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maxRunRecords is how many runs the store keeps
+const maxRunRecords = 500
+
+// TriggerKind identifies what started a run
+type TriggerKind string
+
+const (
+	TriggerCron      TriggerKind = "cron"
+	TriggerDiskSpace TriggerKind = "disk_space"
+	TriggerManual    TriggerKind = "manual"
+)
+
+// RunStatus is the outcome of a scheduled run
+type RunStatus string
+
+const (
+	RunCompleted RunStatus = "completed"
+	RunDeferred  RunStatus = "deferred" // Quiet hours or battery; retried when conditions allow
+	RunFailed    RunStatus = "failed"
+	RunNoop      RunStatus = "nothing_to_clean"
+)
+
+// RunRecord records a single scheduled cleanup
+type RunRecord struct {
+	ID                string      `json:"id"`
+	Trigger           TriggerKind `json:"trigger"`
+	Status            RunStatus   `json:"status"`
+	Reason            string      `json:"reason,omitempty"` // Why the run was deferred or failed
+	StartedAt         time.Time   `json:"started_at"`
+	FinishedAt        time.Time   `json:"finished_at"`
+	Locations         []string    `json:"locations"`
+	ScannedFiles      int         `json:"scanned_files"`
+	SelectedFiles     int         `json:"selected_files"`
+	TargetBytes       int64       `json:"target_bytes"` // 0 cleans every eligible file
+	DeletedCount      int         `json:"deleted_count"`
+	FailedCount       int         `json:"failed_count"`
+	FreedBytes        int64       `json:"freed_bytes"`
+	FreeSpaceBefore   uint64      `json:"free_space_before,omitempty"`
+	FreeSpaceAfter    uint64      `json:"free_space_after,omitempty"`
+	BackupSessionID   string      `json:"backup_session_id,omitempty"`
+	QuarantineBatchID string      `json:"quarantine_batch_id,omitempty"`
+	Power             PowerState  `json:"power"`
+	Errors            []string    `json:"errors,omitempty"`
+}
+
+// RunStore persists run records in a JSON file
+type RunStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewRunStore creates a run store backed by runs.json in dir
+func NewRunStore(dir string) (*RunStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create scheduler directory: %w", err)
+	}
+	return &RunStore{path: filepath.Join(dir, "runs.json")}, nil
+}
+
+// Append adds a record, dropping the oldest beyond maxRunRecords
+func (rs *RunStore) Append(record RunRecord) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	records, err := rs.load()
+	if err != nil {
+		return err
+	}
+	records = append(records, record)
+	if len(records) > maxRunRecords {
+		records = records[len(records)-maxRunRecords:]
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run records: %w", err)
+	}
+	tmpPath := rs.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write run records: %w", err)
+	}
+	if err := os.Rename(tmpPath, rs.path); err != nil {
+		return fmt.Errorf("failed to save run records: %w", err)
+	}
+	return nil
+}
+
+// List returns up to limit records, newest first. A non-positive limit returns all.
+func (rs *RunStore) List(limit int) ([]RunRecord, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	records, err := rs.load()
+	if err != nil {
+		return nil, err
+	}
+	newest := make([]RunRecord, 0, len(records))
+	for i := len(records) - 1; i >= 0 && (limit <= 0 || len(newest) < limit); i-- {
+		newest = append(newest, records[i])
+	}
+	return newest, nil
+}
+
+// load reads all records, oldest first
+func (rs *RunStore) load() ([]RunRecord, error) {
+	data, err := os.ReadFile(rs.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run records: %w", err)
+	}
+
+	var records []RunRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse run records: %w", err)
+	}
+	return records, nil
+}
@@ -0,0 +1,434 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cache_app/pkg/catalog"
+	"cache_app/pkg/deletion"
+	"cache_app/pkg/recommend"
+	"cache_app/pkg/safety"
+)
+
+// DefaultDiskCooldown is the minimum time between free space runs, so that a
+// volume that cannot be cleaned above the threshold is not rescanned constantly
+const DefaultDiskCooldown = 6 * time.Hour
+
+// ErrLocked is returned by Start when another scheduler owns the lock
+var ErrLocked = errors.New("another scheduler is already running")
+
+// Policy configures when the scheduler runs and what it may clean
+type Policy struct {
+	Cron              string        `json:"cron"`           // Five-field cron expression, empty disables
+	MinFreeBytes      uint64        `json:"min_free_bytes"` // Run when free space drops below, 0 disables
+	WatchPath         string        `json:"watch_path"`     // Volume checked for free space
+	Locations         []string      `json:"locations"`      // Catalog location IDs that may be cleaned
+	QuietHoursStart   string        `json:"quiet_hours_start"`
+	QuietHoursEnd     string        `json:"quiet_hours_end"`
+	SkipOnBattery     bool          `json:"skip_on_battery"`
+	MinBatteryPercent int           `json:"min_battery_percent"` // Run on battery above this charge, 0 never
+	DiskCooldown      time.Duration `json:"disk_cooldown"`
+}
+
+// ScanFunc scans a catalog location
+type ScanFunc func(location catalog.Location) (recommend.ScannedLocation, error)
+
+// CleanFunc removes the files selected by a run
+type CleanFunc func(files []string, operation string) (*deletion.DeletionResult, error)
+
+// Status describes the scheduler state
+type Status struct {
+	Started     bool          `json:"started"`
+	Busy        bool          `json:"busy"` // A run is in progress
+	NextCronRun *time.Time    `json:"next_cron_run,omitempty"`
+	Pending     []TriggerKind `json:"pending"` // Triggers waiting for quiet hours or power
+	Policy      Policy        `json:"policy"`
+}
+
+// Scheduler runs scan and cleanup cycles on a cron schedule or when free disk
+// space runs low. Only Safe items in the policy's locations are cleaned.
+type Scheduler struct {
+	mu        sync.Mutex
+	policy    Policy
+	cron      *CronSchedule
+	quiet     *QuietHours
+	catalog   *catalog.Catalog
+	scan      ScanFunc
+	clean     CleanFunc
+	runs      *RunStore
+	power     func() PowerState
+	freeSpace func(string) (uint64, error)
+	now       func() time.Time
+
+	nextCron    time.Time
+	lastDiskRun time.Time
+	pending     map[TriggerKind]bool // Triggers that fired but have not run yet
+	deferred    map[TriggerKind]bool // Pending triggers whose deferral was recorded
+	busy        bool
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// New creates a scheduler. Runs are recorded in runs.
+func New(policy Policy, locations *catalog.Catalog, scan ScanFunc, clean CleanFunc, runs *RunStore) (*Scheduler, error) {
+	s := &Scheduler{
+		policy:    policy,
+		catalog:   locations,
+		scan:      scan,
+		clean:     clean,
+		runs:      runs,
+		power:     ReadPowerState,
+		freeSpace: FreeSpace,
+		now:       time.Now,
+		pending:   make(map[TriggerKind]bool),
+		deferred:  make(map[TriggerKind]bool),
+	}
+	if s.catalog == nil {
+		s.catalog = &catalog.Catalog{}
+	}
+	if s.policy.DiskCooldown <= 0 {
+		s.policy.DiskCooldown = DefaultDiskCooldown
+	}
+	if s.policy.WatchPath == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			s.policy.WatchPath = home
+		}
+	}
+
+	var err error
+	if policy.Cron != "" {
+		if s.cron, err = ParseCron(policy.Cron); err != nil {
+			return nil, err
+		}
+	}
+	if s.quiet, err = ParseQuietHours(policy.QuietHoursStart, policy.QuietHoursEnd); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Start checks the triggers every interval until Stop is called. Only one
+// scheduler may run per run store, so the GUI and a headless daemon do not
+// clean twice. onRun receives every recorded run, onError failures to record.
+func (s *Scheduler) Start(interval time.Duration, onRun func(RunRecord), onError func(error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return nil
+	}
+	if err := s.runs.lock(); err != nil {
+		return err
+	}
+
+	if s.cron != nil {
+		s.nextCron = s.cron.Next(s.now())
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	s.stop, s.done = stop, done
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				for _, record := range s.tick(s.now(), onError) {
+					if onRun != nil {
+						onRun(record)
+					}
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops the scheduler started by Start and waits for a running cleanup to finish
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+	s.runs.unlock()
+}
+
+// Status returns the current scheduler state
+func (s *Scheduler) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := Status{
+		Started: s.stop != nil,
+		Busy:    s.busy,
+		Pending: make([]TriggerKind, 0, len(s.pending)),
+		Policy:  s.policy,
+	}
+	if status.Started && !s.nextCron.IsZero() {
+		next := s.nextCron
+		status.NextCronRun = &next
+	}
+	for _, trigger := range []TriggerKind{TriggerCron, TriggerDiskSpace} {
+		if s.pending[trigger] {
+			status.Pending = append(status.Pending, trigger)
+		}
+	}
+	return status
+}
+
+// RunNow runs a cleanup immediately, ignoring quiet hours and power state
+func (s *Scheduler) RunNow() (RunRecord, error) {
+	if !s.setBusy() {
+		return RunRecord{}, fmt.Errorf("a scheduled cleanup is already running")
+	}
+	defer s.clearBusy()
+
+	record := s.run(TriggerManual, 0, s.power())
+	return record, s.runs.Append(record)
+}
+
+// tick fires due triggers and runs those whose conditions allow it
+func (s *Scheduler) tick(now time.Time, onError func(error)) []RunRecord {
+	s.mu.Lock()
+	if s.cron != nil && !s.nextCron.IsZero() && !now.Before(s.nextCron) {
+		s.pending[TriggerCron] = true
+		s.nextCron = s.cron.Next(now)
+	}
+	checkDisk := s.policy.MinFreeBytes > 0 && now.Sub(s.lastDiskRun) >= s.policy.DiskCooldown
+	s.mu.Unlock()
+
+	var diskTarget int64
+	if checkDisk {
+		free, err := s.freeSpace(s.policy.WatchPath)
+		s.mu.Lock()
+		if err == nil && free < s.policy.MinFreeBytes {
+			s.pending[TriggerDiskSpace] = true
+			diskTarget = int64(s.policy.MinFreeBytes - free)
+		} else {
+			// Space recovered on its own
+			delete(s.pending, TriggerDiskSpace)
+			delete(s.deferred, TriggerDiskSpace)
+		}
+		s.mu.Unlock()
+	}
+
+	var records []RunRecord
+	cronRan := false
+	for _, trigger := range []TriggerKind{TriggerCron, TriggerDiskSpace} {
+		s.mu.Lock()
+		pending := s.pending[trigger]
+		s.mu.Unlock()
+		// After a cron run free space is checked again on the next tick
+		if !pending || (trigger == TriggerDiskSpace && cronRan) || !s.setBusy() {
+			continue
+		}
+
+		// Cron runs clean everything eligible, free space runs only what is missing
+		var target int64
+		if trigger == TriggerDiskSpace {
+			target = diskTarget
+		}
+		record, ran := s.attempt(trigger, now, target)
+		cronRan = cronRan || (ran && trigger == TriggerCron)
+		if ran || record.Status == RunDeferred {
+			if err := s.runs.Append(record); err != nil && onError != nil {
+				onError(err)
+			}
+			records = append(records, record)
+		}
+		s.clearBusy()
+	}
+	return records
+}
+
+// attempt runs a pending trigger, or defers it while quiet hours or battery
+// power forbid cleaning. A deferral is recorded once per pending trigger.
+func (s *Scheduler) attempt(trigger TriggerKind, now time.Time, target int64) (RunRecord, bool) {
+	power := s.power()
+	if reason := s.blockedReason(now, power); reason != "" {
+		s.mu.Lock()
+		alreadyRecorded := s.deferred[trigger]
+		s.deferred[trigger] = true
+		s.mu.Unlock()
+		if alreadyRecorded {
+			return RunRecord{}, false
+		}
+		return RunRecord{
+			ID:         fmt.Sprintf("run_%d", now.UnixNano()),
+			Trigger:    trigger,
+			Status:     RunDeferred,
+			Reason:     reason,
+			StartedAt:  now,
+			FinishedAt: now,
+			Locations:  s.policy.Locations,
+			Power:      power,
+		}, false
+	}
+
+	record := s.run(trigger, target, power)
+	s.mu.Lock()
+	delete(s.pending, trigger)
+	delete(s.deferred, trigger)
+	if trigger == TriggerDiskSpace {
+		s.lastDiskRun = now
+	}
+	s.mu.Unlock()
+	return record, true
+}
+
+// blockedReason explains why cleaning may not start now, or returns ""
+func (s *Scheduler) blockedReason(now time.Time, power PowerState) string {
+	if s.quiet.Contains(now) {
+		return fmt.Sprintf("quiet hours %s-%s", s.policy.QuietHoursStart, s.policy.QuietHoursEnd)
+	}
+	if s.policy.SkipOnBattery && power.Known && power.OnBattery {
+		if s.policy.MinBatteryPercent <= 0 || power.Percent < s.policy.MinBatteryPercent {
+			return "running on battery power"
+		}
+	}
+	return ""
+}
+
+// run scans the policy's locations and cleans their Safe items, freeing at
+// least target bytes when possible or everything eligible when target is 0
+func (s *Scheduler) run(trigger TriggerKind, target int64, power PowerState) RunRecord {
+	record := RunRecord{
+		ID:          fmt.Sprintf("run_%d", s.now().UnixNano()),
+		Trigger:     trigger,
+		StartedAt:   s.now(),
+		Locations:   s.policy.Locations,
+		TargetBytes: target,
+		Power:       power,
+	}
+	finish := func(status RunStatus, reason string) RunRecord {
+		record.Status = status
+		record.Reason = reason
+		record.FinishedAt = s.now()
+		return record
+	}
+	if free, err := s.freeSpace(s.policy.WatchPath); err == nil {
+		record.FreeSpaceBefore = free
+	}
+
+	if len(s.policy.Locations) == 0 {
+		return finish(RunNoop, "no locations are enabled for automatic cleanup")
+	}
+
+	var scanned []recommend.ScannedLocation
+	for _, id := range s.policy.Locations {
+		location, ok := s.catalog.Get(id)
+		if !ok {
+			record.Errors = append(record.Errors, fmt.Sprintf("unknown location: %s", id))
+			continue
+		}
+		result, err := s.scan(*location)
+		if err != nil {
+			record.Errors = append(record.Errors, fmt.Sprintf("failed to scan %s: %v", id, err))
+			continue
+		}
+		for _, file := range result.Files {
+			if !file.IsDir {
+				record.ScannedFiles++
+			}
+		}
+		scanned = append(scanned, result)
+	}
+	if len(scanned) == 0 {
+		return finish(RunFailed, "no location could be scanned")
+	}
+
+	recommendation := recommend.NewEngine(s.catalog).Recommend(scanned, recommend.Options{
+		TargetBytes: target,
+		MaxLevel:    safety.Safe,
+	})
+	files := make([]string, 0, len(recommendation.Items))
+	for _, item := range recommendation.Items {
+		files = append(files, item.Path)
+		record.SelectedFiles += item.FileCount
+	}
+	if len(files) == 0 {
+		return finish(RunNoop, "no Safe items found")
+	}
+
+	result, err := s.clean(files, "scheduled_"+record.ID)
+	if result != nil {
+		record.DeletedCount = result.DeletedCount
+		record.FailedCount = result.FailedCount
+		record.FreedBytes = result.DeletedSize
+		record.BackupSessionID = result.BackupSessionID
+		record.QuarantineBatchID = result.QuarantineBatchID
+	}
+	if free, err := s.freeSpace(s.policy.WatchPath); err == nil {
+		record.FreeSpaceAfter = free
+	}
+	if err != nil {
+		return finish(RunFailed, err.Error())
+	}
+	return finish(RunCompleted, "")
+}
+
+// setBusy marks a run as in progress, or returns false if one already is
+func (s *Scheduler) setBusy() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.busy {
+		return false
+	}
+	s.busy = true
+	return true
+}
+
+// clearBusy marks the run as finished
+func (s *Scheduler) clearBusy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.busy = false
+}
+
+// lock takes the scheduler lock next to the run records. A lock left by a
+// process that no longer exists is taken over.
+func (rs *RunStore) lock() error {
+	path := rs.lockPath()
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			_, err = file.WriteString(strconv.Itoa(os.Getpid()))
+			file.Close()
+			return err
+		}
+		if !os.IsExist(err) {
+			return fmt.Errorf("failed to create scheduler lock: %w", err)
+		}
+
+		data, _ := os.ReadFile(path)
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && processAlive(pid) {
+			return fmt.Errorf("%w (pid %d)", ErrLocked, pid)
+		}
+		os.Remove(path)
+	}
+	return ErrLocked
+}
+
+// unlock releases the scheduler lock
+func (rs *RunStore) unlock() {
+	os.Remove(rs.lockPath())
+}
+
+// lockPath returns the path of the scheduler lock file
+func (rs *RunStore) lockPath() string {
+	return filepath.Join(filepath.Dir(rs.path), "scheduler.pid")
+}
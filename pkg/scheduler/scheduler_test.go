@@ -0,0 +1,228 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"cache_app/pkg/catalog"
+	"cache_app/pkg/deletion"
+	"cache_app/pkg/recommend"
+	"cache_app/pkg/safety"
+)
+
+func TestCronSchedule(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 6, day, hour, minute, 0, 0, time.UTC) // 2024-06-01 is a Saturday
+	}
+
+	tests := []struct {
+		expression string
+		from       time.Time
+		want       time.Time
+	}{
+		{"0 3 * * 0", at(1, 12, 0), at(2, 3, 0)},
+		{"@daily", at(1, 12, 0), at(2, 0, 0)},
+		{"*/15 * * * *", at(1, 12, 7), at(1, 12, 15)},
+		{"30 9-17/4 * * 1-5", at(1, 12, 0), at(3, 9, 30)},
+		{"0 0 * * 7", at(3, 0, 0), at(9, 0, 0)},
+		// Day of month and day of week both restricted: either may match
+		{"0 0 15 * 1", at(1, 0, 0), at(3, 0, 0)},
+	}
+	for _, test := range tests {
+		schedule, err := ParseCron(test.expression)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", test.expression, err)
+		}
+		if next := schedule.Next(test.from); !next.Equal(test.want) {
+			t.Errorf("%q after %v: expected %v, got %v", test.expression, test.from, test.want, next)
+		}
+	}
+
+	for _, invalid := range []string{"", "* * * *", "60 * * * *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "@hourlyish"} {
+		if _, err := ParseCron(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	quiet, err := ParseQuietHours("22:00", "07:00")
+	if err != nil {
+		t.Fatalf("Failed to parse quiet hours: %v", err)
+	}
+	for hour, want := range map[int]bool{21: false, 22: true, 2: true, 7: false, 12: false} {
+		if got := quiet.Contains(time.Date(2024, 6, 1, hour, 0, 0, 0, time.Local)); got != want {
+			t.Errorf("Hour %d: expected quiet %v, got %v", hour, want, got)
+		}
+	}
+
+	if none, err := ParseQuietHours("", ""); err != nil || none.Contains(time.Now()) {
+		t.Errorf("Expected no quiet hours, got %v, %v", none, err)
+	}
+	if _, err := ParseQuietHours("22:00", ""); err == nil {
+		t.Error("Expected a missing end to be rejected")
+	}
+}
+
+func TestRunStore(t *testing.T) {
+	runs, err := NewRunStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create run store: %v", err)
+	}
+	for _, id := range []string{"first", "second", "third"} {
+		if err := runs.Append(RunRecord{ID: id}); err != nil {
+			t.Fatalf("Failed to append run: %v", err)
+		}
+	}
+
+	records, err := runs.List(2)
+	if err != nil {
+		t.Fatalf("Failed to list runs: %v", err)
+	}
+	if len(records) != 2 || records[0].ID != "third" || records[1].ID != "second" {
+		t.Errorf("Expected the two newest runs, got %+v", records)
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	locations, err := catalog.Parse([]byte(`{
+	  "user_caches": [
+	    {"id": "browser", "name": "Browser Cache", "path": "/cache/browser", "safety_level": "safe", "cleanup_risk": "low"},
+	    {"id": "mail", "name": "Mail", "path": "/cache/mail", "safety_level": "safe", "cleanup_risk": "low"}
+	  ]
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to parse catalog: %v", err)
+	}
+
+	file := func(path string, size int64, level safety.SafetyLevel) recommend.ScannedFile {
+		return recommend.ScannedFile{
+			Path:           path,
+			Size:           size,
+			Classification: &safety.SafetyClassification{Level: level, Confidence: 90},
+		}
+	}
+	var scannedIDs []string
+	scan := func(location catalog.Location) (recommend.ScannedLocation, error) {
+		scannedIDs = append(scannedIDs, location.ID)
+		return recommend.ScannedLocation{ID: location.ID, Name: location.Name, Path: location.Path, Files: []recommend.ScannedFile{
+			file("/cache/browser/a", 400, safety.Safe),
+			file("/cache/browser/b", 300, safety.Safe),
+			file("/cache/browser/c", 200, safety.Caution),
+			file("/cache/browser/d", 100, safety.Risky),
+		}}, nil
+	}
+	var cleaned [][]string
+	clean := func(files []string, operation string) (*deletion.DeletionResult, error) {
+		cleaned = append(cleaned, files)
+		return &deletion.DeletionResult{DeletedCount: len(files), DeletedSize: 100}, nil
+	}
+
+	newScheduler := func(t *testing.T, policy Policy) *Scheduler {
+		runs, err := NewRunStore(t.TempDir())
+		if err != nil {
+			t.Fatalf("Failed to create run store: %v", err)
+		}
+		s, err := New(policy, locations, scan, clean, runs)
+		if err != nil {
+			t.Fatalf("Failed to create scheduler: %v", err)
+		}
+		s.power = func() PowerState { return PowerState{Known: true, Percent: 100} }
+		s.freeSpace = func(string) (uint64, error) { return 1 << 40, nil }
+		scannedIDs, cleaned = nil, nil
+		return s
+	}
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 6, 1, hour, minute, 0, 0, time.Local)
+	}
+
+	t.Run("CronDeferredByQuietHours", func(t *testing.T) {
+		s := newScheduler(t, Policy{Cron: "0 3 * * *", Locations: []string{"browser"}, QuietHoursStart: "01:00", QuietHoursEnd: "06:00"})
+		s.nextCron = s.cron.Next(at(2, 0))
+
+		records := s.tick(at(3, 0), nil)
+		if len(records) != 1 || records[0].Status != RunDeferred || len(cleaned) != 0 {
+			t.Fatalf("Expected a deferred run, got %+v", records)
+		}
+		if records := s.tick(at(4, 0), nil); len(records) != 0 {
+			t.Errorf("Expected the deferral to be recorded once, got %+v", records)
+		}
+
+		records = s.tick(at(6, 0), nil)
+		if len(records) != 1 || records[0].Status != RunCompleted || records[0].Trigger != TriggerCron {
+			t.Fatalf("Expected the cron run after quiet hours, got %+v", records)
+		}
+		// Only the Safe files are cleaned
+		if len(cleaned) != 1 || len(cleaned[0]) != 2 || records[0].SelectedFiles != 2 || records[0].ScannedFiles != 4 {
+			t.Errorf("Expected the two Safe files to be cleaned, got %v (%+v)", cleaned, records[0])
+		}
+
+		stored, err := s.runs.List(0)
+		if err != nil || len(stored) != 2 {
+			t.Errorf("Expected the deferred and completed runs to be stored, got %+v, %v", stored, err)
+		}
+		if len(s.Status().Pending) != 0 {
+			t.Errorf("Expected no pending triggers, got %v", s.Status().Pending)
+		}
+	})
+
+	t.Run("DiskSpace", func(t *testing.T) {
+		s := newScheduler(t, Policy{MinFreeBytes: 1000, Locations: []string{"browser", "unknown"}})
+		s.freeSpace = func(string) (uint64, error) { return 700, nil }
+
+		records := s.tick(at(12, 0), nil)
+		if len(records) != 1 || records[0].Trigger != TriggerDiskSpace || records[0].TargetBytes != 300 {
+			t.Fatalf("Expected a free space run for 300 bytes, got %+v", records)
+		}
+		// The largest Safe file covers the missing space
+		if len(cleaned) != 1 || len(cleaned[0]) != 1 || cleaned[0][0] != "/cache/browser/a" {
+			t.Errorf("Expected only the largest Safe file, got %v", cleaned)
+		}
+		if len(records[0].Errors) != 1 {
+			t.Errorf("Expected the unknown location to be reported, got %v", records[0].Errors)
+		}
+
+		if records := s.tick(at(13, 0), nil); len(records) != 0 {
+			t.Errorf("Expected no run during the cooldown, got %+v", records)
+		}
+	})
+
+	t.Run("Battery", func(t *testing.T) {
+		s := newScheduler(t, Policy{Locations: []string{"browser"}, SkipOnBattery: true, MinBatteryPercent: 50})
+		s.power = func() PowerState { return PowerState{Known: true, OnBattery: true, Percent: 20} }
+		if reason := s.blockedReason(at(12, 0), s.power()); reason == "" {
+			t.Error("Expected a low battery to block cleaning")
+		}
+		s.power = func() PowerState { return PowerState{Known: true, OnBattery: true, Percent: 80} }
+		if reason := s.blockedReason(at(12, 0), s.power()); reason != "" {
+			t.Errorf("Expected a charged battery to allow cleaning, got %q", reason)
+		}
+
+		// Manual runs ignore the power state
+		s.power = func() PowerState { return PowerState{Known: true, OnBattery: true, Percent: 5} }
+		record, err := s.RunNow()
+		if err != nil || record.Status != RunCompleted || record.Trigger != TriggerManual {
+			t.Errorf("Expected a completed manual run, got %+v, %v", record, err)
+		}
+		if len(scannedIDs) != 1 || scannedIDs[0] != "browser" {
+			t.Errorf("Expected only the allowed location to be scanned, got %v", scannedIDs)
+		}
+	})
+
+	t.Run("Lock", func(t *testing.T) {
+		s := newScheduler(t, Policy{Locations: []string{"browser"}})
+		if err := s.Start(time.Hour, nil, nil); err != nil {
+			t.Fatalf("Failed to start scheduler: %v", err)
+		}
+		defer s.Stop()
+
+		other, err := New(Policy{}, locations, scan, clean, s.runs)
+		if err != nil {
+			t.Fatalf("Failed to create scheduler: %v", err)
+		}
+		if err := other.Start(time.Hour, nil, nil); !errors.Is(err, ErrLocked) {
+			t.Errorf("Expected the second scheduler to be locked out, got %v", err)
+		}
+	})
+}